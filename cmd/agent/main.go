@@ -9,10 +9,8 @@ import (
 	"time"
 
 	"github.com/angryscorp/alert-metrics/internal/buildinfo"
-	"github.com/angryscorp/alert-metrics/internal/crypto"
 	"github.com/angryscorp/alert-metrics/internal/domain"
 	grpcclient "github.com/angryscorp/alert-metrics/internal/grpc/client"
-	cryptohttp "github.com/angryscorp/alert-metrics/internal/http/crypto"
 	"github.com/angryscorp/alert-metrics/internal/http/realip"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricreporter"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/shutdown"
@@ -20,11 +18,10 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/angryscorp/alert-metrics/internal/config/agent"
-	"github.com/angryscorp/alert-metrics/internal/http/gzipper"
-	"github.com/angryscorp/alert-metrics/internal/http/hash"
-	"github.com/angryscorp/alert-metrics/internal/http/retry"
+	"github.com/angryscorp/alert-metrics/internal/http/reporttransport"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricmonitor"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricworker"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
 var (
@@ -58,54 +55,42 @@ func main() {
 	select {}
 }
 
-func buildTransport(cryptoKeyPath, hashKey string, retryIntervals []time.Duration, logger zerolog.Logger) http.RoundTripper {
-	// Base transport
-	transport := http.DefaultTransport
-
-	// Real IP transport
-	transport = realip.New(transport)
-
-	// Gzip transport
-	transport = gzipper.NewGzipTransport(transport)
-
-	// Crypto transport
-	if cryptoKeyPath != "" {
-		encryptor, err := crypto.NewPublicKeyEncrypter(cryptoKeyPath)
-		if err != nil {
-			log.Fatal(err.Error())
-		}
-		transport = cryptohttp.EncryptorMiddleware(encryptor)(transport)
-	}
-
-	// Hash transport
-	transport = hash.NewHashTransport(transport, hashKey)
-
-	// Retry transport
-	transport = retry.New(transport, retryIntervals, logger)
-
-	return transport
-}
-
 func initMetricReporter(cfg agent.Config) domain.MetricReporter {
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 
+	agentID := cfg.AgentID
+	if agentID == "" {
+		agentID, _ = os.Hostname()
+	}
+
 	if cfg.UseGRPC {
-		metricReporter, err := grpcclient.New("sf", logger)
+		agentInfo := domain.AgentInfo{ID: agentID, Address: realip.LocalIP(), Version: buildVersion}
+		metricReporter, err := grpcclient.New(cfg.Addresses(), agentInfo, logger)
 		if err != nil {
 			log.Fatal(err.Error())
 		}
 		return metricReporter
 	}
 
-	return metricreporter.NewHTTPMetricReporter(
-		"http://"+cfg.Address,
-		&http.Client{
-			Transport: buildTransport(
-				cfg.PathToCryptoKey,
-				cfg.HashKey,
-				[]time.Duration{time.Second, time.Second * 3, time.Second * 5},
-				logger,
-			),
+	addresses := cfg.Addresses()
+	if len(addresses) == 0 {
+		log.Fatal("no server address")
+	}
+
+	transport, err := reporttransport.New(
+		reporttransport.Options{
+			Addresses:     addresses,
+			Agent:         domain.AgentInfo{ID: agentID, Version: buildVersion},
+			CryptoKeyPath: cfg.PathToCryptoKey,
+			HashKey:       cfg.HashKey,
+			Backoff:       resilience.Backoff{Retries: 3, Initial: time.Second, Max: 5 * time.Second, Jitter: 0.2},
+			Breaker:       resilience.NewBreaker(5, 30*time.Second),
 		},
+		logger,
 	)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	return metricreporter.NewHTTPMetricReporter("http://"+addresses[0], &http.Client{Transport: transport})
 }
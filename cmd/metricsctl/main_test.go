@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/kvmetricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+// writeFileStorage writes the metrics to a file storage, the first one into the snapshot and the others into the WAL.
+func writeFileStorage(t *testing.T, path string, metrics ...domain.Metric) {
+	t.Helper()
+	ctx := context.Background()
+	storage, err := metricstorage.NewFileMetricStorage(
+		metricstorage.NewMemoryMetricStorage(), zerolog.Nop(), 0, path, true, metricstorage.FsyncAlways, snapshotsKept)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.UpdateMetric(ctx, metrics[0]))
+	storage.Compact()
+	require.NoError(t, storage.UpdateMetrics(ctx, metrics[1:]))
+}
+
+// readFiles returns the contents of all files in the directory.
+func readFiles(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		files[entry.Name()] = data
+	}
+	return files
+}
+
+func assertMetrics(t *testing.T, storage domain.MetricStorage, alloc float64, pollCount int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	metric, found, err := storage.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, alloc, *metric.Value)
+
+	metric, found, err = storage.GetMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, pollCount, *metric.Delta)
+}
+
+func TestMigrate_FileToBolt(t *testing.T) {
+	sourceDir := t.TempDir()
+	source := filepath.Join(sourceDir, "metrics.dump")
+	writeFileStorage(t, source, gauge("Alloc", 1.5), counter("PollCount", 5), counter("PollCount", 2))
+	files := readFiles(t, sourceDir)
+
+	target := filepath.Join(t.TempDir(), "metrics.db")
+	logger := zerolog.Nop()
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"migrate", "--from", "file://" + source, "--to", "bolt://" + target}, &out, &logger))
+	assert.Equal(t, "migrated 1 gauges and 1 counters, 0 replaced, 2 verified\n", out.String())
+
+	assert.Equal(t, files, readFiles(t, sourceDir), "the source files are left as they are")
+
+	storage, err := kvmetricstorage.New(target, &logger)
+	require.NoError(t, err)
+	defer func() { _ = storage.Close() }()
+	assertMetrics(t, storage, 1.5, 7)
+}
+
+func TestMigrate_BoltToFile(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	source := filepath.Join(t.TempDir(), "metrics.db")
+	storage, err := kvmetricstorage.New(source, &logger)
+	require.NoError(t, err)
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 2), counter("PollCount", 3)}))
+	require.NoError(t, storage.Close())
+
+	// The target has metrics already, they are replaced
+	target := filepath.Join(t.TempDir(), "metrics.dump")
+	writeFileStorage(t, target, gauge("Alloc", 1), counter("PollCount", 10))
+
+	var out bytes.Buffer
+	args := []string{"migrate", "--from", "bolt://" + source, "--to", "file://" + target}
+	assert.Error(t, run(args, &out, &logger), "a target with metrics is only overwritten on request")
+	require.NoError(t, run(append(args, "--overwrite"), &out, &logger))
+
+	restored, err := metricstorage.ReadFileMetricStorage(target, snapshotsKept, logger)
+	require.NoError(t, err)
+	assertMetrics(t, restored, 2, 3)
+}
+
+func TestMigrate_MissingSource(t *testing.T) {
+	logger := zerolog.Nop()
+	target := filepath.Join(t.TempDir(), "metrics.dump")
+	writeFileStorage(t, target, gauge("Alloc", 1), counter("PollCount", 10))
+	files := readFiles(t, filepath.Dir(target))
+
+	dir := t.TempDir()
+	for _, from := range []string{"file://" + filepath.Join(dir, "missing.dump"), "bolt://" + filepath.Join(dir, "missing.db")} {
+		t.Run(from, func(t *testing.T) {
+			err := run([]string{"migrate", "--from", from, "--to", "file://" + target, "--overwrite"}, &bytes.Buffer{}, &logger)
+			assert.ErrorIs(t, err, os.ErrNotExist)
+			assert.Equal(t, files, readFiles(t, filepath.Dir(target)), "the target is not touched")
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no source is created")
+}
+
+func TestRun_Usage(t *testing.T) {
+	logger := zerolog.Nop()
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"export"}},
+		{name: "missing target", args: []string{"migrate", "--from", "file:///tmp/metrics.dump"}},
+		{name: "same storages", args: []string{"migrate", "--from", "bolt:///tmp/metrics.db", "--to", "bolt:///tmp/metrics.db"}},
+		{name: "unsupported storage", args: []string{"migrate", "--from", "redis://localhost", "--to", "bolt:///tmp/metrics.db"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, run(tt.args, &bytes.Buffer{}, &logger))
+		})
+	}
+}
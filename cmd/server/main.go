@@ -1,10 +1,15 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,20 +21,39 @@ import (
 
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/shutdown"
 
+	grpcclient "github.com/angryscorp/alert-metrics/internal/grpc/client"
 	grpcserver "github.com/angryscorp/alert-metrics/internal/grpc/server"
 
 	"github.com/angryscorp/alert-metrics/internal/buildinfo"
 	"github.com/angryscorp/alert-metrics/internal/config/server"
 	"github.com/angryscorp/alert-metrics/internal/crypto"
 	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/agentinfo"
 	cryptohttp "github.com/angryscorp/alert-metrics/internal/http/crypto"
 	"github.com/angryscorp/alert-metrics/internal/http/gzipper"
 	"github.com/angryscorp/alert-metrics/internal/http/handler"
 	"github.com/angryscorp/alert-metrics/internal/http/hash"
 	"github.com/angryscorp/alert-metrics/internal/http/logger"
+	"github.com/angryscorp/alert-metrics/internal/http/realip"
+	"github.com/angryscorp/alert-metrics/internal/http/reporttransport"
 	"github.com/angryscorp/alert-metrics/internal/http/router"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/alerting"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/anomaly"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/cardinalitylimiter"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/cluster"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/dbmetricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/federation"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/heartbeat"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/history"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/kvmetricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metriccache"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metrichistory"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricreaper"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricreporter"
 	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/rules"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/silence"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
 var (
@@ -50,12 +74,181 @@ func main() {
 
 	zeroLogger := zerolog.New(os.Stdout).With().Timestamp().Logger()
 
-	store, err := storeSelector(config, &zeroLogger)
+	shutdownCh := shutdown.NewGracefulShutdownNotifier()
+
+	// The workers finishing with a last round on shutdown are awaited before exiting
+	var workers sync.WaitGroup
+
+	store, err := storeSelector(config, shutdownCh, &workers, &zeroLogger)
+	if err != nil {
+		panic(err)
+	}
+	rollupStore, err := rollupStoreSelector(config, store)
 	if err != nil {
 		panic(err)
 	}
+	// A clustered server notifies only while it leads the cluster
+	clustered, _ := store.(*cluster.Storage)
+	var election *cluster.Election
+	var leadership domain.Leadership
+	if clustered != nil {
+		election = cluster.NewElection(clusterNodeName(config), 3*time.Duration(config.ClusterSyncIntervalInSeconds)*time.Second)
+		leadership = election
+	}
+
+	limitedStore, err := cardinalitylimiter.New(
+		store,
+		cardinalitylimiter.Limits{
+			MaxSeries:          config.MaxSeries,
+			MaxSeriesPerPrefix: config.MaxSeriesPerPrefix,
+			MaxSeriesPerTenant: config.MaxSeriesPerTenant,
+		},
+		&zeroLogger,
+	)
+	if err != nil {
+		panic(err)
+	}
+	store = limitedStore
+
+	metricHistory, err := newMetricHistory(config, store, rollupStore, &zeroLogger)
+	if err != nil {
+		panic(err)
+	}
+	go metricHistory.Run(shutdownCh)
+	store = metricHistory
+
+	silenceStore, err := silenceStoreSelector(config)
+	if err != nil {
+		panic(err)
+	}
+
+	historyStore, err := historyStoreSelector(config)
+	if err != nil {
+		panic(err)
+	}
+	alertHistory := history.NewRecorder(historyStore, &zeroLogger)
+
+	silencer, err := silence.New(context.Background(), silenceStore, alertHistory, &zeroLogger)
+	if err != nil {
+		panic(err)
+	}
+
+	ruleStore, err := ruleStoreSelector(config)
+	if err != nil {
+		panic(err)
+	}
+
+	templates, err := newTemplates(config, store, ruleStore)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	routes, err := newRouter(config, templates, silencer, alertHistory, &zeroLogger)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	go routes.Run(shutdownCh)
+
+	notifiers := []domain.AlertNotifier{routes}
+	var alertmanager *alerting.AlertmanagerNotifier
+	if config.AlertmanagerURL != "" {
+		alertmanager = alerting.NewAlertmanagerNotifier(config.AlertmanagerURL, templates, &zeroLogger)
+		notifiers = append(notifiers, alertmanager)
+	}
+
+	dispatcher := alerting.NewDispatcher(
+		alerting.NewMultiNotifier(notifiers...),
+		silencer,
+		alertHistory,
+		leadership,
+		alerting.GroupingOptions{
+			GroupBy:        splitList(config.AlertGroupBy),
+			GroupWait:      time.Duration(config.AlertGroupWaitInSeconds) * time.Second,
+			GroupInterval:  time.Duration(config.AlertGroupIntervalInSeconds) * time.Second,
+			RepeatInterval: time.Duration(config.AlertRepeatIntervalInSeconds) * time.Second,
+		},
+		&zeroLogger,
+	)
+	go dispatcher.Run(shutdownCh)
+
+	alertManager := alerting.New(dispatcher, silencer, alertHistory, &zeroLogger)
+	if alertmanager != nil {
+		go alertmanager.Run(alertManager, leadership, shutdownCh)
+	}
+
+	var anomalies domain.AnomalyReporter
+	if config.AnomalyThreshold > 0 {
+		detector := anomaly.New(
+			store,
+			anomaly.Options{
+				Threshold: config.AnomalyThreshold,
+				Alpha:     config.AnomalyAlpha,
+				Seasonal:  config.AnomalySeasonal,
+			},
+			alertManager,
+			&zeroLogger,
+		)
+		store = detector
+		anomalies = detector
+	}
+
+	if config.MetricTTLInSeconds > 0 {
+		reaper := metricreaper.New(store, time.Duration(config.MetricTTLInSeconds)*time.Second, &zeroLogger)
+		go reaper.Run(shutdownCh)
+	}
+
+	heartbeats := heartbeat.New(
+		time.Duration(config.AgentReportIntervalInSeconds)*time.Second,
+		config.AgentMissedReports,
+		alertManager,
+		&zeroLogger,
+	)
+	go heartbeats.Run(shutdownCh)
+
+	// The replicated metrics are merged through all decorators, like local updates
+	var member domain.ClusterMember
+	if clustered != nil {
+		node, err := clusterNode(config, clustered, store, election, heartbeats, &zeroLogger)
+		if err != nil {
+			panic(err)
+		}
+		go node.Run(shutdownCh)
+		member = node
+	}
+
+	ruleEngine, err := rules.New(context.Background(), ruleStore, store, metricHistory, alertManager, &zeroLogger)
+	if err != nil {
+		panic(err)
+	}
+	go ruleEngine.Run(time.Duration(config.RuleEvalIntervalInSeconds)*time.Second, shutdownCh)
+
+	if config.ForwardAddress != "" {
+		forwarder, err := newForwarder(config, store, zeroLogger)
+		if err != nil {
+			panic(err)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			forwarder.Run(time.Duration(config.ForwardIntervalInSeconds)*time.Second, shutdownCh)
+		}()
+	}
+
+	deps := serverDeps{
+		store:       store,
+		cardinality: limitedStore,
+		metrics:     metricHistory,
+		heartbeats:  heartbeats,
+		alerts:      alertManager,
+		anomalies:   anomalies,
+		silences:    silencer,
+		rules:       ruleEngine,
+		history:     alertHistory,
+		templates:   templates,
+		escalations: routes,
+		cluster:     member,
+	}
 
-	shutdownCh := shutdown.NewGracefulShutdownNotifier()
 	serverCount := 1 // HTTP always running
 	if config.UseGRPC {
 		serverCount = 2 // + gRPC server
@@ -68,7 +261,7 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := runHTTPServer(config, store, zeroLogger, shutdownCh); err != nil {
+		if err := runHTTPServer(config, deps, zeroLogger, shutdownCh); err != nil {
 			errChan <- fmt.Errorf("HTTP server error: %w", err)
 		}
 	}()
@@ -78,7 +271,7 @@ func main() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := runGRPCServer(config, store, zeroLogger, shutdownCh); err != nil {
+			if err := runGRPCServer(config, deps, zeroLogger, shutdownCh); err != nil {
 				errChan <- fmt.Errorf("gRPC server error: %w", err)
 			}
 		}()
@@ -94,38 +287,326 @@ func main() {
 			log.Printf("Server error: %v", err)
 		}
 	}
+
+	// A server that failed to start leaves the workers running
+	select {
+	case <-shutdownCh:
+		workers.Wait()
+	default:
+	}
 }
 
-func storeSelector(config server.Config, logger *zerolog.Logger) (domain.MetricStorage, error) {
+// serverDeps holds the components shared by the HTTP and gRPC servers.
+type serverDeps struct {
+	store       domain.MetricStorage
+	cardinality domain.CardinalityReporter
+	metrics     *metrichistory.Store
+	heartbeats  *heartbeat.Tracker
+	alerts      *alerting.Manager
+	anomalies   domain.AnomalyReporter
+	silences    *silence.Silencer
+	rules       *rules.Engine
+	history     *history.Recorder
+	templates   *alerting.Templates
+	escalations *alerting.Router
+	cluster     domain.ClusterMember
+}
+
+func storeSelector(
+	config server.Config,
+	shutdownCh <-chan struct{},
+	workers *sync.WaitGroup,
+	logger *zerolog.Logger,
+) (domain.MetricStorage, error) {
+	if config.ClusterPeers != "" {
+		return clusterStorage(config, logger)
+	}
+
 	if config.DatabaseDSN != "" {
 		if err := dbmetricstorage.Migrate(config.DatabaseDSN); err != nil {
 			return nil, fmt.Errorf("failed to migrate database: %w", err)
 		}
 
-		retryIntervals := []time.Duration{time.Second, time.Second * 3, time.Second * 5}
+		backoff := resilience.Backoff{Retries: 3, Initial: time.Second, Max: 5 * time.Second, Jitter: 0.2}
+		breaker := resilience.NewBreaker(5, 30*time.Second)
 		var dbStore domain.MetricStorage
 		dbStore, err := dbmetricstorage.New(config.DatabaseDSN, logger)
 		if err != nil {
 			return nil, err
 		}
 
-		return dbmetricstorage.NewRetryableDBStorage(dbStore, retryIntervals, logger), nil
+		retryableStore := dbmetricstorage.NewRetryableDBStorage(dbStore, backoff, breaker, logger)
+		if config.MetricCacheTTLInSeconds == 0 {
+			return retryableStore, nil
+		}
+
+		cache := metriccache.New(retryableStore, time.Duration(config.MetricCacheTTLInSeconds)*time.Second)
+		go dbmetricstorage.NewChangeListener(config.DatabaseDSN, cache, logger).Run(shutdownCh)
+		return cache, nil
+	}
+
+	if config.KVStoragePath != "" {
+		return kvmetricstorage.New(config.KVStoragePath, logger)
 	}
 
 	if config.FileStoragePath != "" {
-		return metricstorage.NewFileMetricStorage(
+		fileStore, err := metricstorage.NewFileMetricStorage(
 			metricstorage.NewMemoryMetricStorage(),
 			*logger,
 			time.Duration(config.StoreIntervalInSeconds)*time.Second,
 			config.FileStoragePath,
 			config.ShouldRestore,
-		), nil
+			metricstorage.FsyncPolicy(config.WALFsync),
+			config.SnapshotKeep,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// The final snapshot is taken on shutdown
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fileStore.Run(shutdownCh)
+		}()
+		return fileStore, nil
 	}
 
 	return metricstorage.NewMemoryMetricStorage(), nil
 }
 
-func runHTTPServer(config server.Config, store domain.MetricStorage, zeroLogger zerolog.Logger, shutdownCh <-chan struct{}) error {
+// clusterStorage keeps the metrics in memory to be replicated with the peers over gRPC. A restarted server gets the
+// metrics back from its peers, so they are not kept in the file storage.
+func clusterStorage(config server.Config, logger *zerolog.Logger) (domain.MetricStorage, error) {
+	if config.DatabaseDSN != "" {
+		return nil, errors.New("clustering replicates in-memory metrics and cannot be combined with a database")
+	}
+	if config.KVStoragePath != "" {
+		return nil, errors.New("clustering replicates in-memory metrics and cannot be combined with the KV storage")
+	}
+	if !config.UseGRPC {
+		return nil, errors.New("clustering replicates over gRPC and requires the gRPC server")
+	}
+	if config.FileStoragePath != "" {
+		logger.Warn().Str("path", config.FileStoragePath).Msg("clustered servers do not store metrics in the file storage, they restore them from their peers")
+	}
+
+	return cluster.New(clusterNodeName(config)), nil
+}
+
+func clusterNodeName(config server.Config) string {
+	return cmp.Or(config.ClusterNodeID, hostname())
+}
+
+// clusterNode replicates the clustered storage with the peers, merging their metrics through the decorated store,
+// and shares the tracked agents with them.
+func clusterNode(
+	config server.Config,
+	storage *cluster.Storage,
+	store domain.MetricStorage,
+	election *cluster.Election,
+	agents domain.SharedAgentRegistry,
+	logger *zerolog.Logger,
+) (*cluster.Node, error) {
+	replica, ok := store.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+
+	var peers []domain.ReplicaPeer
+	for _, address := range splitList(config.ClusterPeers) {
+		peer, err := grpcclient.NewPeer(address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cluster peer %s: %w", address, err)
+		}
+		peers = append(peers, peer)
+	}
+
+	return cluster.NewNode(storage, replica, peers, election, agents, time.Duration(config.ClusterSyncIntervalInSeconds)*time.Second, logger), nil
+}
+
+// newForwarder makes the server an agent of the upstream server, reporting over the same transports as the agents.
+// The spool is kept in the same place as the metrics.
+func newForwarder(config server.Config, store domain.MetricStorage, logger zerolog.Logger) (*federation.Forwarder, error) {
+	addresses := splitList(config.ForwardAddress)
+	edge := cmp.Or(config.ForwardEdgeName, hostname())
+
+	var sender domain.MetricBatchSender
+	if config.ForwardUseGRPC {
+		reporter, err := grpcclient.New(addresses, domain.AgentInfo{ID: edge, Address: realip.LocalIP(), Version: buildVersion}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create upstream gRPC client: %w", err)
+		}
+		sender = reporter
+	} else {
+		transport, err := reporttransport.New(
+			reporttransport.Options{
+				Addresses:     addresses,
+				Agent:         domain.AgentInfo{ID: edge, Version: buildVersion},
+				CryptoKeyPath: config.ForwardCryptoKey,
+				HashKey:       config.ForwardHashKey,
+				Backoff:       resilience.Backoff{Retries: 3, Initial: time.Second, Max: 5 * time.Second, Jitter: 0.2},
+				Breaker:       resilience.NewBreaker(5, 30*time.Second),
+			},
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create upstream transport: %w", err)
+		}
+		sender = metricreporter.NewHTTPMetricReporter("http://"+addresses[0], &http.Client{Transport: transport})
+	}
+
+	spoolPath := config.ForwardSpoolPath
+	if spoolPath == "" && config.FileStoragePath != "" {
+		spoolPath = config.FileStoragePath + ".spool"
+	}
+
+	return federation.New(
+		store,
+		sender,
+		federation.Options{
+			Prefix:       config.ForwardPrefix,
+			BatchSize:    config.ForwardBatchSize,
+			SpoolPath:    spoolPath,
+			SpoolMaxSize: config.ForwardSpoolMaxBytes,
+		},
+		&logger,
+	)
+}
+
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// splitList splits a comma-separated config value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// silenceStoreSelector keeps silences in the same place as the metrics.
+func silenceStoreSelector(config server.Config) (domain.SilenceStorage, error) {
+	if config.DatabaseDSN != "" {
+		return dbmetricstorage.NewSilenceStorage(config.DatabaseDSN)
+	}
+
+	if config.FileStoragePath != "" {
+		return silence.NewFileStorage(config.FileStoragePath + ".silences"), nil
+	}
+
+	return silence.NewMemoryStorage(), nil
+}
+
+// rollupStoreSelector keeps the metric rollups in the same place as the metrics, none are kept without a storage.
+func rollupStoreSelector(config server.Config, store domain.MetricStorage) (domain.RollupStorage, error) {
+	if config.DatabaseDSN != "" {
+		return dbmetricstorage.NewRollupStorage(config.DatabaseDSN)
+	}
+
+	// The KV storage holds the database file open, the rollups are kept in the same database
+	if boltStore, ok := store.(*kvmetricstorage.BoltMetricStorage); ok {
+		return boltStore, nil
+	}
+
+	if config.FileStoragePath != "" {
+		return metrichistory.NewFileStorage(config.FileStoragePath + ".rollups"), nil
+	}
+
+	return nil, nil
+}
+
+// newMetricHistory keeps the recent metric values and their rollups, restoring the rollups kept in the rollup storage.
+func newMetricHistory(
+	config server.Config,
+	store domain.MetricStorage,
+	rollupStore domain.RollupStorage,
+	logger *zerolog.Logger,
+) (*metrichistory.Store, error) {
+	retention := time.Duration(config.MetricHistoryRetentionInSeconds) * time.Second
+	policies := []domain.RetentionPolicy{
+		{Resolution: time.Minute, Retention: time.Duration(config.MinuteRollupRetentionInSeconds) * time.Second},
+		{Resolution: time.Hour, Retention: time.Duration(config.HourRollupRetentionInSeconds) * time.Second},
+	}
+
+	if rollupStore == nil {
+		return metrichistory.New(store, retention, policies...), nil
+	}
+	return metrichistory.NewPersistent(context.Background(), store, rollupStore, logger, retention, policies...)
+}
+
+// newTemplates loads the notification templates, failing on invalid templates before the server starts.
+func newTemplates(config server.Config, metrics domain.MetricStorage, rules domain.RuleStorage) (*alerting.Templates, error) {
+	var sources map[string]string
+	if config.NotificationTemplatesPath != "" {
+		var err error
+		sources, err = alerting.LoadTemplateSources(config.NotificationTemplatesPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return alerting.NewTemplates(sources, cmp.Or(config.ExternalURL, "http://"+config.Address), metrics, rules)
+}
+
+// newRouter loads the notification routing, sending all alerts to the log when none is configured.
+func newRouter(
+	config server.Config,
+	templates *alerting.Templates,
+	silencer domain.AlertSilencer,
+	history domain.HistoryRecorder,
+	logger *zerolog.Logger,
+) (*alerting.Router, error) {
+	routing := alerting.DefaultRouting
+	if config.RoutingConfigPath != "" {
+		var err error
+		routing, err = alerting.LoadRouting(config.RoutingConfigPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	notifiers := alerting.NewReceiverNotifiers(routing.Receivers, templates, logger)
+	return alerting.NewRouter(routing, notifiers, silencer, history, logger)
+}
+
+// historyStoreSelector keeps the alert history in the same place as the metrics.
+func historyStoreSelector(config server.Config) (domain.HistoryStorage, error) {
+	if config.DatabaseDSN != "" {
+		return dbmetricstorage.NewHistoryStorage(config.DatabaseDSN)
+	}
+
+	if config.FileStoragePath != "" {
+		return history.NewFileStorage(config.FileStoragePath + ".history.jsonl"), nil
+	}
+
+	return history.NewMemoryStorage(), nil
+}
+
+// ruleStoreSelector keeps alert rules in the same place as the metrics.
+func ruleStoreSelector(config server.Config) (domain.RuleStorage, error) {
+	if config.DatabaseDSN != "" {
+		return dbmetricstorage.NewRuleStorage(config.DatabaseDSN)
+	}
+
+	if config.FileStoragePath != "" {
+		return rules.NewFileStorage(config.FileStoragePath + ".rules"), nil
+	}
+
+	return rules.NewMemoryStorage(), nil
+}
+
+func runHTTPServer(
+	config server.Config,
+	deps serverDeps,
+	zeroLogger zerolog.Logger,
+	shutdownCh <-chan struct{},
+) error {
 	engine := gin.New()
 	engine.
 		Use(logger.New(zeroLogger)).
@@ -133,6 +614,8 @@ func runHTTPServer(config server.Config, store domain.MetricStorage, zeroLogger
 		Use(gzipper.UnzipMiddleware()).
 		Use(hash.NewHashValidator(config.HashKey)).
 		Use(subnet.NewTrustedSubnetMiddleware(config.TrustedSubnet)).
+		Use(agentinfo.NewHeartbeatMiddleware(deps.heartbeats)).
+		Use(agentinfo.NewTenantMiddleware()).
 		Use(gzip.Gzip(gzip.DefaultCompression))
 
 	if config.PathToCryptoKey != "" {
@@ -144,16 +627,28 @@ func runHTTPServer(config server.Config, store domain.MetricStorage, zeroLogger
 	}
 
 	mr := router.New(engine, &zeroLogger)
-	mr.RegisterPingHandler(handler.NewPingHandler(store))
-	mr.RegisterMetricsHandler(handler.NewMetricsHandler(store))
-	mr.RegisterMetricsJSONHandler(handler.NewMetricsJSONHandler(store))
+	mr.RegisterPingHandler(handler.NewPingHandler(deps.store))
+	mr.RegisterMetricsHandler(handler.NewMetricsHandler(deps.store))
+	mr.RegisterMetricsJSONHandler(handler.NewMetricsJSONHandler(deps.store))
+	mr.RegisterMetricHistoryHandler(handler.NewMetricHistoryHandler(deps.metrics))
+	mr.RegisterCardinalityHandler(handler.NewCardinalityHandler(deps.cardinality))
+	mr.RegisterStaleMetricsHandler(handler.NewStaleMetricsHandler(deps.store))
+	mr.RegisterAgentsHandler(handler.NewAgentsHandler(deps.heartbeats))
+	mr.RegisterAlertsHandler(handler.NewAlertsHandler(deps.alerts, deps.history))
+	mr.RegisterSilencesHandler(handler.NewSilencesHandler(deps.silences))
+	mr.RegisterRulesHandler(handler.NewRulesHandler(deps.rules))
+	mr.RegisterTemplatesHandler(handler.NewTemplatesHandler(deps.templates))
+	mr.RegisterEscalationsHandler(handler.NewEscalationsHandler(deps.escalations))
+	if deps.anomalies != nil {
+		mr.RegisterAnomaliesHandler(handler.NewAnomaliesHandler(deps.anomalies))
+	}
 
 	zeroLogger.Info().Str("address", config.Address).Msg("starting HTTP server")
 	return mr.Run(config.Address, shutdownCh)
 }
 
-func runGRPCServer(config server.Config, store domain.MetricStorage, zeroLogger zerolog.Logger, shutdownCh <-chan struct{}) error {
-	grpcSrv := grpcserver.NewGRPCServer(store, zeroLogger)
+func runGRPCServer(config server.Config, deps serverDeps, zeroLogger zerolog.Logger, shutdownCh <-chan struct{}) error {
+	grpcSrv := grpcserver.NewGRPCServer(deps.store, deps.heartbeats, deps.silences, deps.rules, deps.cluster, zeroLogger)
 
 	zeroLogger.Info().Str("address", config.GRPCAddress).Msg("starting gRPC server")
 	return grpcSrv.Run(config.GRPCAddress, shutdownCh)
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/caarlos0/env/v6"
 )
@@ -17,19 +18,21 @@ type Config struct {
 	RateLimit               int    `env:"RATE_LIMIT"`
 	PathToCryptoKey         string `env:"CRYPTO_KEY" json:"crypto_key"`
 	UseGRPC                 bool   `env:"USE_GRPC" json:"use_grpc"`
+	AgentID                 string `env:"AGENT_ID" json:"agent_id"`
 }
 
 func NewConfig() (Config, error) {
 	configPath := flag.String("c", "", "Path to the config file")
 	flag.StringVar(configPath, "config", "", "Path to the config file")
 
-	address := flag.String("a", "localhost:8080", "HTTP agent address (default: localhost:8080)")
+	address := flag.String("a", "localhost:8080", "Server address, or comma-separated addresses of cluster servers to fail over between (default: localhost:8080)")
 	pollIntervalInSeconds := flag.Int("p", 2, "Poll interval in seconds (default: 2)")
 	reportIntervalInSeconds := flag.Int("r", 10, "Report interval in seconds (default: 10)")
 	hashKey := flag.String("k", "", "Key for calculating hash (default: none)")
 	rateLimit := flag.Int("l", 10, "Rate limit (default: 10)")
 	pathToCryptoKey := flag.String("crypto-key", "", "Path to a file with a public key (default: none)")
 	useGRPC := flag.Bool("g", false, "Use GRPC instead of HTTP (default: false)")
+	agentID := flag.String("id", "", "Agent ID reported to the server (default: host name)")
 
 	flag.Parse()
 
@@ -80,6 +83,10 @@ func NewConfig() (Config, error) {
 		config.UseGRPC = *useGRPC
 	}
 
+	if *agentID != "" {
+		config.AgentID = *agentID
+	}
+
 	// ENV vars
 	err = env.Parse(&config)
 	if err != nil {
@@ -89,6 +96,18 @@ func NewConfig() (Config, error) {
 	return config, nil
 }
 
+// Addresses returns the servers listed in Address. The agent reports to the first one and fails over to the next
+// one when a server is unavailable.
+func (cfg Config) Addresses() []string {
+	var addresses []string
+	for _, address := range strings.Split(cfg.Address, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
 func (cfg *Config) loadFromFile(filePath string) error {
 	if filePath == "" {
 		return nil
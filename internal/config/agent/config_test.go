@@ -21,6 +21,7 @@ func TestNewConfig(t *testing.T) {
 			"KEY":             "secret123",
 			"RATE_LIMIT":      "20",
 			"CRYPTO_KEY":      "file.pem",
+			"AGENT_ID":        "agent-1",
 		}
 
 		expected := Config{
@@ -30,6 +31,7 @@ func TestNewConfig(t *testing.T) {
 			HashKey:                 "secret123",
 			RateLimit:               20,
 			PathToCryptoKey:         "file.pem",
+			AgentID:                 "agent-1",
 		}
 
 		for key, value := range envVars {
@@ -46,3 +48,12 @@ func TestNewConfig(t *testing.T) {
 		assert.Equal(t, expected, config)
 	})
 }
+
+func TestConfig_Addresses(t *testing.T) {
+	assert.Equal(t, []string{"localhost:8080"}, Config{Address: "localhost:8080"}.Addresses())
+	assert.Equal(t,
+		[]string{"metrics-1:8080", "metrics-2:8080"},
+		Config{Address: "metrics-1:8080, metrics-2:8080,"}.Addresses(),
+	)
+	assert.Empty(t, Config{}.Addresses())
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -10,16 +11,54 @@ import (
 )
 
 type Config struct {
-	Address                string `env:"ADDRESS" json:"address"`
-	StoreIntervalInSeconds int    `env:"STORE_INTERVAL" json:"store_interval"`
-	FileStoragePath        string `env:"FILE_STORAGE_PATH" json:"store_file"`
-	ShouldRestore          bool   `env:"RESTORE" json:"restore"`
-	DatabaseDSN            string `env:"DATABASE_DSN" json:"database_dsn"`
-	HashKey                string `env:"KEY"`
-	PathToCryptoKey        string `env:"CRYPTO_KEY" json:"crypto_key"`
-	TrustedSubnet          string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
-	UseGRPC                bool   `env:"USE_GRPC" json:"use_grpc"`
-	GRPCAddress            string `env:"GRPC_ADDRESS" json:"grpc_address"`
+	Address                         string  `env:"ADDRESS" json:"address"`
+	StoreIntervalInSeconds          int     `env:"STORE_INTERVAL" json:"store_interval"`
+	FileStoragePath                 string  `env:"FILE_STORAGE_PATH" json:"store_file"`
+	ShouldRestore                   bool    `env:"RESTORE" json:"restore"`
+	DatabaseDSN                     string  `env:"DATABASE_DSN" json:"database_dsn"`
+	KVStoragePath                   string  `env:"KV_STORAGE_PATH" json:"kv_storage_path"`
+	MetricCacheTTLInSeconds         int     `env:"METRIC_CACHE_TTL" json:"metric_cache_ttl"`
+	HashKey                         string  `env:"KEY"`
+	PathToCryptoKey                 string  `env:"CRYPTO_KEY" json:"crypto_key"`
+	TrustedSubnet                   string  `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	UseGRPC                         bool    `env:"USE_GRPC" json:"use_grpc"`
+	GRPCAddress                     string  `env:"GRPC_ADDRESS" json:"grpc_address"`
+	MaxSeries                       int     `env:"MAX_SERIES" json:"max_series"`
+	MaxSeriesPerPrefix              int     `env:"MAX_SERIES_PER_PREFIX" json:"max_series_per_prefix"`
+	MaxSeriesPerTenant              int     `env:"MAX_SERIES_PER_TENANT" json:"max_series_per_tenant"`
+	MetricTTLInSeconds              int     `env:"METRIC_TTL" json:"metric_ttl"`
+	AgentReportIntervalInSeconds    int     `env:"AGENT_REPORT_INTERVAL" json:"agent_report_interval"`
+	AgentMissedReports              int     `env:"AGENT_MISSED_REPORTS" json:"agent_missed_reports"`
+	AnomalyThreshold                float64 `env:"ANOMALY_THRESHOLD" json:"anomaly_threshold"`
+	AnomalyAlpha                    float64 `env:"ANOMALY_ALPHA" json:"anomaly_alpha"`
+	AnomalySeasonal                 bool    `env:"ANOMALY_SEASONAL" json:"anomaly_seasonal"`
+	AlertGroupBy                    string  `env:"ALERT_GROUP_BY" json:"alert_group_by"`
+	AlertGroupWaitInSeconds         int     `env:"ALERT_GROUP_WAIT" json:"alert_group_wait"`
+	AlertGroupIntervalInSeconds     int     `env:"ALERT_GROUP_INTERVAL" json:"alert_group_interval"`
+	AlertRepeatIntervalInSeconds    int     `env:"ALERT_REPEAT_INTERVAL" json:"alert_repeat_interval"`
+	RuleEvalIntervalInSeconds       int     `env:"RULE_EVAL_INTERVAL" json:"rule_eval_interval"`
+	AlertmanagerURL                 string  `env:"ALERTMANAGER_URL" json:"alertmanager_url"`
+	ExternalURL                     string  `env:"EXTERNAL_URL" json:"external_url"`
+	NotificationTemplatesPath       string  `env:"NOTIFICATION_TEMPLATES" json:"notification_templates"`
+	MetricHistoryRetentionInSeconds int     `env:"METRIC_HISTORY_RETENTION" json:"metric_history_retention"`
+	MinuteRollupRetentionInSeconds  int     `env:"MINUTE_ROLLUP_RETENTION" json:"minute_rollup_retention"`
+	HourRollupRetentionInSeconds    int     `env:"HOUR_ROLLUP_RETENTION" json:"hour_rollup_retention"`
+	RoutingConfigPath               string  `env:"ROUTING_CONFIG" json:"routing_config"`
+	WALFsync                        string  `env:"WAL_FSYNC" json:"wal_fsync"`
+	SnapshotKeep                    int     `env:"SNAPSHOT_KEEP" json:"snapshot_keep"`
+	ClusterPeers                    string  `env:"CLUSTER_PEERS" json:"cluster_peers"`
+	ClusterNodeID                   string  `env:"CLUSTER_NODE_ID" json:"cluster_node_id"`
+	ClusterSyncIntervalInSeconds    int     `env:"CLUSTER_SYNC_INTERVAL" json:"cluster_sync_interval"`
+	ForwardAddress                  string  `env:"FORWARD_ADDRESS" json:"forward_address"`
+	ForwardUseGRPC                  bool    `env:"FORWARD_USE_GRPC" json:"forward_use_grpc"`
+	ForwardIntervalInSeconds        int     `env:"FORWARD_INTERVAL" json:"forward_interval"`
+	ForwardPrefix                   string  `env:"FORWARD_PREFIX" json:"forward_prefix"`
+	ForwardEdgeName                 string  `env:"FORWARD_EDGE_NAME" json:"forward_edge_name"`
+	ForwardBatchSize                int     `env:"FORWARD_BATCH_SIZE" json:"forward_batch_size"`
+	ForwardHashKey                  string  `env:"FORWARD_KEY"`
+	ForwardCryptoKey                string  `env:"FORWARD_CRYPTO_KEY" json:"forward_crypto_key"`
+	ForwardSpoolPath                string  `env:"FORWARD_SPOOL_PATH" json:"forward_spool_path"`
+	ForwardSpoolMaxBytes            int64   `env:"FORWARD_SPOOL_MAX_BYTES" json:"forward_spool_max_bytes"`
 }
 
 func NewConfig() (Config, error) {
@@ -31,11 +70,49 @@ func NewConfig() (Config, error) {
 	fileStoragePath := flag.String("f", "alert_monitoring_metrics.dump", "File storage path (default: alert_monitoring_metrics.dump)")
 	shouldRestore := flag.Bool("r", false, "Restore from file (default: false)")
 	databaseDSN := flag.String("d", "", "Database DSN (default: empty, file storage will be used)")
+	kvStoragePath := flag.String("kv-storage-path", "", "Path to an embedded metrics database, used instead of the file storage (default: empty)")
+	metricCacheTTL := flag.Int("metric-cache-ttl", 60, "Seconds database metrics are cached for, kept in sync between replicas by notifications (default: 60, 0 disables the cache)")
 	hashKey := flag.String("k", "", "Key for calculating hash (default: none)")
 	pathToCryptoKey := flag.String("crypto-key", "", "Path to a file with a private key (default: none)")
 	isSubnetTrusted := flag.String("t", "", "Path to a file with a public key (default: none)")
 	useGRPC := flag.Bool("g", false, "Use also GRPC for incoming requests (default: false)")
 	grpcAddress := flag.String("ga", "localhost:443", "gRPC server address (default: localhost:443)")
+	maxSeries := flag.Int("max-series", 0, "Maximum number of stored series (default: 0, unlimited)")
+	metricTTLInSeconds := flag.Int("ttl", 0, "Delete metrics not updated within TTL in seconds (default: 0, never)")
+	maxSeriesPerPrefix := flag.Int("max-series-per-prefix", 0, "Maximum number of stored series per metric name prefix (default: 0, unlimited)")
+	maxSeriesPerTenant := flag.Int("max-series-per-tenant", 0, "Maximum number of series created per agent ID (default: 0, unlimited)")
+	agentReportInterval := flag.Int("agent-report-interval", 10, "Expected agent report interval in seconds (default: 10)")
+	anomalyThreshold := flag.Float64("anomaly-threshold", 0, "Report gauge values deviating from the mean by more standard deviations (default: 0, disabled)")
+	anomalyAlpha := flag.Float64("anomaly-alpha", 0.1, "EWMA smoothing factor of the anomaly baseline, (0, 1] (default: 0.1)")
+	anomalySeasonal := flag.Bool("anomaly-seasonal", false, "Keep an additional anomaly baseline per hour of day (default: false)")
+	alertGroupBy := flag.String("alert-group-by", "alertname", "Comma-separated labels alert notifications are grouped by, ... for no grouping (default: alertname)")
+	alertGroupWait := flag.Int("alert-group-wait", 30, "Seconds a new alert group waits for more alerts before notifying (default: 30)")
+	alertGroupInterval := flag.Int("alert-group-interval", 300, "Minimum seconds between notifications of a changed alert group (default: 300)")
+	alertRepeatInterval := flag.Int("alert-repeat-interval", 14400, "Seconds before an unchanged alert notification is repeated (default: 14400)")
+	ruleEvalInterval := flag.Int("rule-eval-interval", 15, "Alert rule evaluation interval in seconds (default: 15)")
+	alertmanagerURL := flag.String("alertmanager-url", "", "Alertmanager base URL alerts are pushed to (default: none)")
+	externalURL := flag.String("external-url", "", "URL the server is reachable at, used in alert links (default: http:// + server address)")
+	notificationTemplates := flag.String("notification-templates", "", "Path to a JSON file with notification templates per receiver (default: none, built-in templates)")
+	walFsync := flag.String("wal-fsync", "interval", "When the metrics WAL is flushed to disk: always, interval (every second) or never (default: interval)")
+	snapshotKeep := flag.Int("snapshot-keep", 3, "Number of metric snapshots kept to restore from when the newest is damaged (default: 3)")
+	routingConfig := flag.String("routing-config", "", "Path to a JSON file with notification receivers, routes and escalation steps (default: none, all alerts to the log)")
+	metricHistoryRetention := flag.Int("metric-history-retention", 172800, "Seconds of raw metric values kept for range queries and rate, delta and avg_over_time in rules (default: 172800, 48 hours)")
+	minuteRollupRetention := flag.Int("minute-rollup-retention", 2592000, "Seconds of per-minute metric rollups kept, in memory about 3 MB per series, and restored from the metric storage (default: 2592000, 30 days)")
+	hourRollupRetention := flag.Int("hour-rollup-retention", 31536000, "Seconds of per-hour metric rollups kept, in memory about 630 KB per series, and restored from the metric storage (default: 31536000, 1 year)")
+	clusterPeers := flag.String("cluster-peers", "", "Comma-separated gRPC addresses of the other servers the in-memory metrics are replicated with (default: none, no clustering)")
+	clusterNodeID := flag.String("cluster-node-id", "", "Name of the server in the cluster, unique and kept across restarts (default: host name)")
+	clusterSyncInterval := flag.Int("cluster-sync-interval", 10, "Seconds between pulls of the metrics of all cluster peers, catching up on missed replication (default: 10)")
+	forwardAddress := flag.String("forward-address", "", "Comma-separated addresses of the upstream server metrics are forwarded to, failing over in order (default: none, no forwarding)")
+	forwardUseGRPC := flag.Bool("forward-grpc", false, "Forward metrics over gRPC instead of HTTP (default: false)")
+	forwardInterval := flag.Int("forward-interval", 10, "Seconds between forwards of the metric updates to the upstream server (default: 10)")
+	forwardPrefix := flag.String("forward-prefix", "", "Prefix of the forwarded metric names, identifying this server upstream (default: none)")
+	forwardEdgeName := flag.String("forward-edge-name", "", "Agent ID this server reports to the upstream server with (default: host name)")
+	forwardBatchSize := flag.Int("forward-batch-size", 500, "Maximum number of metrics forwarded in a single request (default: 500)")
+	forwardHashKey := flag.String("forward-key", "", "Key for calculating the hash of forwarded metrics (default: none)")
+	forwardCryptoKey := flag.String("forward-crypto-key", "", "Path to a file with the public key of the upstream server (default: none)")
+	forwardSpoolPath := flag.String("forward-spool-path", "", "File keeping the metrics not forwarded while the upstream server is unavailable (default: file storage path + .spool)")
+	forwardSpoolMaxBytes := flag.Int64("forward-spool-max-bytes", 64<<20, "Maximum size of the forwarding spool in bytes (default: 64 MiB)")
+	agentMissedReports := flag.Int("agent-missed-reports", 3, "Alert when an agent misses this many reports in a row (default: 3, 0 disables)")
 
 	flag.Parse()
 
@@ -78,6 +155,14 @@ func NewConfig() (Config, error) {
 		config.DatabaseDSN = *databaseDSN
 	}
 
+	if *kvStoragePath != "" {
+		config.KVStoragePath = *kvStoragePath
+	}
+
+	if *metricCacheTTL != 0 {
+		config.MetricCacheTTLInSeconds = *metricCacheTTL
+	}
+
 	if *hashKey != "" {
 		config.HashKey = *hashKey
 	}
@@ -98,12 +183,202 @@ func NewConfig() (Config, error) {
 		config.GRPCAddress = *grpcAddress
 	}
 
+	if *maxSeries != 0 {
+		config.MaxSeries = *maxSeries
+	}
+
+	if *maxSeriesPerPrefix != 0 {
+		config.MaxSeriesPerPrefix = *maxSeriesPerPrefix
+	}
+
+	if *maxSeriesPerTenant != 0 {
+		config.MaxSeriesPerTenant = *maxSeriesPerTenant
+	}
+
+	if *metricTTLInSeconds != 0 {
+		config.MetricTTLInSeconds = *metricTTLInSeconds
+	}
+
+	if *agentReportInterval != 0 {
+		config.AgentReportIntervalInSeconds = *agentReportInterval
+	}
+
+	if *agentMissedReports != 0 {
+		config.AgentMissedReports = *agentMissedReports
+	}
+
+	if *anomalyThreshold != 0 {
+		config.AnomalyThreshold = *anomalyThreshold
+	}
+
+	if *anomalyAlpha != 0 {
+		config.AnomalyAlpha = *anomalyAlpha
+	}
+
+	if flag.Lookup("anomaly-seasonal").Value.String() == "true" {
+		config.AnomalySeasonal = *anomalySeasonal
+	}
+
+	if *alertGroupBy != "" {
+		config.AlertGroupBy = *alertGroupBy
+	}
+
+	if *alertGroupWait != 0 {
+		config.AlertGroupWaitInSeconds = *alertGroupWait
+	}
+
+	if *alertGroupInterval != 0 {
+		config.AlertGroupIntervalInSeconds = *alertGroupInterval
+	}
+
+	if *alertRepeatInterval != 0 {
+		config.AlertRepeatIntervalInSeconds = *alertRepeatInterval
+	}
+
+	if *ruleEvalInterval != 0 {
+		config.RuleEvalIntervalInSeconds = *ruleEvalInterval
+	}
+
+	if *alertmanagerURL != "" {
+		config.AlertmanagerURL = *alertmanagerURL
+	}
+
+	if *externalURL != "" {
+		config.ExternalURL = *externalURL
+	}
+
+	if *notificationTemplates != "" {
+		config.NotificationTemplatesPath = *notificationTemplates
+	}
+
+	if *walFsync != "" {
+		config.WALFsync = *walFsync
+	}
+
+	if *snapshotKeep != 0 {
+		config.SnapshotKeep = *snapshotKeep
+	}
+
+	if *routingConfig != "" {
+		config.RoutingConfigPath = *routingConfig
+	}
+
+	if *metricHistoryRetention != 0 {
+		config.MetricHistoryRetentionInSeconds = *metricHistoryRetention
+	}
+
+	if *minuteRollupRetention != 0 {
+		config.MinuteRollupRetentionInSeconds = *minuteRollupRetention
+	}
+
+	if *hourRollupRetention != 0 {
+		config.HourRollupRetentionInSeconds = *hourRollupRetention
+	}
+
+	if *clusterPeers != "" {
+		config.ClusterPeers = *clusterPeers
+	}
+
+	if *clusterNodeID != "" {
+		config.ClusterNodeID = *clusterNodeID
+	}
+
+	if *clusterSyncInterval != 0 {
+		config.ClusterSyncIntervalInSeconds = *clusterSyncInterval
+	}
+
+	if *forwardAddress != "" {
+		config.ForwardAddress = *forwardAddress
+	}
+
+	if flag.Lookup("forward-grpc").Value.String() == "true" {
+		config.ForwardUseGRPC = *forwardUseGRPC
+	}
+
+	if *forwardInterval != 0 {
+		config.ForwardIntervalInSeconds = *forwardInterval
+	}
+
+	if *forwardPrefix != "" {
+		config.ForwardPrefix = *forwardPrefix
+	}
+
+	if *forwardEdgeName != "" {
+		config.ForwardEdgeName = *forwardEdgeName
+	}
+
+	if *forwardBatchSize != 0 {
+		config.ForwardBatchSize = *forwardBatchSize
+	}
+
+	if *forwardHashKey != "" {
+		config.ForwardHashKey = *forwardHashKey
+	}
+
+	if *forwardCryptoKey != "" {
+		config.ForwardCryptoKey = *forwardCryptoKey
+	}
+
+	if *forwardSpoolPath != "" {
+		config.ForwardSpoolPath = *forwardSpoolPath
+	}
+
+	if *forwardSpoolMaxBytes != 0 {
+		config.ForwardSpoolMaxBytes = *forwardSpoolMaxBytes
+	}
+
 	// ENV vars
 	err = env.Parse(&config)
 	if err != nil {
 		return Config{}, err
 	}
 
+	if config.AnomalyThreshold > 0 && (config.AnomalyAlpha <= 0 || config.AnomalyAlpha > 1) {
+		return Config{}, fmt.Errorf("anomaly alpha must be in (0, 1], got %g", config.AnomalyAlpha)
+	}
+
+	if config.MetricCacheTTLInSeconds < 0 {
+		return Config{}, fmt.Errorf("metric cache TTL must not be negative, got %d", config.MetricCacheTTLInSeconds)
+	}
+
+	if config.RuleEvalIntervalInSeconds <= 0 {
+		return Config{}, fmt.Errorf("rule evaluation interval must be positive, got %d", config.RuleEvalIntervalInSeconds)
+	}
+
+	if config.MetricHistoryRetentionInSeconds <= 0 {
+		return Config{}, fmt.Errorf("metric history retention must be positive, got %d", config.MetricHistoryRetentionInSeconds)
+	}
+
+	if config.MinuteRollupRetentionInSeconds < config.MetricHistoryRetentionInSeconds ||
+		config.HourRollupRetentionInSeconds < config.MinuteRollupRetentionInSeconds {
+		return Config{}, fmt.Errorf("rollups must be kept at least as long as the finer metric history, got %d, %d and %d seconds",
+			config.MetricHistoryRetentionInSeconds, config.MinuteRollupRetentionInSeconds, config.HourRollupRetentionInSeconds)
+	}
+
+	if config.ClusterPeers != "" && config.ClusterSyncIntervalInSeconds <= 0 {
+		return Config{}, fmt.Errorf("cluster sync interval must be positive, got %d", config.ClusterSyncIntervalInSeconds)
+	}
+
+	// The servers of a cluster learn about the agents reporting to their peers with every sync
+	if absence := config.AgentMissedReports * config.AgentReportIntervalInSeconds; config.ClusterPeers != "" && absence > 0 &&
+		config.ClusterSyncIntervalInSeconds >= absence {
+		return Config{}, fmt.Errorf("cluster sync interval must be shorter than the %d seconds an agent may miss reports, got %d",
+			absence, config.ClusterSyncIntervalInSeconds)
+	}
+
+	if config.ForwardAddress != "" {
+		// Every server of a cluster has all metrics, each would forward them
+		if config.ClusterPeers != "" {
+			return Config{}, errors.New("forwarding cannot be combined with clustering")
+		}
+		if config.ForwardIntervalInSeconds <= 0 {
+			return Config{}, fmt.Errorf("forward interval must be positive, got %d", config.ForwardIntervalInSeconds)
+		}
+		if config.ForwardBatchSize <= 0 {
+			return Config{}, fmt.Errorf("forward batch size must be positive, got %d", config.ForwardBatchSize)
+		}
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// HeaderAgentID and HeaderAgentVersion name the HTTP headers and the gRPC metadata keys identifying the agent.
+const (
+	HeaderAgentID      = "X-Agent-ID"
+	HeaderAgentVersion = "X-Agent-Version"
+)
+
+// AgentInfo identifies the agent that sent a report.
+type AgentInfo struct {
+	ID      string
+	Address string
+	Version string
+}
+
+// AgentStatus describes the reporting state of a known agent.
+type AgentStatus struct {
+	ID               string    `json:"id"`
+	Address          string    `json:"address,omitempty"`
+	Version          string    `json:"version,omitempty"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	Reports          int64     `json:"reports"`
+	ReportsPerMinute float64   `json:"reports_per_minute"`
+	Absent           bool      `json:"absent"`
+}
+
+// HeartbeatRecorder records agent reports.
+type HeartbeatRecorder interface {
+	RecordHeartbeat(agent AgentInfo)
+}
+
+// AgentRegistry lists the agents that have reported to the server.
+type AgentRegistry interface {
+	Agents() []AgentStatus
+}
+
+// SharedAgentRegistry is the agent registry of a cluster server. The servers exchange their agents, so an agent
+// that fails over to another server is not taken for absent.
+// MergeAgents merges the agents tracked by a peer, keeping the latest report of every agent.
+type SharedAgentRegistry interface {
+	AgentRegistry
+	MergeAgents(agents []AgentStatus)
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+type AlertState string
+
+const (
+	AlertStateFiring   AlertState = "firing"
+	AlertStateResolved AlertState = "resolved"
+)
+
+// Alert is a condition detected by one of the server subsystems, identified by its name and labels.
+type Alert struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	State       AlertState        `json:"state"`
+	StartsAt    time.Time         `json:"starts_at"`
+	EndsAt      time.Time         `json:"ends_at,omitzero"`
+	SilencedBy  []string          `json:"silenced_by,omitempty"`
+}
+
+// Fingerprint identifies an alert by its name and labels, so repeated detections of the same condition match.
+func (a Alert) Fingerprint() string {
+	var sb strings.Builder
+	sb.WriteString(a.Name)
+	for _, key := range slices.Sorted(maps.Keys(a.Labels)) {
+		sb.WriteString("," + key + "=" + a.Labels[key])
+	}
+	return sb.String()
+}
+
+// AlertPublisher receives alerts from the subsystems detecting them.
+type AlertPublisher interface {
+	Fire(ctx context.Context, alert Alert)
+	Resolve(ctx context.Context, alert Alert)
+}
+
+// Notification is a group of alerts delivered to a receiver as one message.
+type Notification struct {
+	GroupKey    string            `json:"group_key"`
+	GroupLabels map[string]string `json:"group_labels"`
+	Status      AlertState        `json:"status"`
+	Alerts      []Alert           `json:"alerts"`
+}
+
+// Firing returns the firing alerts of the notification.
+func (n Notification) Firing() []Alert {
+	return n.withState(AlertStateFiring)
+}
+
+// Resolved returns the resolved alerts of the notification.
+func (n Notification) Resolved() []Alert {
+	return n.withState(AlertStateResolved)
+}
+
+func (n Notification) withState(state AlertState) []Alert {
+	var alerts []Alert
+	for _, alert := range n.Alerts {
+		if alert.State == state {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// AlertNotifier delivers notifications to a receiver.
+type AlertNotifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// AlertReader lists the currently firing alerts.
+type AlertReader interface {
+	ActiveAlerts() []Alert
+}
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlert_Fingerprint(t *testing.T) {
+	a := Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "a1", "dc": "eu"}}
+	b := Alert{Name: "AgentAbsent", Labels: map[string]string{"dc": "eu", "agent": "a1"}, State: AlertStateResolved}
+	c := Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "a2", "dc": "eu"}}
+
+	assert.Equal(t, "AgentAbsent,agent=a1,dc=eu", a.Fingerprint())
+	assert.Equal(t, a.Fingerprint(), b.Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), c.Fingerprint())
+	assert.Equal(t, "Anomaly", Alert{Name: "Anomaly"}.Fingerprint())
+}
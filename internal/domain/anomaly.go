@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Anomaly describes a gauge value that deviates from the series baseline by more than the configured threshold.
+type Anomaly struct {
+	MetricID   string    `json:"id"`
+	Value      float64   `json:"value"`
+	Mean       float64   `json:"mean"`
+	StdDev     float64   `json:"stddev"`
+	ZScore     float64   `json:"zscore"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// AnomalyReporter lists the series whose latest value is anomalous.
+type AnomalyReporter interface {
+	Anomalies() []Anomaly
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrCardinalityLimitExceeded is returned when accepting a metric would create a series above the configured limits.
+var ErrCardinalityLimitExceeded = errors.New("series limit exceeded")
+
+// PrefixCardinality holds the number of known series and rejected new series for a single metric name prefix.
+type PrefixCardinality struct {
+	Prefix   string `json:"prefix"`
+	Series   int    `json:"series"`
+	Rejected int64  `json:"rejected"`
+}
+
+// TenantCardinality holds the number of series created and new series rejected for a single tenant.
+type TenantCardinality struct {
+	Tenant   string `json:"tenant"`
+	Series   int    `json:"series"`
+	Rejected int64  `json:"rejected"`
+}
+
+// CardinalityReport summarizes the series currently tracked by storage and the configured limits.
+// Zero limits mean the corresponding limit is disabled.
+type CardinalityReport struct {
+	TotalSeries        int                 `json:"total_series"`
+	MaxSeries          int                 `json:"max_series"`
+	MaxSeriesPerPrefix int                 `json:"max_series_per_prefix"`
+	MaxSeriesPerTenant int                 `json:"max_series_per_tenant"`
+	RejectedTotal      int64               `json:"rejected_total"`
+	TopPrefixes        []PrefixCardinality `json:"top_prefixes"`
+	TopTenants         []TenantCardinality `json:"top_tenants"`
+}
+
+// CardinalityReporter defines a source of cardinality reports limited to the given number of top prefixes.
+type CardinalityReporter interface {
+	CardinalityReport(top int) CardinalityReport
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context accounting the series created with it to the tenant, the ID of the reporting agent.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant set by WithTenant, empty if the series are not accounted to any.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// MetricPrefix returns the prefix a metric name is grouped by for cardinality accounting.
+// The prefix is the part of the name before the first '.', '_' or ':' with trailing digits removed,
+// so "CPUutilization12" and "CPUutilization3" share the "CPUutilization" prefix.
+func MetricPrefix(name string) string {
+	if i := strings.IndexAny(name, "._:"); i > 0 {
+		name = name[:i]
+	}
+
+	prefix := strings.TrimRight(name, "0123456789")
+	if prefix == "" {
+		return name
+	}
+
+	return prefix
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "trailing digits", input: "CPUutilization12", expected: "CPUutilization"},
+		{name: "plain name", input: "HeapInuse", expected: "HeapInuse"},
+		{name: "underscore separator", input: "http_requests_total", expected: "http"},
+		{name: "dot separator", input: "node1.cpu", expected: "node"},
+		{name: "digits only", input: "12345", expected: "12345"},
+		{name: "leading separator", input: "_private", expected: "_private"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MetricPrefix(tt.input))
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, TenantFromContext(ctx))
+	assert.Equal(t, "agent-1", TenantFromContext(WithTenant(ctx, "agent-1")))
+}
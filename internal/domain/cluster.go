@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotReplicated is returned by metric storage decorators asked to merge states while the decorated storage is
+// not replicated.
+var ErrNotReplicated = errors.New("metric storage is not replicated")
+
+// CounterSlot holds the total a single replica added to a counter. Only the replica itself changes its slot,
+// every change increments the version, so of two copies of a slot the one with the higher version is current.
+type CounterSlot struct {
+	Replica string
+	Total   int64
+	Version uint64
+}
+
+// ReplicatedMetric is the state of a metric that servers of a cluster exchange and merge. Merging states in any
+// order and any number of times gives the same result, so replicas that received the same updates agree.
+// Gauges keep the last written value, ordered by UpdatedAt and then by Writer, and are deleted while DeletedAt
+// is not before UpdatedAt. Counters are the sum of their slots minus the Deleted slots, the slots at the last
+// deletion, and exist while a replica added to the counter after the deletion.
+type ReplicatedMetric struct {
+	ID        string
+	MType     MetricType
+	Value     float64
+	UpdatedAt time.Time
+	Timestamp time.Time
+	Writer    string
+	DeletedAt time.Time
+	Slots     []CounterSlot
+	Deleted   []CounterSlot
+}
+
+// MetricChange is a metric changed by merging the states of a peer. Metric holds the current value unless the
+// merge deleted the metric.
+type MetricChange struct {
+	Metric  Metric
+	Deleted bool
+}
+
+// MetricReplica is the local state of a cluster server. The metric storage decorators implement it as well, so
+// the changes replicated from peers pass through them like local updates.
+// Merge merges the states received from a peer and returns the metrics that changed.
+// ReplicatedMetrics returns the states of all metrics, including deleted ones, for a peer to merge.
+type MetricReplica interface {
+	Merge(ctx context.Context, metrics []ReplicatedMetric) ([]MetricChange, error)
+	ReplicatedMetrics(ctx context.Context) ([]ReplicatedMetric, error)
+}
+
+// ClusterState is the state a server of a cluster returns to a peer pulling it.
+type ClusterState struct {
+	Node    string
+	Metrics []ReplicatedMetric
+	Agents  []AgentStatus
+}
+
+// ClusterMember is the local server as its peers see it.
+// Merge merges the states of changed metrics pushed by a peer.
+// State returns the state of the server for a peer to merge.
+type ClusterMember interface {
+	Merge(ctx context.Context, metrics []ReplicatedMetric) error
+	State(ctx context.Context) (ClusterState, error)
+}
+
+// ReplicaPeer is another server of the cluster.
+// Replicate sends the states of changed metrics to the peer.
+// Sync returns the state of the peer.
+type ReplicaPeer interface {
+	Address() string
+	Replicate(ctx context.Context, metrics []ReplicatedMetric) error
+	Sync(ctx context.Context) (ClusterState, error)
+}
+
+// Leadership tells whether the server is the leader of its cluster, the one that sends the notifications.
+type Leadership interface {
+	IsLeader() bool
+}
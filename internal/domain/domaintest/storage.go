@@ -0,0 +1,27 @@
+// Package domaintest provides helpers for the tests of domain.MetricStorage implementations and decorators.
+package domaintest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// GetMetric reads the metric, failing the test on a storage error.
+func GetMetric(t *testing.T, storage domain.MetricStorage, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
+	t.Helper()
+	metric, found, err := storage.GetMetric(context.Background(), metricType, metricName)
+	require.NoError(t, err)
+	return metric, found
+}
+
+// AllMetrics reads all metrics, failing the test on a storage error.
+func AllMetrics(t *testing.T, storage domain.MetricStorage) []domain.Metric {
+	t.Helper()
+	metrics, err := storage.GetAllMetrics(context.Background())
+	require.NoError(t, err)
+	return metrics
+}
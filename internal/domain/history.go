@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+type HistoryEventType string
+
+const (
+	HistoryAlertFiring        HistoryEventType = "alert_firing"
+	HistoryAlertResolved      HistoryEventType = "alert_resolved"
+	HistoryAlertEscalated     HistoryEventType = "alert_escalated"
+	HistoryAlertAcknowledged  HistoryEventType = "alert_acknowledged"
+	HistoryNotificationSent   HistoryEventType = "notification_sent"
+	HistoryNotificationFailed HistoryEventType = "notification_failed"
+	HistorySilenceCreated     HistoryEventType = "silence_created"
+	HistorySilenceUpdated     HistoryEventType = "silence_updated"
+	HistorySilenceDeleted     HistoryEventType = "silence_deleted"
+)
+
+// HistoryEvent is an entry of the append-only alert history. Alert and notification events
+// describe a single alert, silence events a single silence.
+type HistoryEvent struct {
+	Time      time.Time         `json:"time"`
+	Type      HistoryEventType  `json:"type"`
+	AlertName string            `json:"alert_name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// Silences holds the IDs of the silences muting the alert at the time of the event.
+	Silences  []string `json:"silences,omitempty"`
+	GroupKey  string   `json:"group_key,omitempty"`
+	SilenceID string   `json:"silence_id,omitempty"`
+	Actor     string   `json:"actor,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// HistoryFilter selects history events, empty fields match everything.
+type HistoryFilter struct {
+	Types     []HistoryEventType
+	AlertName string
+	// Labels have to be present on the event with the same values.
+	Labels    map[string]string
+	SilenceID string
+	Actor     string
+	// Since and Until limit the event time to [Since, Until).
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of returned events, the newest are kept.
+	Limit int
+}
+
+// Matches reports whether the event satisfies the filter, ignoring the limit.
+func (f HistoryFilter) Matches(event HistoryEvent) bool {
+	if len(f.Types) > 0 && !slices.Contains(f.Types, event.Type) {
+		return false
+	}
+
+	if f.AlertName != "" && event.AlertName != f.AlertName {
+		return false
+	}
+
+	for name, value := range f.Labels {
+		if actual, ok := event.Labels[name]; !ok || actual != value {
+			return false
+		}
+	}
+
+	if f.SilenceID != "" && event.SilenceID != f.SilenceID && !slices.Contains(event.Silences, f.SilenceID) {
+		return false
+	}
+
+	if f.Actor != "" && event.Actor != f.Actor {
+		return false
+	}
+
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+
+	return f.Until.IsZero() || event.Time.Before(f.Until)
+}
+
+// HistoryRecorder records history events. Failures to record are logged and do not affect the caller.
+type HistoryRecorder interface {
+	Record(ctx context.Context, event HistoryEvent)
+}
+
+// HistoryReader queries the history, returning the newest matching events first.
+type HistoryReader interface {
+	QueryHistory(ctx context.Context, filter HistoryFilter) ([]HistoryEvent, error)
+}
+
+// HistoryStorage persists the history.
+type HistoryStorage interface {
+	AppendEvent(ctx context.Context, event HistoryEvent) error
+	QueryEvents(ctx context.Context, filter HistoryFilter) ([]HistoryEvent, error)
+}
@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"strconv"
+	"time"
 )
 
 // Metric represents a specific metric with its type, identifier, and optional value fields.
@@ -10,11 +11,15 @@ import (
 // MType indicates the type of the metric (e.g., counter, gauge).
 // Delta holds the value for counter-type metrics when defined.
 // Value holds the value for gauge-type metrics when defined.
+// UpdatedAt holds the time the server received the last update of the metric.
+// Timestamp holds the optional time reported by the client with the last update.
 type Metric struct {
-	ID    string     `json:"id"`
-	MType MetricType `json:"type"`
-	Delta *int64     `json:"delta,omitempty"`
-	Value *float64   `json:"value,omitempty"`
+	ID        string     `json:"id"`
+	MType     MetricType `json:"type"`
+	Delta     *int64     `json:"delta,omitempty"`
+	Value     *float64   `json:"value,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitzero"`
+	Timestamp time.Time  `json:"timestamp,omitzero"`
 }
 
 // NewMetrics creates a new Metric instance using the provided type, name, and value, and validates the inputs.
@@ -60,6 +65,19 @@ func NewMetrics(metricType string, metricName string, value string) (*Metric, er
 	return &result, nil
 }
 
+// MarkReceived sets the server receive time of all metrics, overriding any value provided by the client.
+func MarkReceived(metrics []Metric, at time.Time) {
+	for i := range metrics {
+		metrics[i].UpdatedAt = at
+	}
+}
+
+// IsStale reports whether the metric was last updated before now minus the given age.
+// Metrics without a known update time are never stale.
+func (m Metric) IsStale(now time.Time, age time.Duration) bool {
+	return !m.UpdatedAt.IsZero() && m.UpdatedAt.Before(now.Add(-age))
+}
+
 func (m Metric) StringValue() string {
 	switch m.MType {
 	case MetricTypeGauge:
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Sample is the value of a metric at a point in time. Counter samples hold the accumulated total.
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// MetricHistory provides the recent values of the stored metrics.
+type MetricHistory interface {
+	// Samples returns the samples of the metric taken at or after since, oldest first.
+	Samples(ctx context.Context, metricType MetricType, metricName string, since time.Time) []Sample
+	// Retention is how far back samples are kept.
+	Retention() time.Duration
+}
@@ -1,8 +1,16 @@
 package domain
 
+import "context"
+
 // MetricReporter defines an interface for reporting metrics including individual, raw, or batch metric data.
 type MetricReporter interface {
 	ReportRawMetric(metricType MetricType, key string, value string)
 	ReportMetric(metric Metric)
 	ReportBatch(metrics []Metric)
 }
+
+// MetricBatchSender sends a batch of metrics to a server. Unlike ReportBatch it returns an error when the server
+// did not accept the batch, so the caller can keep it and send it again.
+type MetricBatchSender interface {
+	SendBatch(ctx context.Context, metrics []Metric) error
+}
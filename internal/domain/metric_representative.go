@@ -2,17 +2,23 @@ package domain
 
 import (
 	"slices"
+	"time"
 )
 
 // MetricRepresentative represents a simplified version of a metric with type, name, and value for easier manipulation.
 type MetricRepresentative struct {
-	Type  MetricType
-	Name  string
-	Value string
+	Type      MetricType
+	Name      string
+	Value     string
+	UpdatedAt time.Time
 }
 
 func (m MetricRepresentative) String() string {
-	return m.Name + " (" + string(m.Type) + ") = " + m.Value
+	res := m.Name + " (" + string(m.Type) + ") = " + m.Value
+	if !m.UpdatedAt.IsZero() {
+		res += ", updated " + m.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	return res
 }
 
 type MetricRepresentatives []MetricRepresentative
@@ -22,9 +28,10 @@ func NewMetricRepresentatives(metrics []Metric) MetricRepresentatives {
 	res := make(MetricRepresentatives, len(metrics))
 	for i, metric := range metrics {
 		res[i] = MetricRepresentative{
-			Type:  metric.MType,
-			Name:  metric.ID,
-			Value: metric.StringValue(),
+			Type:      metric.MType,
+			Name:      metric.ID,
+			Value:     metric.StringValue(),
+			UpdatedAt: metric.UpdatedAt,
 		}
 	}
 	return res
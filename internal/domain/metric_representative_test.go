@@ -2,6 +2,7 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,6 +31,16 @@ func TestMetricRepresentative_String(t *testing.T) {
 			},
 			expected: "requests_total (counter) = 1000",
 		},
+		{
+			name: "metric with update time",
+			metric: MetricRepresentative{
+				Type:      MetricTypeGauge,
+				Name:      "cpu_usage",
+				Value:     "75.5",
+				UpdatedAt: time.Date(2025, 5, 6, 19, 29, 12, 0, time.UTC),
+			},
+			expected: "cpu_usage (gauge) = 75.5, updated 2025-05-06T19:29:12Z",
+		},
 		{
 			name: "empty values",
 			metric: MetricRepresentative{
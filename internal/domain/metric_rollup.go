@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRange = errors.New("invalid range query")
+
+// Resolution is the interval the values of a series are rolled up over, 0 stands for the raw samples.
+type Resolution time.Duration
+
+const ResolutionRaw Resolution = 0
+
+// ParseResolution parses "raw" or a duration such as "1m" or "1h".
+func ParseResolution(value string) (Resolution, error) {
+	if value == "raw" {
+		return ResolutionRaw, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%w: resolution must be raw or a positive duration such as 1m, got %q", ErrInvalidRange, value)
+	}
+	return Resolution(d), nil
+}
+
+// String formats the resolution as "raw" or a short duration such as "1m" or "1h".
+func (r Resolution) String() string {
+	if r == ResolutionRaw {
+		return "raw"
+	}
+
+	s := time.Duration(r).String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+func (r Resolution) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// RetentionPolicy keeps the rollups of the given resolution for the retention period.
+type RetentionPolicy struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// ValidateRetentionPolicies checks that the rollup policies are ordered from the finest resolution, that every
+// resolution is a multiple of the previous one, so rollups can be built from the finer ones, and that every
+// level keeps its data longer than the previous one, starting with the raw retention.
+func ValidateRetentionPolicies(raw time.Duration, policies []RetentionPolicy) error {
+	var resolution time.Duration
+	retention := raw
+	for _, policy := range policies {
+		if policy.Resolution <= 0 {
+			return fmt.Errorf("rollup resolution must be positive, got %s", policy.Resolution)
+		}
+		if resolution > 0 && (policy.Resolution <= resolution || policy.Resolution%resolution != 0) {
+			return fmt.Errorf("rollup resolution %s must be a multiple of %s", policy.Resolution, resolution)
+		}
+		if policy.Retention < retention {
+			return fmt.Errorf("%s rollups must be kept at least as long as the finer data, %s", policy.Resolution, retention)
+		}
+		resolution, retention = policy.Resolution, policy.Retention
+	}
+	return nil
+}
+
+// Rollup aggregates the values of a series over the interval starting at Start. Gauges have Min, Max, Avg and
+// Last set; counters have Sum, the increase over the interval, and Last, the total at its end.
+// A raw sample is returned as a rollup of Count 1.
+type Rollup struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+	Avg   float64   `json:"avg"`
+	Sum   float64   `json:"sum"`
+	Last  float64   `json:"last"`
+}
+
+// RangeQuery selects the values of a metric between From and To. Without a Resolution the finest one keeping
+// the whole range that returns at most MaxPoints is picked.
+type RangeQuery struct {
+	MType      MetricType
+	ID         string
+	From       time.Time
+	To         time.Time
+	Resolution *Resolution
+	MaxPoints  int
+}
+
+// MetricRange is the result of a RangeQuery, oldest point first.
+type MetricRange struct {
+	ID         string     `json:"id"`
+	MType      MetricType `json:"type"`
+	Resolution Resolution `json:"resolution"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	Points     []Rollup   `json:"points"`
+}
+
+// MetricRangeReader answers range queries over the metric history.
+type MetricRangeReader interface {
+	QueryRange(ctx context.Context, query RangeQuery) (MetricRange, error)
+}
+
+// RollupSeries is a run of rollups of one metric at one resolution, oldest first.
+type RollupSeries struct {
+	MType      MetricType
+	ID         string
+	Resolution Resolution
+	Rollups    []Rollup
+}
+
+// RollupStorage keeps the rollups of the metric history across restarts. Rollups are only ever appended for
+// intervals after the stored ones of the series, and dropped once they expire or the metric is deleted.
+type RollupStorage interface {
+	// LoadRollups returns all stored rollups, each series oldest first.
+	LoadRollups(ctx context.Context) ([]RollupSeries, error)
+	AppendRollups(ctx context.Context, series []RollupSeries) error
+	// PruneRollups drops the rollups of the resolution starting before the cutoff.
+	PruneRollups(ctx context.Context, resolution Resolution, before time.Time) error
+	// DeleteRollups drops the rollups of every resolution of the metric.
+	DeleteRollups(ctx context.Context, metricType MetricType, metricName string) error
+	// DeleteRollupsByPrefix drops the rollups of the metrics whose names start with the prefix.
+	DeleteRollupsByPrefix(ctx context.Context, prefix string) error
+}
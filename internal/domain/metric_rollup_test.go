@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolution(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Resolution
+	}{
+		{input: "raw", expected: ResolutionRaw},
+		{input: "1m", expected: Resolution(time.Minute)},
+		{input: "1h", expected: Resolution(time.Hour)},
+		{input: "1h30m", expected: Resolution(90 * time.Minute)},
+		{input: "30s", expected: Resolution(30 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			resolution, err := ParseResolution(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, resolution)
+			assert.Equal(t, tt.input, resolution.String())
+		})
+	}
+
+	for _, input := range []string{"", "minute", "-1m", "0s"} {
+		_, err := ParseResolution(input)
+		assert.ErrorIs(t, err, ErrInvalidRange, input)
+	}
+}
+
+func TestValidateRetentionPolicies(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name     string
+		policies []RetentionPolicy
+		wantErr  bool
+	}{
+		{name: "none"},
+		{name: "minutes and hours", policies: []RetentionPolicy{{time.Minute, 30 * day}, {time.Hour, 365 * day}}},
+		{name: "zero resolution", policies: []RetentionPolicy{{0, day}}, wantErr: true},
+		{name: "not a multiple", policies: []RetentionPolicy{{time.Minute, day}, {90 * time.Second, day}}, wantErr: true},
+		{name: "finer after coarser", policies: []RetentionPolicy{{time.Hour, day}, {time.Minute, day}}, wantErr: true},
+		{name: "shorter than raw", policies: []RetentionPolicy{{time.Minute, time.Hour}}, wantErr: true},
+		{name: "shorter than finer", policies: []RetentionPolicy{{time.Minute, 30 * day}, {time.Hour, day}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRetentionPolicies(2*day, tt.policies)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -1,17 +1,62 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrStorageUnavailable is wrapped by read errors of storages that can fail, like a database that is down,
+// so callers can tell an outage from a missing metric.
+var ErrStorageUnavailable = errors.New("metric storage is unavailable")
+
+// ErrNotStoredState is returned by metric storage decorators asked for the stored state of updated metrics while
+// the decorated storage does not return it. Nothing is updated then.
+var ErrNotStoredState = errors.New("metric storage does not return the stored state")
+
+// ErrNotStaleDeleter is returned by metric storage decorators asked to delete a stale metric while the decorated
+// storage can not check the staleness with the deletion. Nothing is deleted then.
+var ErrNotStaleDeleter = errors.New("metric storage does not delete stale metrics")
 
 // MetricStorage defines an interface for managing and interacting with metrics in storage.
-// GetAllMetrics retrieves all stored metrics.
+// GetAllMetrics retrieves all stored metrics. Returns an error if the storage could not be read.
 // UpdateMetric updates a single metric in storage.
 // UpdateMetrics updates multiple metrics in storage.
-// GetMetric retrieves a specific metric by type and name. Returns the metric and a boolean indicating if found.
+// GetMetric retrieves a specific metric by type and name. Returns the metric, a boolean indicating if found and
+// an error if the storage could not be read, which is not the same as the metric not being found.
+// DeleteMetric removes a specific metric by type and name. Returns a boolean indicating if the metric existed.
+// DeleteByPrefix removes all metrics whose name starts with the given prefix. Returns the number of removed metrics.
 // Ping checks the liveness of the storage connection.
 type MetricStorage interface {
-	GetAllMetrics(ctx context.Context) []Metric
+	GetAllMetrics(ctx context.Context) ([]Metric, error)
 	UpdateMetric(ctx context.Context, metric Metric) error
 	UpdateMetrics(ctx context.Context, metrics []Metric) error
-	GetMetric(ctx context.Context, metricType MetricType, metricName string) (Metric, bool)
+	GetMetric(ctx context.Context, metricType MetricType, metricName string) (Metric, bool, error)
+	DeleteMetric(ctx context.Context, metricType MetricType, metricName string) (bool, error)
+	DeleteByPrefix(ctx context.Context, prefix string) (int, error)
 	Ping(ctx context.Context) error
 }
+
+// MetricInvalidator drops cached metrics that were changed in a storage shared with other server replicas.
+// Invalidate drops a single metric. Suspend drops all cached metrics and stops caching while changes may be
+// missed, for example while the change feed is disconnected, until Resume is called.
+type MetricInvalidator interface {
+	Invalidate(metricType MetricType, metricName string)
+	Suspend()
+	Resume()
+}
+
+// StoredStateUpdater is a MetricStorage returning the state of the metrics it updates, so counters come with their
+// total without reading them back.
+// UpdateMetricsStored updates the metrics like UpdateMetrics and returns the stored state of each updated metric
+// once, in no particular order.
+type StoredStateUpdater interface {
+	UpdateMetricsStored(ctx context.Context, metrics []Metric) ([]Metric, error)
+}
+
+// StaleMetricDeleter is a MetricStorage deleting metrics that went stale.
+// DeleteMetricIfStale removes the metric like DeleteMetric, but only if it was last updated before the given time,
+// checked together with the deletion so a concurrent update keeps the metric. Returns whether it was removed.
+type StaleMetricDeleter interface {
+	DeleteMetricIfStale(ctx context.Context, metricType MetricType, metricName string, before time.Time) (bool, error)
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -241,6 +242,26 @@ func TestMetric_StringValue_NilPointers(t *testing.T) {
 	})
 }
 
+func TestMetric_IsStale(t *testing.T) {
+	now := time.Date(2025, 5, 6, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		metric   Metric
+		expected bool
+	}{
+		{name: "recently updated", metric: Metric{UpdatedAt: now.Add(-time.Minute)}, expected: false},
+		{name: "updated long ago", metric: Metric{UpdatedAt: now.Add(-time.Hour)}, expected: true},
+		{name: "unknown update time", metric: Metric{}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.metric.IsStale(now, 5*time.Minute))
+		})
+	}
+}
+
 func pointerFrom[T any](v T) *T {
 	return &v
 }
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid notification template")
+	ErrUnknownReceiver = errors.New("unknown receiver")
+)
+
+// NotificationTemplates renders the notification text of each receiver from a template.
+type NotificationTemplates interface {
+	// Templates returns the template source of each receiver.
+	Templates() map[string]string
+	// Preview renders the given template source, or the receiver template if it is empty, for the alerts.
+	// Without alerts a sample alert is rendered.
+	Preview(ctx context.Context, receiver string, source string, alerts []Alert) (string, error)
+}
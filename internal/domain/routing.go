@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidRouting     = errors.New("invalid routing")
+	ErrInvalidAck         = errors.New("invalid acknowledgement")
+	ErrEscalationNotFound = errors.New("escalation not found")
+)
+
+// Receiver is a team or person notifications are routed to. Receivers without a webhook URL are
+// notified through the server log.
+type Receiver struct {
+	Name       string `json:"name"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// EscalationStep notifies another receiver when a firing alert is still unacknowledged After its first notification.
+type EscalationStep struct {
+	After    Duration `json:"after"`
+	Receiver string   `json:"receiver"`
+}
+
+// Route sends the alerts matching its metric prefix and matchers to its receiver. The child routes are checked
+// in order and the first matching one takes over, unless it has Continue set, in which case the following
+// children are checked as well. A child without a receiver or escalation steps inherits those of its parent.
+type Route struct {
+	Receiver string `json:"receiver,omitempty"`
+	// MetricPrefix selects the alerts whose metric label starts with the prefix.
+	MetricPrefix string           `json:"metric_prefix,omitempty"`
+	Matchers     []Matcher        `json:"matchers,omitempty"`
+	Escalation   []EscalationStep `json:"escalation,omitempty"`
+	Continue     bool             `json:"continue,omitempty"`
+	Routes       []Route          `json:"routes,omitempty"`
+}
+
+// Matches reports whether the alert satisfies the metric prefix and all matchers of the route, ignoring its children.
+func (r Route) Matches(alert Alert) bool {
+	if r.MetricPrefix != "" {
+		metric, ok := alert.Labels[MetricLabel]
+		if !ok || !strings.HasPrefix(metric, r.MetricPrefix) {
+			return false
+		}
+	}
+
+	for _, matcher := range r.Matchers {
+		if !matcher.Matches(alert) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoutingConfig is the routing tree with the receivers it refers to. The root route matches every alert.
+type RoutingConfig struct {
+	Receivers []Receiver `json:"receivers"`
+	Route     Route      `json:"route"`
+}
+
+func (c RoutingConfig) Validate() error {
+	receivers := make(map[string]bool, len(c.Receivers))
+	for _, receiver := range c.Receivers {
+		if receiver.Name == "" {
+			return fmt.Errorf("%w: receiver name is required", ErrInvalidRouting)
+		}
+		if receivers[receiver.Name] {
+			return fmt.Errorf("%w: duplicate receiver %s", ErrInvalidRouting, receiver.Name)
+		}
+		if receiver.WebhookURL != "" {
+			u, err := url.Parse(receiver.WebhookURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: receiver %s: webhook_url must be an http(s) URL", ErrInvalidRouting, receiver.Name)
+			}
+		}
+		receivers[receiver.Name] = true
+	}
+
+	if c.Route.Receiver == "" {
+		return fmt.Errorf("%w: the root route needs a receiver", ErrInvalidRouting)
+	}
+	if c.Route.MetricPrefix != "" || len(c.Route.Matchers) > 0 {
+		return fmt.Errorf("%w: the root route matches every alert and cannot have a metric prefix or matchers", ErrInvalidRouting)
+	}
+
+	return validateRoute(c.Route, receivers)
+}
+
+func validateRoute(route Route, receivers map[string]bool) error {
+	if route.Receiver != "" && !receivers[route.Receiver] {
+		return fmt.Errorf("%w: unknown receiver %s", ErrInvalidRouting, route.Receiver)
+	}
+
+	for i := range route.Matchers {
+		if err := route.Matchers[i].Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidRouting, err)
+		}
+	}
+
+	var previous Duration
+	for _, step := range route.Escalation {
+		if !receivers[step.Receiver] {
+			return fmt.Errorf("%w: escalation to unknown receiver %s", ErrInvalidRouting, step.Receiver)
+		}
+		if step.After <= previous {
+			return fmt.Errorf("%w: escalation steps must have increasing positive delays, got %s after %s", ErrInvalidRouting, step.After, previous)
+		}
+		previous = step.After
+	}
+
+	for _, child := range route.Routes {
+		if err := validateRoute(child, receivers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Acknowledgement stops the escalation of the firing alert with the given name and labels.
+type Acknowledgement struct {
+	AlertName      string            `json:"alertname"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	AcknowledgedBy string            `json:"acknowledged_by"`
+	Comment        string            `json:"comment,omitempty"`
+}
+
+func (a Acknowledgement) Validate() error {
+	if a.AlertName == "" {
+		return fmt.Errorf("%w: alertname is required", ErrInvalidAck)
+	}
+	if a.AcknowledgedBy == "" {
+		return fmt.Errorf("%w: acknowledged_by is required", ErrInvalidAck)
+	}
+	return nil
+}
+
+// Escalation is the escalation state of a firing alert routed to a route with escalation steps.
+type Escalation struct {
+	Alert Alert `json:"alert"`
+	// Notified lists the receivers that were notified about the alert, in order.
+	Notified       []string        `json:"notified"`
+	NotifiedAt     time.Time       `json:"notified_at"`
+	NextStep       *EscalationStep `json:"next_step,omitempty"`
+	NextStepAt     time.Time       `json:"next_step_at,omitzero"`
+	AcknowledgedBy string          `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt time.Time       `json:"acknowledged_at,omitzero"`
+	Comment        string          `json:"comment,omitempty"`
+}
+
+// EscalationManager lists the escalations of firing alerts and stops them when alerts are acknowledged.
+type EscalationManager interface {
+	Escalations() []Escalation
+	Acknowledge(ctx context.Context, ack Acknowledgement) (Escalation, error)
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrInvalidRule  = errors.New("invalid rule")
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrRuleExists   = errors.New("rule already exists")
+)
+
+// MetricLabel is the alert label holding the ID of the metric a rule fired for.
+const MetricLabel = "metric"
+
+var ruleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_:-]+$`)
+
+// Rule raises an alert named after the rule for every metric matched by its expression,
+// once the expression has been true for the metric for at least For.
+type Rule struct {
+	Name        string            `json:"name"`
+	Expr        string            `json:"expr"`
+	For         Duration          `json:"for"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// Validate checks the rule fields, the expression is validated by the rule engine.
+func (r Rule) Validate() error {
+	if !ruleNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("%w: name must consist of letters, digits, '_', ':' and '-'", ErrInvalidRule)
+	}
+
+	if r.Expr == "" {
+		return fmt.Errorf("%w: expr is required", ErrInvalidRule)
+	}
+
+	if r.For < 0 {
+		return fmt.Errorf("%w: for must not be negative", ErrInvalidRule)
+	}
+
+	return nil
+}
+
+// RuleSample is the value of a single metric in a rule evaluation.
+type RuleSample struct {
+	MetricID string  `json:"metric_id"`
+	Value    float64 `json:"value"`
+}
+
+// RuleTestResult is the outcome of a rule dry-run against the current metrics.
+type RuleTestResult struct {
+	// Series holds the current values of all metrics selected by the expression.
+	Series []RuleSample `json:"series"`
+	// Matches holds the metrics for which the expression is true.
+	Matches []RuleSample `json:"matches"`
+	// Alerts holds the alerts the rule would raise, ignoring its For duration.
+	Alerts []Alert `json:"alerts"`
+}
+
+// RuleStorage persists rules.
+type RuleStorage interface {
+	ListRules(ctx context.Context) ([]Rule, error)
+	SaveRule(ctx context.Context, rule Rule) error
+	DeleteRule(ctx context.Context, name string) (bool, error)
+}
+
+// RuleManager manages alert rules on behalf of the API.
+type RuleManager interface {
+	ListRules(ctx context.Context) []Rule
+	GetRule(ctx context.Context, name string) (Rule, bool)
+	CreateRule(ctx context.Context, rule Rule) (Rule, error)
+	UpdateRule(ctx context.Context, rule Rule) (Rule, error)
+	DeleteRule(ctx context.Context, name string) (bool, error)
+	TestRule(ctx context.Context, rule Rule) (RuleTestResult, error)
+}
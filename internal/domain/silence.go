@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	ErrInvalidSilence  = errors.New("invalid silence")
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+// AlertNameLabel is the matcher name selecting alerts by their name instead of a label.
+const AlertNameLabel = "alertname"
+
+type MatchOperator string
+
+const (
+	MatchEqual     MatchOperator = "="
+	MatchNotEqual  MatchOperator = "!="
+	MatchRegexp    MatchOperator = "=~"
+	MatchNotRegexp MatchOperator = "!~"
+)
+
+// Matcher selects alerts by the value of a label or, for AlertNameLabel, by the alert name.
+// Regular expressions are anchored and must match the whole value.
+type Matcher struct {
+	Name     string        `json:"name"`
+	Operator MatchOperator `json:"operator"`
+	Value    string        `json:"value"`
+	// re is the compiled expression of a regexp matcher, set by Validate and when the matcher is decoded
+	re *regexp.Regexp
+}
+
+// Validate checks the matcher and keeps its compiled expression for Matches.
+func (m *Matcher) Validate() error {
+	if m.Name == "" {
+		return errors.New("matcher name is required")
+	}
+
+	switch m.Operator {
+	case MatchEqual, MatchNotEqual:
+		return nil
+	case MatchRegexp, MatchNotRegexp:
+		re, err := m.compile()
+		if err != nil {
+			return fmt.Errorf("matcher %s: %w", m.Name, err)
+		}
+		m.re = re
+		return nil
+	default:
+		return fmt.Errorf("matcher %s: unknown operator %q", m.Name, m.Operator)
+	}
+}
+
+// UnmarshalJSON decodes the matcher and compiles its expression. An invalid one is left to Validate.
+func (m *Matcher) UnmarshalJSON(data []byte) error {
+	type plain Matcher
+	if err := json.Unmarshal(data, (*plain)(m)); err != nil {
+		return err
+	}
+
+	if m.Operator == MatchRegexp || m.Operator == MatchNotRegexp {
+		m.re, _ = m.compile()
+	}
+	return nil
+}
+
+func (m Matcher) compile() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + m.Value + ")$")
+}
+
+// Matches reports whether the alert satisfies the matcher. Missing labels match as empty values.
+func (m Matcher) Matches(alert Alert) bool {
+	value := alert.Labels[m.Name]
+	if m.Name == AlertNameLabel {
+		value = alert.Name
+	}
+
+	switch m.Operator {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp, MatchNotRegexp:
+		// A matcher built without Validate is compiled on every match
+		re := m.re
+		if re == nil {
+			var err error
+			if re, err = m.compile(); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(value) == (m.Operator == MatchRegexp)
+	default:
+		return false
+	}
+}
+
+type SilenceState string
+
+const (
+	SilenceStatePending SilenceState = "pending"
+	SilenceStateActive  SilenceState = "active"
+	SilenceStateExpired SilenceState = "expired"
+)
+
+// Silence mutes notifications of the alerts matching all of its matchers between StartsAt and EndsAt.
+type Silence struct {
+	ID        string       `json:"id"`
+	Matchers  []Matcher    `json:"matchers"`
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    time.Time    `json:"ends_at"`
+	CreatedBy string       `json:"created_by"`
+	Comment   string       `json:"comment"`
+	CreatedAt time.Time    `json:"created_at"`
+	State     SilenceState `json:"state,omitempty"`
+}
+
+func (s Silence) Validate() error {
+	if len(s.Matchers) == 0 {
+		return fmt.Errorf("%w: at least one matcher is required", ErrInvalidSilence)
+	}
+
+	for i := range s.Matchers {
+		if err := s.Matchers[i].Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSilence, err)
+		}
+	}
+
+	if s.CreatedBy == "" {
+		return fmt.Errorf("%w: created_by is required", ErrInvalidSilence)
+	}
+
+	if !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSilence)
+	}
+
+	return nil
+}
+
+func (s Silence) StateAt(now time.Time) SilenceState {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilenceStatePending
+	case now.Before(s.EndsAt):
+		return SilenceStateActive
+	default:
+		return SilenceStateExpired
+	}
+}
+
+// Matches reports whether the alert satisfies all matchers of the silence, regardless of its time range.
+func (s Silence) Matches(alert Alert) bool {
+	for _, matcher := range s.Matchers {
+		if !matcher.Matches(alert) {
+			return false
+		}
+	}
+	return len(s.Matchers) > 0
+}
+
+// SilenceStorage persists silences.
+type SilenceStorage interface {
+	ListSilences(ctx context.Context) ([]Silence, error)
+	SaveSilence(ctx context.Context, silence Silence) error
+	DeleteSilence(ctx context.Context, id string) (bool, error)
+}
+
+// SilenceManager manages silences on behalf of the API.
+type SilenceManager interface {
+	ListSilences(ctx context.Context) []Silence
+	GetSilence(ctx context.Context, id string) (Silence, bool)
+	CreateSilence(ctx context.Context, silence Silence) (Silence, error)
+	UpdateSilence(ctx context.Context, silence Silence) (Silence, error)
+	DeleteSilence(ctx context.Context, id string) (bool, error)
+}
+
+// AlertSilencer tells which active silences mute an alert.
+type AlertSilencer interface {
+	SilencedBy(alert Alert) []string
+}
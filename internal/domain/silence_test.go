@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Matches(t *testing.T) {
+	alert := Alert{Name: "MetricAnomaly", Labels: map[string]string{"metric": "CPUutilization12"}}
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		want    bool
+	}{
+		{name: "equal label", matcher: Matcher{Name: "metric", Operator: MatchEqual, Value: "CPUutilization12"}, want: true},
+		{name: "equal alert name", matcher: Matcher{Name: AlertNameLabel, Operator: MatchEqual, Value: "MetricAnomaly"}, want: true},
+		{name: "not equal", matcher: Matcher{Name: "metric", Operator: MatchNotEqual, Value: "CPUutilization12"}, want: false},
+		{name: "regexp", matcher: Matcher{Name: "metric", Operator: MatchRegexp, Value: "CPUutilization[0-9]+"}, want: true},
+		{name: "regexp is anchored", matcher: Matcher{Name: "metric", Operator: MatchRegexp, Value: "CPU"}, want: false},
+		{name: "not regexp", matcher: Matcher{Name: "metric", Operator: MatchNotRegexp, Value: "Heap.*"}, want: true},
+		{name: "missing label matches empty", matcher: Matcher{Name: "agent", Operator: MatchEqual, Value: ""}, want: true},
+		{name: "unknown operator", matcher: Matcher{Name: "metric", Operator: "~", Value: "CPUutilization12"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.matcher.Matches(alert))
+		})
+	}
+}
+
+func TestMatcher_Compiled(t *testing.T) {
+	alert := Alert{Name: "MetricAnomaly", Labels: map[string]string{"metric": "CPUutilization12"}}
+
+	silence := Silence{Matchers: []Matcher{{Name: "metric", Operator: MatchRegexp, Value: "CPU.*"}}}
+	_ = silence.Validate()
+	require.NotNil(t, silence.Matchers[0].re, "validated matchers keep the compiled expression")
+	assert.True(t, silence.Matches(alert))
+
+	var decoded Silence
+	require.NoError(t, json.Unmarshal([]byte(`{"matchers":[{"name":"metric","operator":"!~","value":"Heap.*"}]}`), &decoded))
+	require.NotNil(t, decoded.Matchers[0].re, "decoded matchers are compiled")
+	assert.True(t, decoded.Matches(alert))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"matchers":[{"name":"metric","operator":"=~","value":"("}]}`), &decoded))
+	assert.Error(t, decoded.Validate())
+	assert.False(t, decoded.Matches(alert))
+}
+
+func TestSilence_Validate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := Silence{
+		Matchers:  []Matcher{{Name: AlertNameLabel, Operator: MatchEqual, Value: "AgentAbsent"}},
+		StartsAt:  start,
+		EndsAt:    start.Add(time.Hour),
+		CreatedBy: "ops",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *Silence)
+		valid  bool
+	}{
+		{name: "valid", modify: func(s *Silence) {}, valid: true},
+		{name: "no matchers", modify: func(s *Silence) { s.Matchers = nil }},
+		{name: "no creator", modify: func(s *Silence) { s.CreatedBy = "" }},
+		{name: "ends before start", modify: func(s *Silence) { s.EndsAt = start.Add(-time.Minute) }},
+		{name: "bad regexp", modify: func(s *Silence) { s.Matchers = []Matcher{{Name: "metric", Operator: MatchRegexp, Value: "("}} }},
+		{name: "bad operator", modify: func(s *Silence) { s.Matchers = []Matcher{{Name: "metric", Value: "x"}} }},
+		{name: "empty matcher name", modify: func(s *Silence) { s.Matchers = []Matcher{{Operator: MatchEqual, Value: "x"}} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silence := valid
+			tt.modify(&silence)
+
+			err := silence.Validate()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidSilence)
+			}
+		})
+	}
+}
+
+func TestSilence_StateAt(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	silence := Silence{StartsAt: start, EndsAt: start.Add(time.Hour)}
+
+	assert.Equal(t, SilenceStatePending, silence.StateAt(start.Add(-time.Second)))
+	assert.Equal(t, SilenceStateActive, silence.StateAt(start))
+	assert.Equal(t, SilenceStateExpired, silence.StateAt(start.Add(time.Hour)))
+}
@@ -2,11 +2,15 @@ package client
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 
 	"github.com/angryscorp/alert-metrics/internal/grpc/mapper"
 
@@ -16,16 +20,52 @@ import (
 
 const contextTimeout = 5 * time.Second
 
+// serviceConfig connects to the first reachable server, moving on to the next one when it goes down, and retries
+// reports failing while no server is reachable.
+const serviceConfig = `{
+	"loadBalancingConfig": [{"pick_first": {}}],
+	"methodConfig": [{
+		"name": [{"service": "MetricsService"}],
+		"retryPolicy": {
+			"maxAttempts": 3,
+			"initialBackoff": "1s",
+			"maxBackoff": "2s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
+var (
+	_ domain.MetricReporter    = (*GRPCMetricReporter)(nil)
+	_ domain.MetricBatchSender = (*GRPCMetricReporter)(nil)
+)
+
 type GRPCMetricReporter struct {
 	client grpcmetrics.MetricsServiceClient
 	conn   *grpc.ClientConn
 	logger zerolog.Logger
 }
 
-func New(address string, logger zerolog.Logger) (*GRPCMetricReporter, error) {
+// New connects to the servers at the addresses, failing over between them, for example the servers of a cluster.
+func New(addresses []string, agent domain.AgentInfo, logger zerolog.Logger) (*GRPCMetricReporter, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no server address")
+	}
+
+	servers := manual.NewBuilderWithScheme("servers")
+	state := resolver.State{Addresses: make([]resolver.Address, len(addresses))}
+	for i, address := range addresses {
+		state.Addresses[i] = resolver.Address{Addr: address}
+	}
+	servers.InitialState(state)
+
 	conn, err := grpc.NewClient(
-		address,
+		servers.Scheme()+":///metrics",
+		grpc.WithResolvers(servers),
+		grpc.WithDefaultServiceConfig(serviceConfig),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(agentInfoInterceptor(agent)),
 	)
 	if err != nil {
 		return nil, err
@@ -84,6 +124,15 @@ func (gr *GRPCMetricReporter) ReportBatch(metrics []domain.Metric) {
 	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
 	defer cancel()
 
+	if err := gr.SendBatch(ctx, metrics); err != nil {
+		gr.logger.Error().Err(err).Int("count", len(metrics)).Msg("failed to report batch via gRPC")
+		return
+	}
+
+	gr.logger.Debug().Int("count", len(metrics)).Msg("batch reported via gRPC")
+}
+
+func (gr *GRPCMetricReporter) SendBatch(ctx context.Context, metrics []domain.Metric) error {
 	protoMetrics := make([]*grpcmetrics.Metric, len(metrics))
 	for i, metric := range metrics {
 		protoMetrics[i] = mapper.MetricToProto(metric)
@@ -94,12 +143,7 @@ func (gr *GRPCMetricReporter) ReportBatch(metrics []domain.Metric) {
 	}
 
 	_, err := gr.client.ReportBatch(ctx, req)
-	if err != nil {
-		gr.logger.Error().Err(err).Int("count", len(metrics)).Msg("failed to report batch via gRPC")
-		return
-	}
-
-	gr.logger.Debug().Int("count", len(metrics)).Msg("batch reported via gRPC")
+	return err
 }
 
 func (gr *GRPCMetricReporter) Close() error {
@@ -108,3 +152,24 @@ func (gr *GRPCMetricReporter) Close() error {
 	}
 	return nil
 }
+
+// agentInfoInterceptor identifies the agent to the server the same way the HTTP transports do.
+func agentInfoInterceptor(agent domain.AgentInfo) grpc.UnaryClientInterceptor {
+	var pairs []string
+	if agent.ID != "" {
+		pairs = append(pairs, domain.HeaderAgentID, agent.ID)
+	}
+	if agent.Address != "" {
+		pairs = append(pairs, "X-Real-IP", agent.Address)
+	}
+	if agent.Version != "" {
+		pairs = append(pairs, domain.HeaderAgentVersion, agent.Version)
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if len(pairs) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
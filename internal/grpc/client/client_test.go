@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
+)
+
+// recordingServer records the ids of the reported batches.
+type recordingServer struct {
+	grpcmetrics.UnimplementedMetricsServiceServer
+	mu  sync.Mutex
+	ids []string
+}
+
+func (s *recordingServer) ReportBatch(ctx context.Context, req *grpcmetrics.ReportBatchRequest) (*grpcmetrics.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, metric := range req.Metrics {
+		s.ids = append(s.ids, metric.Id)
+	}
+	return &grpcmetrics.Empty{}, nil
+}
+
+func (s *recordingServer) reported() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ids...)
+}
+
+// startServer serves the metrics service on a local port until the test ends or the returned server is stopped.
+func startServer(t *testing.T) (*recordingServer, *grpc.Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	recorder := &recordingServer{}
+	server := grpc.NewServer()
+	grpcmetrics.RegisterMetricsServiceServer(server, recorder)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return recorder, server, listener.Addr().String()
+}
+
+func TestGRPCMetricReporter_Failover(t *testing.T) {
+	first, firstServer, firstAddress := startServer(t)
+	second, _, secondAddress := startServer(t)
+
+	reporter, err := New([]string{firstAddress, secondAddress}, domain.AgentInfo{ID: "agent-1"}, zerolog.Nop())
+	require.NoError(t, err)
+	defer reporter.Close()
+
+	delta := int64(1)
+	reporter.ReportBatch([]domain.Metric{{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: &delta}})
+	assert.Equal(t, []string{"PollCount"}, first.reported(), "the first server is used while it is up")
+
+	firstServer.Stop()
+	require.Eventually(t, func() bool {
+		reporter.ReportBatch([]domain.Metric{{ID: "Failover", MType: domain.MetricTypeCounter, Delta: &delta}})
+		return len(second.reported()) > 0
+	}, 10*time.Second, 100*time.Millisecond)
+	assert.Contains(t, second.reported(), "Failover")
+}
+
+func TestNew_WithoutAddress(t *testing.T) {
+	_, err := New(nil, domain.AgentInfo{}, zerolog.Nop())
+	assert.Error(t, err)
+}
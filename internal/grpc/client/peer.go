@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/grpc/mapper"
+	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
+)
+
+// Peer connects to the cluster service of another server.
+type Peer struct {
+	address string
+	client  grpcmetrics.ClusterServiceClient
+	conn    *grpc.ClientConn
+}
+
+var _ domain.ReplicaPeer = (*Peer)(nil)
+
+func NewPeer(address string) (*Peer, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Peer{
+		address: address,
+		client:  grpcmetrics.NewClusterServiceClient(conn),
+		conn:    conn,
+	}, nil
+}
+
+func (p *Peer) Address() string {
+	return p.address
+}
+
+func (p *Peer) Replicate(ctx context.Context, metrics []domain.ReplicatedMetric) error {
+	req := &grpcmetrics.ReplicateRequest{
+		Metrics: make([]*grpcmetrics.ReplicatedMetric, len(metrics)),
+	}
+	for i, metric := range metrics {
+		req.Metrics[i] = mapper.ReplicatedMetricToProto(metric)
+	}
+
+	_, err := p.client.Replicate(ctx, req)
+	return err
+}
+
+func (p *Peer) Sync(ctx context.Context) (domain.ClusterState, error) {
+	resp, err := p.client.Sync(ctx, &grpcmetrics.Empty{})
+	if err != nil {
+		return domain.ClusterState{}, err
+	}
+
+	return mapper.ClusterStateToDomain(resp), nil
+}
+
+func (p *Peer) Close() error {
+	return p.conn.Close()
+}
@@ -1,6 +1,10 @@
 package mapper
 
 import (
+	"maps"
+	"slices"
+	"time"
+
 	"github.com/angryscorp/alert-metrics/internal/domain"
 	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
 )
@@ -19,6 +23,11 @@ func MetricToProto(metric domain.Metric) *grpcmetrics.Metric {
 		protoMetric.Value = metric.Value
 	}
 
+	if !metric.Timestamp.IsZero() {
+		timestamp := metric.Timestamp.UnixMilli()
+		protoMetric.Timestamp = &timestamp
+	}
+
 	return protoMetric
 }
 
@@ -32,3 +41,154 @@ func MetricTypeToProto(metricType domain.MetricType) grpcmetrics.MetricType {
 		return grpcmetrics.MetricType_METRIC_TYPE_UNSPECIFIED
 	}
 }
+
+func SilenceToProto(silence domain.Silence) *grpcmetrics.Silence {
+	protoSilence := &grpcmetrics.Silence{
+		Id:        silence.ID,
+		Matchers:  make([]*grpcmetrics.Matcher, len(silence.Matchers)),
+		StartsAt:  timeToProto(silence.StartsAt),
+		EndsAt:    timeToProto(silence.EndsAt),
+		CreatedBy: silence.CreatedBy,
+		Comment:   silence.Comment,
+		CreatedAt: timeToProto(silence.CreatedAt),
+		State:     string(silence.State),
+	}
+
+	for i, matcher := range silence.Matchers {
+		protoSilence.Matchers[i] = &grpcmetrics.Matcher{
+			Name:     matcher.Name,
+			Operator: MatchOperatorToProto(matcher.Operator),
+			Value:    matcher.Value,
+		}
+	}
+
+	return protoSilence
+}
+
+func MatchOperatorToProto(operator domain.MatchOperator) grpcmetrics.MatchOperator {
+	switch operator {
+	case domain.MatchEqual:
+		return grpcmetrics.MatchOperator_MATCH_OPERATOR_EQUAL
+	case domain.MatchNotEqual:
+		return grpcmetrics.MatchOperator_MATCH_OPERATOR_NOT_EQUAL
+	case domain.MatchRegexp:
+		return grpcmetrics.MatchOperator_MATCH_OPERATOR_REGEXP
+	case domain.MatchNotRegexp:
+		return grpcmetrics.MatchOperator_MATCH_OPERATOR_NOT_REGEXP
+	default:
+		return grpcmetrics.MatchOperator_MATCH_OPERATOR_UNSPECIFIED
+	}
+}
+
+func RuleToProto(rule domain.Rule) *grpcmetrics.Rule {
+	return &grpcmetrics.Rule{
+		Name:        rule.Name,
+		Expr:        rule.Expr,
+		ForSeconds:  int64(time.Duration(rule.For) / time.Second),
+		Labels:      labelsToProto(rule.Labels),
+		Annotations: labelsToProto(rule.Annotations),
+		CreatedAt:   timeToProto(rule.CreatedAt),
+		UpdatedAt:   timeToProto(rule.UpdatedAt),
+	}
+}
+
+func AlertToProto(alert domain.Alert) *grpcmetrics.Alert {
+	return &grpcmetrics.Alert{
+		Name:        alert.Name,
+		Labels:      labelsToProto(alert.Labels),
+		Annotations: labelsToProto(alert.Annotations),
+		State:       string(alert.State),
+		StartsAt:    timeToProto(alert.StartsAt),
+		EndsAt:      timeToProto(alert.EndsAt),
+	}
+}
+
+func RuleTestResultToProto(result domain.RuleTestResult) *grpcmetrics.TestRuleResponse {
+	resp := &grpcmetrics.TestRuleResponse{
+		Series:  samplesToProto(result.Series),
+		Matches: samplesToProto(result.Matches),
+		Alerts:  make([]*grpcmetrics.Alert, len(result.Alerts)),
+	}
+	for i, alert := range result.Alerts {
+		resp.Alerts[i] = AlertToProto(alert)
+	}
+
+	return resp
+}
+
+func samplesToProto(samples []domain.RuleSample) []*grpcmetrics.RuleSample {
+	protoSamples := make([]*grpcmetrics.RuleSample, len(samples))
+	for i, sample := range samples {
+		protoSamples[i] = &grpcmetrics.RuleSample{MetricId: sample.MetricID, Value: sample.Value}
+	}
+	return protoSamples
+}
+
+// labelsToProto converts the label map to a list ordered by name.
+func labelsToProto(labels map[string]string) []*grpcmetrics.Label {
+	protoLabels := make([]*grpcmetrics.Label, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		protoLabels = append(protoLabels, &grpcmetrics.Label{Name: name, Value: labels[name]})
+	}
+	return protoLabels
+}
+
+// timeToProto converts the time to unix milliseconds, keeping the zero time as 0.
+func timeToProto(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func ReplicatedMetricToProto(metric domain.ReplicatedMetric) *grpcmetrics.ReplicatedMetric {
+	return &grpcmetrics.ReplicatedMetric{
+		Id:        metric.ID,
+		Type:      MetricTypeToProto(metric.MType),
+		Value:     metric.Value,
+		UpdatedAt: nanosToProto(metric.UpdatedAt),
+		Timestamp: timeToProto(metric.Timestamp),
+		Writer:    metric.Writer,
+		DeletedAt: nanosToProto(metric.DeletedAt),
+		Slots:     counterSlotsToProto(metric.Slots),
+		Deleted:   counterSlotsToProto(metric.Deleted),
+	}
+}
+
+func counterSlotsToProto(slots []domain.CounterSlot) []*grpcmetrics.CounterSlot {
+	protoSlots := make([]*grpcmetrics.CounterSlot, len(slots))
+	for i, slot := range slots {
+		protoSlots[i] = &grpcmetrics.CounterSlot{Replica: slot.Replica, Total: slot.Total, Version: slot.Version}
+	}
+	return protoSlots
+}
+
+// nanosToProto converts the time to unix nanoseconds, keeping the zero time as 0. Replicated update times need
+// the full precision, they order the writes.
+func nanosToProto(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func ClusterStateToProto(state domain.ClusterState) *grpcmetrics.SyncResponse {
+	resp := &grpcmetrics.SyncResponse{
+		Metrics: make([]*grpcmetrics.ReplicatedMetric, len(state.Metrics)),
+		Node:    state.Node,
+		Agents:  make([]*grpcmetrics.AgentHeartbeat, len(state.Agents)),
+	}
+	for i, metric := range state.Metrics {
+		resp.Metrics[i] = ReplicatedMetricToProto(metric)
+	}
+	for i, agent := range state.Agents {
+		resp.Agents[i] = &grpcmetrics.AgentHeartbeat{
+			Id:        agent.ID,
+			Address:   agent.Address,
+			Version:   agent.Version,
+			FirstSeen: nanosToProto(agent.FirstSeen),
+			LastSeen:  nanosToProto(agent.LastSeen),
+		}
+	}
+	return resp
+}
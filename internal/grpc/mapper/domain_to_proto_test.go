@@ -2,6 +2,7 @@ package mapper
 
 import (
 	"testing"
+	"time"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
 	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
@@ -39,6 +40,21 @@ func TestMetricToProto(t *testing.T) {
 				Value: &[]float64{3.14}[0],
 			},
 		},
+		{
+			name: "gauge metric with timestamp",
+			input: domain.Metric{
+				ID:        "test_gauge",
+				MType:     domain.MetricTypeGauge,
+				Value:     &[]float64{3.14}[0],
+				Timestamp: time.UnixMilli(1746559752000),
+			},
+			want: &grpcmetrics.Metric{
+				Id:        "test_gauge",
+				Type:      grpcmetrics.MetricType_METRIC_TYPE_GAUGE,
+				Value:     &[]float64{3.14}[0],
+				Timestamp: &[]int64{1746559752000}[0],
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,6 +76,10 @@ func TestMetricToProto(t *testing.T) {
 			if !equalFloat64Ptr(got.Value, tt.want.Value) {
 				t.Errorf("MetricToProto().Value = %v, want %v", ptrValue(got.Value), ptrValue(tt.want.Value))
 			}
+
+			if !equalInt64Ptr(got.Timestamp, tt.want.Timestamp) {
+				t.Errorf("MetricToProto().Timestamp = %v, want %v", ptrValue(got.Timestamp), ptrValue(tt.want.Timestamp))
+			}
 		})
 	}
 }
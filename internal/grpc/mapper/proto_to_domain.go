@@ -1,6 +1,8 @@
 package mapper
 
 import (
+	"time"
+
 	"github.com/angryscorp/alert-metrics/internal/domain"
 	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
 )
@@ -19,6 +21,10 @@ func MetricToDomain(protoMetric *grpcmetrics.Metric) domain.Metric {
 		metric.Value = protoMetric.Value
 	}
 
+	if protoMetric.Timestamp != nil {
+		metric.Timestamp = time.UnixMilli(*protoMetric.Timestamp)
+	}
+
 	return metric
 }
 
@@ -32,3 +38,135 @@ func MetricTypeToDomain(protoType grpcmetrics.MetricType) domain.MetricType {
 		return domain.MetricTypeCounter // default fallback
 	}
 }
+
+func SilenceToDomain(protoSilence *grpcmetrics.Silence) domain.Silence {
+	silence := domain.Silence{
+		ID:        protoSilence.Id,
+		Matchers:  make([]domain.Matcher, len(protoSilence.Matchers)),
+		StartsAt:  timeToDomain(protoSilence.StartsAt),
+		EndsAt:    timeToDomain(protoSilence.EndsAt),
+		CreatedBy: protoSilence.CreatedBy,
+		Comment:   protoSilence.Comment,
+		CreatedAt: timeToDomain(protoSilence.CreatedAt),
+		State:     domain.SilenceState(protoSilence.State),
+	}
+
+	for i, matcher := range protoSilence.Matchers {
+		silence.Matchers[i] = domain.Matcher{
+			Name:     matcher.Name,
+			Operator: MatchOperatorToDomain(matcher.Operator),
+			Value:    matcher.Value,
+		}
+	}
+
+	return silence
+}
+
+// MatchOperatorToDomain maps an unspecified operator to an empty one, which fails silence validation.
+func MatchOperatorToDomain(operator grpcmetrics.MatchOperator) domain.MatchOperator {
+	switch operator {
+	case grpcmetrics.MatchOperator_MATCH_OPERATOR_EQUAL:
+		return domain.MatchEqual
+	case grpcmetrics.MatchOperator_MATCH_OPERATOR_NOT_EQUAL:
+		return domain.MatchNotEqual
+	case grpcmetrics.MatchOperator_MATCH_OPERATOR_REGEXP:
+		return domain.MatchRegexp
+	case grpcmetrics.MatchOperator_MATCH_OPERATOR_NOT_REGEXP:
+		return domain.MatchNotRegexp
+	default:
+		return ""
+	}
+}
+
+func RuleToDomain(protoRule *grpcmetrics.Rule) domain.Rule {
+	return domain.Rule{
+		Name:        protoRule.Name,
+		Expr:        protoRule.Expr,
+		For:         domain.Duration(time.Duration(protoRule.ForSeconds) * time.Second),
+		Labels:      labelsToDomain(protoRule.Labels),
+		Annotations: labelsToDomain(protoRule.Annotations),
+		CreatedAt:   timeToDomain(protoRule.CreatedAt),
+		UpdatedAt:   timeToDomain(protoRule.UpdatedAt),
+	}
+}
+
+// labelsToDomain converts the label list to a map, keeping an empty list as nil.
+func labelsToDomain(protoLabels []*grpcmetrics.Label) map[string]string {
+	if len(protoLabels) == 0 {
+		return nil
+	}
+
+	labels := make(map[string]string, len(protoLabels))
+	for _, label := range protoLabels {
+		labels[label.Name] = label.Value
+	}
+	return labels
+}
+
+// timeToDomain converts unix milliseconds to time, keeping 0 as the zero time.
+func timeToDomain(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// ReplicatedMetricToDomain keeps an unspecified metric type, which the replica rejects.
+func ReplicatedMetricToDomain(protoMetric *grpcmetrics.ReplicatedMetric) domain.ReplicatedMetric {
+	metric := domain.ReplicatedMetric{
+		ID:        protoMetric.Id,
+		Value:     protoMetric.Value,
+		UpdatedAt: nanosToDomain(protoMetric.UpdatedAt),
+		Timestamp: timeToDomain(protoMetric.Timestamp),
+		Writer:    protoMetric.Writer,
+		DeletedAt: nanosToDomain(protoMetric.DeletedAt),
+		Slots:     counterSlotsToDomain(protoMetric.Slots),
+		Deleted:   counterSlotsToDomain(protoMetric.Deleted),
+	}
+	if protoMetric.Type != grpcmetrics.MetricType_METRIC_TYPE_UNSPECIFIED {
+		metric.MType = MetricTypeToDomain(protoMetric.Type)
+	}
+
+	return metric
+}
+
+func counterSlotsToDomain(protoSlots []*grpcmetrics.CounterSlot) []domain.CounterSlot {
+	if len(protoSlots) == 0 {
+		return nil
+	}
+
+	slots := make([]domain.CounterSlot, len(protoSlots))
+	for i, slot := range protoSlots {
+		slots[i] = domain.CounterSlot{Replica: slot.Replica, Total: slot.Total, Version: slot.Version}
+	}
+	return slots
+}
+
+// nanosToDomain converts unix nanoseconds to time, keeping 0 as the zero time.
+func nanosToDomain(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// ClusterStateToDomain keeps only the reports of the agents, the report counts are per server.
+func ClusterStateToDomain(resp *grpcmetrics.SyncResponse) domain.ClusterState {
+	state := domain.ClusterState{
+		Node:    resp.Node,
+		Metrics: make([]domain.ReplicatedMetric, len(resp.Metrics)),
+	}
+	for i, protoMetric := range resp.Metrics {
+		state.Metrics[i] = ReplicatedMetricToDomain(protoMetric)
+	}
+	for _, agent := range resp.Agents {
+		state.Agents = append(state.Agents, domain.AgentStatus{
+			ID:        agent.Id,
+			Address:   agent.Address,
+			Version:   agent.Version,
+			FirstSeen: nanosToDomain(agent.FirstSeen),
+			LastSeen:  nanosToDomain(agent.LastSeen),
+		})
+	}
+	return state
+}
@@ -1,7 +1,9 @@
 package mapper
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
 	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
@@ -39,6 +41,21 @@ func TestMetricToDomain(t *testing.T) {
 				Value: &[]float64{3.14}[0],
 			},
 		},
+		{
+			name: "counter metric with timestamp",
+			input: &grpcmetrics.Metric{
+				Id:        "test_counter",
+				Type:      grpcmetrics.MetricType_METRIC_TYPE_COUNTER,
+				Delta:     &[]int64{42}[0],
+				Timestamp: &[]int64{1746559752000}[0],
+			},
+			want: domain.Metric{
+				ID:        "test_counter",
+				MType:     domain.MetricTypeCounter,
+				Delta:     &[]int64{42}[0],
+				Timestamp: time.UnixMilli(1746559752000),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -60,6 +77,10 @@ func TestMetricToDomain(t *testing.T) {
 			if !equalFloat64Ptr(got.Value, tt.want.Value) {
 				t.Errorf("MetricToDomain().Value = %v, want %v", ptrValue(got.Value), ptrValue(tt.want.Value))
 			}
+
+			if !got.Timestamp.Equal(tt.want.Timestamp) {
+				t.Errorf("MetricToDomain().Timestamp = %v, want %v", got.Timestamp, tt.want.Timestamp)
+			}
 		})
 	}
 }
@@ -91,3 +112,108 @@ func TestMetricTypeToDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestSilenceRoundTrip(t *testing.T) {
+	start := time.UnixMilli(1735689600000)
+	silence := domain.Silence{
+		ID: "id-1",
+		Matchers: []domain.Matcher{
+			{Name: domain.AlertNameLabel, Operator: domain.MatchEqual, Value: "AgentAbsent"},
+			{Name: "agent", Operator: domain.MatchNotRegexp, Value: "db-.*"},
+		},
+		StartsAt:  start,
+		EndsAt:    start.Add(time.Hour),
+		CreatedBy: "ops",
+		Comment:   "deploy",
+		State:     domain.SilenceStateActive,
+	}
+
+	protoSilence := SilenceToProto(silence)
+	if protoSilence.CreatedAt != 0 {
+		t.Errorf("zero created_at = %d, want 0", protoSilence.CreatedAt)
+	}
+	if protoSilence.Matchers[1].Operator != grpcmetrics.MatchOperator_MATCH_OPERATOR_NOT_REGEXP {
+		t.Errorf("operator = %v, want NOT_REGEXP", protoSilence.Matchers[1].Operator)
+	}
+
+	got := SilenceToDomain(protoSilence)
+	if !reflect.DeepEqual(got, silence) {
+		t.Errorf("SilenceToDomain(SilenceToProto()) = %v, want %v", got, silence)
+	}
+
+	if op := MatchOperatorToDomain(grpcmetrics.MatchOperator_MATCH_OPERATOR_UNSPECIFIED); op != "" {
+		t.Errorf("unspecified operator = %q, want empty", op)
+	}
+}
+
+func TestRuleRoundTrip(t *testing.T) {
+	created := time.UnixMilli(1735689600000)
+	rule := domain.Rule{
+		Name:        "HighCPU",
+		Expr:        "CPUutilization* > 90",
+		For:         domain.Duration(5 * time.Minute),
+		Labels:      map[string]string{"severity": "critical", "team": "ops"},
+		Annotations: map[string]string{"summary": "CPU is busy"},
+		CreatedAt:   created,
+		UpdatedAt:   created.Add(time.Minute),
+	}
+
+	protoRule := RuleToProto(rule)
+	if protoRule.ForSeconds != 300 {
+		t.Errorf("for_seconds = %d, want 300", protoRule.ForSeconds)
+	}
+	if len(protoRule.Labels) != 2 || protoRule.Labels[0].Name != "severity" {
+		t.Errorf("labels = %v, want ordered by name", protoRule.Labels)
+	}
+
+	got := RuleToDomain(protoRule)
+	if !reflect.DeepEqual(got, rule) {
+		t.Errorf("RuleToDomain(RuleToProto()) = %v, want %v", got, rule)
+	}
+}
+
+func TestReplicatedMetricRoundTrip(t *testing.T) {
+	metric := domain.ReplicatedMetric{
+		ID:        "PollCount",
+		MType:     domain.MetricTypeCounter,
+		UpdatedAt: time.Unix(0, 1735689600123456789),
+		Timestamp: time.UnixMilli(1735689600000),
+		Writer:    "node1",
+		Slots:     []domain.CounterSlot{{Replica: "node1", Total: 5, Version: 2}, {Replica: "node2", Total: -1, Version: 1}},
+		Deleted:   []domain.CounterSlot{{Replica: "node1", Total: 3, Version: 1}},
+	}
+
+	protoMetric := ReplicatedMetricToProto(metric)
+	if protoMetric.UpdatedAt != 1735689600123456789 {
+		t.Errorf("updated_at = %d, want nanoseconds", protoMetric.UpdatedAt)
+	}
+
+	got := ReplicatedMetricToDomain(protoMetric)
+	if !reflect.DeepEqual(got, metric) {
+		t.Errorf("ReplicatedMetricToDomain(ReplicatedMetricToProto()) = %v, want %v", got, metric)
+	}
+
+	got = ReplicatedMetricToDomain(&grpcmetrics.ReplicatedMetric{Id: "Alloc"})
+	if got.MType != "" {
+		t.Errorf("unspecified type = %q, want empty", got.MType)
+	}
+}
+
+func TestClusterStateRoundTrip(t *testing.T) {
+	state := domain.ClusterState{
+		Node:    "metrics-1",
+		Metrics: []domain.ReplicatedMetric{{ID: "Alloc", MType: domain.MetricTypeGauge, Value: 1.5, Writer: "metrics-1"}},
+		Agents: []domain.AgentStatus{{
+			ID:        "agent-1",
+			Address:   "10.0.0.1",
+			Version:   "1.2.0",
+			FirstSeen: time.Unix(0, 1735689600000000001),
+			LastSeen:  time.Unix(0, 1735689660000000001),
+		}},
+	}
+
+	got := ClusterStateToDomain(ClusterStateToProto(state))
+	if !reflect.DeepEqual(got, state) {
+		t.Errorf("ClusterStateToDomain(ClusterStateToProto()) = %v, want %v", got, state)
+	}
+}
@@ -72,13 +72,70 @@ func (MetricType) EnumDescriptor() ([]byte, []int) {
 	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{0}
 }
 
+// MatchOperator selects how a matcher compares the label value
+type MatchOperator int32
+
+const (
+	MatchOperator_MATCH_OPERATOR_UNSPECIFIED MatchOperator = 0
+	MatchOperator_MATCH_OPERATOR_EQUAL       MatchOperator = 1
+	MatchOperator_MATCH_OPERATOR_NOT_EQUAL   MatchOperator = 2
+	MatchOperator_MATCH_OPERATOR_REGEXP      MatchOperator = 3
+	MatchOperator_MATCH_OPERATOR_NOT_REGEXP  MatchOperator = 4
+)
+
+// Enum value maps for MatchOperator.
+var (
+	MatchOperator_name = map[int32]string{
+		0: "MATCH_OPERATOR_UNSPECIFIED",
+		1: "MATCH_OPERATOR_EQUAL",
+		2: "MATCH_OPERATOR_NOT_EQUAL",
+		3: "MATCH_OPERATOR_REGEXP",
+		4: "MATCH_OPERATOR_NOT_REGEXP",
+	}
+	MatchOperator_value = map[string]int32{
+		"MATCH_OPERATOR_UNSPECIFIED": 0,
+		"MATCH_OPERATOR_EQUAL":       1,
+		"MATCH_OPERATOR_NOT_EQUAL":   2,
+		"MATCH_OPERATOR_REGEXP":      3,
+		"MATCH_OPERATOR_NOT_REGEXP":  4,
+	}
+)
+
+func (x MatchOperator) Enum() *MatchOperator {
+	p := new(MatchOperator)
+	*p = x
+	return p
+}
+
+func (x MatchOperator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MatchOperator) Descriptor() protoreflect.EnumDescriptor {
+	return file_internal_grpc_proto_metrics_proto_enumTypes[1].Descriptor()
+}
+
+func (MatchOperator) Type() protoreflect.EnumType {
+	return &file_internal_grpc_proto_metrics_proto_enumTypes[1]
+}
+
+func (x MatchOperator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MatchOperator.Descriptor instead.
+func (MatchOperator) EnumDescriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{1}
+}
+
 // Metric represents a single metric
 type Metric struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	Type          MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=MetricType" json:"type,omitempty"`
-	Delta         *int64                 `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`  // for counter metrics
-	Value         *float64               `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"` // for gauge metrics
+	Delta         *int64                 `protobuf:"varint,3,opt,name=delta,proto3,oneof" json:"delta,omitempty"`         // for counter metrics
+	Value         *float64               `protobuf:"fixed64,4,opt,name=value,proto3,oneof" json:"value,omitempty"`        // for gauge metrics
+	Timestamp     *int64                 `protobuf:"varint,5,opt,name=timestamp,proto3,oneof" json:"timestamp,omitempty"` // client time of the metric in unix milliseconds
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -141,6 +198,13 @@ func (x *Metric) GetValue() float64 {
 	return 0
 }
 
+func (x *Metric) GetTimestamp() int64 {
+	if x != nil && x.Timestamp != nil {
+		return *x.Timestamp
+	}
+	return 0
+}
+
 // ReportRawMetricRequest for ReportRawMetric method
 type ReportRawMetricRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -292,6 +356,194 @@ func (x *ReportBatchRequest) GetMetrics() []*Metric {
 	return nil
 }
 
+// DeleteMetricRequest for DeleteMetric method
+type DeleteMetricRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MetricType    MetricType             `protobuf:"varint,1,opt,name=metric_type,json=metricType,proto3,enum=MetricType" json:"metric_type,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMetricRequest) Reset() {
+	*x = DeleteMetricRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMetricRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMetricRequest) ProtoMessage() {}
+
+func (x *DeleteMetricRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMetricRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMetricRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteMetricRequest) GetMetricType() MetricType {
+	if x != nil {
+		return x.MetricType
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *DeleteMetricRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// DeleteMetricResponse for DeleteMetric method
+type DeleteMetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMetricResponse) Reset() {
+	*x = DeleteMetricResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMetricResponse) ProtoMessage() {}
+
+func (x *DeleteMetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMetricResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMetricResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteMetricResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// DeleteByPrefixRequest for DeleteByPrefix method
+type DeleteByPrefixRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteByPrefixRequest) Reset() {
+	*x = DeleteByPrefixRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteByPrefixRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteByPrefixRequest) ProtoMessage() {}
+
+func (x *DeleteByPrefixRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteByPrefixRequest.ProtoReflect.Descriptor instead.
+func (*DeleteByPrefixRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteByPrefixRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+// DeleteByPrefixResponse for DeleteByPrefix method
+type DeleteByPrefixResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       int64                  `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteByPrefixResponse) Reset() {
+	*x = DeleteByPrefixResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteByPrefixResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteByPrefixResponse) ProtoMessage() {}
+
+func (x *DeleteByPrefixResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteByPrefixResponse.ProtoReflect.Descriptor instead.
+func (*DeleteByPrefixResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteByPrefixResponse) GetDeleted() int64 {
+	if x != nil {
+		return x.Deleted
+	}
+	return 0
+}
+
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -300,7 +552,7 @@ type Empty struct {
 
 func (x *Empty) Reset() {
 	*x = Empty{}
-	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[4]
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -312,7 +564,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[4]
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -325,40 +577,1624 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{4}
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{8}
 }
 
-var File_internal_grpc_proto_metrics_proto protoreflect.FileDescriptor
+// Matcher selects alerts by a label value or, for the "alertname" name, by the alert name
+type Matcher struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Operator      MatchOperator          `protobuf:"varint,2,opt,name=operator,proto3,enum=MatchOperator" json:"operator,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_internal_grpc_proto_metrics_proto_rawDesc = "" +
-	"\n" +
-	"!internal/grpc/proto/metrics.proto\"\x83\x01\n" +
-	"\x06Metric\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
-	"\x04type\x18\x02 \x01(\x0e2\v.MetricTypeR\x04type\x12\x19\n" +
-	"\x05delta\x18\x03 \x01(\x03H\x00R\x05delta\x88\x01\x01\x12\x19\n" +
-	"\x05value\x18\x04 \x01(\x01H\x01R\x05value\x88\x01\x01B\b\n" +
-	"\x06_deltaB\b\n" +
-	"\x06_value\"n\n" +
-	"\x16ReportRawMetricRequest\x12,\n" +
-	"\vmetric_type\x18\x01 \x01(\x0e2\v.MetricTypeR\n" +
-	"metricType\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\tR\x05value\"6\n" +
-	"\x13ReportMetricRequest\x12\x1f\n" +
-	"\x06metric\x18\x01 \x01(\v2\a.MetricR\x06metric\"7\n" +
-	"\x12ReportBatchRequest\x12!\n" +
-	"\ametrics\x18\x01 \x03(\v2\a.MetricR\ametrics\"\a\n" +
-	"\x05Empty*Y\n" +
-	"\n" +
-	"MetricType\x12\x1b\n" +
-	"\x17METRIC_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
-	"\x13METRIC_TYPE_COUNTER\x10\x01\x12\x15\n" +
-	"\x11METRIC_TYPE_GAUGE\x10\x022\x9e\x01\n" +
-	"\x0eMetricsService\x122\n" +
-	"\x0fReportRawMetric\x12\x17.ReportRawMetricRequest\x1a\x06.Empty\x12,\n" +
-	"\fReportMetric\x12\x14.ReportMetricRequest\x1a\x06.Empty\x12*\n" +
-	"\vReportBatch\x12\x13.ReportBatchRequest\x1a\x06.EmptyB\x0eZ\fgrpc/metricsb\x06proto3"
+func (x *Matcher) Reset() {
+	*x = Matcher{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Matcher) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Matcher) ProtoMessage() {}
+
+func (x *Matcher) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Matcher.ProtoReflect.Descriptor instead.
+func (*Matcher) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Matcher) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Matcher) GetOperator() MatchOperator {
+	if x != nil {
+		return x.Operator
+	}
+	return MatchOperator_MATCH_OPERATOR_UNSPECIFIED
+}
+
+func (x *Matcher) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// Silence mutes notifications of the alerts matching all matchers within its time range
+type Silence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Matchers      []*Matcher             `protobuf:"bytes,2,rep,name=matchers,proto3" json:"matchers,omitempty"`
+	StartsAt      int64                  `protobuf:"varint,3,opt,name=starts_at,json=startsAt,proto3" json:"starts_at,omitempty"` // unix milliseconds
+	EndsAt        int64                  `protobuf:"varint,4,opt,name=ends_at,json=endsAt,proto3" json:"ends_at,omitempty"`       // unix milliseconds
+	CreatedBy     string                 `protobuf:"bytes,5,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	Comment       string                 `protobuf:"bytes,6,opt,name=comment,proto3" json:"comment,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix milliseconds, set by the server
+	State         string                 `protobuf:"bytes,8,opt,name=state,proto3" json:"state,omitempty"`                           // pending, active or expired, set by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Silence) Reset() {
+	*x = Silence{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Silence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Silence) ProtoMessage() {}
+
+func (x *Silence) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Silence.ProtoReflect.Descriptor instead.
+func (*Silence) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Silence) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Silence) GetMatchers() []*Matcher {
+	if x != nil {
+		return x.Matchers
+	}
+	return nil
+}
+
+func (x *Silence) GetStartsAt() int64 {
+	if x != nil {
+		return x.StartsAt
+	}
+	return 0
+}
+
+func (x *Silence) GetEndsAt() int64 {
+	if x != nil {
+		return x.EndsAt
+	}
+	return 0
+}
+
+func (x *Silence) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *Silence) GetComment() string {
+	if x != nil {
+		return x.Comment
+	}
+	return ""
+}
+
+func (x *Silence) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Silence) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+// ListSilencesResponse for ListSilences method
+type ListSilencesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Silences      []*Silence             `protobuf:"bytes,1,rep,name=silences,proto3" json:"silences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSilencesResponse) Reset() {
+	*x = ListSilencesResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSilencesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSilencesResponse) ProtoMessage() {}
+
+func (x *ListSilencesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSilencesResponse.ProtoReflect.Descriptor instead.
+func (*ListSilencesResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListSilencesResponse) GetSilences() []*Silence {
+	if x != nil {
+		return x.Silences
+	}
+	return nil
+}
+
+// GetSilenceRequest for GetSilence method
+type GetSilenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSilenceRequest) Reset() {
+	*x = GetSilenceRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSilenceRequest) ProtoMessage() {}
+
+func (x *GetSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSilenceRequest.ProtoReflect.Descriptor instead.
+func (*GetSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetSilenceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// CreateSilenceRequest for CreateSilence method
+type CreateSilenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Silence       *Silence               `protobuf:"bytes,1,opt,name=silence,proto3" json:"silence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSilenceRequest) Reset() {
+	*x = CreateSilenceRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSilenceRequest) ProtoMessage() {}
+
+func (x *CreateSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSilenceRequest.ProtoReflect.Descriptor instead.
+func (*CreateSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CreateSilenceRequest) GetSilence() *Silence {
+	if x != nil {
+		return x.Silence
+	}
+	return nil
+}
+
+// UpdateSilenceRequest for UpdateSilence method
+type UpdateSilenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Silence       *Silence               `protobuf:"bytes,1,opt,name=silence,proto3" json:"silence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSilenceRequest) Reset() {
+	*x = UpdateSilenceRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSilenceRequest) ProtoMessage() {}
+
+func (x *UpdateSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSilenceRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdateSilenceRequest) GetSilence() *Silence {
+	if x != nil {
+		return x.Silence
+	}
+	return nil
+}
+
+// DeleteSilenceRequest for DeleteSilence method
+type DeleteSilenceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSilenceRequest) Reset() {
+	*x = DeleteSilenceRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSilenceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSilenceRequest) ProtoMessage() {}
+
+func (x *DeleteSilenceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSilenceRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSilenceRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteSilenceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// DeleteSilenceResponse for DeleteSilence method
+type DeleteSilenceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSilenceResponse) Reset() {
+	*x = DeleteSilenceResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSilenceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSilenceResponse) ProtoMessage() {}
+
+func (x *DeleteSilenceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSilenceResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSilenceResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteSilenceResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// Label is a name and value pair of rule labels, annotations and alert labels
+type Label struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Label) Reset() {
+	*x = Label{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Label) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Label) ProtoMessage() {}
+
+func (x *Label) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Label.ProtoReflect.Descriptor instead.
+func (*Label) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Label) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Label) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+// Rule raises an alert for every metric its expression holds true for at least for_seconds
+type Rule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Expr          string                 `protobuf:"bytes,2,opt,name=expr,proto3" json:"expr,omitempty"`
+	ForSeconds    int64                  `protobuf:"varint,3,opt,name=for_seconds,json=forSeconds,proto3" json:"for_seconds,omitempty"`
+	Labels        []*Label               `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty"`
+	Annotations   []*Label               `protobuf:"bytes,5,rep,name=annotations,proto3" json:"annotations,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // unix milliseconds, set by the server
+	UpdatedAt     int64                  `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix milliseconds, set by the server
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rule) Reset() {
+	*x = Rule{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rule) ProtoMessage() {}
+
+func (x *Rule) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rule.ProtoReflect.Descriptor instead.
+func (*Rule) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Rule) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Rule) GetExpr() string {
+	if x != nil {
+		return x.Expr
+	}
+	return ""
+}
+
+func (x *Rule) GetForSeconds() int64 {
+	if x != nil {
+		return x.ForSeconds
+	}
+	return 0
+}
+
+func (x *Rule) GetLabels() []*Label {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Rule) GetAnnotations() []*Label {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Rule) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Rule) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+// Alert is a condition detected by the server
+type Alert struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Labels        []*Label               `protobuf:"bytes,2,rep,name=labels,proto3" json:"labels,omitempty"`
+	Annotations   []*Label               `protobuf:"bytes,3,rep,name=annotations,proto3" json:"annotations,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`                        // firing or resolved
+	StartsAt      int64                  `protobuf:"varint,5,opt,name=starts_at,json=startsAt,proto3" json:"starts_at,omitempty"` // unix milliseconds
+	EndsAt        int64                  `protobuf:"varint,6,opt,name=ends_at,json=endsAt,proto3" json:"ends_at,omitempty"`       // unix milliseconds, 0 while firing
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Alert) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Alert) GetLabels() []*Label {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Alert) GetAnnotations() []*Label {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Alert) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Alert) GetStartsAt() int64 {
+	if x != nil {
+		return x.StartsAt
+	}
+	return 0
+}
+
+func (x *Alert) GetEndsAt() int64 {
+	if x != nil {
+		return x.EndsAt
+	}
+	return 0
+}
+
+// RuleSample is the value of a single metric in a rule evaluation
+type RuleSample struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MetricId      string                 `protobuf:"bytes,1,opt,name=metric_id,json=metricId,proto3" json:"metric_id,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleSample) Reset() {
+	*x = RuleSample{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleSample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleSample) ProtoMessage() {}
+
+func (x *RuleSample) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleSample.ProtoReflect.Descriptor instead.
+func (*RuleSample) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RuleSample) GetMetricId() string {
+	if x != nil {
+		return x.MetricId
+	}
+	return ""
+}
+
+func (x *RuleSample) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// ListRulesResponse for ListRules method
+type ListRulesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rules         []*Rule                `protobuf:"bytes,1,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRulesResponse) Reset() {
+	*x = ListRulesResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRulesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRulesResponse) ProtoMessage() {}
+
+func (x *ListRulesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRulesResponse.ProtoReflect.Descriptor instead.
+func (*ListRulesResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListRulesResponse) GetRules() []*Rule {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+// GetRuleRequest for GetRule method
+type GetRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRuleRequest) Reset() {
+	*x = GetRuleRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRuleRequest) ProtoMessage() {}
+
+func (x *GetRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRuleRequest.ProtoReflect.Descriptor instead.
+func (*GetRuleRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetRuleRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// CreateRuleRequest for CreateRule method
+type CreateRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          *Rule                  `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRuleRequest) Reset() {
+	*x = CreateRuleRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRuleRequest) ProtoMessage() {}
+
+func (x *CreateRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRuleRequest.ProtoReflect.Descriptor instead.
+func (*CreateRuleRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CreateRuleRequest) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+// UpdateRuleRequest for UpdateRule method
+type UpdateRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          *Rule                  `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRuleRequest) Reset() {
+	*x = UpdateRuleRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRuleRequest) ProtoMessage() {}
+
+func (x *UpdateRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRuleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRuleRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *UpdateRuleRequest) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+// DeleteRuleRequest for DeleteRule method
+type DeleteRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleRequest) Reset() {
+	*x = DeleteRuleRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleRequest) ProtoMessage() {}
+
+func (x *DeleteRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRuleRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DeleteRuleRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// DeleteRuleResponse for DeleteRule method
+type DeleteRuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       bool                   `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRuleResponse) Reset() {
+	*x = DeleteRuleResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRuleResponse) ProtoMessage() {}
+
+func (x *DeleteRuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRuleResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRuleResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DeleteRuleResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// TestRuleRequest for TestRule method
+type TestRuleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rule          *Rule                  `protobuf:"bytes,1,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestRuleRequest) Reset() {
+	*x = TestRuleRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestRuleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestRuleRequest) ProtoMessage() {}
+
+func (x *TestRuleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestRuleRequest.ProtoReflect.Descriptor instead.
+func (*TestRuleRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TestRuleRequest) GetRule() *Rule {
+	if x != nil {
+		return x.Rule
+	}
+	return nil
+}
+
+// TestRuleResponse holds the outcome of a rule dry-run against the current metrics
+type TestRuleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Series        []*RuleSample          `protobuf:"bytes,1,rep,name=series,proto3" json:"series,omitempty"`
+	Matches       []*RuleSample          `protobuf:"bytes,2,rep,name=matches,proto3" json:"matches,omitempty"`
+	Alerts        []*Alert               `protobuf:"bytes,3,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TestRuleResponse) Reset() {
+	*x = TestRuleResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TestRuleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TestRuleResponse) ProtoMessage() {}
+
+func (x *TestRuleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TestRuleResponse.ProtoReflect.Descriptor instead.
+func (*TestRuleResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TestRuleResponse) GetSeries() []*RuleSample {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *TestRuleResponse) GetMatches() []*RuleSample {
+	if x != nil {
+		return x.Matches
+	}
+	return nil
+}
+
+func (x *TestRuleResponse) GetAlerts() []*Alert {
+	if x != nil {
+		return x.Alerts
+	}
+	return nil
+}
+
+// CounterSlot is the total a single replica added to a counter, versioned by the number of its changes
+type CounterSlot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Replica       string                 `protobuf:"bytes,1,opt,name=replica,proto3" json:"replica,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Version       uint64                 `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CounterSlot) Reset() {
+	*x = CounterSlot{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CounterSlot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CounterSlot) ProtoMessage() {}
+
+func (x *CounterSlot) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CounterSlot.ProtoReflect.Descriptor instead.
+func (*CounterSlot) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CounterSlot) GetReplica() string {
+	if x != nil {
+		return x.Replica
+	}
+	return ""
+}
+
+func (x *CounterSlot) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *CounterSlot) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// ReplicatedMetric is the state of a metric replicated between the servers of a cluster
+type ReplicatedMetric struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=MetricType" json:"type,omitempty"`
+	Value         float64                `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`                         // last written gauge value
+	UpdatedAt     int64                  `protobuf:"varint,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix nanoseconds
+	Timestamp     int64                  `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                  // unix milliseconds
+	Writer        string                 `protobuf:"bytes,6,opt,name=writer,proto3" json:"writer,omitempty"`                         // replica of the last write
+	DeletedAt     int64                  `protobuf:"varint,7,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"` // unix nanoseconds, gauge deletion
+	Slots         []*CounterSlot         `protobuf:"bytes,8,rep,name=slots,proto3" json:"slots,omitempty"`
+	Deleted       []*CounterSlot         `protobuf:"bytes,9,rep,name=deleted,proto3" json:"deleted,omitempty"` // slots at the last counter deletion
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicatedMetric) Reset() {
+	*x = ReplicatedMetric{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicatedMetric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicatedMetric) ProtoMessage() {}
+
+func (x *ReplicatedMetric) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicatedMetric.ProtoReflect.Descriptor instead.
+func (*ReplicatedMetric) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ReplicatedMetric) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ReplicatedMetric) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *ReplicatedMetric) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *ReplicatedMetric) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *ReplicatedMetric) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ReplicatedMetric) GetWriter() string {
+	if x != nil {
+		return x.Writer
+	}
+	return ""
+}
+
+func (x *ReplicatedMetric) GetDeletedAt() int64 {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return 0
+}
+
+func (x *ReplicatedMetric) GetSlots() []*CounterSlot {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+func (x *ReplicatedMetric) GetDeleted() []*CounterSlot {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
+// ReplicateRequest for Replicate method
+type ReplicateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*ReplicatedMetric    `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateRequest) Reset() {
+	*x = ReplicateRequest{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateRequest) ProtoMessage() {}
+
+func (x *ReplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ReplicateRequest) GetMetrics() []*ReplicatedMetric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+// AgentHeartbeat is the latest report of an agent seen by a server of a cluster
+type AgentHeartbeat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	FirstSeen     int64                  `protobuf:"varint,4,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"` // unix nanoseconds
+	LastSeen      int64                  `protobuf:"varint,5,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`    // unix nanoseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentHeartbeat) Reset() {
+	*x = AgentHeartbeat{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentHeartbeat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentHeartbeat) ProtoMessage() {}
+
+func (x *AgentHeartbeat) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentHeartbeat.ProtoReflect.Descriptor instead.
+func (*AgentHeartbeat) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *AgentHeartbeat) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AgentHeartbeat) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *AgentHeartbeat) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *AgentHeartbeat) GetFirstSeen() int64 {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return 0
+}
+
+func (x *AgentHeartbeat) GetLastSeen() int64 {
+	if x != nil {
+		return x.LastSeen
+	}
+	return 0
+}
+
+// SyncResponse for Sync method
+type SyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*ReplicatedMetric    `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	Node          string                 `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"` // node name of the server, used to elect the leader
+	Agents        []*AgentHeartbeat      `protobuf:"bytes,3,rep,name=agents,proto3" json:"agents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncResponse) Reset() {
+	*x = SyncResponse{}
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncResponse) ProtoMessage() {}
+
+func (x *SyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpc_proto_metrics_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncResponse.ProtoReflect.Descriptor instead.
+func (*SyncResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpc_proto_metrics_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *SyncResponse) GetMetrics() []*ReplicatedMetric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *SyncResponse) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *SyncResponse) GetAgents() []*AgentHeartbeat {
+	if x != nil {
+		return x.Agents
+	}
+	return nil
+}
+
+var File_internal_grpc_proto_metrics_proto protoreflect.FileDescriptor
+
+const file_internal_grpc_proto_metrics_proto_rawDesc = "" +
+	"\n" +
+	"!internal/grpc/proto/metrics.proto\"\xb4\x01\n" +
+	"\x06Metric\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\x04type\x18\x02 \x01(\x0e2\v.MetricTypeR\x04type\x12\x19\n" +
+	"\x05delta\x18\x03 \x01(\x03H\x00R\x05delta\x88\x01\x01\x12\x19\n" +
+	"\x05value\x18\x04 \x01(\x01H\x01R\x05value\x88\x01\x01\x12!\n" +
+	"\ttimestamp\x18\x05 \x01(\x03H\x02R\ttimestamp\x88\x01\x01B\b\n" +
+	"\x06_deltaB\b\n" +
+	"\x06_valueB\f\n" +
+	"\n" +
+	"_timestamp\"n\n" +
+	"\x16ReportRawMetricRequest\x12,\n" +
+	"\vmetric_type\x18\x01 \x01(\x0e2\v.MetricTypeR\n" +
+	"metricType\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"6\n" +
+	"\x13ReportMetricRequest\x12\x1f\n" +
+	"\x06metric\x18\x01 \x01(\v2\a.MetricR\x06metric\"7\n" +
+	"\x12ReportBatchRequest\x12!\n" +
+	"\ametrics\x18\x01 \x03(\v2\a.MetricR\ametrics\"S\n" +
+	"\x13DeleteMetricRequest\x12,\n" +
+	"\vmetric_type\x18\x01 \x01(\x0e2\v.MetricTypeR\n" +
+	"metricType\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"0\n" +
+	"\x14DeleteMetricResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"/\n" +
+	"\x15DeleteByPrefixRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"2\n" +
+	"\x16DeleteByPrefixResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\x03R\adeleted\"\a\n" +
+	"\x05Empty\"_\n" +
+	"\aMatcher\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12*\n" +
+	"\boperator\x18\x02 \x01(\x0e2\x0e.MatchOperatorR\boperator\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"\xe3\x01\n" +
+	"\aSilence\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12$\n" +
+	"\bmatchers\x18\x02 \x03(\v2\b.MatcherR\bmatchers\x12\x1b\n" +
+	"\tstarts_at\x18\x03 \x01(\x03R\bstartsAt\x12\x17\n" +
+	"\aends_at\x18\x04 \x01(\x03R\x06endsAt\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x05 \x01(\tR\tcreatedBy\x12\x18\n" +
+	"\acomment\x18\x06 \x01(\tR\acomment\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt\x12\x14\n" +
+	"\x05state\x18\b \x01(\tR\x05state\"<\n" +
+	"\x14ListSilencesResponse\x12$\n" +
+	"\bsilences\x18\x01 \x03(\v2\b.SilenceR\bsilences\"#\n" +
+	"\x11GetSilenceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\":\n" +
+	"\x14CreateSilenceRequest\x12\"\n" +
+	"\asilence\x18\x01 \x01(\v2\b.SilenceR\asilence\":\n" +
+	"\x14UpdateSilenceRequest\x12\"\n" +
+	"\asilence\x18\x01 \x01(\v2\b.SilenceR\asilence\"&\n" +
+	"\x14DeleteSilenceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"1\n" +
+	"\x15DeleteSilenceResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\"1\n" +
+	"\x05Label\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\"\xd7\x01\n" +
+	"\x04Rule\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04expr\x18\x02 \x01(\tR\x04expr\x12\x1f\n" +
+	"\vfor_seconds\x18\x03 \x01(\x03R\n" +
+	"forSeconds\x12\x1e\n" +
+	"\x06labels\x18\x04 \x03(\v2\x06.LabelR\x06labels\x12(\n" +
+	"\vannotations\x18\x05 \x03(\v2\x06.LabelR\vannotations\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\x03R\tupdatedAt\"\xb1\x01\n" +
+	"\x05Alert\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1e\n" +
+	"\x06labels\x18\x02 \x03(\v2\x06.LabelR\x06labels\x12(\n" +
+	"\vannotations\x18\x03 \x03(\v2\x06.LabelR\vannotations\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x1b\n" +
+	"\tstarts_at\x18\x05 \x01(\x03R\bstartsAt\x12\x17\n" +
+	"\aends_at\x18\x06 \x01(\x03R\x06endsAt\"?\n" +
+	"\n" +
+	"RuleSample\x12\x1b\n" +
+	"\tmetric_id\x18\x01 \x01(\tR\bmetricId\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\"0\n" +
+	"\x11ListRulesResponse\x12\x1b\n" +
+	"\x05rules\x18\x01 \x03(\v2\x05.RuleR\x05rules\"$\n" +
+	"\x0eGetRuleRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\".\n" +
+	"\x11CreateRuleRequest\x12\x19\n" +
+	"\x04rule\x18\x01 \x01(\v2\x05.RuleR\x04rule\".\n" +
+	"\x11UpdateRuleRequest\x12\x19\n" +
+	"\x04rule\x18\x01 \x01(\v2\x05.RuleR\x04rule\"'\n" +
+	"\x11DeleteRuleRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\".\n" +
+	"\x12DeleteRuleResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x01(\bR\adeleted\",\n" +
+	"\x0fTestRuleRequest\x12\x19\n" +
+	"\x04rule\x18\x01 \x01(\v2\x05.RuleR\x04rule\"~\n" +
+	"\x10TestRuleResponse\x12#\n" +
+	"\x06series\x18\x01 \x03(\v2\v.RuleSampleR\x06series\x12%\n" +
+	"\amatches\x18\x02 \x03(\v2\v.RuleSampleR\amatches\x12\x1e\n" +
+	"\x06alerts\x18\x03 \x03(\v2\x06.AlertR\x06alerts\"W\n" +
+	"\vCounterSlot\x12\x18\n" +
+	"\areplica\x18\x01 \x01(\tR\areplica\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x04R\aversion\"\x99\x02\n" +
+	"\x10ReplicatedMetric\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1f\n" +
+	"\x04type\x18\x02 \x01(\x0e2\v.MetricTypeR\x04type\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x01R\x05value\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\x03R\tupdatedAt\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\x12\x16\n" +
+	"\x06writer\x18\x06 \x01(\tR\x06writer\x12\x1d\n" +
+	"\n" +
+	"deleted_at\x18\a \x01(\x03R\tdeletedAt\x12\"\n" +
+	"\x05slots\x18\b \x03(\v2\f.CounterSlotR\x05slots\x12&\n" +
+	"\adeleted\x18\t \x03(\v2\f.CounterSlotR\adeleted\"?\n" +
+	"\x10ReplicateRequest\x12+\n" +
+	"\ametrics\x18\x01 \x03(\v2\x11.ReplicatedMetricR\ametrics\"\x90\x01\n" +
+	"\x0eAgentHeartbeat\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\x04 \x01(\x03R\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\x05 \x01(\x03R\blastSeen\"x\n" +
+	"\fSyncResponse\x12+\n" +
+	"\ametrics\x18\x01 \x03(\v2\x11.ReplicatedMetricR\ametrics\x12\x12\n" +
+	"\x04node\x18\x02 \x01(\tR\x04node\x12'\n" +
+	"\x06agents\x18\x03 \x03(\v2\x0f.AgentHeartbeatR\x06agents*Y\n" +
+	"\n" +
+	"MetricType\x12\x1b\n" +
+	"\x17METRIC_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13METRIC_TYPE_COUNTER\x10\x01\x12\x15\n" +
+	"\x11METRIC_TYPE_GAUGE\x10\x02*\xa1\x01\n" +
+	"\rMatchOperator\x12\x1e\n" +
+	"\x1aMATCH_OPERATOR_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14MATCH_OPERATOR_EQUAL\x10\x01\x12\x1c\n" +
+	"\x18MATCH_OPERATOR_NOT_EQUAL\x10\x02\x12\x19\n" +
+	"\x15MATCH_OPERATOR_REGEXP\x10\x03\x12\x1d\n" +
+	"\x19MATCH_OPERATOR_NOT_REGEXP\x10\x042\x9e\x02\n" +
+	"\x0eMetricsService\x122\n" +
+	"\x0fReportRawMetric\x12\x17.ReportRawMetricRequest\x1a\x06.Empty\x12,\n" +
+	"\fReportMetric\x12\x14.ReportMetricRequest\x1a\x06.Empty\x12*\n" +
+	"\vReportBatch\x12\x13.ReportBatchRequest\x1a\x06.Empty\x12;\n" +
+	"\fDeleteMetric\x12\x14.DeleteMetricRequest\x1a\x15.DeleteMetricResponse\x12A\n" +
+	"\x0eDeleteByPrefix\x12\x16.DeleteByPrefixRequest\x1a\x17.DeleteByPrefixResponse2\x96\x04\n" +
+	"\x0fAlertingService\x12-\n" +
+	"\fListSilences\x12\x06.Empty\x1a\x15.ListSilencesResponse\x12*\n" +
+	"\n" +
+	"GetSilence\x12\x12.GetSilenceRequest\x1a\b.Silence\x120\n" +
+	"\rCreateSilence\x12\x15.CreateSilenceRequest\x1a\b.Silence\x120\n" +
+	"\rUpdateSilence\x12\x15.UpdateSilenceRequest\x1a\b.Silence\x12>\n" +
+	"\rDeleteSilence\x12\x15.DeleteSilenceRequest\x1a\x16.DeleteSilenceResponse\x12'\n" +
+	"\tListRules\x12\x06.Empty\x1a\x12.ListRulesResponse\x12!\n" +
+	"\aGetRule\x12\x0f.GetRuleRequest\x1a\x05.Rule\x12'\n" +
+	"\n" +
+	"CreateRule\x12\x12.CreateRuleRequest\x1a\x05.Rule\x12'\n" +
+	"\n" +
+	"UpdateRule\x12\x12.UpdateRuleRequest\x1a\x05.Rule\x125\n" +
+	"\n" +
+	"DeleteRule\x12\x12.DeleteRuleRequest\x1a\x13.DeleteRuleResponse\x12/\n" +
+	"\bTestRule\x12\x10.TestRuleRequest\x1a\x11.TestRuleResponse2W\n" +
+	"\x0eClusterService\x12&\n" +
+	"\tReplicate\x12\x11.ReplicateRequest\x1a\x06.Empty\x12\x1d\n" +
+	"\x04Sync\x12\x06.Empty\x1a\r.SyncResponseB\x0eZ\fgrpc/metricsb\x06proto3"
 
 var (
 	file_internal_grpc_proto_metrics_proto_rawDescOnce sync.Once
@@ -372,32 +2208,115 @@ func file_internal_grpc_proto_metrics_proto_rawDescGZIP() []byte {
 	return file_internal_grpc_proto_metrics_proto_rawDescData
 }
 
-var file_internal_grpc_proto_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_internal_grpc_proto_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_internal_grpc_proto_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_internal_grpc_proto_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 34)
 var file_internal_grpc_proto_metrics_proto_goTypes = []any{
 	(MetricType)(0),                // 0: MetricType
-	(*Metric)(nil),                 // 1: Metric
-	(*ReportRawMetricRequest)(nil), // 2: ReportRawMetricRequest
-	(*ReportMetricRequest)(nil),    // 3: ReportMetricRequest
-	(*ReportBatchRequest)(nil),     // 4: ReportBatchRequest
-	(*Empty)(nil),                  // 5: Empty
+	(MatchOperator)(0),             // 1: MatchOperator
+	(*Metric)(nil),                 // 2: Metric
+	(*ReportRawMetricRequest)(nil), // 3: ReportRawMetricRequest
+	(*ReportMetricRequest)(nil),    // 4: ReportMetricRequest
+	(*ReportBatchRequest)(nil),     // 5: ReportBatchRequest
+	(*DeleteMetricRequest)(nil),    // 6: DeleteMetricRequest
+	(*DeleteMetricResponse)(nil),   // 7: DeleteMetricResponse
+	(*DeleteByPrefixRequest)(nil),  // 8: DeleteByPrefixRequest
+	(*DeleteByPrefixResponse)(nil), // 9: DeleteByPrefixResponse
+	(*Empty)(nil),                  // 10: Empty
+	(*Matcher)(nil),                // 11: Matcher
+	(*Silence)(nil),                // 12: Silence
+	(*ListSilencesResponse)(nil),   // 13: ListSilencesResponse
+	(*GetSilenceRequest)(nil),      // 14: GetSilenceRequest
+	(*CreateSilenceRequest)(nil),   // 15: CreateSilenceRequest
+	(*UpdateSilenceRequest)(nil),   // 16: UpdateSilenceRequest
+	(*DeleteSilenceRequest)(nil),   // 17: DeleteSilenceRequest
+	(*DeleteSilenceResponse)(nil),  // 18: DeleteSilenceResponse
+	(*Label)(nil),                  // 19: Label
+	(*Rule)(nil),                   // 20: Rule
+	(*Alert)(nil),                  // 21: Alert
+	(*RuleSample)(nil),             // 22: RuleSample
+	(*ListRulesResponse)(nil),      // 23: ListRulesResponse
+	(*GetRuleRequest)(nil),         // 24: GetRuleRequest
+	(*CreateRuleRequest)(nil),      // 25: CreateRuleRequest
+	(*UpdateRuleRequest)(nil),      // 26: UpdateRuleRequest
+	(*DeleteRuleRequest)(nil),      // 27: DeleteRuleRequest
+	(*DeleteRuleResponse)(nil),     // 28: DeleteRuleResponse
+	(*TestRuleRequest)(nil),        // 29: TestRuleRequest
+	(*TestRuleResponse)(nil),       // 30: TestRuleResponse
+	(*CounterSlot)(nil),            // 31: CounterSlot
+	(*ReplicatedMetric)(nil),       // 32: ReplicatedMetric
+	(*ReplicateRequest)(nil),       // 33: ReplicateRequest
+	(*AgentHeartbeat)(nil),         // 34: AgentHeartbeat
+	(*SyncResponse)(nil),           // 35: SyncResponse
 }
 var file_internal_grpc_proto_metrics_proto_depIdxs = []int32{
-	0, // 0: Metric.type:type_name -> MetricType
-	0, // 1: ReportRawMetricRequest.metric_type:type_name -> MetricType
-	1, // 2: ReportMetricRequest.metric:type_name -> Metric
-	1, // 3: ReportBatchRequest.metrics:type_name -> Metric
-	2, // 4: MetricsService.ReportRawMetric:input_type -> ReportRawMetricRequest
-	3, // 5: MetricsService.ReportMetric:input_type -> ReportMetricRequest
-	4, // 6: MetricsService.ReportBatch:input_type -> ReportBatchRequest
-	5, // 7: MetricsService.ReportRawMetric:output_type -> Empty
-	5, // 8: MetricsService.ReportMetric:output_type -> Empty
-	5, // 9: MetricsService.ReportBatch:output_type -> Empty
-	7, // [7:10] is the sub-list for method output_type
-	4, // [4:7] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	0,  // 0: Metric.type:type_name -> MetricType
+	0,  // 1: ReportRawMetricRequest.metric_type:type_name -> MetricType
+	2,  // 2: ReportMetricRequest.metric:type_name -> Metric
+	2,  // 3: ReportBatchRequest.metrics:type_name -> Metric
+	0,  // 4: DeleteMetricRequest.metric_type:type_name -> MetricType
+	1,  // 5: Matcher.operator:type_name -> MatchOperator
+	11, // 6: Silence.matchers:type_name -> Matcher
+	12, // 7: ListSilencesResponse.silences:type_name -> Silence
+	12, // 8: CreateSilenceRequest.silence:type_name -> Silence
+	12, // 9: UpdateSilenceRequest.silence:type_name -> Silence
+	19, // 10: Rule.labels:type_name -> Label
+	19, // 11: Rule.annotations:type_name -> Label
+	19, // 12: Alert.labels:type_name -> Label
+	19, // 13: Alert.annotations:type_name -> Label
+	20, // 14: ListRulesResponse.rules:type_name -> Rule
+	20, // 15: CreateRuleRequest.rule:type_name -> Rule
+	20, // 16: UpdateRuleRequest.rule:type_name -> Rule
+	20, // 17: TestRuleRequest.rule:type_name -> Rule
+	22, // 18: TestRuleResponse.series:type_name -> RuleSample
+	22, // 19: TestRuleResponse.matches:type_name -> RuleSample
+	21, // 20: TestRuleResponse.alerts:type_name -> Alert
+	0,  // 21: ReplicatedMetric.type:type_name -> MetricType
+	31, // 22: ReplicatedMetric.slots:type_name -> CounterSlot
+	31, // 23: ReplicatedMetric.deleted:type_name -> CounterSlot
+	32, // 24: ReplicateRequest.metrics:type_name -> ReplicatedMetric
+	32, // 25: SyncResponse.metrics:type_name -> ReplicatedMetric
+	34, // 26: SyncResponse.agents:type_name -> AgentHeartbeat
+	3,  // 27: MetricsService.ReportRawMetric:input_type -> ReportRawMetricRequest
+	4,  // 28: MetricsService.ReportMetric:input_type -> ReportMetricRequest
+	5,  // 29: MetricsService.ReportBatch:input_type -> ReportBatchRequest
+	6,  // 30: MetricsService.DeleteMetric:input_type -> DeleteMetricRequest
+	8,  // 31: MetricsService.DeleteByPrefix:input_type -> DeleteByPrefixRequest
+	10, // 32: AlertingService.ListSilences:input_type -> Empty
+	14, // 33: AlertingService.GetSilence:input_type -> GetSilenceRequest
+	15, // 34: AlertingService.CreateSilence:input_type -> CreateSilenceRequest
+	16, // 35: AlertingService.UpdateSilence:input_type -> UpdateSilenceRequest
+	17, // 36: AlertingService.DeleteSilence:input_type -> DeleteSilenceRequest
+	10, // 37: AlertingService.ListRules:input_type -> Empty
+	24, // 38: AlertingService.GetRule:input_type -> GetRuleRequest
+	25, // 39: AlertingService.CreateRule:input_type -> CreateRuleRequest
+	26, // 40: AlertingService.UpdateRule:input_type -> UpdateRuleRequest
+	27, // 41: AlertingService.DeleteRule:input_type -> DeleteRuleRequest
+	29, // 42: AlertingService.TestRule:input_type -> TestRuleRequest
+	33, // 43: ClusterService.Replicate:input_type -> ReplicateRequest
+	10, // 44: ClusterService.Sync:input_type -> Empty
+	10, // 45: MetricsService.ReportRawMetric:output_type -> Empty
+	10, // 46: MetricsService.ReportMetric:output_type -> Empty
+	10, // 47: MetricsService.ReportBatch:output_type -> Empty
+	7,  // 48: MetricsService.DeleteMetric:output_type -> DeleteMetricResponse
+	9,  // 49: MetricsService.DeleteByPrefix:output_type -> DeleteByPrefixResponse
+	13, // 50: AlertingService.ListSilences:output_type -> ListSilencesResponse
+	12, // 51: AlertingService.GetSilence:output_type -> Silence
+	12, // 52: AlertingService.CreateSilence:output_type -> Silence
+	12, // 53: AlertingService.UpdateSilence:output_type -> Silence
+	18, // 54: AlertingService.DeleteSilence:output_type -> DeleteSilenceResponse
+	23, // 55: AlertingService.ListRules:output_type -> ListRulesResponse
+	20, // 56: AlertingService.GetRule:output_type -> Rule
+	20, // 57: AlertingService.CreateRule:output_type -> Rule
+	20, // 58: AlertingService.UpdateRule:output_type -> Rule
+	28, // 59: AlertingService.DeleteRule:output_type -> DeleteRuleResponse
+	30, // 60: AlertingService.TestRule:output_type -> TestRuleResponse
+	10, // 61: ClusterService.Replicate:output_type -> Empty
+	35, // 62: ClusterService.Sync:output_type -> SyncResponse
+	45, // [45:63] is the sub-list for method output_type
+	27, // [27:45] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_internal_grpc_proto_metrics_proto_init() }
@@ -411,10 +2330,10 @@ func file_internal_grpc_proto_metrics_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_grpc_proto_metrics_proto_rawDesc), len(file_internal_grpc_proto_metrics_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   5,
+			NumEnums:      2,
+			NumMessages:   34,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   3,
 		},
 		GoTypes:           file_internal_grpc_proto_metrics_proto_goTypes,
 		DependencyIndexes: file_internal_grpc_proto_metrics_proto_depIdxs,
@@ -23,6 +23,8 @@ const (
 	MetricsService_ReportRawMetric_FullMethodName = "/MetricsService/ReportRawMetric"
 	MetricsService_ReportMetric_FullMethodName    = "/MetricsService/ReportMetric"
 	MetricsService_ReportBatch_FullMethodName     = "/MetricsService/ReportBatch"
+	MetricsService_DeleteMetric_FullMethodName    = "/MetricsService/DeleteMetric"
+	MetricsService_DeleteByPrefix_FullMethodName  = "/MetricsService/DeleteByPrefix"
 )
 
 // MetricsServiceClient is the client API for MetricsService service.
@@ -37,6 +39,10 @@ type MetricsServiceClient interface {
 	ReportMetric(ctx context.Context, in *ReportMetricRequest, opts ...grpc.CallOption) (*Empty, error)
 	// ReportBatch reports multiple metrics in batch
 	ReportBatch(ctx context.Context, in *ReportBatchRequest, opts ...grpc.CallOption) (*Empty, error)
+	// DeleteMetric removes a single metric by type and id
+	DeleteMetric(ctx context.Context, in *DeleteMetricRequest, opts ...grpc.CallOption) (*DeleteMetricResponse, error)
+	// DeleteByPrefix removes all metrics whose id starts with the prefix
+	DeleteByPrefix(ctx context.Context, in *DeleteByPrefixRequest, opts ...grpc.CallOption) (*DeleteByPrefixResponse, error)
 }
 
 type metricsServiceClient struct {
@@ -77,6 +83,26 @@ func (c *metricsServiceClient) ReportBatch(ctx context.Context, in *ReportBatchR
 	return out, nil
 }
 
+func (c *metricsServiceClient) DeleteMetric(ctx context.Context, in *DeleteMetricRequest, opts ...grpc.CallOption) (*DeleteMetricResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMetricResponse)
+	err := c.cc.Invoke(ctx, MetricsService_DeleteMetric_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsServiceClient) DeleteByPrefix(ctx context.Context, in *DeleteByPrefixRequest, opts ...grpc.CallOption) (*DeleteByPrefixResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteByPrefixResponse)
+	err := c.cc.Invoke(ctx, MetricsService_DeleteByPrefix_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServiceServer is the server API for MetricsService service.
 // All implementations must embed UnimplementedMetricsServiceServer
 // for forward compatibility.
@@ -89,6 +115,10 @@ type MetricsServiceServer interface {
 	ReportMetric(context.Context, *ReportMetricRequest) (*Empty, error)
 	// ReportBatch reports multiple metrics in batch
 	ReportBatch(context.Context, *ReportBatchRequest) (*Empty, error)
+	// DeleteMetric removes a single metric by type and id
+	DeleteMetric(context.Context, *DeleteMetricRequest) (*DeleteMetricResponse, error)
+	// DeleteByPrefix removes all metrics whose id starts with the prefix
+	DeleteByPrefix(context.Context, *DeleteByPrefixRequest) (*DeleteByPrefixResponse, error)
 	mustEmbedUnimplementedMetricsServiceServer()
 }
 
@@ -108,6 +138,12 @@ func (UnimplementedMetricsServiceServer) ReportMetric(context.Context, *ReportMe
 func (UnimplementedMetricsServiceServer) ReportBatch(context.Context, *ReportBatchRequest) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ReportBatch not implemented")
 }
+func (UnimplementedMetricsServiceServer) DeleteMetric(context.Context, *DeleteMetricRequest) (*DeleteMetricResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMetric not implemented")
+}
+func (UnimplementedMetricsServiceServer) DeleteByPrefix(context.Context, *DeleteByPrefixRequest) (*DeleteByPrefixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteByPrefix not implemented")
+}
 func (UnimplementedMetricsServiceServer) mustEmbedUnimplementedMetricsServiceServer() {}
 func (UnimplementedMetricsServiceServer) testEmbeddedByValue()                        {}
 
@@ -183,6 +219,42 @@ func _MetricsService_ReportBatch_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetricsService_DeleteMetric_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMetricRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).DeleteMetric(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_DeleteMetric_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).DeleteMetric(ctx, req.(*DeleteMetricRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MetricsService_DeleteByPrefix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteByPrefixRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).DeleteByPrefix(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetricsService_DeleteByPrefix_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).DeleteByPrefix(ctx, req.(*DeleteByPrefixRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetricsService_ServiceDesc is the grpc.ServiceDesc for MetricsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -202,6 +274,670 @@ var MetricsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportBatch",
 			Handler:    _MetricsService_ReportBatch_Handler,
 		},
+		{
+			MethodName: "DeleteMetric",
+			Handler:    _MetricsService_DeleteMetric_Handler,
+		},
+		{
+			MethodName: "DeleteByPrefix",
+			Handler:    _MetricsService_DeleteByPrefix_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/grpc/proto/metrics.proto",
+}
+
+const (
+	AlertingService_ListSilences_FullMethodName  = "/AlertingService/ListSilences"
+	AlertingService_GetSilence_FullMethodName    = "/AlertingService/GetSilence"
+	AlertingService_CreateSilence_FullMethodName = "/AlertingService/CreateSilence"
+	AlertingService_UpdateSilence_FullMethodName = "/AlertingService/UpdateSilence"
+	AlertingService_DeleteSilence_FullMethodName = "/AlertingService/DeleteSilence"
+	AlertingService_ListRules_FullMethodName     = "/AlertingService/ListRules"
+	AlertingService_GetRule_FullMethodName       = "/AlertingService/GetRule"
+	AlertingService_CreateRule_FullMethodName    = "/AlertingService/CreateRule"
+	AlertingService_UpdateRule_FullMethodName    = "/AlertingService/UpdateRule"
+	AlertingService_DeleteRule_FullMethodName    = "/AlertingService/DeleteRule"
+	AlertingService_TestRule_FullMethodName      = "/AlertingService/TestRule"
+)
+
+// AlertingServiceClient is the client API for AlertingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AlertingService defines the gRPC service for alert management
+type AlertingServiceClient interface {
+	// ListSilences returns all silences
+	ListSilences(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListSilencesResponse, error)
+	// GetSilence returns a single silence by id
+	GetSilence(ctx context.Context, in *GetSilenceRequest, opts ...grpc.CallOption) (*Silence, error)
+	// CreateSilence creates a silence and returns it with the assigned id
+	CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error)
+	// UpdateSilence replaces an existing silence
+	UpdateSilence(ctx context.Context, in *UpdateSilenceRequest, opts ...grpc.CallOption) (*Silence, error)
+	// DeleteSilence removes a silence by id
+	DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error)
+	// ListRules returns all alert rules
+	ListRules(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListRulesResponse, error)
+	// GetRule returns a single alert rule by name
+	GetRule(ctx context.Context, in *GetRuleRequest, opts ...grpc.CallOption) (*Rule, error)
+	// CreateRule creates an alert rule, its expression has to match existing metrics
+	CreateRule(ctx context.Context, in *CreateRuleRequest, opts ...grpc.CallOption) (*Rule, error)
+	// UpdateRule replaces an existing alert rule
+	UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*Rule, error)
+	// DeleteRule removes an alert rule by name
+	DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*DeleteRuleResponse, error)
+	// TestRule evaluates a rule against the current metrics without saving it
+	TestRule(ctx context.Context, in *TestRuleRequest, opts ...grpc.CallOption) (*TestRuleResponse, error)
+}
+
+type alertingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAlertingServiceClient(cc grpc.ClientConnInterface) AlertingServiceClient {
+	return &alertingServiceClient{cc}
+}
+
+func (c *alertingServiceClient) ListSilences(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListSilencesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSilencesResponse)
+	err := c.cc.Invoke(ctx, AlertingService_ListSilences_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) GetSilence(ctx context.Context, in *GetSilenceRequest, opts ...grpc.CallOption) (*Silence, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Silence)
+	err := c.cc.Invoke(ctx, AlertingService_GetSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) CreateSilence(ctx context.Context, in *CreateSilenceRequest, opts ...grpc.CallOption) (*Silence, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Silence)
+	err := c.cc.Invoke(ctx, AlertingService_CreateSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) UpdateSilence(ctx context.Context, in *UpdateSilenceRequest, opts ...grpc.CallOption) (*Silence, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Silence)
+	err := c.cc.Invoke(ctx, AlertingService_UpdateSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) DeleteSilence(ctx context.Context, in *DeleteSilenceRequest, opts ...grpc.CallOption) (*DeleteSilenceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSilenceResponse)
+	err := c.cc.Invoke(ctx, AlertingService_DeleteSilence_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) ListRules(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRulesResponse)
+	err := c.cc.Invoke(ctx, AlertingService_ListRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) GetRule(ctx context.Context, in *GetRuleRequest, opts ...grpc.CallOption) (*Rule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Rule)
+	err := c.cc.Invoke(ctx, AlertingService_GetRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) CreateRule(ctx context.Context, in *CreateRuleRequest, opts ...grpc.CallOption) (*Rule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Rule)
+	err := c.cc.Invoke(ctx, AlertingService_CreateRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*Rule, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Rule)
+	err := c.cc.Invoke(ctx, AlertingService_UpdateRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*DeleteRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRuleResponse)
+	err := c.cc.Invoke(ctx, AlertingService_DeleteRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *alertingServiceClient) TestRule(ctx context.Context, in *TestRuleRequest, opts ...grpc.CallOption) (*TestRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TestRuleResponse)
+	err := c.cc.Invoke(ctx, AlertingService_TestRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AlertingServiceServer is the server API for AlertingService service.
+// All implementations must embed UnimplementedAlertingServiceServer
+// for forward compatibility.
+//
+// AlertingService defines the gRPC service for alert management
+type AlertingServiceServer interface {
+	// ListSilences returns all silences
+	ListSilences(context.Context, *Empty) (*ListSilencesResponse, error)
+	// GetSilence returns a single silence by id
+	GetSilence(context.Context, *GetSilenceRequest) (*Silence, error)
+	// CreateSilence creates a silence and returns it with the assigned id
+	CreateSilence(context.Context, *CreateSilenceRequest) (*Silence, error)
+	// UpdateSilence replaces an existing silence
+	UpdateSilence(context.Context, *UpdateSilenceRequest) (*Silence, error)
+	// DeleteSilence removes a silence by id
+	DeleteSilence(context.Context, *DeleteSilenceRequest) (*DeleteSilenceResponse, error)
+	// ListRules returns all alert rules
+	ListRules(context.Context, *Empty) (*ListRulesResponse, error)
+	// GetRule returns a single alert rule by name
+	GetRule(context.Context, *GetRuleRequest) (*Rule, error)
+	// CreateRule creates an alert rule, its expression has to match existing metrics
+	CreateRule(context.Context, *CreateRuleRequest) (*Rule, error)
+	// UpdateRule replaces an existing alert rule
+	UpdateRule(context.Context, *UpdateRuleRequest) (*Rule, error)
+	// DeleteRule removes an alert rule by name
+	DeleteRule(context.Context, *DeleteRuleRequest) (*DeleteRuleResponse, error)
+	// TestRule evaluates a rule against the current metrics without saving it
+	TestRule(context.Context, *TestRuleRequest) (*TestRuleResponse, error)
+	mustEmbedUnimplementedAlertingServiceServer()
+}
+
+// UnimplementedAlertingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAlertingServiceServer struct{}
+
+func (UnimplementedAlertingServiceServer) ListSilences(context.Context, *Empty) (*ListSilencesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSilences not implemented")
+}
+func (UnimplementedAlertingServiceServer) GetSilence(context.Context, *GetSilenceRequest) (*Silence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSilence not implemented")
+}
+func (UnimplementedAlertingServiceServer) CreateSilence(context.Context, *CreateSilenceRequest) (*Silence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSilence not implemented")
+}
+func (UnimplementedAlertingServiceServer) UpdateSilence(context.Context, *UpdateSilenceRequest) (*Silence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSilence not implemented")
+}
+func (UnimplementedAlertingServiceServer) DeleteSilence(context.Context, *DeleteSilenceRequest) (*DeleteSilenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSilence not implemented")
+}
+func (UnimplementedAlertingServiceServer) ListRules(context.Context, *Empty) (*ListRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRules not implemented")
+}
+func (UnimplementedAlertingServiceServer) GetRule(context.Context, *GetRuleRequest) (*Rule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRule not implemented")
+}
+func (UnimplementedAlertingServiceServer) CreateRule(context.Context, *CreateRuleRequest) (*Rule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRule not implemented")
+}
+func (UnimplementedAlertingServiceServer) UpdateRule(context.Context, *UpdateRuleRequest) (*Rule, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRule not implemented")
+}
+func (UnimplementedAlertingServiceServer) DeleteRule(context.Context, *DeleteRuleRequest) (*DeleteRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRule not implemented")
+}
+func (UnimplementedAlertingServiceServer) TestRule(context.Context, *TestRuleRequest) (*TestRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TestRule not implemented")
+}
+func (UnimplementedAlertingServiceServer) mustEmbedUnimplementedAlertingServiceServer() {}
+func (UnimplementedAlertingServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeAlertingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AlertingServiceServer will
+// result in compilation errors.
+type UnsafeAlertingServiceServer interface {
+	mustEmbedUnimplementedAlertingServiceServer()
+}
+
+func RegisterAlertingServiceServer(s grpc.ServiceRegistrar, srv AlertingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAlertingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AlertingService_ServiceDesc, srv)
+}
+
+func _AlertingService_ListSilences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).ListSilences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_ListSilences_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).ListSilences(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_GetSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).GetSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_GetSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).GetSilence(ctx, req.(*GetSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_CreateSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).CreateSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_CreateSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).CreateSilence(ctx, req.(*CreateSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_UpdateSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).UpdateSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_UpdateSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).UpdateSilence(ctx, req.(*UpdateSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_DeleteSilence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSilenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).DeleteSilence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_DeleteSilence_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).DeleteSilence(ctx, req.(*DeleteSilenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_ListRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).ListRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_ListRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).ListRules(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_GetRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).GetRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_GetRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).GetRule(ctx, req.(*GetRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_CreateRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).CreateRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_CreateRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).CreateRule(ctx, req.(*CreateRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_UpdateRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).UpdateRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_UpdateRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).UpdateRule(ctx, req.(*UpdateRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_DeleteRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).DeleteRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_DeleteRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).DeleteRule(ctx, req.(*DeleteRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AlertingService_TestRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServiceServer).TestRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AlertingService_TestRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServiceServer).TestRule(ctx, req.(*TestRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AlertingService_ServiceDesc is the grpc.ServiceDesc for AlertingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AlertingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "AlertingService",
+	HandlerType: (*AlertingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSilences",
+			Handler:    _AlertingService_ListSilences_Handler,
+		},
+		{
+			MethodName: "GetSilence",
+			Handler:    _AlertingService_GetSilence_Handler,
+		},
+		{
+			MethodName: "CreateSilence",
+			Handler:    _AlertingService_CreateSilence_Handler,
+		},
+		{
+			MethodName: "UpdateSilence",
+			Handler:    _AlertingService_UpdateSilence_Handler,
+		},
+		{
+			MethodName: "DeleteSilence",
+			Handler:    _AlertingService_DeleteSilence_Handler,
+		},
+		{
+			MethodName: "ListRules",
+			Handler:    _AlertingService_ListRules_Handler,
+		},
+		{
+			MethodName: "GetRule",
+			Handler:    _AlertingService_GetRule_Handler,
+		},
+		{
+			MethodName: "CreateRule",
+			Handler:    _AlertingService_CreateRule_Handler,
+		},
+		{
+			MethodName: "UpdateRule",
+			Handler:    _AlertingService_UpdateRule_Handler,
+		},
+		{
+			MethodName: "DeleteRule",
+			Handler:    _AlertingService_DeleteRule_Handler,
+		},
+		{
+			MethodName: "TestRule",
+			Handler:    _AlertingService_TestRule_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "internal/grpc/proto/metrics.proto",
+}
+
+const (
+	ClusterService_Replicate_FullMethodName = "/ClusterService/Replicate"
+	ClusterService_Sync_FullMethodName      = "/ClusterService/Sync"
+)
+
+// ClusterServiceClient is the client API for ClusterService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ClusterService replicates the metrics between the servers of a cluster
+type ClusterServiceClient interface {
+	// Replicate merges the changed metrics of a peer
+	Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Sync returns the state of all metrics, including deleted ones, for a peer to merge
+	Sync(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncResponse, error)
+}
+
+type clusterServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClusterServiceClient(cc grpc.ClientConnInterface) ClusterServiceClient {
+	return &clusterServiceClient{cc}
+}
+
+func (c *clusterServiceClient) Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ClusterService_Replicate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clusterServiceClient) Sync(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, ClusterService_Sync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClusterServiceServer is the server API for ClusterService service.
+// All implementations must embed UnimplementedClusterServiceServer
+// for forward compatibility.
+//
+// ClusterService replicates the metrics between the servers of a cluster
+type ClusterServiceServer interface {
+	// Replicate merges the changed metrics of a peer
+	Replicate(context.Context, *ReplicateRequest) (*Empty, error)
+	// Sync returns the state of all metrics, including deleted ones, for a peer to merge
+	Sync(context.Context, *Empty) (*SyncResponse, error)
+	mustEmbedUnimplementedClusterServiceServer()
+}
+
+// UnimplementedClusterServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedClusterServiceServer struct{}
+
+func (UnimplementedClusterServiceServer) Replicate(context.Context, *ReplicateRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Replicate not implemented")
+}
+func (UnimplementedClusterServiceServer) Sync(context.Context, *Empty) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedClusterServiceServer) mustEmbedUnimplementedClusterServiceServer() {}
+func (UnimplementedClusterServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeClusterServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClusterServiceServer will
+// result in compilation errors.
+type UnsafeClusterServiceServer interface {
+	mustEmbedUnimplementedClusterServiceServer()
+}
+
+func RegisterClusterServiceServer(s grpc.ServiceRegistrar, srv ClusterServiceServer) {
+	// If the following call pancis, it indicates UnimplementedClusterServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ClusterService_ServiceDesc, srv)
+}
+
+func _ClusterService_Replicate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplicateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).Replicate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClusterService_Replicate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).Replicate(ctx, req.(*ReplicateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClusterService_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClusterServiceServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClusterService_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClusterServiceServer).Sync(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ClusterService_ServiceDesc is the grpc.ServiceDesc for ClusterService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ClusterService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ClusterService",
+	HandlerType: (*ClusterServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Replicate",
+			Handler:    _ClusterService_Replicate_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _ClusterService_Sync_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/grpc/proto/metrics.proto",
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/angryscorp/alert-metrics/internal/grpc/mapper"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
+)
+
+type AlertingServer struct {
+	grpcmetrics.UnimplementedAlertingServiceServer
+	silences domain.SilenceManager
+	rules    domain.RuleManager
+	logger   zerolog.Logger
+}
+
+var _ grpcmetrics.AlertingServiceServer = (*AlertingServer)(nil)
+
+func NewAlertingServer(silences domain.SilenceManager, rules domain.RuleManager, logger zerolog.Logger) *AlertingServer {
+	return &AlertingServer{
+		silences: silences,
+		rules:    rules,
+		logger:   logger,
+	}
+}
+
+func (s *AlertingServer) ListSilences(ctx context.Context, _ *grpcmetrics.Empty) (*grpcmetrics.ListSilencesResponse, error) {
+	silences := s.silences.ListSilences(ctx)
+
+	resp := &grpcmetrics.ListSilencesResponse{
+		Silences: make([]*grpcmetrics.Silence, len(silences)),
+	}
+	for i, silence := range silences {
+		resp.Silences[i] = mapper.SilenceToProto(silence)
+	}
+
+	return resp, nil
+}
+
+func (s *AlertingServer) GetSilence(ctx context.Context, req *grpcmetrics.GetSilenceRequest) (*grpcmetrics.Silence, error) {
+	silence, ok := s.silences.GetSilence(ctx, req.Id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "silence %s not found", req.Id)
+	}
+
+	return mapper.SilenceToProto(silence), nil
+}
+
+func (s *AlertingServer) CreateSilence(ctx context.Context, req *grpcmetrics.CreateSilenceRequest) (*grpcmetrics.Silence, error) {
+	if req.Silence == nil {
+		return nil, status.Error(codes.InvalidArgument, "silence is required")
+	}
+
+	silence, err := s.silences.CreateSilence(ctx, mapper.SilenceToDomain(req.Silence))
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to create silence")
+		return nil, silenceError(err)
+	}
+
+	return mapper.SilenceToProto(silence), nil
+}
+
+func (s *AlertingServer) UpdateSilence(ctx context.Context, req *grpcmetrics.UpdateSilenceRequest) (*grpcmetrics.Silence, error) {
+	if req.Silence == nil {
+		return nil, status.Error(codes.InvalidArgument, "silence is required")
+	}
+
+	silence, err := s.silences.UpdateSilence(ctx, mapper.SilenceToDomain(req.Silence))
+	if err != nil {
+		s.logger.Error().Err(err).Str("silence_id", req.Silence.Id).Msg("failed to update silence")
+		return nil, silenceError(err)
+	}
+
+	return mapper.SilenceToProto(silence), nil
+}
+
+func (s *AlertingServer) DeleteSilence(ctx context.Context, req *grpcmetrics.DeleteSilenceRequest) (*grpcmetrics.DeleteSilenceResponse, error) {
+	deleted, err := s.silences.DeleteSilence(ctx, req.Id)
+	if err != nil {
+		s.logger.Error().Err(err).Str("silence_id", req.Id).Msg("failed to delete silence")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &grpcmetrics.DeleteSilenceResponse{Deleted: deleted}, nil
+}
+
+func (s *AlertingServer) ListRules(ctx context.Context, _ *grpcmetrics.Empty) (*grpcmetrics.ListRulesResponse, error) {
+	rules := s.rules.ListRules(ctx)
+
+	resp := &grpcmetrics.ListRulesResponse{
+		Rules: make([]*grpcmetrics.Rule, len(rules)),
+	}
+	for i, rule := range rules {
+		resp.Rules[i] = mapper.RuleToProto(rule)
+	}
+
+	return resp, nil
+}
+
+func (s *AlertingServer) GetRule(ctx context.Context, req *grpcmetrics.GetRuleRequest) (*grpcmetrics.Rule, error) {
+	rule, ok := s.rules.GetRule(ctx, req.Name)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "rule %s not found", req.Name)
+	}
+
+	return mapper.RuleToProto(rule), nil
+}
+
+func (s *AlertingServer) CreateRule(ctx context.Context, req *grpcmetrics.CreateRuleRequest) (*grpcmetrics.Rule, error) {
+	if req.Rule == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	rule, err := s.rules.CreateRule(ctx, mapper.RuleToDomain(req.Rule))
+	if err != nil {
+		s.logger.Error().Err(err).Str("rule", req.Rule.Name).Msg("failed to create rule")
+		return nil, ruleError(err)
+	}
+
+	return mapper.RuleToProto(rule), nil
+}
+
+func (s *AlertingServer) UpdateRule(ctx context.Context, req *grpcmetrics.UpdateRuleRequest) (*grpcmetrics.Rule, error) {
+	if req.Rule == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	rule, err := s.rules.UpdateRule(ctx, mapper.RuleToDomain(req.Rule))
+	if err != nil {
+		s.logger.Error().Err(err).Str("rule", req.Rule.Name).Msg("failed to update rule")
+		return nil, ruleError(err)
+	}
+
+	return mapper.RuleToProto(rule), nil
+}
+
+func (s *AlertingServer) DeleteRule(ctx context.Context, req *grpcmetrics.DeleteRuleRequest) (*grpcmetrics.DeleteRuleResponse, error) {
+	deleted, err := s.rules.DeleteRule(ctx, req.Name)
+	if err != nil {
+		s.logger.Error().Err(err).Str("rule", req.Name).Msg("failed to delete rule")
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &grpcmetrics.DeleteRuleResponse{Deleted: deleted}, nil
+}
+
+func (s *AlertingServer) TestRule(ctx context.Context, req *grpcmetrics.TestRuleRequest) (*grpcmetrics.TestRuleResponse, error) {
+	if req.Rule == nil {
+		return nil, status.Error(codes.InvalidArgument, "rule is required")
+	}
+
+	result, err := s.rules.TestRule(ctx, mapper.RuleToDomain(req.Rule))
+	if err != nil {
+		return nil, ruleError(err)
+	}
+
+	return mapper.RuleTestResultToProto(result), nil
+}
+
+// silenceError maps a silence manager error to a gRPC status error.
+func silenceError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSilence):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrSilenceNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// ruleError maps a rule manager error to a gRPC status error.
+func ruleError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRule):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrRuleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrRuleExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/angryscorp/alert-metrics/internal/grpc/mapper"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	grpcmetrics "github.com/angryscorp/alert-metrics/internal/grpc/metrics"
+)
+
+type ClusterServer struct {
+	grpcmetrics.UnimplementedClusterServiceServer
+	member domain.ClusterMember
+	logger zerolog.Logger
+}
+
+var _ grpcmetrics.ClusterServiceServer = (*ClusterServer)(nil)
+
+func NewClusterServer(member domain.ClusterMember, logger zerolog.Logger) *ClusterServer {
+	return &ClusterServer{
+		member: member,
+		logger: logger,
+	}
+}
+
+func (s *ClusterServer) Replicate(ctx context.Context, req *grpcmetrics.ReplicateRequest) (*grpcmetrics.Empty, error) {
+	s.logger.Debug().
+		Int("count", len(req.Metrics)).
+		Msg("received replicated metrics via gRPC")
+
+	metrics := make([]domain.ReplicatedMetric, len(req.Metrics))
+	for i, protoMetric := range req.Metrics {
+		metrics[i] = mapper.ReplicatedMetricToDomain(protoMetric)
+	}
+
+	if err := s.member.Merge(ctx, metrics); err != nil {
+		s.logger.Error().Err(err).
+			Int("count", len(metrics)).
+			Msg("failed to merge replicated metrics")
+		return &grpcmetrics.Empty{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &grpcmetrics.Empty{}, nil
+}
+
+func (s *ClusterServer) Sync(ctx context.Context, _ *grpcmetrics.Empty) (*grpcmetrics.SyncResponse, error) {
+	state, err := s.member.State(ctx)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to read cluster state")
+		return &grpcmetrics.SyncResponse{}, err
+	}
+
+	return mapper.ClusterStateToProto(state), nil
+}
@@ -16,15 +16,30 @@ type GRPCServer struct {
 	logger zerolog.Logger
 }
 
-func NewGRPCServer(storage domain.MetricStorage, logger zerolog.Logger) *GRPCServer {
+func NewGRPCServer(
+	storage domain.MetricStorage,
+	heartbeats domain.HeartbeatRecorder,
+	silences domain.SilenceManager,
+	rules domain.RuleManager,
+	member domain.ClusterMember,
+	logger zerolog.Logger,
+) *GRPCServer {
 	var opts []grpc.ServerOption
 
-	opts = append(opts, grpc.UnaryInterceptor(loggingInterceptor(logger)))
+	opts = append(opts, grpc.ChainUnaryInterceptor(
+		loggingInterceptor(logger),
+		heartbeatInterceptor(heartbeats),
+		tenantInterceptor(),
+	))
 
 	grpcServer := grpc.NewServer(opts...)
 	metricsServer := NewMetricsServer(storage, logger)
 
 	grpcmetrics.RegisterMetricsServiceServer(grpcServer, metricsServer)
+	grpcmetrics.RegisterAlertingServiceServer(grpcServer, NewAlertingServer(silences, rules, logger))
+	if member != nil {
+		grpcmetrics.RegisterClusterServiceServer(grpcServer, NewClusterServer(member, logger))
+	}
 
 	return &GRPCServer{
 		server: grpcServer,
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// heartbeatInterceptor records a heartbeat for every successful report RPC.
+// The agent is identified by its metadata, falling back to the peer address.
+func heartbeatInterceptor(recorder domain.HeartbeatRecorder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil || !strings.Contains(info.FullMethod, "/Report") {
+			return resp, err
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		agent := domain.AgentInfo{
+			ID:      firstValue(md, domain.HeaderAgentID),
+			Address: firstValue(md, "X-Real-IP"),
+			Version: firstValue(md, domain.HeaderAgentVersion),
+		}
+		if agent.Address == "" {
+			if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+				agent.Address = p.Addr.String()
+				if host, _, splitErr := net.SplitHostPort(agent.Address); splitErr == nil {
+					agent.Address = host
+				}
+			}
+		}
+
+		recorder.RecordHeartbeat(agent)
+
+		return resp, nil
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -2,8 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/angryscorp/alert-metrics/internal/grpc/mapper"
 
@@ -44,11 +48,12 @@ func (s *MetricsServer) ReportRawMetric(ctx context.Context, req *grpcmetrics.Re
 		return &grpcmetrics.Empty{}, err
 	}
 
+	metric.UpdatedAt = time.Now()
 	if err := s.storage.UpdateMetric(ctx, *metric); err != nil {
 		s.logger.Error().Err(err).
 			Str("key", req.Key).
 			Msg("failed to update raw metric")
-		return &grpcmetrics.Empty{}, err
+		return &grpcmetrics.Empty{}, updateError(err)
 	}
 
 	return &grpcmetrics.Empty{}, nil
@@ -61,12 +66,13 @@ func (s *MetricsServer) ReportMetric(ctx context.Context, req *grpcmetrics.Repor
 		Msg("received metric via gRPC")
 
 	metric := mapper.MetricToDomain(req.Metric)
+	metric.UpdatedAt = time.Now()
 
 	if err := s.storage.UpdateMetric(ctx, metric); err != nil {
 		s.logger.Error().Err(err).
 			Str("metric_id", req.Metric.Id).
 			Msg("failed to update metric")
-		return &grpcmetrics.Empty{}, err
+		return &grpcmetrics.Empty{}, updateError(err)
 	}
 
 	return &grpcmetrics.Empty{}, nil
@@ -81,13 +87,64 @@ func (s *MetricsServer) ReportBatch(ctx context.Context, req *grpcmetrics.Report
 	for i, protoMetric := range req.Metrics {
 		metrics[i] = mapper.MetricToDomain(protoMetric)
 	}
+	domain.MarkReceived(metrics, time.Now())
 
 	if err := s.storage.UpdateMetrics(ctx, metrics); err != nil {
 		s.logger.Error().Err(err).
 			Int("count", len(metrics)).
 			Msg("failed to update batch")
-		return &grpcmetrics.Empty{}, err
+		return &grpcmetrics.Empty{}, updateError(err)
 	}
 
 	return &grpcmetrics.Empty{}, nil
 }
+
+func (s *MetricsServer) DeleteMetric(ctx context.Context, req *grpcmetrics.DeleteMetricRequest) (*grpcmetrics.DeleteMetricResponse, error) {
+	s.logger.Debug().
+		Str("metric_id", req.Id).
+		Str("type", req.MetricType.String()).
+		Msg("received delete metric via gRPC")
+
+	if req.MetricType == grpcmetrics.MetricType_METRIC_TYPE_UNSPECIFIED {
+		return &grpcmetrics.DeleteMetricResponse{}, status.Error(codes.InvalidArgument, "metric type is required")
+	}
+
+	deleted, err := s.storage.DeleteMetric(ctx, mapper.MetricTypeToDomain(req.MetricType), req.Id)
+	if err != nil {
+		s.logger.Error().Err(err).
+			Str("metric_id", req.Id).
+			Msg("failed to delete metric")
+		return &grpcmetrics.DeleteMetricResponse{}, err
+	}
+
+	return &grpcmetrics.DeleteMetricResponse{Deleted: deleted}, nil
+}
+
+func (s *MetricsServer) DeleteByPrefix(ctx context.Context, req *grpcmetrics.DeleteByPrefixRequest) (*grpcmetrics.DeleteByPrefixResponse, error) {
+	s.logger.Debug().
+		Str("prefix", req.Prefix).
+		Msg("received delete by prefix via gRPC")
+
+	if req.Prefix == "" {
+		return &grpcmetrics.DeleteByPrefixResponse{}, status.Error(codes.InvalidArgument, "prefix is required")
+	}
+
+	deleted, err := s.storage.DeleteByPrefix(ctx, req.Prefix)
+	if err != nil {
+		s.logger.Error().Err(err).
+			Str("prefix", req.Prefix).
+			Msg("failed to delete metrics by prefix")
+		return &grpcmetrics.DeleteByPrefixResponse{}, err
+	}
+
+	return &grpcmetrics.DeleteByPrefixResponse{Deleted: int64(deleted)}, nil
+}
+
+// updateError converts storage update errors to gRPC status errors with a matching code.
+func updateError(err error) error {
+	if errors.Is(err, domain.ErrCardinalityLimitExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// tenantInterceptor accounts the series created by a call to the agent of its agent ID metadata, like the
+// tenant middleware of the HTTP server.
+func tenantInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if tenant := firstValue(md, domain.HeaderAgentID); tenant != "" {
+			ctx = domain.WithTenant(ctx, tenant)
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,36 @@
+package agentinfo
+
+import (
+	"net/http"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Transport identifies the agent to the server by setting the agent ID and version headers.
+type Transport struct {
+	transport http.RoundTripper
+	id        string
+	version   string
+}
+
+func New(transport http.RoundTripper, id, version string) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Transport{
+		transport: transport,
+		id:        id,
+		version:   version,
+	}
+}
+
+func (rt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rt.id != "" {
+		req.Header.Set(domain.HeaderAgentID, rt.id)
+	}
+	if rt.version != "" {
+		req.Header.Set(domain.HeaderAgentVersion, rt.version)
+	}
+	return rt.transport.RoundTrip(req)
+}
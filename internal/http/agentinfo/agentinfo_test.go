@@ -0,0 +1,134 @@
+package agentinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type recordingRecorder struct {
+	agents []domain.AgentInfo
+}
+
+func (r *recordingRecorder) RecordHeartbeat(agent domain.AgentInfo) {
+	r.agents = append(r.agents, agent)
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: New(http.DefaultTransport, "agent-1", "v1.2.3")}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "agent-1", header.Get(domain.HeaderAgentID))
+	assert.Equal(t, "v1.2.3", header.Get(domain.HeaderAgentVersion))
+}
+
+func TestHeartbeatMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		headers  map[string]string
+		expected []domain.AgentInfo
+	}{
+		{
+			name:   "update with agent headers",
+			method: http.MethodPost,
+			path:   "/updates/",
+			status: http.StatusOK,
+			headers: map[string]string{
+				domain.HeaderAgentID:      "agent-1",
+				domain.HeaderAgentVersion: "v1",
+				"X-Real-IP":               "10.0.0.1",
+			},
+			expected: []domain.AgentInfo{{ID: "agent-1", Address: "10.0.0.1", Version: "v1"}},
+		},
+		{
+			name:     "update without headers uses client address",
+			method:   http.MethodPost,
+			path:     "/update/",
+			status:   http.StatusOK,
+			expected: []domain.AgentInfo{{Address: "192.0.2.1"}},
+		},
+		{
+			name:   "failed update",
+			method: http.MethodPost,
+			path:   "/updates/",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "read request",
+			method: http.MethodPost,
+			path:   "/value/",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &recordingRecorder{}
+			router := gin.New()
+			router.Use(NewHeartbeatMiddleware(recorder))
+			router.Handle(tt.method, tt.path, func(c *gin.Context) {
+				c.Status(tt.status)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, recorder.agents)
+		})
+	}
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{name: "agent ID header", headers: map[string]string{domain.HeaderAgentID: "agent-1"}, expected: "agent-1"},
+		{name: "no header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tenant string
+			router := gin.New()
+			router.Use(NewTenantMiddleware())
+			router.POST("/updates/", func(c *gin.Context) {
+				tenant = domain.TenantFromContext(c.Request.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.expected, tenant)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package agentinfo
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// NewHeartbeatMiddleware records a heartbeat for every successful metric update.
+// The agent is identified by the agent ID header, falling back to X-Real-IP and the client address.
+func NewHeartbeatMiddleware(recorder domain.HeartbeatRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Request.Method != http.MethodPost || !strings.HasPrefix(c.FullPath(), "/update") {
+			return
+		}
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		address := c.GetHeader("X-Real-IP")
+		if address == "" {
+			address = c.ClientIP()
+		}
+
+		recorder.RecordHeartbeat(domain.AgentInfo{
+			ID:      c.GetHeader(domain.HeaderAgentID),
+			Address: address,
+			Version: c.GetHeader(domain.HeaderAgentVersion),
+		})
+	}
+}
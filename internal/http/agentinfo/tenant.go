@@ -0,0 +1,18 @@
+package agentinfo
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// NewTenantMiddleware accounts the series created by a request to the agent of its agent ID header, so the
+// per-tenant series limits apply to it. Requests without the header are not accounted to any tenant.
+func NewTenantMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenant := c.GetHeader(domain.HeaderAgentID); tenant != "" {
+			c.Request = c.Request.WithContext(domain.WithTenant(c.Request.Context(), tenant))
+		}
+		c.Next()
+	}
+}
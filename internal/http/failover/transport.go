@@ -0,0 +1,58 @@
+package failover
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/resilience"
+)
+
+// Transport sends the requests to one of several servers. A request failing with a network error or a retryable
+// status moves on to the next server, so the retry transport wrapping it repeats the request against that one
+// if it may.
+type Transport struct {
+	transport http.RoundTripper
+	hosts     []string
+	current   atomic.Uint64
+	logger    zerolog.Logger
+}
+
+// New sends the requests to the hosts, host:port each, starting with the first one.
+func New(transport http.RoundTripper, hosts []string, logger zerolog.Logger) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Transport{
+		transport: transport,
+		hosts:     hosts,
+		logger:    logger,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.hosts) == 0 {
+		return t.transport.RoundTrip(req)
+	}
+
+	index := t.current.Load()
+	host := t.hosts[index%uint64(len(t.hosts))]
+
+	// The request must not be modified, the retry transport sends it again
+	req = req.Clone(req.Context())
+	req.URL.Host = host
+	req.Host = ""
+
+	resp, err := t.transport.RoundTrip(req)
+	failed := err != nil && resilience.ClassifyHTTPError(err) != resilience.Permanent ||
+		err == nil && resilience.RetryableStatus(resp.StatusCode)
+	// Concurrent requests failing on the same server move on only once
+	if failed && len(t.hosts) > 1 && t.current.CompareAndSwap(index, index+1) {
+		next := t.hosts[(index+1)%uint64(len(t.hosts))]
+		t.logger.Warn().Err(err).Str("host", host).Str("next", next).Msg("server unavailable, failing over")
+	}
+
+	return resp, err
+}
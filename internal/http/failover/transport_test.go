@@ -0,0 +1,88 @@
+package failover
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/http/retry"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
+)
+
+func serverHost(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	var hits []string
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, "healthy")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	outage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits = append(hits, "outage")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer outage.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	transport := New(nil, []string{serverHost(down), serverHost(outage), serverHost(healthy)}, zerolog.Nop())
+	client := &http.Client{Transport: transport}
+
+	request := func() (*http.Response, error) {
+		resp, err := client.Post("http://localhost/updates/", "application/json", strings.NewReader("[]"))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	_, err := request()
+	assert.Error(t, err, "the unreachable server fails the request")
+	resp, err := request()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp, err = request()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = request()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "a working server is kept")
+	assert.Equal(t, []string{"outage", "healthy", "healthy"}, hits)
+}
+
+func TestTransport_WithRetry(t *testing.T) {
+	var body string
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	client := &http.Client{
+		Transport: retry.New(
+			New(nil, []string{serverHost(down), serverHost(healthy)}, zerolog.Nop()),
+			resilience.Backoff{Retries: 1, Initial: time.Millisecond},
+			nil,
+			zerolog.Nop(),
+		),
+	}
+
+	resp, err := client.Post("http://localhost/updates/", "application/json", strings.NewReader(`[{"id":"PollCount"}]`))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the retry goes to the next server")
+	assert.Equal(t, `[{"id":"PollCount"}]`, body)
+}
@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type AgentsHandler struct {
+	registry domain.AgentRegistry
+}
+
+func NewAgentsHandler(registry domain.AgentRegistry) AgentsHandler {
+	return AgentsHandler{
+		registry: registry,
+	}
+}
+
+var _ router.AgentsHandler = (*AgentsHandler)(nil)
+
+// GetAgents returns the last-seen time, version and report rate of every known agent as JSON.
+func (handler AgentsHandler) GetAgents(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.registry.Agents())
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type stubAgentRegistry []domain.AgentStatus
+
+func (s stubAgentRegistry) Agents() []domain.AgentStatus {
+	return s
+}
+
+func TestAgentsHandler_GetAgents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	lastSeen := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry := stubAgentRegistry{
+		{ID: "agent-1", Version: "v1", FirstSeen: lastSeen.Add(-time.Hour), LastSeen: lastSeen, Reports: 360, ReportsPerMinute: 6},
+		{ID: "10.0.0.2", Address: "10.0.0.2", FirstSeen: lastSeen, LastSeen: lastSeen, Reports: 1, ReportsPerMinute: 1, Absent: true},
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/agents", NewAgentsHandler(registry).GetAgents)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/agents", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var agents []domain.AgentStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &agents))
+	assert.Equal(t, []domain.AgentStatus(registry), agents)
+}
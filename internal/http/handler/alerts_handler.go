@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+// maxHistoryLimit caps the number of history events returned by a single request.
+const maxHistoryLimit = 1000
+
+var historyEventTypes = []domain.HistoryEventType{
+	domain.HistoryAlertFiring,
+	domain.HistoryAlertResolved,
+	domain.HistoryAlertEscalated,
+	domain.HistoryAlertAcknowledged,
+	domain.HistoryNotificationSent,
+	domain.HistoryNotificationFailed,
+	domain.HistorySilenceCreated,
+	domain.HistorySilenceUpdated,
+	domain.HistorySilenceDeleted,
+}
+
+type AlertsHandler struct {
+	alerts  domain.AlertReader
+	history domain.HistoryReader
+}
+
+func NewAlertsHandler(alerts domain.AlertReader, history domain.HistoryReader) AlertsHandler {
+	return AlertsHandler{
+		alerts:  alerts,
+		history: history,
+	}
+}
+
+var _ router.AlertsHandler = (*AlertsHandler)(nil)
+
+// GetAlerts returns the currently firing alerts as JSON.
+func (handler AlertsHandler) GetAlerts(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.alerts.ActiveAlerts())
+}
+
+// GetHistory returns the alert history as JSON, newest first. The events can be filtered by the query parameters
+// "type" (comma-separated), "alertname", "label" (repeatable, as name=value), "silence_id", "actor",
+// "since" and "until" (RFC 3339), and limited by "limit" (default 100, at most 1000).
+func (handler AlertsHandler) GetHistory(c *gin.Context) {
+	filter, err := historyFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := handler.history.QueryHistory(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func historyFilter(c *gin.Context) (domain.HistoryFilter, error) {
+	filter := domain.HistoryFilter{
+		AlertName: c.Query("alertname"),
+		SilenceID: c.Query("silence_id"),
+		Actor:     c.Query("actor"),
+	}
+
+	if types := c.Query("type"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			eventType := domain.HistoryEventType(strings.TrimSpace(t))
+			if !slices.Contains(historyEventTypes, eventType) {
+				return domain.HistoryFilter{}, fmt.Errorf("unknown event type %q", t)
+			}
+			filter.Types = append(filter.Types, eventType)
+		}
+	}
+
+	for _, label := range c.QueryArray("label") {
+		name, value, ok := strings.Cut(label, "=")
+		if !ok || name == "" {
+			return domain.HistoryFilter{}, fmt.Errorf("label must be given as name=value, got %q", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[name] = value
+	}
+
+	for param, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return domain.HistoryFilter{}, fmt.Errorf("%s must be an RFC 3339 time such as 2025-01-01T00:00:00Z", param)
+		}
+		*target = parsed
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxHistoryLimit {
+			return domain.HistoryFilter{}, fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
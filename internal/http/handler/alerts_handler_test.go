@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// stubHistoryReader records the last filter it was queried with.
+type stubHistoryReader struct {
+	filter domain.HistoryFilter
+}
+
+func (s *stubHistoryReader) QueryHistory(_ context.Context, filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	s.filter = filter
+	return []domain.HistoryEvent{}, nil
+}
+
+type stubAlertReader []domain.Alert
+
+func (s stubAlertReader) ActiveAlerts() []domain.Alert {
+	return s
+}
+
+func TestAlertsHandler_GetHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reader := &stubHistoryReader{}
+	router := gin.New()
+	router.GET("/api/v1/alerts/history", NewAlertsHandler(stubAlertReader{}, reader).GetHistory)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFilter domain.HistoryFilter
+	}{
+		{name: "no filter", wantStatus: http.StatusOK},
+		{
+			name:       "all filters",
+			query:      "?type=alert_firing,silence_created&alertname=AgentAbsent&label=agent=a1&label=env=prod&silence_id=s1&actor=ops&since=2025-01-07T00:00:00Z&until=2025-01-08T00:00:00Z&limit=10",
+			wantStatus: http.StatusOK,
+			wantFilter: domain.HistoryFilter{
+				Types:     []domain.HistoryEventType{domain.HistoryAlertFiring, domain.HistorySilenceCreated},
+				AlertName: "AgentAbsent",
+				Labels:    map[string]string{"agent": "a1", "env": "prod"},
+				SilenceID: "s1",
+				Actor:     "ops",
+				Since:     time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+				Until:     time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC),
+				Limit:     10,
+			},
+		},
+		{name: "unknown type", query: "?type=fired", wantStatus: http.StatusBadRequest},
+		{name: "malformed label", query: "?label=agent", wantStatus: http.StatusBadRequest},
+		{name: "malformed time", query: "?since=tuesday", wantStatus: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=5000", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader.filter = domain.HistoryFilter{}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/alerts/history"+tt.query, nil))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantFilter, reader.filter)
+				assert.JSONEq(t, "[]", w.Body.String())
+			}
+		})
+	}
+}
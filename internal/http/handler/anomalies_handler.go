@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type AnomaliesHandler struct {
+	reporter domain.AnomalyReporter
+}
+
+func NewAnomaliesHandler(reporter domain.AnomalyReporter) AnomaliesHandler {
+	return AnomaliesHandler{
+		reporter: reporter,
+	}
+}
+
+var _ router.AnomaliesHandler = (*AnomaliesHandler)(nil)
+
+// GetAnomalies returns the gauges whose latest value is anomalous as JSON, the most deviating first.
+func (handler AnomaliesHandler) GetAnomalies(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.reporter.Anomalies())
+}
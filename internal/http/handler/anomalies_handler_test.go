@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type stubAnomalyReporter []domain.Anomaly
+
+func (s stubAnomalyReporter) Anomalies() []domain.Anomaly {
+	return s
+}
+
+func TestAnomaliesHandler_GetAnomalies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reporter := stubAnomalyReporter{
+		{MetricID: "HeapInuse", Value: 150, Mean: 100, StdDev: 5, ZScore: 10, DetectedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/anomalies", NewAnomaliesHandler(reporter).GetAnomalies)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/anomalies", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var anomalies []domain.Anomaly
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &anomalies))
+	assert.Equal(t, []domain.Anomaly(reporter), anomalies)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+const defaultTopPrefixes = 10
+
+type CardinalityHandler struct {
+	reporter domain.CardinalityReporter
+}
+
+func NewCardinalityHandler(reporter domain.CardinalityReporter) CardinalityHandler {
+	return CardinalityHandler{
+		reporter: reporter,
+	}
+}
+
+var _ router.CardinalityHandler = (*CardinalityHandler)(nil)
+
+// GetCardinality returns the number of tracked series, the configured limits and the top prefixes and tenants as JSON.
+// The optional "top" query parameter limits the number of prefixes and tenants, 0 returns all of them.
+func (handler CardinalityHandler) GetCardinality(c *gin.Context) {
+	top := defaultTopPrefixes
+	if raw := c.Query("top"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "top must be a non-negative integer"})
+			return
+		}
+		top = v
+	}
+
+	c.JSON(http.StatusOK, handler.reporter.CardinalityReport(top))
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type stubCardinalityReporter struct {
+	top int
+}
+
+func (s *stubCardinalityReporter) CardinalityReport(top int) domain.CardinalityReport {
+	s.top = top
+	return domain.CardinalityReport{
+		TotalSeries:   3,
+		MaxSeries:     10,
+		RejectedTotal: 2,
+		TopPrefixes:   []domain.PrefixCardinality{{Prefix: "CPUutilization", Series: 3, Rejected: 2}},
+	}
+}
+
+func TestCardinalityHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testCases := []struct {
+		name        string
+		path        string
+		response    int
+		expectedTop int
+	}{
+		{name: "default top", path: "/api/v1/cardinality", response: http.StatusOK, expectedTop: defaultTopPrefixes},
+		{name: "custom top", path: "/api/v1/cardinality?top=5", response: http.StatusOK, expectedTop: 5},
+		{name: "all prefixes", path: "/api/v1/cardinality?top=0", response: http.StatusOK, expectedTop: 0},
+		{name: "invalid top", path: "/api/v1/cardinality?top=abc", response: http.StatusBadRequest},
+		{name: "negative top", path: "/api/v1/cardinality?top=-1", response: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter := &stubCardinalityReporter{}
+			handler := NewCardinalityHandler(reporter)
+			router := gin.New()
+			router.GET("/api/v1/cardinality", handler.GetCardinality)
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.response, w.Code)
+			if tc.response != http.StatusOK {
+				return
+			}
+
+			var report domain.CardinalityReport
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+			assert.Equal(t, tc.expectedTop, reporter.top)
+			assert.Equal(t, 3, report.TotalSeries)
+			assert.Equal(t, int64(2), report.RejectedTotal)
+		})
+	}
+}
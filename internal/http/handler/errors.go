@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// updateErrorStatus maps an error returned by a storage update to an HTTP status code, using fallback for unknown errors.
+func updateErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, domain.ErrCardinalityLimitExceeded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrStorageUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return fallback
+	}
+}
+
+// readErrorStatus maps an error returned by a storage read to an HTTP status code.
+func readErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrStorageUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type EscalationsHandler struct {
+	escalations domain.EscalationManager
+}
+
+func NewEscalationsHandler(escalations domain.EscalationManager) EscalationsHandler {
+	return EscalationsHandler{
+		escalations: escalations,
+	}
+}
+
+var _ router.EscalationsHandler = (*EscalationsHandler)(nil)
+
+// GetEscalations returns the escalation state of the firing alerts with escalation steps as JSON.
+func (handler EscalationsHandler) GetEscalations(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.escalations.Escalations())
+}
+
+// Acknowledge stops the escalation of the firing alert identified by the alert name and labels in the JSON body.
+func (handler EscalationsHandler) Acknowledge(c *gin.Context) {
+	var ack domain.Acknowledgement
+	if err := c.ShouldBindJSON(&ack); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	escalation, err := handler.escalations.Acknowledge(c.Request.Context(), ack)
+	if err != nil {
+		c.JSON(escalationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, escalation)
+}
+
+func escalationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidAck):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrEscalationNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/alerting"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/history"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/silence"
+)
+
+func TestEscalationsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	recorder := history.NewRecorder(history.NewMemoryStorage(), &logger)
+	silencer, err := silence.New(ctx, silence.NewMemoryStorage(), recorder, &logger)
+	require.NoError(t, err)
+
+	routing := domain.RoutingConfig{
+		Receivers: []domain.Receiver{{Name: "ops"}, {Name: "lead"}},
+		Route: domain.Route{
+			Receiver:   "ops",
+			Escalation: []domain.EscalationStep{{After: domain.Duration(15 * time.Minute), Receiver: "lead"}},
+		},
+	}
+	templates, err := alerting.NewTemplates(nil, "", nil, nil)
+	require.NoError(t, err)
+	escalations, err := alerting.NewRouter(routing, alerting.NewReceiverNotifiers(routing.Receivers, templates, &logger), silencer, recorder, &logger)
+	require.NoError(t, err)
+
+	alert := domain.Alert{Name: "HighCPU", Labels: map[string]string{domain.MetricLabel: "CPUutilization1"}, State: domain.AlertStateFiring}
+	require.NoError(t, escalations.Notify(ctx, domain.Notification{Status: domain.AlertStateFiring, Alerts: []domain.Alert{alert}}))
+
+	h := NewEscalationsHandler(escalations)
+	router := gin.New()
+	router.GET("/api/v1/escalations", h.GetEscalations)
+	router.POST("/api/v1/alerts/acknowledge", h.Acknowledge)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/escalations", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []domain.Escalation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, "lead", list[0].NextStep.Receiver)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "missing actor", body: `{"alertname":"HighCPU","labels":{"metric":"CPUutilization1"}}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", body: `{"alertname":`, wantStatus: http.StatusBadRequest},
+		{name: "unknown alert", body: `{"alertname":"HighCPU","acknowledged_by":"alice"}`, wantStatus: http.StatusNotFound},
+		{name: "acknowledged", body: `{"alertname":"HighCPU","labels":{"metric":"CPUutilization1"},"acknowledged_by":"alice"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/alerts/acknowledge", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+		})
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/escalations", nil))
+	list = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, "alice", list[0].AcknowledgedBy)
+	assert.Nil(t, list[0].NextStep)
+}
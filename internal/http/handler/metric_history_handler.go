@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+// maxRangePoints caps the number of points returned by a single range query.
+const maxRangePoints = 10000
+
+type MetricHistoryHandler struct {
+	history domain.MetricRangeReader
+}
+
+func NewMetricHistoryHandler(history domain.MetricRangeReader) MetricHistoryHandler {
+	return MetricHistoryHandler{
+		history: history,
+	}
+}
+
+var _ router.MetricHistoryHandler = (*MetricHistoryHandler)(nil)
+
+// GetMetricHistory returns the values of a metric over time as JSON. The range is given by the query parameters
+// "from" and "to" (RFC 3339, the last hour by default). The resolution, "raw" or a rollup interval such as "1m"
+// or "1h", is picked to return at most "max_points" points (default 1000) unless given by "resolution".
+func (handler MetricHistoryHandler) GetMetricHistory(c *gin.Context) {
+	query, err := rangeQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := handler.history.QueryRange(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(rangeErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func rangeQuery(c *gin.Context) (domain.RangeQuery, error) {
+	metricType, err := domain.NewMetricType(c.Param("metricType"))
+	if err != nil {
+		return domain.RangeQuery{}, err
+	}
+
+	query := domain.RangeQuery{MType: metricType, ID: c.Param("metricName")}
+
+	for param, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return domain.RangeQuery{}, fmt.Errorf("%s must be an RFC 3339 time such as 2025-01-01T00:00:00Z", param)
+		}
+		*target = parsed
+	}
+
+	if value := c.Query("resolution"); value != "" {
+		resolution, err := domain.ParseResolution(value)
+		if err != nil {
+			return domain.RangeQuery{}, err
+		}
+		query.Resolution = &resolution
+	}
+
+	if value := c.Query("max_points"); value != "" {
+		maxPoints, err := strconv.Atoi(value)
+		if err != nil || maxPoints <= 0 || maxPoints > maxRangePoints {
+			return domain.RangeQuery{}, fmt.Errorf("max_points must be between 1 and %d", maxRangePoints)
+		}
+		query.MaxPoints = maxPoints
+	}
+
+	return query, nil
+}
+
+func rangeErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrInvalidRange) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
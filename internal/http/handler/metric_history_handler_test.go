@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// stubRangeReader records the last query it was asked and rejects empty ranges.
+type stubRangeReader struct {
+	query domain.RangeQuery
+}
+
+func (s *stubRangeReader) QueryRange(_ context.Context, query domain.RangeQuery) (domain.MetricRange, error) {
+	s.query = query
+	if !query.From.IsZero() && !query.From.Before(query.To) {
+		return domain.MetricRange{}, fmt.Errorf("%w: from must be before to", domain.ErrInvalidRange)
+	}
+	return domain.MetricRange{ID: query.ID, MType: query.MType, Resolution: domain.Resolution(time.Minute), Points: []domain.Rollup{}}, nil
+}
+
+func TestMetricHistoryHandler_GetMetricHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	reader := &stubRangeReader{}
+	router := gin.New()
+	router.GET("/api/v1/metrics/:metricType/:metricName/history", NewMetricHistoryHandler(reader).GetMetricHistory)
+
+	hour := domain.Resolution(time.Hour)
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantQuery  domain.RangeQuery
+	}{
+		{
+			name:       "defaults",
+			path:       "/api/v1/metrics/gauge/Alloc/history",
+			wantStatus: http.StatusOK,
+			wantQuery:  domain.RangeQuery{MType: domain.MetricTypeGauge, ID: "Alloc"},
+		},
+		{
+			name:       "all parameters",
+			path:       "/api/v1/metrics/counter/PollCount/history?from=2025-01-07T00:00:00Z&to=2025-01-08T00:00:00Z&resolution=1h&max_points=50",
+			wantStatus: http.StatusOK,
+			wantQuery: domain.RangeQuery{
+				MType:      domain.MetricTypeCounter,
+				ID:         "PollCount",
+				From:       time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+				To:         time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC),
+				Resolution: &hour,
+				MaxPoints:  50,
+			},
+		},
+		{name: "unknown type", path: "/api/v1/metrics/histogram/Alloc/history", wantStatus: http.StatusBadRequest},
+		{name: "malformed time", path: "/api/v1/metrics/gauge/Alloc/history?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "malformed resolution", path: "/api/v1/metrics/gauge/Alloc/history?resolution=minutely", wantStatus: http.StatusBadRequest},
+		{name: "too many points", path: "/api/v1/metrics/gauge/Alloc/history?max_points=20000", wantStatus: http.StatusBadRequest},
+		{
+			name:       "empty range",
+			path:       "/api/v1/metrics/gauge/Alloc/history?from=2025-01-08T00:00:00Z&to=2025-01-07T00:00:00Z",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader.query = domain.RangeQuery{}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantQuery, reader.query)
+				assert.JSONEq(t, fmt.Sprintf(`{"id": %q, "type": %q, "resolution": "1m", "from": "0001-01-01T00:00:00Z", "to": "0001-01-01T00:00:00Z", "points": []}`,
+					tt.wantQuery.ID, tt.wantQuery.MType), w.Body.String())
+			}
+		})
+	}
+}
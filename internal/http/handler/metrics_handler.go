@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -31,7 +32,11 @@ func (handler MetricsHandler) GetMetric(c *gin.Context) {
 		return
 	}
 
-	metrics, ok := handler.storage.GetMetric(c.Request.Context(), metricType, c.Param("metricName"))
+	metrics, ok, err := handler.storage.GetMetric(c.Request.Context(), metricType, c.Param("metricName"))
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	if !ok {
 		c.Status(http.StatusNotFound)
 		return
@@ -40,9 +45,14 @@ func (handler MetricsHandler) GetMetric(c *gin.Context) {
 	c.String(http.StatusOK, metrics.StringValue())
 }
 
-// GetAllMetrics retrieves all stored metrics and returns them as an HTML response. Shows a "No data" message if no metrics exist.
+// GetAllMetrics retrieves all stored metrics and returns them as an HTML response. Shows a "No data" message if no metrics exist,
+// responds with StatusServiceUnavailable if the storage can not be read.
 func (handler MetricsHandler) GetAllMetrics(c *gin.Context) {
-	allMetrics := handler.storage.GetAllMetrics(c.Request.Context())
+	allMetrics, err := handler.storage.GetAllMetrics(c.Request.Context())
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	if len(allMetrics) == 0 {
 		c.Data(http.StatusOK, "text/html", []byte("<h3>No data</h3>"))
 		return
@@ -59,16 +69,58 @@ func (handler MetricsHandler) GetAllMetrics(c *gin.Context) {
 // UpdateMetrics processes an update request for a specific metric and responds with an appropriate HTTP status code.
 func (handler MetricsHandler) UpdateMetrics(c *gin.Context) {
 	if err := handler.update(c.Request.Context(), c.Param("metricType"), c.Param("metricName"), c.Param("metricValue")); err != nil {
+		c.JSON(updateErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// DeleteMetric removes the specified metric from storage. Responds with StatusNotFound if the metric does not exist.
+func (handler MetricsHandler) DeleteMetric(c *gin.Context) {
+	metricType, err := domain.NewMetricType(c.Param("metricType"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	found, err := handler.storage.DeleteMetric(c.Request.Context(), metricType, c.Param("metricName"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !found {
+		c.Status(http.StatusNotFound)
+		return
 	}
+
 	c.Status(http.StatusOK)
 }
 
+// DeleteMetricsByPrefix removes all metrics whose name starts with the required "prefix" query parameter
+// and returns the number of removed metrics as JSON.
+func (handler MetricsHandler) DeleteMetricsByPrefix(c *gin.Context) {
+	prefix := c.Query("prefix")
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		return
+	}
+
+	deleted, err := handler.storage.DeleteByPrefix(c.Request.Context(), prefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
 func (handler MetricsHandler) update(ctx context.Context, rawMetricType string, metricName string, metricValue string) error {
 	metrics, err := domain.NewMetrics(rawMetricType, metricName, metricValue)
 	if err != nil {
 		return err
 	}
+	metrics.UpdatedAt = time.Now()
 
 	return handler.storage.UpdateMetric(ctx, *metrics)
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -34,7 +35,7 @@ func TestMetricsHandler(t *testing.T) {
 						ID:    "test_counter",
 						MType: "counter",
 						Delta: func() *int64 { v := int64(42); return &v }(),
-					}, true)
+					}, true, nil)
 			},
 		},
 		{
@@ -48,7 +49,7 @@ func TestMetricsHandler(t *testing.T) {
 						ID:    "test_gauge",
 						MType: "gauge",
 						Value: func() *float64 { v := 3.14; return &v }(),
-					}, true)
+					}, true, nil)
 			},
 		},
 		{
@@ -58,7 +59,17 @@ func TestMetricsHandler(t *testing.T) {
 			response: http.StatusNotFound,
 			setupMock: func(m *MockMetricStorage) {
 				m.On("GetMetric", mock.AnythingOfType("context.backgroundCtx"), mock.AnythingOfType("domain.MetricType"), "unknown").
-					Return(domain.Metric{}, false)
+					Return(domain.Metric{}, false, nil)
+			},
+		},
+		{
+			name:     "GetMetric returns StatusServiceUnavailable when storage is down",
+			method:   http.MethodGet,
+			path:     "/value/counter/test_counter",
+			response: http.StatusServiceUnavailable,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetMetric", mock.AnythingOfType("context.backgroundCtx"), mock.AnythingOfType("domain.MetricType"), "test_counter").
+					Return(domain.Metric{}, false, fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable))
 			},
 		},
 		{
@@ -126,7 +137,7 @@ func TestMetricsHandler(t *testing.T) {
 			response: http.StatusOK,
 			setupMock: func(m *MockMetricStorage) {
 				m.On("GetAllMetrics", mock.AnythingOfType("context.backgroundCtx")).
-					Return([]domain.Metric{})
+					Return([]domain.Metric{}, nil)
 			},
 		},
 		{
@@ -142,9 +153,73 @@ func TestMetricsHandler(t *testing.T) {
 							MType: "counter",
 							Delta: func() *int64 { v := int64(42); return &v }(),
 						},
-					})
+					}, nil)
 			},
 		},
+		{
+			name:     "GetAllMetrics returns StatusServiceUnavailable when storage is down",
+			method:   http.MethodGet,
+			path:     "/",
+			response: http.StatusServiceUnavailable,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetAllMetrics", mock.AnythingOfType("context.backgroundCtx")).
+					Return([]domain.Metric(nil), fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable))
+			},
+		},
+		{
+			name:     "DeleteMetric returns StatusOK for existing metric",
+			method:   http.MethodDelete,
+			path:     "/value/gauge/test_gauge",
+			response: http.StatusOK,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("DeleteMetric", mock.AnythingOfType("context.backgroundCtx"), domain.MetricTypeGauge, "test_gauge").
+					Return(true, nil)
+			},
+		},
+		{
+			name:     "DeleteMetric returns StatusNotFound for non-existing metric",
+			method:   http.MethodDelete,
+			path:     "/value/counter/unknown",
+			response: http.StatusNotFound,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("DeleteMetric", mock.AnythingOfType("context.backgroundCtx"), domain.MetricTypeCounter, "unknown").
+					Return(false, nil)
+			},
+		},
+		{
+			name:      "DeleteMetric returns StatusBadRequest for invalid metric type",
+			method:    http.MethodDelete,
+			path:      "/value/invalid/test",
+			response:  http.StatusBadRequest,
+			setupMock: func(m *MockMetricStorage) {},
+		},
+		{
+			name:     "DeleteMetric returns StatusInternalServerError for storage error",
+			method:   http.MethodDelete,
+			path:     "/value/gauge/test_gauge",
+			response: http.StatusInternalServerError,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("DeleteMetric", mock.AnythingOfType("context.backgroundCtx"), domain.MetricTypeGauge, "test_gauge").
+					Return(false, errors.New("storage error"))
+			},
+		},
+		{
+			name:     "DeleteMetricsByPrefix returns StatusOK",
+			method:   http.MethodDelete,
+			path:     "/api/v1/metrics?prefix=CPUutilization",
+			response: http.StatusOK,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("DeleteByPrefix", mock.AnythingOfType("context.backgroundCtx"), "CPUutilization").
+					Return(64, nil)
+			},
+		},
+		{
+			name:      "DeleteMetricsByPrefix returns StatusBadRequest without prefix",
+			method:    http.MethodDelete,
+			path:      "/api/v1/metrics",
+			response:  http.StatusBadRequest,
+			setupMock: func(m *MockMetricStorage) {},
+		},
 		{
 			name:      "Returns StatusNotFound for wrong method on update",
 			method:    http.MethodGet,
@@ -181,6 +256,8 @@ func TestMetricsHandler(t *testing.T) {
 			router.GET("/", handler.GetAllMetrics)
 			router.GET("/value/:metricType/:metricName", handler.GetMetric)
 			router.POST("/update/:metricType/:metricName/:metricValue", handler.UpdateMetrics)
+			router.DELETE("/value/:metricType/:metricName", handler.DeleteMetric)
+			router.DELETE("/api/v1/metrics", handler.DeleteMetricsByPrefix)
 
 			req, _ := http.NewRequest(tc.method, tc.path, nil)
 			w := httptest.NewRecorder()
@@ -210,6 +287,8 @@ func TestMetricsHandler(t *testing.T) {
 			GetMetric(*gin.Context)
 			GetAllMetrics(*gin.Context)
 			UpdateMetrics(*gin.Context)
+			DeleteMetric(*gin.Context)
+			DeleteMetricsByPrefix(*gin.Context)
 		})(nil), handler)
 	})
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -36,7 +37,11 @@ func (handler MetricsJSONHandler) FetchMetricsJSON(c *gin.Context) {
 		return
 	}
 
-	res, ok := handler.storage.GetMetric(c.Request.Context(), metrics.MType, metrics.ID)
+	res, ok, err := handler.storage.GetMetric(c.Request.Context(), metrics.MType, metrics.ID)
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 	if ok {
 		c.JSON(http.StatusOK, res)
 		return
@@ -57,10 +62,12 @@ func (handler MetricsJSONHandler) UpdateMetricsJSON(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	metric.UpdatedAt = time.Now()
 
 	metric, err := handler.updateMetrics(c.Request.Context(), metric)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(updateErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, metric)
@@ -78,10 +85,12 @@ func (handler MetricsJSONHandler) BatchUpdateFetchMetrics(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	domain.MarkReceived(metrics, time.Now())
 
 	err := handler.storage.UpdateMetrics(c.Request.Context(), metrics)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(updateErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
 	}
 
 	c.JSON(http.StatusOK, metrics)
@@ -101,7 +110,10 @@ func (handler MetricsJSONHandler) updateMetrics(ctx context.Context, metrics dom
 		return domain.Metric{}, err
 	}
 
-	res, ok := handler.storage.GetMetric(ctx, metrics.MType, metrics.ID)
+	res, ok, err := handler.storage.GetMetric(ctx, metrics.MType, metrics.ID)
+	if err != nil {
+		return domain.Metric{}, err
+	}
 	if !ok {
 		return domain.Metric{}, errors.New("failed to get updated metrics")
 	}
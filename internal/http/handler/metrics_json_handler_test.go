@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -40,7 +41,7 @@ func TestMetricsJSONHandler(t *testing.T) {
 						ID:    "test_counter",
 						MType: "counter",
 						Delta: func() *int64 { v := int64(42); return &v }(),
-					}, true)
+					}, true, nil)
 			},
 		},
 		{
@@ -52,7 +53,19 @@ func TestMetricsJSONHandler(t *testing.T) {
 			response:    http.StatusNotFound,
 			setupMock: func(m *MockMetricStorage) {
 				m.On("GetMetric", mock.AnythingOfType("context.backgroundCtx"), domain.MetricType("counter"), "unknown").
-					Return(domain.Metric{}, false)
+					Return(domain.Metric{}, false, nil)
+			},
+		},
+		{
+			name:        "FetchMetricsJSON returns StatusServiceUnavailable when storage is down",
+			method:      http.MethodPost,
+			path:        "/value/",
+			body:        domain.Metric{ID: "test_counter", MType: "counter"},
+			contentType: "application/json",
+			response:    http.StatusServiceUnavailable,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetMetric", mock.AnythingOfType("context.backgroundCtx"), domain.MetricType("counter"), "test_counter").
+					Return(domain.Metric{}, false, fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable))
 			},
 		},
 		{
@@ -88,7 +101,7 @@ func TestMetricsJSONHandler(t *testing.T) {
 						ID:    "test_counter",
 						MType: "counter",
 						Delta: func() *int64 { v := int64(42); return &v }(),
-					}, true)
+					}, true, nil)
 			},
 		},
 		{
@@ -166,6 +179,18 @@ func TestMetricsJSONHandler(t *testing.T) {
 					Return(errors.New("storage error"))
 			},
 		},
+		{
+			name:        "BatchUpdateFetchMetrics returns StatusUnprocessableEntity for series limit",
+			method:      http.MethodPost,
+			path:        "/updates/",
+			body:        []domain.Metric{{ID: "test", MType: "counter"}},
+			contentType: "application/json",
+			response:    http.StatusUnprocessableEntity,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("UpdateMetrics", mock.Anything, mock.AnythingOfType("[]domain.Metric")).
+					Return(fmt.Errorf("series test (counter) rejected: %w", domain.ErrCardinalityLimitExceeded))
+			},
+		},
 		{
 			name:        "Returns StatusNotFound for wrong method",
 			method:      http.MethodGet,
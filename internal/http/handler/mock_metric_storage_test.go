@@ -18,9 +18,9 @@ func (m *MockMetricStorage) Ping(ctx context.Context) error {
 	return args.Error(0)
 }
 
-func (m *MockMetricStorage) GetAllMetrics(ctx context.Context) []domain.Metric {
+func (m *MockMetricStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]domain.Metric)
+	return args.Get(0).([]domain.Metric), args.Error(1)
 }
 
 func (m *MockMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
@@ -33,7 +33,17 @@ func (m *MockMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.
 	return args.Error(0)
 }
 
-func (m *MockMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
+func (m *MockMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
 	args := m.Called(ctx, metricType, metricName)
-	return args.Get(0).(domain.Metric), args.Bool(1)
+	return args.Get(0).(domain.Metric), args.Bool(1), args.Error(2)
+}
+
+func (m *MockMetricStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	args := m.Called(ctx, metricType, metricName)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMetricStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	args := m.Called(ctx, prefix)
+	return args.Int(0), args.Error(1)
 }
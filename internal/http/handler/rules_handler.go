@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type RulesHandler struct {
+	rules domain.RuleManager
+}
+
+func NewRulesHandler(rules domain.RuleManager) RulesHandler {
+	return RulesHandler{
+		rules: rules,
+	}
+}
+
+var _ router.RulesHandler = (*RulesHandler)(nil)
+
+// ListRules returns all alert rules as JSON.
+func (handler RulesHandler) ListRules(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.rules.ListRules(c.Request.Context()))
+}
+
+func (handler RulesHandler) GetRule(c *gin.Context) {
+	rule, ok := handler.rules.GetRule(c.Request.Context(), c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateRule creates a rule from the JSON body, its expression has to match existing metrics.
+func (handler RulesHandler) CreateRule(c *gin.Context) {
+	var rule domain.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := handler.rules.CreateRule(c.Request.Context(), rule)
+	if err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateRule replaces the expression, duration, labels and annotations of an existing rule.
+func (handler RulesHandler) UpdateRule(c *gin.Context) {
+	var rule domain.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rule.Name = c.Param("name")
+
+	updated, err := handler.rules.UpdateRule(c.Request.Context(), rule)
+	if err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (handler RulesHandler) DeleteRule(c *gin.Context) {
+	found, err := handler.rules.DeleteRule(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestRule evaluates the rule from the JSON body against the current metrics without saving it.
+func (handler RulesHandler) TestRule(c *gin.Context) {
+	var rule domain.Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := handler.rules.TestRule(c.Request.Context(), rule)
+	if err != nil {
+		c.JSON(ruleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func ruleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidRule):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrRuleExists):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrStorageUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
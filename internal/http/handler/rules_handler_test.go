@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metrichistory"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/rules"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Fire(context.Context, domain.Alert)    {}
+func (nopPublisher) Resolve(context.Context, domain.Alert) {}
+
+func TestRulesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	metrics := metricstorage.NewMemoryMetricStorage()
+	value := 95.0
+	require.NoError(t, metrics.UpdateMetric(ctx, domain.Metric{ID: "CPUutilization1", MType: domain.MetricTypeGauge, Value: &value}))
+
+	engine, err := rules.New(ctx, rules.NewMemoryStorage(), metrics, metrichistory.New(metrics, time.Hour), nopPublisher{}, &logger)
+	require.NoError(t, err)
+
+	h := NewRulesHandler(engine)
+	router := gin.New()
+	router.GET("/api/v1/rules", h.ListRules)
+	router.POST("/api/v1/rules", h.CreateRule)
+	router.POST("/api/v1/rules/test", h.TestRule)
+	router.GET("/api/v1/rules/:name", h.GetRule)
+	router.PUT("/api/v1/rules/:name", h.UpdateRule)
+	router.DELETE("/api/v1/rules/:name", h.DeleteRule)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	body := `{"name":"HighCPU","expr":"CPUutilization* > 90","for":"5m","labels":{"severity":"critical"}}`
+
+	w := do(http.MethodPost, "/api/v1/rules/test", body)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result domain.RuleTestResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result.Matches, 1)
+
+	w = do(http.MethodPost, "/api/v1/rules", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created domain.Rule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "HighCPU", created.Name)
+	assert.Contains(t, w.Body.String(), `"for":"5m0s"`)
+
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/v1/rules", body).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/rules", `{"name":"Unknown","expr":"Missing > 1"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/rules", `{"name":"BadFor","expr":"CPUutilization1 > 1","for":"soon"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/rules/test", `{"name":"Broken","expr":"CPUutilization1 >"}`).Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/rules/HighCPU", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/rules/missing", "").Code)
+
+	updated := `{"expr":"CPUutilization* > 99"}`
+	w = do(http.MethodPut, "/api/v1/rules/HighCPU", updated)
+	require.Equal(t, http.StatusOK, w.Code)
+	var rule domain.Rule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rule))
+	assert.Equal(t, "CPUutilization* > 99", rule.Expr)
+	assert.Equal(t, created.CreatedAt, rule.CreatedAt)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/api/v1/rules/missing", updated).Code)
+
+	w = do(http.MethodGet, "/api/v1/rules", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []domain.Rule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list, 1)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/v1/rules/HighCPU", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/rules/HighCPU", "").Code)
+}
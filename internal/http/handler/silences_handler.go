@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type SilencesHandler struct {
+	silences domain.SilenceManager
+}
+
+func NewSilencesHandler(silences domain.SilenceManager) SilencesHandler {
+	return SilencesHandler{
+		silences: silences,
+	}
+}
+
+var _ router.SilencesHandler = (*SilencesHandler)(nil)
+
+// ListSilences returns all silences with their current state as JSON.
+func (handler SilencesHandler) ListSilences(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.silences.ListSilences(c.Request.Context()))
+}
+
+func (handler SilencesHandler) GetSilence(c *gin.Context) {
+	silence, ok := handler.silences.GetSilence(c.Request.Context(), c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "silence not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, silence)
+}
+
+// CreateSilence creates a silence from the JSON body and returns it with the assigned ID.
+func (handler SilencesHandler) CreateSilence(c *gin.Context) {
+	var silence domain.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := handler.silences.CreateSilence(c.Request.Context(), silence)
+	if err != nil {
+		c.JSON(silenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdateSilence replaces the matchers, time range and comment of an existing silence.
+func (handler SilencesHandler) UpdateSilence(c *gin.Context) {
+	var silence domain.Silence
+	if err := c.ShouldBindJSON(&silence); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	silence.ID = c.Param("id")
+
+	updated, err := handler.silences.UpdateSilence(c.Request.Context(), silence)
+	if err != nil {
+		c.JSON(silenceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (handler SilencesHandler) DeleteSilence(c *gin.Context) {
+	found, err := handler.silences.DeleteSilence(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "silence not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func silenceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSilence):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrSilenceNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/history"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/silence"
+)
+
+func TestSilencesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := zerolog.Nop()
+
+	silencer, err := silence.New(context.Background(), silence.NewMemoryStorage(), history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+
+	h := NewSilencesHandler(silencer)
+	router := gin.New()
+	router.GET("/api/v1/silences", h.ListSilences)
+	router.POST("/api/v1/silences", h.CreateSilence)
+	router.GET("/api/v1/silences/:id", h.GetSilence)
+	router.PUT("/api/v1/silences/:id", h.UpdateSilence)
+	router.DELETE("/api/v1/silences/:id", h.DeleteSilence)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	endsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"matchers":[{"name":"alertname","operator":"=","value":"AgentAbsent"}],"ends_at":"` + endsAt + `","created_by":"ops","comment":"deploy"}`
+
+	w := do(http.MethodPost, "/api/v1/silences", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created domain.Silence
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, domain.SilenceStateActive, created.State)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/silences", `{"created_by":"ops"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/silences", `{`).Code)
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/silences/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/silences/missing", "").Code)
+
+	updated := strings.Replace(body, "deploy", "longer deploy", 1)
+	w = do(http.MethodPut, "/api/v1/silences/"+created.ID, updated)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "longer deploy")
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/api/v1/silences/missing", updated).Code)
+
+	w = do(http.MethodGet, "/api/v1/silences", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var silences []domain.Silence
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &silences))
+	assert.Len(t, silences, 1)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/v1/silences/"+created.ID, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/silences/"+created.ID, "").Code)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type StaleMetricsHandler struct {
+	storage domain.MetricStorage
+}
+
+func NewStaleMetricsHandler(storage domain.MetricStorage) StaleMetricsHandler {
+	return StaleMetricsHandler{
+		storage: storage,
+	}
+}
+
+var _ router.StaleMetricsHandler = (*StaleMetricsHandler)(nil)
+
+// GetStaleMetrics returns metrics not updated within the duration given by the required "older_than" query parameter
+// (e.g. "5m" or "1h30m") as JSON, the least recently updated first.
+func (handler StaleMetricsHandler) GetStaleMetrics(c *gin.Context) {
+	olderThan, err := time.ParseDuration(c.Query("older_than"))
+	if err != nil || olderThan < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be a non-negative duration such as 5m or 1h"})
+		return
+	}
+
+	metrics, err := handler.storage.GetAllMetrics(c.Request.Context())
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	stale := make([]domain.Metric, 0)
+	for _, metric := range metrics {
+		if metric.IsStale(now, olderThan) {
+			stale = append(stale, metric)
+		}
+	}
+
+	slices.SortFunc(stale, func(a, b domain.Metric) int {
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+
+	c.JSON(http.StatusOK, stale)
+}
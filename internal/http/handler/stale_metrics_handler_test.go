@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestStaleMetricsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	now := time.Now()
+	metrics := []domain.Metric{
+		{ID: "fresh", MType: "gauge", Value: func() *float64 { v := 1.0; return &v }(), UpdatedAt: now},
+		{ID: "day_old", MType: "gauge", Value: func() *float64 { v := 2.0; return &v }(), UpdatedAt: now.Add(-24 * time.Hour)},
+		{ID: "week_old", MType: "counter", Delta: func() *int64 { v := int64(3); return &v }(), UpdatedAt: now.Add(-7 * 24 * time.Hour)},
+	}
+
+	testCases := []struct {
+		name        string
+		path        string
+		response    int
+		setupMock   func(*MockMetricStorage)
+		expectedIDs []string
+	}{
+		{
+			name:     "returns metrics older than duration",
+			path:     "/api/v1/stale?older_than=1h",
+			response: http.StatusOK,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetAllMetrics", mock.AnythingOfType("context.backgroundCtx")).Return(metrics, nil)
+			},
+			expectedIDs: []string{"week_old", "day_old"},
+		},
+		{
+			name:     "returns empty list when nothing is stale",
+			path:     "/api/v1/stale?older_than=720h",
+			response: http.StatusOK,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetAllMetrics", mock.AnythingOfType("context.backgroundCtx")).Return(metrics, nil)
+			},
+			expectedIDs: []string{},
+		},
+		{
+			name:     "returns StatusServiceUnavailable when storage is down",
+			path:     "/api/v1/stale?older_than=1h",
+			response: http.StatusServiceUnavailable,
+			setupMock: func(m *MockMetricStorage) {
+				m.On("GetAllMetrics", mock.AnythingOfType("context.backgroundCtx")).
+					Return([]domain.Metric(nil), fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable))
+			},
+		},
+		{
+			name:      "returns StatusBadRequest without duration",
+			path:      "/api/v1/stale",
+			response:  http.StatusBadRequest,
+			setupMock: func(m *MockMetricStorage) {},
+		},
+		{
+			name:      "returns StatusBadRequest for invalid duration",
+			path:      "/api/v1/stale?older_than=week",
+			response:  http.StatusBadRequest,
+			setupMock: func(m *MockMetricStorage) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage := &MockMetricStorage{}
+			tc.setupMock(mockStorage)
+
+			handler := NewStaleMetricsHandler(mockStorage)
+			router := gin.New()
+			router.GET("/api/v1/stale", handler.GetStaleMetrics)
+
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.response, w.Code)
+			mockStorage.AssertExpectations(t)
+			if tc.response != http.StatusOK {
+				return
+			}
+
+			var result []domain.Metric
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			ids := make([]string, 0, len(result))
+			for _, metric := range result {
+				ids = append(ids, metric.ID)
+				assert.False(t, metric.UpdatedAt.IsZero())
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/router"
+)
+
+type TemplatesHandler struct {
+	templates domain.NotificationTemplates
+}
+
+func NewTemplatesHandler(templates domain.NotificationTemplates) TemplatesHandler {
+	return TemplatesHandler{
+		templates: templates,
+	}
+}
+
+var _ router.TemplatesHandler = (*TemplatesHandler)(nil)
+
+type previewRequest struct {
+	Receiver string         `json:"receiver" binding:"required"`
+	Template string         `json:"template"`
+	Alerts   []domain.Alert `json:"alerts"`
+}
+
+// GetTemplates returns the notification template of each receiver as JSON.
+func (handler TemplatesHandler) GetTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, handler.templates.Templates())
+}
+
+// PreviewTemplate renders the template from the JSON body, or the configured template of the receiver,
+// for the given alerts or a sample alert.
+func (handler TemplatesHandler) PreviewTemplate(c *gin.Context) {
+	var req previewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	text, err := handler.templates.Preview(c.Request.Context(), req.Receiver, req.Template, req.Alerts)
+	if err != nil {
+		c.JSON(templateErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"receiver": req.Receiver, "text": text})
+}
+
+func templateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTemplate):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrUnknownReceiver):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
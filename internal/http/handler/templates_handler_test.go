@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/alerting"
+)
+
+func TestTemplatesHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	templates, err := alerting.NewTemplates(nil, "http://metrics.local", nil, nil)
+	require.NoError(t, err)
+
+	h := NewTemplatesHandler(templates)
+	router := gin.New()
+	router.GET("/api/v1/templates", h.GetTemplates)
+	router.POST("/api/v1/templates/preview", h.PreviewTemplate)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/templates", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var sources map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sources))
+	assert.Equal(t, alerting.DefaultTemplates, sources)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantText   string
+	}{
+		{
+			name:       "custom template with alerts",
+			body:       `{"receiver":"log","template":"{{range .Alerts}}{{.Name}} {{.State}}{{end}}","alerts":[{"name":"AgentAbsent","state":"firing"}]}`,
+			wantStatus: http.StatusOK,
+			wantText:   "AgentAbsent firing",
+		},
+		{
+			name:       "configured template with sample alert",
+			body:       `{"receiver":"alertmanager"}`,
+			wantStatus: http.StatusOK,
+			wantText:   "HighCPU is firing: CPUutilization1 = 95.5, rule CPUutilization* > 90 for 5m0s",
+		},
+		{name: "invalid template", body: `{"receiver":"log","template":"{{.Missing}}"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown receiver", body: `{"receiver":"pager"}`, wantStatus: http.StatusNotFound},
+		{name: "missing receiver", body: `{}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/templates/preview", strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var resp struct {
+					Text string `json:"text"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tt.wantText, resp.Text)
+			}
+		})
+	}
+}
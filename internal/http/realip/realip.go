@@ -15,7 +15,7 @@ func New(transport http.RoundTripper) *Transport {
 		transport = http.DefaultTransport
 	}
 
-	realIP := getLocalIP()
+	realIP := LocalIP()
 
 	return &Transport{
 		transport: transport,
@@ -30,7 +30,8 @@ func (rt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return rt.transport.RoundTrip(req)
 }
 
-func getLocalIP() string {
+// LocalIP returns the first non-loopback IPv4 address of the host, or an empty string.
+func LocalIP() string {
 	addrs, err := net.InterfaceAddrs()
 	if err != nil {
 		return ""
@@ -39,8 +39,8 @@ func TestTransport_RoundTrip(t *testing.T) {
 	}
 }
 
-func TestGetLocalIP(t *testing.T) {
-	ip := getLocalIP()
+func TestLocalIP(t *testing.T) {
+	ip := LocalIP()
 	if ip == "" {
 		t.Log("Warning: Could not get local IP address")
 	} else {
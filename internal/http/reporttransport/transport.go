@@ -0,0 +1,68 @@
+package reporttransport
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/crypto"
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/http/agentinfo"
+	cryptohttp "github.com/angryscorp/alert-metrics/internal/http/crypto"
+	"github.com/angryscorp/alert-metrics/internal/http/failover"
+	"github.com/angryscorp/alert-metrics/internal/http/gzipper"
+	"github.com/angryscorp/alert-metrics/internal/http/hash"
+	"github.com/angryscorp/alert-metrics/internal/http/realip"
+	"github.com/angryscorp/alert-metrics/internal/http/retry"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
+)
+
+// Options configure the requests reporting metrics to a server.
+type Options struct {
+	// Addresses are the servers the requests fail over between.
+	Addresses []string
+	// Agent identifies the reporter to the server.
+	Agent domain.AgentInfo
+	// CryptoKeyPath is the public key of the server the bodies are encrypted with, none if empty.
+	CryptoKeyPath string
+	// HashKey signs the bodies, none if empty.
+	HashKey string
+	Backoff resilience.Backoff
+	Breaker *resilience.Breaker
+}
+
+// New returns the transport of the agent reporting to the server, also used by servers forwarding to an upstream one.
+func New(options Options, logger zerolog.Logger) (http.RoundTripper, error) {
+	// Base transport
+	transport := http.DefaultTransport
+
+	// Failover transport
+	transport = failover.New(transport, options.Addresses, logger)
+
+	// Real IP transport
+	transport = realip.New(transport)
+
+	// Agent ID transport
+	transport = agentinfo.New(transport, options.Agent.ID, options.Agent.Version)
+
+	// Gzip transport
+	transport = gzipper.NewGzipTransport(transport)
+
+	// Crypto transport
+	if options.CryptoKeyPath != "" {
+		encryptor, err := crypto.NewPublicKeyEncrypter(options.CryptoKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encrypter: %w", err)
+		}
+		transport = cryptohttp.EncryptorMiddleware(encryptor)(transport)
+	}
+
+	// Hash transport
+	transport = hash.NewHashTransport(transport, options.HashKey)
+
+	// Retry transport
+	transport = retry.New(transport, options.Backoff, options.Breaker, logger)
+
+	return transport, nil
+}
@@ -4,39 +4,48 @@ import (
 	"bytes"
 	"io"
 	"net/http"
-	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
+// Transport retries requests failing with network errors or retryable statuses, with backoff and behind
+// a circuit breaker. Requests that are not idempotent, such as counter updates, are only retried after network
+// errors if they were not sent at all. The response of the last attempt is returned, also if its status is still
+// an error.
 type Transport struct {
-	transport      http.RoundTripper
-	retryIntervals []time.Duration
-	logger         zerolog.Logger
+	transport http.RoundTripper
+	retrier   *resilience.Retrier
+	// idempotent retries also requests that may have reached the server before the connection was lost
+	idempotent *resilience.Retrier
+	logger     zerolog.Logger
 }
 
 func New(
 	transport http.RoundTripper,
-	retryIntervals []time.Duration,
+	backoff resilience.Backoff,
+	breaker *resilience.Breaker,
 	logger zerolog.Logger,
 ) *Transport {
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
 
-	return &Transport{
-		transport:      transport,
-		retryIntervals: append([]time.Duration{0}, retryIntervals...),
-		logger:         logger,
+	rt := &Transport{
+		transport: transport,
+		logger:    logger,
 	}
+	rt.retrier = resilience.NewRetrier(backoff, breaker, resilience.ClassifyHTTPError, &rt.logger)
+	rt.idempotent = resilience.NewRetrier(backoff, breaker, resilience.Idempotent(resilience.ClassifyHTTPError), &rt.logger)
+
+	return rt
 }
 
 func (rt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	var resp *http.Response
-	var err error
-
 	var reqBody []byte
 	if req.Body != nil {
+		var err error
 		reqBody, err = io.ReadAll(req.Body)
 		if err != nil {
 			return nil, err
@@ -44,23 +53,50 @@ func (rt *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		_ = req.Body.Close()
 	}
 
-	for n, interval := range rt.retryIntervals {
-		if reqBody != nil {
-			req.Body = io.NopCloser(bytes.NewBuffer(reqBody))
+	retrier := rt.retrier
+	if idempotent(req) {
+		retrier = rt.idempotent
+	}
+
+	var resp *http.Response
+	err := retrier.Do(req.Context(), func() error {
+		if resp != nil {
+			// The previous attempt got a retryable status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			resp = nil
 		}
 
-		if n > 0 {
-			rt.logger.Warn().Msgf("retrying request %d in %f", n, interval.Seconds())
-			time.Sleep(interval)
+		if reqBody != nil {
+			req.Body = io.NopCloser(bytes.NewReader(reqBody))
 		}
 
+		var err error
 		resp, err = rt.transport.RoundTrip(req)
-		if err == nil {
-			return resp, nil
+		if err != nil {
+			resp = nil
+			return err
+		}
+
+		if resilience.RetryableStatus(resp.StatusCode) {
+			return resilience.NewStatusError(resp)
 		}
+		return nil
+	})
 
-		rt.logger.Error().Err(err).Msgf("failed to send request %s", err)
+	if resp != nil {
+		return resp, nil
 	}
 
+	rt.logger.Error().Err(err).Msg("failed to send request")
 	return nil, err
 }
+
+// idempotent tells whether the request can be applied twice, by its method or an Idempotency-Key header.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
@@ -1,43 +1,53 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
 func TestNew(t *testing.T) {
 	t.Run("with custom transport", func(t *testing.T) {
 		transport := &mockTransport{}
-		retryIntervals := []time.Duration{time.Second}
 
-		result := New(transport, retryIntervals, zerolog.Nop())
+		result := New(transport, testBackoff, nil, zerolog.Nop())
 
 		require.NotNil(t, result)
 		assert.Equal(t, transport, result.transport)
-		assert.Equal(t, []time.Duration{0, time.Second}, result.retryIntervals)
 	})
 }
 
+var testBackoff = resilience.Backoff{Retries: 1, Initial: time.Millisecond}
+
+func response(statusCode int) *http.Response {
+	return &http.Response{StatusCode: statusCode, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("ok"))}
+}
+
 func TestTransport_RoundTrip(t *testing.T) {
 	tests := []struct {
-		name          string
-		mockResponses []mockResponse
-		expectedCalls int
-		expectError   bool
+		name           string
+		mockResponses  []mockResponse
+		expectedCalls  int
+		expectedStatus int
+		expectError    bool
 	}{
 		{
 			name: "success on first try",
 			mockResponses: []mockResponse{
-				{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, err: nil},
+				{resp: response(http.StatusOK), err: nil},
 			},
 			expectedCalls: 1,
 			expectError:   false,
@@ -45,17 +55,60 @@ func TestTransport_RoundTrip(t *testing.T) {
 		{
 			name: "success after retry",
 			mockResponses: []mockResponse{
-				{resp: nil, err: errors.New("network error")},
-				{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader("ok"))}, err: nil},
+				{resp: nil, err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}},
+				{resp: response(http.StatusOK), err: nil},
 			},
 			expectedCalls: 2,
 			expectError:   false,
 		},
+		{
+			name: "success after retryable status",
+			mockResponses: []mockResponse{
+				{resp: response(http.StatusServiceUnavailable)},
+				{resp: response(http.StatusOK)},
+			},
+			expectedCalls:  2,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "too many requests is retried",
+			mockResponses: []mockResponse{
+				{resp: response(http.StatusTooManyRequests)},
+				{resp: response(http.StatusOK)},
+			},
+			expectedCalls:  2,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "client error is not retried",
+			mockResponses: []mockResponse{
+				{resp: response(http.StatusBadRequest)},
+			},
+			expectedCalls:  1,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "last retryable status is returned",
+			mockResponses: []mockResponse{
+				{resp: response(http.StatusBadGateway)},
+				{resp: response(http.StatusServiceUnavailable)},
+			},
+			expectedCalls:  2,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "request error is not retried",
+			mockResponses: []mockResponse{
+				{err: errors.New("failed to encrypt body")},
+			},
+			expectedCalls: 1,
+			expectError:   true,
+		},
 		{
 			name: "all retries fail",
 			mockResponses: []mockResponse{
-				{resp: nil, err: errors.New("error 1")},
-				{resp: nil, err: errors.New("error 2")},
+				{resp: nil, err: io.ErrUnexpectedEOF},
+				{resp: nil, err: syscall.ECONNRESET},
 			},
 			expectedCalls: 2,
 			expectError:   true,
@@ -65,7 +118,7 @@ func TestTransport_RoundTrip(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockTransport := &mockTransport{responses: tt.mockResponses}
-			transport := New(mockTransport, []time.Duration{time.Millisecond}, zerolog.Nop())
+			transport := New(mockTransport, testBackoff, nil, zerolog.Nop())
 
 			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
 			resp, err := transport.RoundTrip(req)
@@ -80,14 +133,104 @@ func TestTransport_RoundTrip(t *testing.T) {
 				assert.Nil(t, resp)
 			} else {
 				require.NoError(t, err)
-				assert.NotNil(t, resp)
+				require.NotNil(t, resp)
+				if tt.expectedStatus != 0 {
+					assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+				}
+			}
+
+			assert.Equal(t, tt.expectedCalls, mockTransport.callCount)
+		})
+	}
+}
+
+func TestTransport_RoundTripReplaysBody(t *testing.T) {
+	var bodies []string
+	mockTransport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			return response(http.StatusInternalServerError), nil
+		}
+		return response(http.StatusOK), nil
+	})
+	transport := New(mockTransport, testBackoff, nil, zerolog.Nop())
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("payload"))
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestTransport_RoundTripNonIdempotent(t *testing.T) {
+	lost := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+	refused := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name          string
+		method        string
+		header        http.Header
+		err           error
+		expectedCalls int
+	}{
+		{name: "lost response of a post", method: http.MethodPost, err: lost, expectedCalls: 1},
+		{name: "refused post", method: http.MethodPost, err: refused, expectedCalls: 2},
+		{name: "lost response of a post with idempotency key", method: http.MethodPost, header: http.Header{"Idempotency-Key": {"k1"}}, err: lost, expectedCalls: 2},
+		{name: "lost response of a get", method: http.MethodGet, err: lost, expectedCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTransport := &mockTransport{responses: []mockResponse{{err: tt.err}, {resp: response(http.StatusOK)}}}
+			transport := New(mockTransport, testBackoff, nil, zerolog.Nop())
+
+			req := httptest.NewRequest(tt.method, "http://example.com/updates/", strings.NewReader("payload"))
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			resp, err := transport.RoundTrip(req)
+			if resp != nil {
+				_ = resp.Body.Close()
 			}
 
+			assert.Equal(t, tt.expectedCalls == 1, err != nil)
 			assert.Equal(t, tt.expectedCalls, mockTransport.callCount)
 		})
 	}
 }
 
+func TestTransport_RoundTripStopsOnCancel(t *testing.T) {
+	mockTransport := &mockTransport{responses: []mockResponse{{err: syscall.ECONNREFUSED}, {resp: response(http.StatusOK)}}}
+	transport := New(mockTransport, resilience.Backoff{Retries: 1, Initial: time.Hour}, nil, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(ctx)
+
+	_, err := transport.RoundTrip(req)
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Equal(t, 1, mockTransport.callCount, "the retry is not waited for")
+}
+
+func TestTransport_RoundTripCircuitBreaker(t *testing.T) {
+	mockTransport := &mockTransport{responses: []mockResponse{{err: syscall.ECONNREFUSED}, {err: syscall.ECONNREFUSED}}}
+	transport := New(mockTransport, testBackoff, resilience.NewBreaker(2, time.Hour), zerolog.Nop())
+
+	_, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+	require.Error(t, err)
+
+	_, err = transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+	assert.ErrorIs(t, err, resilience.ErrCircuitOpen)
+	assert.Equal(t, 2, mockTransport.callCount, "no request is sent while the breaker is open")
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 type mockResponse struct {
 	resp *http.Response
 	err  error
@@ -10,6 +10,8 @@ type MetricsHandler interface {
 	GetMetric(c *gin.Context)
 	GetAllMetrics(c *gin.Context)
 	UpdateMetrics(c *gin.Context)
+	DeleteMetric(c *gin.Context)
+	DeleteMetricsByPrefix(c *gin.Context)
 }
 
 type MetricsJSONHandler interface {
@@ -17,3 +19,55 @@ type MetricsJSONHandler interface {
 	UpdateMetricsJSON(c *gin.Context)
 	BatchUpdateFetchMetrics(c *gin.Context)
 }
+
+type MetricHistoryHandler interface {
+	GetMetricHistory(c *gin.Context)
+}
+
+type CardinalityHandler interface {
+	GetCardinality(c *gin.Context)
+}
+
+type StaleMetricsHandler interface {
+	GetStaleMetrics(c *gin.Context)
+}
+
+type AgentsHandler interface {
+	GetAgents(c *gin.Context)
+}
+
+type AlertsHandler interface {
+	GetAlerts(c *gin.Context)
+	GetHistory(c *gin.Context)
+}
+
+type AnomaliesHandler interface {
+	GetAnomalies(c *gin.Context)
+}
+
+type SilencesHandler interface {
+	ListSilences(c *gin.Context)
+	GetSilence(c *gin.Context)
+	CreateSilence(c *gin.Context)
+	UpdateSilence(c *gin.Context)
+	DeleteSilence(c *gin.Context)
+}
+
+type RulesHandler interface {
+	ListRules(c *gin.Context)
+	GetRule(c *gin.Context)
+	CreateRule(c *gin.Context)
+	UpdateRule(c *gin.Context)
+	DeleteRule(c *gin.Context)
+	TestRule(c *gin.Context)
+}
+
+type TemplatesHandler interface {
+	GetTemplates(c *gin.Context)
+	PreviewTemplate(c *gin.Context)
+}
+
+type EscalationsHandler interface {
+	GetEscalations(c *gin.Context)
+	Acknowledge(c *gin.Context)
+}
@@ -69,6 +69,8 @@ func (mr *MetricRouter) RegisterMetricsHandler(handler MetricsHandler) {
 	mr.engine.GET("/value/:metricType/:metricName", handler.GetMetric)
 	mr.engine.GET("/", handler.GetAllMetrics)
 	mr.engine.POST("/update/:metricType/:metricName/:metricValue", handler.UpdateMetrics)
+	mr.engine.DELETE("/value/:metricType/:metricName", handler.DeleteMetric)
+	mr.engine.DELETE("/api/v1/metrics", handler.DeleteMetricsByPrefix)
 }
 
 func (mr *MetricRouter) RegisterMetricsJSONHandler(handler MetricsJSONHandler) {
@@ -77,6 +79,58 @@ func (mr *MetricRouter) RegisterMetricsJSONHandler(handler MetricsJSONHandler) {
 	mr.engine.POST("/updates/", handler.BatchUpdateFetchMetrics)
 }
 
+func (mr *MetricRouter) RegisterMetricHistoryHandler(handler MetricHistoryHandler) {
+	mr.engine.GET("/api/v1/metrics/:metricType/:metricName/history", handler.GetMetricHistory)
+}
+
+func (mr *MetricRouter) RegisterCardinalityHandler(handler CardinalityHandler) {
+	mr.engine.GET("/api/v1/cardinality", handler.GetCardinality)
+}
+
+func (mr *MetricRouter) RegisterStaleMetricsHandler(handler StaleMetricsHandler) {
+	mr.engine.GET("/api/v1/stale", handler.GetStaleMetrics)
+}
+
+func (mr *MetricRouter) RegisterAgentsHandler(handler AgentsHandler) {
+	mr.engine.GET("/api/v1/agents", handler.GetAgents)
+}
+
+func (mr *MetricRouter) RegisterAlertsHandler(handler AlertsHandler) {
+	mr.engine.GET("/api/v1/alerts", handler.GetAlerts)
+	mr.engine.GET("/api/v1/alerts/history", handler.GetHistory)
+}
+
+func (mr *MetricRouter) RegisterAnomaliesHandler(handler AnomaliesHandler) {
+	mr.engine.GET("/api/v1/anomalies", handler.GetAnomalies)
+}
+
+func (mr *MetricRouter) RegisterSilencesHandler(handler SilencesHandler) {
+	mr.engine.GET("/api/v1/silences", handler.ListSilences)
+	mr.engine.POST("/api/v1/silences", handler.CreateSilence)
+	mr.engine.GET("/api/v1/silences/:id", handler.GetSilence)
+	mr.engine.PUT("/api/v1/silences/:id", handler.UpdateSilence)
+	mr.engine.DELETE("/api/v1/silences/:id", handler.DeleteSilence)
+}
+
+func (mr *MetricRouter) RegisterRulesHandler(handler RulesHandler) {
+	mr.engine.GET("/api/v1/rules", handler.ListRules)
+	mr.engine.POST("/api/v1/rules", handler.CreateRule)
+	mr.engine.POST("/api/v1/rules/test", handler.TestRule)
+	mr.engine.GET("/api/v1/rules/:name", handler.GetRule)
+	mr.engine.PUT("/api/v1/rules/:name", handler.UpdateRule)
+	mr.engine.DELETE("/api/v1/rules/:name", handler.DeleteRule)
+}
+
+func (mr *MetricRouter) RegisterTemplatesHandler(handler TemplatesHandler) {
+	mr.engine.GET("/api/v1/templates", handler.GetTemplates)
+	mr.engine.POST("/api/v1/templates/preview", handler.PreviewTemplate)
+}
+
+func (mr *MetricRouter) RegisterEscalationsHandler(handler EscalationsHandler) {
+	mr.engine.GET("/api/v1/escalations", handler.GetEscalations)
+	mr.engine.POST("/api/v1/alerts/acknowledge", handler.Acknowledge)
+}
+
 func (mr *MetricRouter) registerNoRoutes() {
 	mr.engine.NoRoute(func(c *gin.Context) {
 		c.Status(http.StatusNotFound)
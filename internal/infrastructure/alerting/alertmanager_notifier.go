@@ -0,0 +1,193 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// alertmanagerResendInterval is how often firing alerts are sent to Alertmanager again.
+// Alertmanager resolves alerts that are not refreshed before their endsAt.
+const alertmanagerResendInterval = time.Minute
+
+// alertmanagerTimeout limits a single request to Alertmanager.
+const alertmanagerTimeout = 10 * time.Second
+
+// postableAlert is an alert in the format of the Alertmanager /api/v2/alerts endpoint.
+type postableAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt,omitzero"`
+	EndsAt       time.Time         `json:"endsAt,omitzero"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// AlertmanagerNotifier pushes alerts to an Alertmanager instance, which takes over grouping and routing.
+// The alertmanager receiver template is rendered for each alert into its description annotation.
+// Firing alerts are sent with an end time a few resend intervals ahead and refreshed by Run,
+// so Alertmanager resolves them on its own if this server stops.
+type AlertmanagerNotifier struct {
+	url       string
+	templates *Templates
+	client    *http.Client
+	logger    *zerolog.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+	// known holds the fingerprints of the firing alerts Alertmanager was sent
+	known map[string]struct{}
+}
+
+var _ domain.AlertNotifier = (*AlertmanagerNotifier)(nil)
+
+// NewAlertmanagerNotifier creates a notifier posting to the Alertmanager at baseURL. Generator URLs
+// of metric alerts point at the page of their metric.
+func NewAlertmanagerNotifier(baseURL string, templates *Templates, logger *zerolog.Logger) *AlertmanagerNotifier {
+	return &AlertmanagerNotifier{
+		url:       strings.TrimRight(baseURL, "/") + "/api/v2/alerts",
+		templates: templates,
+		client:    &http.Client{Timeout: alertmanagerTimeout},
+		logger:    logger,
+		now:       time.Now,
+		known:     make(map[string]struct{}),
+	}
+}
+
+func (n *AlertmanagerNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	return n.post(ctx, notification.Alerts)
+}
+
+// Run periodically sends the firing alerts again until shutdownCh is closed. In a cluster only the leader sends
+// them, leadership is nil for a server outside a cluster.
+func (n *AlertmanagerNotifier) Run(alerts domain.AlertReader, leadership domain.Leadership, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(alertmanagerResendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			if !isLeader(leadership) {
+				continue
+			}
+
+			if err := n.resend(context.Background(), alerts.ActiveAlerts()); err != nil {
+				n.logger.Error().Err(err).Msg("failed to resend alerts to Alertmanager")
+			}
+		}
+	}
+}
+
+// resend sends the active alerts again. A silenced alert is resent only if Alertmanager knows it already, so it
+// is not resolved there while the silence lasts, and an alert silenced before it was sent stays unknown.
+func (n *AlertmanagerNotifier) resend(ctx context.Context, active []domain.Alert) error {
+	n.mu.Lock()
+	firing := make([]domain.Alert, 0, len(active))
+	fingerprints := make(map[string]struct{}, len(active))
+	for _, alert := range active {
+		fingerprint := alert.Fingerprint()
+		fingerprints[fingerprint] = struct{}{}
+		if _, ok := n.known[fingerprint]; ok || len(alert.SilencedBy) == 0 {
+			firing = append(firing, alert)
+		}
+	}
+	for fingerprint := range n.known {
+		if _, ok := fingerprints[fingerprint]; !ok {
+			delete(n.known, fingerprint)
+		}
+	}
+	n.mu.Unlock()
+
+	return n.post(ctx, firing)
+}
+
+func (n *AlertmanagerNotifier) post(ctx context.Context, alerts []domain.Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	renderer := n.templates.Renderer(ctx, alerts)
+	body := make([]postableAlert, len(alerts))
+	for i, alert := range alerts {
+		body[i] = n.toPostable(renderer, alert)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alerts: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build Alertmanager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alerts to Alertmanager: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("alertmanager responded with %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, alert := range alerts {
+		if alert.State == domain.AlertStateFiring {
+			n.known[alert.Fingerprint()] = struct{}{}
+		} else {
+			delete(n.known, alert.Fingerprint())
+		}
+	}
+
+	return nil
+}
+
+func (n *AlertmanagerNotifier) toPostable(renderer *Renderer, alert domain.Alert) postableAlert {
+	labels := maps.Clone(alert.Labels)
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[domain.AlertNameLabel] = alert.Name
+
+	annotations := alert.Annotations
+	description, err := renderer.Render(ReceiverAlertmanager, domain.Notification{Status: alert.State, Alerts: []domain.Alert{alert}})
+	if err != nil {
+		n.logger.Error().Err(err).Str("alert", alert.Fingerprint()).Msg("failed to render Alertmanager template")
+	}
+	if description != "" {
+		annotations = maps.Clone(annotations)
+		if annotations == nil {
+			annotations = make(map[string]string, 1)
+		}
+		annotations["description"] = description
+	}
+
+	endsAt := alert.EndsAt
+	if alert.State == domain.AlertStateFiring {
+		endsAt = n.now().Add(4 * alertmanagerResendInterval)
+	}
+
+	return postableAlert{
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     alert.StartsAt,
+		EndsAt:       endsAt,
+		GeneratorURL: renderer.Link(alert),
+	}
+}
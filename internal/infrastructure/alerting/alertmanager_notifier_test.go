@@ -0,0 +1,125 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func TestAlertmanagerNotifier_Notify(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	var received []postableAlert
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stub.Close()
+
+	metrics := metricstorage.NewMemoryMetricStorage()
+	value := 95.0
+	require.NoError(t, metrics.UpdateMetric(ctx, domain.Metric{ID: "CPU utilization", MType: domain.MetricTypeGauge, Value: &value}))
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	templates, err := NewTemplates(nil, "http://metrics.local:8080/", metrics, nil)
+	require.NoError(t, err)
+	notifier := NewAlertmanagerNotifier(stub.URL+"/", templates, &logger)
+	notifier.now = func() time.Time { return now }
+
+	err = notifier.Notify(ctx, domain.Notification{Alerts: []domain.Alert{
+		{
+			Name:        "HighCPU",
+			Labels:      map[string]string{domain.MetricLabel: "CPU utilization"},
+			Annotations: map[string]string{"value": "95"},
+			State:       domain.AlertStateFiring,
+			StartsAt:    now.Add(-time.Minute),
+		},
+		{
+			Name:     "AgentAbsent",
+			Labels:   map[string]string{"agent": "a1"},
+			State:    domain.AlertStateResolved,
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now,
+		},
+	}})
+	require.NoError(t, err)
+	require.Len(t, received, 2)
+
+	firing := received[0]
+	assert.Equal(t, map[string]string{"alertname": "HighCPU", "metric": "CPU utilization"}, firing.Labels)
+	assert.Equal(t, "95", firing.Annotations["value"])
+	assert.Equal(t, "HighCPU is firing: CPU utilization = 95", firing.Annotations["description"])
+	assert.True(t, firing.StartsAt.Equal(now.Add(-time.Minute)))
+	assert.True(t, firing.EndsAt.After(now), "firing alerts expire unless they are resent")
+	assert.Equal(t, "http://metrics.local:8080/value/gauge/CPU%20utilization", firing.GeneratorURL)
+
+	resolved := received[1]
+	assert.Equal(t, "AgentAbsent", resolved.Labels["alertname"])
+	assert.True(t, resolved.EndsAt.Equal(now))
+	assert.Equal(t, "http://metrics.local:8080/api/v1/alerts", resolved.GeneratorURL)
+}
+
+func TestAlertmanagerNotifier_Error(t *testing.T) {
+	logger := zerolog.Nop()
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "bad alerts", http.StatusBadRequest)
+	}))
+	defer stub.Close()
+
+	templates, err := NewTemplates(nil, "", metricstorage.NewMemoryMetricStorage(), nil)
+	require.NoError(t, err)
+	notifier := NewAlertmanagerNotifier(stub.URL, templates, &logger)
+
+	err = notifier.Notify(context.Background(), domain.Notification{Alerts: []domain.Alert{{Name: "AgentAbsent", State: domain.AlertStateFiring}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad alerts")
+
+	assert.NoError(t, notifier.Notify(context.Background(), domain.Notification{}), "empty notifications are not sent")
+}
+
+func TestAlertmanagerNotifier_ResendSilenced(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	var received []postableAlert
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stub.Close()
+
+	templates, err := NewTemplates(nil, "", metricstorage.NewMemoryMetricStorage(), nil)
+	require.NoError(t, err)
+	notifier := NewAlertmanagerNotifier(stub.URL, templates, &logger)
+
+	sent := domain.Alert{Name: "HighCPU", Labels: map[string]string{"agent": "a1"}, State: domain.AlertStateFiring}
+	require.NoError(t, notifier.Notify(ctx, domain.Notification{Alerts: []domain.Alert{sent}}))
+
+	// Both alerts are silenced later, only the one Alertmanager knows is kept from expiring there
+	sent.SilencedBy = []string{"s1"}
+	unsent := domain.Alert{Name: "HighCPU", Labels: map[string]string{"agent": "a2"}, State: domain.AlertStateFiring, SilencedBy: []string{"s1"}}
+	received = nil
+	require.NoError(t, notifier.resend(ctx, []domain.Alert{sent, unsent}))
+	require.Len(t, received, 1)
+	assert.Equal(t, "a1", received[0].Labels["agent"])
+
+	// Alerts that are no longer active are forgotten
+	require.NoError(t, notifier.resend(ctx, nil))
+	received = nil
+	require.NoError(t, notifier.resend(ctx, []domain.Alert{sent}))
+	assert.Empty(t, received)
+}
@@ -0,0 +1,47 @@
+package alerting
+
+import (
+	"sync"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// deliveries remembers the targets a failed notification was delivered to, by group, so its retry only goes to
+// the targets that failed. A retry is a notification of the group with the same alerts in the same states.
+type deliveries struct {
+	mu     sync.Mutex
+	groups map[string]delivery
+}
+
+type delivery struct {
+	content   string
+	delivered map[string]bool
+}
+
+func newDeliveries() *deliveries {
+	return &deliveries{groups: make(map[string]delivery)}
+}
+
+// delivered returns the targets an earlier attempt delivered the notification to.
+func (d *deliveries) delivered(notification domain.Notification) map[string]bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	previous, ok := d.groups[notification.GroupKey]
+	if !ok || previous.content != notificationContent(notification.Alerts) {
+		return make(map[string]bool)
+	}
+	return previous.delivered
+}
+
+// record keeps the targets the notification was delivered to until the notification reached all its targets.
+func (d *deliveries) record(notification domain.Notification, delivered map[string]bool, complete bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if complete {
+		delete(d.groups, notification.GroupKey)
+		return
+	}
+	d.groups[notification.GroupKey] = delivery{content: notificationContent(notification.Alerts), delivered: delivered}
+}
@@ -0,0 +1,301 @@
+package alerting
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// GroupByAll puts every alert into its own group, so notifications are only deduplicated.
+const GroupByAll = "..."
+
+// flushCheckInterval is how often groups are checked for being due.
+const flushCheckInterval = time.Second
+
+// GroupingOptions configures how the Dispatcher batches alerts into notifications.
+type GroupingOptions struct {
+	// GroupBy lists the labels alerts are grouped by, "alertname" refers to the alert name.
+	// No labels put all alerts into a single group.
+	GroupBy []string
+	// GroupWait is how long a new group collects alerts before its first notification.
+	GroupWait time.Duration
+	// GroupInterval is the minimum time between notifications of a changed group.
+	GroupInterval time.Duration
+	// RepeatInterval is how long an unchanged group waits before the same notification is sent again.
+	RepeatInterval time.Duration
+}
+
+type groupedAlert struct {
+	alert    domain.Alert
+	notified bool
+}
+
+type alertGroup struct {
+	key        string
+	labels     map[string]string
+	alerts     map[string]*groupedAlert
+	createdAt  time.Time
+	flushedAt  time.Time
+	lastSent   string
+	lastSentAt time.Time
+}
+
+// Dispatcher groups alert state changes and sends one notification per group.
+// Silenced alerts are left out of notifications, and a group notification identical
+// to the previous one is only repeated after the repeat interval.
+// In a cluster every server raises the same alerts, but only the leader sends the notifications. The other servers
+// group the alerts as if they had sent them, so they continue where the leader stopped when they take over.
+type Dispatcher struct {
+	notifier   domain.AlertNotifier
+	silencer   domain.AlertSilencer
+	history    domain.HistoryRecorder
+	leadership domain.Leadership
+	opts       GroupingOptions
+	logger     *zerolog.Logger
+	now        func() time.Time
+	mu         sync.Mutex
+	groups     map[string]*alertGroup
+}
+
+// NewDispatcher creates a dispatcher, leadership is nil for a server outside a cluster.
+func NewDispatcher(
+	notifier domain.AlertNotifier,
+	silencer domain.AlertSilencer,
+	history domain.HistoryRecorder,
+	leadership domain.Leadership,
+	opts GroupingOptions,
+	logger *zerolog.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		notifier:   notifier,
+		silencer:   silencer,
+		history:    history,
+		leadership: leadership,
+		opts:       opts,
+		logger:     logger,
+		now:        time.Now,
+		groups:     make(map[string]*alertGroup),
+	}
+}
+
+// Dispatch adds the latest state of the alert to its group, the notification is sent when the group is due.
+func (d *Dispatcher) Dispatch(alert domain.Alert) {
+	labels := d.groupLabels(alert)
+	key := groupKey(labels)
+	fingerprint := alert.Fingerprint()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	group, ok := d.groups[key]
+	if !ok {
+		group = &alertGroup{
+			key:       key,
+			labels:    labels,
+			alerts:    make(map[string]*groupedAlert),
+			createdAt: d.now(),
+		}
+		d.groups[key] = group
+	}
+
+	existing, ok := group.alerts[fingerprint]
+	if !ok {
+		group.alerts[fingerprint] = &groupedAlert{alert: alert}
+		return
+	}
+
+	// A re-fired alert has to be notified again, resolving keeps the notified flag to announce the resolution
+	if existing.alert.State == domain.AlertStateResolved && alert.State == domain.AlertStateFiring {
+		existing.notified = false
+	}
+	existing.alert = alert
+}
+
+// Run periodically flushes due groups until shutdownCh is closed.
+func (d *Dispatcher) Run(shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(flushCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			d.Flush(context.Background())
+		}
+	}
+}
+
+// Flush sends the notifications of all groups that are due.
+func (d *Dispatcher) Flush(ctx context.Context) {
+	now := d.now()
+
+	d.mu.Lock()
+	var due []*alertGroup
+	for _, group := range d.groups {
+		if d.isDue(group, now) {
+			due = append(due, group)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, group := range due {
+		d.flushGroup(ctx, group, now)
+	}
+}
+
+func (d *Dispatcher) isDue(group *alertGroup, now time.Time) bool {
+	if group.flushedAt.IsZero() {
+		return !now.Before(group.createdAt.Add(d.opts.GroupWait))
+	}
+	return !now.Before(group.flushedAt.Add(d.opts.GroupInterval))
+}
+
+func (d *Dispatcher) flushGroup(ctx context.Context, group *alertGroup, now time.Time) {
+	d.mu.Lock()
+	group.flushedAt = now
+
+	notification := domain.Notification{
+		GroupKey:    group.key,
+		GroupLabels: group.labels,
+		Status:      domain.AlertStateResolved,
+	}
+	sent := make(map[string]domain.Alert)
+	for fingerprint, grouped := range group.alerts {
+		alert := grouped.alert
+		switch {
+		case alert.State == domain.AlertStateResolved && !grouped.notified:
+			// Nobody was told about the alert, so there is nothing to resolve
+			delete(group.alerts, fingerprint)
+			continue
+		case alert.State == domain.AlertStateFiring && len(d.silencer.SilencedBy(alert)) > 0:
+			continue
+		case alert.State == domain.AlertStateFiring:
+			notification.Status = domain.AlertStateFiring
+		}
+
+		sent[fingerprint] = alert
+		notification.Alerts = append(notification.Alerts, alert)
+	}
+
+	content := notificationContent(notification.Alerts)
+	repeat := content == group.lastSent && now.Before(group.lastSentAt.Add(d.opts.RepeatInterval))
+	if len(notification.Alerts) == 0 || repeat {
+		d.removeIfEmpty(group)
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+
+	slices.SortFunc(notification.Alerts, func(a, b domain.Alert) int {
+		if c := a.StartsAt.Compare(b.StartsAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Fingerprint(), b.Fingerprint())
+	})
+
+	if isLeader(d.leadership) {
+		err := d.notifier.Notify(ctx, notification)
+		d.record(ctx, notification, now, err)
+		if err != nil {
+			// The group is retried on the next interval
+			d.logger.Error().Err(err).Str("group", group.key).Msg("failed to send alert notification")
+			return
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	group.lastSent = content
+	group.lastSentAt = now
+	for fingerprint, alert := range sent {
+		grouped, ok := group.alerts[fingerprint]
+		if !ok || grouped.alert.State != alert.State {
+			continue
+		}
+		grouped.notified = true
+		if alert.State == domain.AlertStateResolved {
+			delete(group.alerts, fingerprint)
+		}
+	}
+	d.removeIfEmpty(group)
+}
+
+// record adds a notification attempt to the history, one event per alert.
+func (d *Dispatcher) record(ctx context.Context, notification domain.Notification, at time.Time, err error) {
+	event := domain.HistoryEvent{
+		Time:     at,
+		Type:     domain.HistoryNotificationSent,
+		GroupKey: notification.GroupKey,
+		Message:  "notified " + string(notification.Status),
+	}
+	if err != nil {
+		event.Type = domain.HistoryNotificationFailed
+		event.Message = err.Error()
+	}
+
+	for _, alert := range notification.Alerts {
+		event.AlertName = alert.Name
+		event.Labels = alert.Labels
+		d.history.Record(ctx, event)
+	}
+}
+
+func (d *Dispatcher) removeIfEmpty(group *alertGroup) {
+	if len(group.alerts) == 0 && d.groups[group.key] == group {
+		delete(d.groups, group.key)
+	}
+}
+
+func (d *Dispatcher) groupLabels(alert domain.Alert) map[string]string {
+	if slices.Contains(d.opts.GroupBy, GroupByAll) {
+		labels := maps.Clone(alert.Labels)
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[domain.AlertNameLabel] = alert.Name
+		return labels
+	}
+
+	labels := make(map[string]string, len(d.opts.GroupBy))
+	for _, name := range d.opts.GroupBy {
+		if name == domain.AlertNameLabel {
+			labels[name] = alert.Name
+			continue
+		}
+		labels[name] = alert.Labels[name]
+	}
+	return labels
+}
+
+// isLeader reports whether the server sends notifications, a server outside a cluster always does.
+func isLeader(leadership domain.Leadership) bool {
+	return leadership == nil || leadership.IsLeader()
+}
+
+func groupKey(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		parts = append(parts, name+"="+labels[name])
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// notificationContent identifies the alerts and their states, so identical notifications can be detected.
+func notificationContent(alerts []domain.Alert) string {
+	parts := make([]string, len(alerts))
+	for i, alert := range alerts {
+		parts[i] = alert.Fingerprint() + ":" + string(alert.State)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ";")
+}
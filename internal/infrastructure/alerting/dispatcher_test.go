@@ -0,0 +1,197 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func firing(name, metric string) domain.Alert {
+	return domain.Alert{Name: name, Labels: map[string]string{"metric": metric}, State: domain.AlertStateFiring}
+}
+
+func resolved(name, metric string) domain.Alert {
+	alert := firing(name, metric)
+	alert.State = domain.AlertStateResolved
+	return alert
+}
+
+type dispatcherTest struct {
+	dispatcher *Dispatcher
+	notifier   *recordingNotifier
+	now        time.Time
+}
+
+func newDispatcherTest(silencer stubSilencer, opts GroupingOptions) *dispatcherTest {
+	logger := zerolog.Nop()
+	dt := &dispatcherTest{
+		notifier: &recordingNotifier{},
+		now:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	dt.dispatcher = NewDispatcher(dt.notifier, silencer, &recordingHistory{}, nil, opts, &logger)
+	dt.dispatcher.now = func() time.Time { return dt.now }
+	return dt
+}
+
+// advance moves the clock and flushes the due groups.
+func (dt *dispatcherTest) advance(d time.Duration) {
+	dt.now = dt.now.Add(d)
+	dt.dispatcher.Flush(context.Background())
+}
+
+var defaultOptions = GroupingOptions{
+	GroupBy:        []string{domain.AlertNameLabel},
+	GroupWait:      30 * time.Second,
+	GroupInterval:  5 * time.Minute,
+	RepeatInterval: time.Hour,
+}
+
+func TestDispatcher_GroupsAlerts(t *testing.T) {
+	dt := newDispatcherTest(stubSilencer{}, defaultOptions)
+
+	for i := range 64 {
+		dt.dispatcher.Dispatch(firing("HighCPU", fmt.Sprintf("CPUutilization%d", i)))
+	}
+	dt.dispatcher.Dispatch(firing("MetricAnomaly", "HeapInuse"))
+
+	dt.advance(10 * time.Second)
+	assert.Empty(t, dt.notifier.notifications, "group wait has not passed yet")
+
+	dt.advance(20 * time.Second)
+	require.Len(t, dt.notifier.notifications, 2)
+
+	byGroup := map[string]domain.Notification{}
+	for _, n := range dt.notifier.notifications {
+		byGroup[n.GroupKey] = n
+	}
+	assert.Len(t, byGroup["{alertname=HighCPU}"].Alerts, 64)
+	assert.Equal(t, map[string]string{"alertname": "HighCPU"}, byGroup["{alertname=HighCPU}"].GroupLabels)
+	assert.Equal(t, domain.AlertStateFiring, byGroup["{alertname=HighCPU}"].Status)
+	assert.Len(t, byGroup["{alertname=MetricAnomaly}"].Alerts, 1)
+}
+
+func TestDispatcher_IntervalsAndDedup(t *testing.T) {
+	dt := newDispatcherTest(stubSilencer{}, defaultOptions)
+
+	dt.dispatcher.Dispatch(firing("HighCPU", "CPUutilization1"))
+	dt.advance(30 * time.Second)
+	require.Len(t, dt.notifier.notifications, 1)
+
+	// The same alert detected again is not a new notification
+	dt.dispatcher.Dispatch(firing("HighCPU", "CPUutilization1"))
+	dt.advance(5 * time.Minute)
+	assert.Len(t, dt.notifier.notifications, 1)
+
+	// A new alert in the group waits for the group interval
+	dt.dispatcher.Dispatch(firing("HighCPU", "CPUutilization2"))
+	dt.advance(time.Minute)
+	assert.Len(t, dt.notifier.notifications, 1)
+	dt.advance(4 * time.Minute)
+	require.Len(t, dt.notifier.notifications, 2)
+	assert.Len(t, dt.notifier.notifications[1].Alerts, 2)
+
+	// An unchanged group is repeated after the repeat interval
+	dt.advance(55 * time.Minute)
+	assert.Len(t, dt.notifier.notifications, 2)
+	dt.advance(5 * time.Minute)
+	assert.Len(t, dt.notifier.notifications, 3)
+
+	// Resolutions are sent with the still firing alerts and then dropped from the group
+	dt.dispatcher.Dispatch(resolved("HighCPU", "CPUutilization1"))
+	dt.advance(5 * time.Minute)
+	require.Len(t, dt.notifier.notifications, 4)
+	last := dt.notifier.notifications[3]
+	assert.Equal(t, domain.AlertStateFiring, last.Status)
+	assert.Len(t, last.Firing(), 1)
+	assert.Len(t, last.Resolved(), 1)
+
+	dt.dispatcher.Dispatch(resolved("HighCPU", "CPUutilization2"))
+	dt.advance(5 * time.Minute)
+	require.Len(t, dt.notifier.notifications, 5)
+	assert.Equal(t, domain.AlertStateResolved, dt.notifier.notifications[4].Status)
+	assert.Len(t, dt.notifier.notifications[4].Alerts, 1)
+	assert.Empty(t, dt.dispatcher.groups)
+}
+
+func TestDispatcher_Silences(t *testing.T) {
+	silencer := stubSilencer{"AgentAbsent": true}
+	dt := newDispatcherTest(silencer, GroupingOptions{GroupInterval: time.Minute, RepeatInterval: time.Hour})
+
+	dt.dispatcher.Dispatch(firing("AgentAbsent", "a1"))
+	dt.dispatcher.Dispatch(firing("MetricAnomaly", "HeapInuse"))
+	dt.advance(0)
+
+	require.Len(t, dt.notifier.notifications, 1)
+	assert.Equal(t, "{}", dt.notifier.notifications[0].GroupKey)
+	require.Len(t, dt.notifier.notifications[0].Alerts, 1)
+	assert.Equal(t, "MetricAnomaly", dt.notifier.notifications[0].Alerts[0].Name)
+
+	// The held back alert is notified once the silence ends
+	delete(silencer, "AgentAbsent")
+	dt.advance(time.Minute)
+	require.Len(t, dt.notifier.notifications, 2)
+	assert.Len(t, dt.notifier.notifications[1].Alerts, 2)
+
+	// An alert resolved while silenced is never notified
+	silencer["Other"] = true
+	dt.dispatcher.Dispatch(firing("Other", "x"))
+	dt.advance(time.Minute)
+	dt.dispatcher.Dispatch(resolved("Other", "x"))
+	dt.advance(time.Minute)
+	for _, n := range dt.notifier.notifications {
+		for _, alert := range n.Alerts {
+			assert.NotEqual(t, "Other", alert.Name)
+		}
+	}
+}
+
+type failingNotifier struct {
+	recordingNotifier
+	fail bool
+}
+
+func (n *failingNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	if n.fail {
+		return errors.New("receiver unavailable")
+	}
+	return n.recordingNotifier.Notify(ctx, notification)
+}
+
+func TestDispatcher_RetriesFailedNotification(t *testing.T) {
+	logger := zerolog.Nop()
+	notifier := &failingNotifier{fail: true}
+	history := &recordingHistory{}
+	dispatcher := NewDispatcher(notifier, stubSilencer{}, history, nil, GroupingOptions{GroupInterval: time.Minute, RepeatInterval: time.Hour}, &logger)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dispatcher.now = func() time.Time { return now }
+
+	dispatcher.Dispatch(firing("HighCPU", "CPUutilization1"))
+	dispatcher.Flush(context.Background())
+	assert.Empty(t, notifier.notifications)
+
+	notifier.fail = false
+	now = now.Add(time.Minute)
+	dispatcher.Flush(context.Background())
+	assert.Len(t, notifier.notifications, 1)
+
+	assert.Equal(t, []domain.HistoryEventType{domain.HistoryNotificationFailed, domain.HistoryNotificationSent}, history.types())
+	assert.Equal(t, "receiver unavailable", history.events[0].Message)
+}
+
+func TestDispatcher_GroupByAll(t *testing.T) {
+	dt := newDispatcherTest(stubSilencer{}, GroupingOptions{GroupBy: []string{GroupByAll}})
+
+	dt.dispatcher.Dispatch(firing("HighCPU", "CPUutilization1"))
+	dt.dispatcher.Dispatch(firing("HighCPU", "CPUutilization2"))
+	dt.advance(0)
+
+	assert.Len(t, dt.notifier.notifications, 2)
+}
@@ -0,0 +1,53 @@
+package alerting
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// LogNotifier writes alert notifications to the server log, with the text rendered by the log receiver template.
+type LogNotifier struct {
+	templates *Templates
+	logger    *zerolog.Logger
+}
+
+var _ domain.AlertNotifier = (*LogNotifier)(nil)
+
+func NewLogNotifier(templates *Templates, logger *zerolog.Logger) *LogNotifier {
+	return &LogNotifier{
+		templates: templates,
+		logger:    logger,
+	}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	// A broken template must not block the notification, it is logged without text instead
+	text, err := n.templates.Render(ctx, ReceiverLog, notification)
+	if err != nil {
+		n.logger.Error().Err(err).Str("group", notification.GroupKey).Msg("failed to render log notification template")
+	}
+
+	event := n.logger.Warn()
+	if notification.Status == domain.AlertStateResolved {
+		event = n.logger.Info()
+	}
+
+	names := make([]string, len(notification.Alerts))
+	for i, alert := range notification.Alerts {
+		names[i] = alert.Fingerprint()
+	}
+
+	event.
+		Str("group", notification.GroupKey).
+		Str("status", string(notification.Status)).
+		Int("firing", len(notification.Firing())).
+		Int("resolved", len(notification.Resolved())).
+		Strs("alerts", names).
+		Str("text", text).
+		Msg("alert notification")
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package alerting
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Manager keeps the set of active alerts and passes their state changes to the Dispatcher.
+// Repeated detections of an already firing alert only refresh its annotations.
+type Manager struct {
+	dispatcher *Dispatcher
+	silencer   domain.AlertSilencer
+	history    domain.HistoryRecorder
+	logger     *zerolog.Logger
+	now        func() time.Time
+	mu         sync.Mutex
+	active     map[string]domain.Alert
+}
+
+var _ domain.AlertPublisher = (*Manager)(nil)
+var _ domain.AlertReader = (*Manager)(nil)
+
+func New(dispatcher *Dispatcher, silencer domain.AlertSilencer, history domain.HistoryRecorder, logger *zerolog.Logger) *Manager {
+	return &Manager{
+		dispatcher: dispatcher,
+		silencer:   silencer,
+		history:    history,
+		logger:     logger,
+		now:        time.Now,
+		active:     make(map[string]domain.Alert),
+	}
+}
+
+func (m *Manager) Fire(ctx context.Context, alert domain.Alert) {
+	key := alert.Fingerprint()
+
+	m.mu.Lock()
+	if existing, ok := m.active[key]; ok {
+		existing.Annotations = alert.Annotations
+		m.active[key] = existing
+		m.mu.Unlock()
+
+		m.dispatcher.Dispatch(existing)
+		return
+	}
+
+	alert.State = domain.AlertStateFiring
+	alert.EndsAt = time.Time{}
+	if alert.StartsAt.IsZero() {
+		alert.StartsAt = m.now()
+	}
+	m.active[key] = alert
+	m.mu.Unlock()
+
+	m.logger.Debug().Str("alert", key).Msg("alert firing")
+	m.record(ctx, domain.HistoryAlertFiring, alert, alert.StartsAt)
+	m.dispatcher.Dispatch(alert)
+}
+
+func (m *Manager) Resolve(ctx context.Context, alert domain.Alert) {
+	key := alert.Fingerprint()
+
+	m.mu.Lock()
+	existing, ok := m.active[key]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.active, key)
+	m.mu.Unlock()
+
+	existing.State = domain.AlertStateResolved
+	existing.EndsAt = alert.EndsAt
+	if existing.EndsAt.IsZero() {
+		existing.EndsAt = m.now()
+	}
+	if alert.Annotations != nil {
+		existing.Annotations = alert.Annotations
+	}
+
+	m.logger.Debug().Str("alert", key).Msg("alert resolved")
+	m.record(ctx, domain.HistoryAlertResolved, existing, existing.EndsAt)
+	m.dispatcher.Dispatch(existing)
+}
+
+// ActiveAlerts returns the firing alerts ordered by start time, with the silences muting them.
+func (m *Manager) ActiveAlerts() []domain.Alert {
+	m.mu.Lock()
+	alerts := slices.Collect(maps.Values(m.active))
+	m.mu.Unlock()
+
+	for i := range alerts {
+		alerts[i].SilencedBy = m.silencer.SilencedBy(alerts[i])
+	}
+
+	slices.SortFunc(alerts, func(a, b domain.Alert) int {
+		if c := a.StartsAt.Compare(b.StartsAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Fingerprint(), b.Fingerprint())
+	})
+
+	return alerts
+}
+
+func (m *Manager) record(ctx context.Context, eventType domain.HistoryEventType, alert domain.Alert, at time.Time) {
+	m.history.Record(ctx, domain.HistoryEvent{
+		Time:      at,
+		Type:      eventType,
+		AlertName: alert.Name,
+		Labels:    alert.Labels,
+		Silences:  m.silencer.SilencedBy(alert),
+		Message:   alert.Annotations["summary"],
+	})
+}
@@ -0,0 +1,136 @@
+package alerting
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type recordingNotifier struct {
+	mu            sync.Mutex
+	notifications []domain.Notification
+	// err fails the notifications when set, they are not recorded then
+	err error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification domain.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return n.err
+	}
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+type recordingHistory struct {
+	mu     sync.Mutex
+	events []domain.HistoryEvent
+}
+
+func (h *recordingHistory) Record(_ context.Context, event domain.HistoryEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+}
+
+func (h *recordingHistory) types() []domain.HistoryEventType {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	types := make([]domain.HistoryEventType, len(h.events))
+	for i, event := range h.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+// stubSilencer silences every alert with a name in the set.
+type stubSilencer map[string]bool
+
+func (s stubSilencer) SilencedBy(alert domain.Alert) []string {
+	if s[alert.Name] {
+		return []string{"silence-" + alert.Name}
+	}
+	return nil
+}
+
+func TestManager_FireAndResolve(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	notifier := &recordingNotifier{}
+	history := &recordingHistory{}
+	dispatcher := NewDispatcher(notifier, stubSilencer{}, history, nil, GroupingOptions{}, &logger)
+	manager := New(dispatcher, stubSilencer{}, history, &logger)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	alert := domain.Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "a1"}}
+
+	manager.Fire(ctx, alert)
+	manager.Fire(ctx, domain.Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "a1"}, Annotations: map[string]string{"summary": "updated"}})
+
+	active := manager.ActiveAlerts()
+	require.Len(t, active, 1)
+	assert.Equal(t, domain.AlertStateFiring, active[0].State)
+	assert.Equal(t, now, active[0].StartsAt)
+	assert.Equal(t, "updated", active[0].Annotations["summary"])
+
+	dispatcher.Flush(ctx)
+	require.Len(t, notifier.notifications, 1)
+	require.Len(t, notifier.notifications[0].Alerts, 1)
+	assert.Equal(t, "updated", notifier.notifications[0].Alerts[0].Annotations["summary"])
+
+	now = now.Add(time.Minute)
+	manager.Resolve(ctx, alert)
+	manager.Resolve(ctx, alert)
+	assert.Empty(t, manager.ActiveAlerts())
+
+	dispatcher.Flush(ctx)
+	require.Len(t, notifier.notifications, 2)
+	assert.Equal(t, domain.AlertStateResolved, notifier.notifications[1].Status)
+	assert.Equal(t, now, notifier.notifications[1].Alerts[0].EndsAt)
+
+	assert.Equal(t, []domain.HistoryEventType{
+		domain.HistoryAlertFiring,
+		domain.HistoryNotificationSent,
+		domain.HistoryAlertResolved,
+		domain.HistoryNotificationSent,
+	}, history.types())
+	assert.Equal(t, "AgentAbsent", history.events[2].AlertName)
+	assert.Equal(t, now, history.events[2].Time)
+}
+
+func TestManager_ActiveAlerts(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	silencer := stubSilencer{"B": true}
+	history := &recordingHistory{}
+	manager := New(NewDispatcher(&recordingNotifier{}, silencer, history, nil, GroupingOptions{}, &logger), silencer, history, &logger)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	manager.Fire(ctx, domain.Alert{Name: "B", StartsAt: start.Add(time.Second)})
+	manager.Fire(ctx, domain.Alert{Name: "C", StartsAt: start})
+	manager.Fire(ctx, domain.Alert{Name: "A", StartsAt: start})
+
+	active := manager.ActiveAlerts()
+	require.Len(t, active, 3)
+
+	var names []string
+	for _, alert := range active {
+		names = append(names, alert.Name)
+	}
+	assert.Equal(t, []string{"A", "C", "B"}, names)
+	assert.Equal(t, []string{"silence-B"}, active[2].SilencedBy)
+	assert.Empty(t, active[0].SilencedBy)
+
+	require.Len(t, history.events, 3)
+	assert.Equal(t, []string{"silence-B"}, history.events[0].Silences, "the history tells who muted the alert")
+}
@@ -0,0 +1,46 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// MultiNotifier delivers every notification to all of its notifiers. A retried notification is only delivered to
+// the notifiers that failed it, so the others do not get it twice.
+type MultiNotifier struct {
+	notifiers  []domain.AlertNotifier
+	deliveries *deliveries
+}
+
+var _ domain.AlertNotifier = (*MultiNotifier)(nil)
+
+func NewMultiNotifier(notifiers ...domain.AlertNotifier) *MultiNotifier {
+	return &MultiNotifier{
+		notifiers:  notifiers,
+		deliveries: newDeliveries(),
+	}
+}
+
+// Notify tries the notifiers that did not get the notification yet and returns their joined errors.
+func (m *MultiNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	delivered := m.deliveries.delivered(notification)
+
+	var errs []error
+	for i, notifier := range m.notifiers {
+		key := strconv.Itoa(i)
+		if delivered[key] {
+			continue
+		}
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered[key] = true
+	}
+
+	m.deliveries.record(notification, delivered, len(errs) == 0)
+	return errors.Join(errs...)
+}
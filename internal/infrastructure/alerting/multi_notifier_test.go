@@ -0,0 +1,37 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestMultiNotifier_RetriesFailedNotifiers(t *testing.T) {
+	ctx := context.Background()
+	ok, failing := &recordingNotifier{}, &recordingNotifier{err: errors.New("connection refused")}
+	notifier := NewMultiNotifier(ok, failing)
+	notification := domain.Notification{GroupKey: "{}", Status: domain.AlertStateFiring, Alerts: []domain.Alert{firing("HighCPU", "CPUutilization1")}}
+
+	assert.Error(t, notifier.Notify(ctx, notification))
+	assert.Error(t, notifier.Notify(ctx, notification))
+	failing.err = nil
+	require.NoError(t, notifier.Notify(ctx, notification))
+	assert.Len(t, ok.notifications, 1, "a retry skips the notifiers that delivered it")
+	assert.Len(t, failing.notifications, 1)
+
+	require.NoError(t, notifier.Notify(ctx, notification))
+	assert.Len(t, ok.notifications, 2, "a delivered notification is sent to all notifiers again")
+
+	notification.Alerts = []domain.Alert{resolved("HighCPU", "CPUutilization1")}
+	failing.err = errors.New("connection refused")
+	assert.Error(t, notifier.Notify(ctx, notification))
+	failing.err = nil
+	notification.Alerts = append(notification.Alerts, firing("HeapLeak", "HeapInuse"))
+	require.NoError(t, notifier.Notify(ctx, notification))
+	assert.Len(t, ok.notifications, 4, "a notification with other alerts is no retry")
+}
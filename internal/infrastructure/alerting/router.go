@@ -0,0 +1,378 @@
+package alerting
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// escalationCheckInterval is how often unacknowledged alerts are checked for a due escalation step.
+const escalationCheckInterval = 10 * time.Second
+
+// DefaultReceiver is the receiver of the routing used when none is configured.
+const DefaultReceiver = "default"
+
+// DefaultRouting sends all alerts to the server log.
+var DefaultRouting = domain.RoutingConfig{
+	Receivers: []domain.Receiver{{Name: DefaultReceiver}},
+	Route:     domain.Route{Receiver: DefaultReceiver},
+}
+
+type escalationState struct {
+	alert      domain.Alert
+	steps      []domain.EscalationStep
+	notified   []string
+	notifiedAt time.Time
+	// next is the index of the next step to escalate to
+	next           int
+	acknowledgedBy string
+	acknowledgedAt time.Time
+	comment        string
+	// resolved is set while the resolution is delivered, the state is dropped once every receiver got it
+	resolved bool
+}
+
+// Router delivers each notification to the receivers of the routes its alerts match, splitting the alerts
+// of a group by receiver. Firing alerts of routes with escalation steps are escalated to further receivers
+// until they are acknowledged or resolved, and the resolution is sent to every receiver that was notified.
+type Router struct {
+	route     domain.Route
+	notifiers map[string]domain.AlertNotifier
+	silencer  domain.AlertSilencer
+	history   domain.HistoryRecorder
+	logger    *zerolog.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+	states    map[string]*escalationState
+	// deliveries keeps a retried notification from reaching the receivers that got it already
+	deliveries *deliveries
+}
+
+var _ domain.AlertNotifier = (*Router)(nil)
+var _ domain.EscalationManager = (*Router)(nil)
+
+// NewRouter validates the routing and creates a router delivering to the notifiers of the receivers by name.
+func NewRouter(
+	routing domain.RoutingConfig,
+	notifiers map[string]domain.AlertNotifier,
+	silencer domain.AlertSilencer,
+	history domain.HistoryRecorder,
+	logger *zerolog.Logger,
+) (*Router, error) {
+	if err := routing.Validate(); err != nil {
+		return nil, err
+	}
+
+	for _, receiver := range routing.Receivers {
+		if _, ok := notifiers[receiver.Name]; !ok {
+			return nil, fmt.Errorf("%w: no notifier for receiver %s", domain.ErrInvalidRouting, receiver.Name)
+		}
+	}
+
+	return &Router{
+		route:     routing.Route,
+		notifiers: notifiers,
+		silencer:  silencer,
+		history:   history,
+		logger:    logger,
+		now:       time.Now,
+		states:    make(map[string]*escalationState),
+
+		deliveries: newDeliveries(),
+	}, nil
+}
+
+// NewReceiverNotifiers creates the notifiers of the receivers: webhook receivers post to their URL,
+// the others write to the server log.
+func NewReceiverNotifiers(receivers []domain.Receiver, templates *Templates, logger *zerolog.Logger) map[string]domain.AlertNotifier {
+	notifiers := make(map[string]domain.AlertNotifier, len(receivers))
+	for _, receiver := range receivers {
+		if receiver.WebhookURL != "" {
+			notifiers[receiver.Name] = NewWebhookNotifier(receiver.Name, receiver.WebhookURL, templates, logger)
+			continue
+		}
+		receiverLogger := logger.With().Str("receiver", receiver.Name).Logger()
+		notifiers[receiver.Name] = NewLogNotifier(templates, &receiverLogger)
+	}
+	return notifiers
+}
+
+// LoadRouting reads the receivers and the routing tree from a JSON file.
+func LoadRouting(path string) (domain.RoutingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.RoutingConfig{}, fmt.Errorf("failed to read routing: %w", err)
+	}
+
+	var routing domain.RoutingConfig
+	if err := json.Unmarshal(data, &routing); err != nil {
+		return domain.RoutingConfig{}, fmt.Errorf("failed to parse routing: %w", err)
+	}
+
+	return routing, nil
+}
+
+// Notify sends the alerts of the notification to their receivers, one notification per receiver. A retried
+// notification only goes to the receivers that failed it. The escalation
+// state of a resolved alert is kept until the resolution was delivered to all its receivers, so a retry reaches
+// the escalation receivers again.
+func (r *Router) Notify(ctx context.Context, notification domain.Notification) error {
+	now := r.now()
+	byReceiver := make(map[string][]domain.Alert)
+	resolving := make(map[*escalationState][]string)
+
+	r.mu.Lock()
+	for _, alert := range notification.Alerts {
+		routes := matchRoutes(r.route, domain.Route{}, alert)
+		receivers := make([]string, 0, len(routes))
+		for _, route := range routes {
+			receivers = append(receivers, route.Receiver)
+		}
+
+		fingerprint := alert.Fingerprint()
+		state, ok := r.states[fingerprint]
+		switch {
+		case alert.State == domain.AlertStateResolved && ok:
+			receivers = append(receivers, state.notified...)
+			state.resolved = true
+		case alert.State == domain.AlertStateFiring && ok:
+			state.alert = alert
+			state.resolved = false
+		case alert.State == domain.AlertStateFiring:
+			// Only the first matching route with escalation steps escalates the alert
+			if i := slices.IndexFunc(routes, func(route domain.Route) bool { return len(route.Escalation) > 0 }); i >= 0 {
+				r.states[fingerprint] = &escalationState{
+					alert:      alert,
+					steps:      routes[i].Escalation,
+					notified:   []string{routes[i].Receiver},
+					notifiedAt: now,
+				}
+			}
+		}
+
+		slices.Sort(receivers)
+		receivers = slices.Compact(receivers)
+		for _, receiver := range receivers {
+			byReceiver[receiver] = append(byReceiver[receiver], alert)
+		}
+		if ok && state.resolved {
+			resolving[state] = receivers
+		}
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	delivered := r.deliveries.delivered(notification)
+	failed := make(map[string]bool)
+	for _, receiver := range slices.Sorted(maps.Keys(byReceiver)) {
+		if delivered[receiver] {
+			continue
+		}
+		err := r.notifiers[receiver].Notify(ctx, withAlerts(notification, byReceiver[receiver]))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("receiver %s: %w", receiver, err))
+			failed[receiver] = true
+			continue
+		}
+		delivered[receiver] = true
+	}
+	r.deliveries.record(notification, delivered, len(errs) == 0)
+
+	r.mu.Lock()
+	for state, receivers := range resolving {
+		fingerprint := state.alert.Fingerprint()
+		delivered := !slices.ContainsFunc(receivers, func(receiver string) bool { return failed[receiver] })
+		// The alert may have fired again while the resolution was sent
+		if delivered && state.resolved && r.states[fingerprint] == state {
+			delete(r.states, fingerprint)
+		}
+	}
+	r.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Run periodically escalates the unacknowledged alerts until shutdownCh is closed.
+func (r *Router) Run(shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			r.Escalate(context.Background())
+		}
+	}
+}
+
+// Escalate notifies the receivers of the due escalation steps of unacknowledged alerts. Silenced alerts are
+// not escalated while the silence lasts, and a failed step is retried on the next check.
+func (r *Router) Escalate(ctx context.Context) {
+	now := r.now()
+
+	type escalation struct {
+		fingerprint string
+		alert       domain.Alert
+		step        domain.EscalationStep
+	}
+
+	r.mu.Lock()
+	var due []escalation
+	for fingerprint, state := range r.states {
+		if state.resolved || state.acknowledgedBy != "" || state.next >= len(state.steps) {
+			continue
+		}
+		step := state.steps[state.next]
+		if now.Before(state.notifiedAt.Add(time.Duration(step.After))) || len(r.silencer.SilencedBy(state.alert)) > 0 {
+			continue
+		}
+		due = append(due, escalation{fingerprint: fingerprint, alert: state.alert, step: step})
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(due, func(a, b escalation) int {
+		return cmp.Compare(a.fingerprint, b.fingerprint)
+	})
+
+	for _, e := range due {
+		notification := domain.Notification{
+			GroupKey:    "escalation:" + e.fingerprint,
+			GroupLabels: map[string]string{domain.AlertNameLabel: e.alert.Name},
+			Status:      domain.AlertStateFiring,
+			Alerts:      []domain.Alert{e.alert},
+		}
+		if err := r.notifiers[e.step.Receiver].Notify(ctx, notification); err != nil {
+			r.logger.Error().Err(err).Str("alert", e.fingerprint).Str("receiver", e.step.Receiver).Msg("failed to escalate alert")
+			continue
+		}
+
+		r.mu.Lock()
+		// The alert may have been resolved or acknowledged while the notification was sent
+		if state, ok := r.states[e.fingerprint]; ok && state.next < len(state.steps) && state.steps[state.next] == e.step {
+			state.next++
+			state.notified = append(state.notified, e.step.Receiver)
+		}
+		r.mu.Unlock()
+
+		r.history.Record(ctx, domain.HistoryEvent{
+			Time:      now,
+			Type:      domain.HistoryAlertEscalated,
+			AlertName: e.alert.Name,
+			Labels:    e.alert.Labels,
+			Message:   fmt.Sprintf("escalated to %s after %s unacknowledged", e.step.Receiver, e.step.After),
+		})
+	}
+}
+
+// Escalations returns the escalation state of the alerts routed to routes with escalation steps, ordered by alert.
+func (r *Router) Escalations() []domain.Escalation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	escalations := make([]domain.Escalation, 0, len(r.states))
+	for _, fingerprint := range slices.Sorted(maps.Keys(r.states)) {
+		if state := r.states[fingerprint]; !state.resolved {
+			escalations = append(escalations, state.escalation())
+		}
+	}
+	return escalations
+}
+
+// Acknowledge stops the escalation of the firing alert. Acknowledging it again replaces the acknowledgement.
+func (r *Router) Acknowledge(ctx context.Context, ack domain.Acknowledgement) (domain.Escalation, error) {
+	if err := ack.Validate(); err != nil {
+		return domain.Escalation{}, err
+	}
+
+	alert := domain.Alert{Name: ack.AlertName, Labels: ack.Labels}
+	now := r.now()
+
+	r.mu.Lock()
+	state, ok := r.states[alert.Fingerprint()]
+	if !ok || state.resolved {
+		r.mu.Unlock()
+		return domain.Escalation{}, fmt.Errorf("%w: %s", domain.ErrEscalationNotFound, alert.Fingerprint())
+	}
+	state.acknowledgedBy = ack.AcknowledgedBy
+	state.acknowledgedAt = now
+	state.comment = ack.Comment
+	escalation := state.escalation()
+	r.mu.Unlock()
+
+	r.history.Record(ctx, domain.HistoryEvent{
+		Time:      now,
+		Type:      domain.HistoryAlertAcknowledged,
+		AlertName: alert.Name,
+		Labels:    alert.Labels,
+		Actor:     ack.AcknowledgedBy,
+		Message:   ack.Comment,
+	})
+	r.logger.Info().Str("alert", alert.Fingerprint()).Str("by", ack.AcknowledgedBy).Msg("alert acknowledged")
+
+	return escalation, nil
+}
+
+func (s *escalationState) escalation() domain.Escalation {
+	escalation := domain.Escalation{
+		Alert:          s.alert,
+		Notified:       slices.Clone(s.notified),
+		NotifiedAt:     s.notifiedAt,
+		AcknowledgedBy: s.acknowledgedBy,
+		AcknowledgedAt: s.acknowledgedAt,
+		Comment:        s.comment,
+	}
+	if s.acknowledgedBy == "" && s.next < len(s.steps) {
+		step := s.steps[s.next]
+		escalation.NextStep = &step
+		escalation.NextStepAt = s.notifiedAt.Add(time.Duration(step.After))
+	}
+	return escalation
+}
+
+// matchRoutes returns the routes the alert is delivered to, with the receiver and escalation inherited from
+// their parents. The route itself is used when none of its children match.
+func matchRoutes(route domain.Route, parent domain.Route, alert domain.Alert) []domain.Route {
+	route.Receiver = cmp.Or(route.Receiver, parent.Receiver)
+	if route.Escalation == nil {
+		route.Escalation = parent.Escalation
+	}
+
+	var matched []domain.Route
+	for _, child := range route.Routes {
+		if !child.Matches(alert) {
+			continue
+		}
+		matched = append(matched, matchRoutes(child, route, alert)...)
+		if !child.Continue {
+			break
+		}
+	}
+
+	if len(matched) == 0 {
+		return []domain.Route{route}
+	}
+	return matched
+}
+
+// withAlerts returns the notification with only the given alerts, firing if any of them is.
+func withAlerts(notification domain.Notification, alerts []domain.Alert) domain.Notification {
+	notification.Alerts = alerts
+	notification.Status = domain.AlertStateResolved
+	if slices.ContainsFunc(alerts, func(alert domain.Alert) bool { return alert.State == domain.AlertStateFiring }) {
+		notification.Status = domain.AlertStateFiring
+	}
+	return notification
+}
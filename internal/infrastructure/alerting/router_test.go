@@ -0,0 +1,310 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+var testRouting = domain.RoutingConfig{
+	Receivers: []domain.Receiver{{Name: "ops"}, {Name: "runtime"}, {Name: "runtime-lead"}, {Name: "critical"}},
+	Route: domain.Route{
+		Receiver: "ops",
+		Routes: []domain.Route{
+			{
+				MetricPrefix: "Heap",
+				Receiver:     "runtime",
+				Escalation:   []domain.EscalationStep{{After: domain.Duration(15 * time.Minute), Receiver: "runtime-lead"}},
+				Routes: []domain.Route{
+					// Inherits the runtime receiver and its escalation
+					{Matchers: []domain.Matcher{{Name: domain.AlertNameLabel, Operator: domain.MatchEqual, Value: "HeapLeak"}}},
+				},
+			},
+			{
+				Matchers: []domain.Matcher{{Name: "severity", Operator: domain.MatchEqual, Value: "critical"}},
+				Receiver: "critical",
+				Continue: true,
+			},
+			{MetricPrefix: "CPU", Receiver: "runtime"},
+		},
+	},
+}
+
+type routerTest struct {
+	router    *Router
+	notifiers map[string]*recordingNotifier
+	history   *recordingHistory
+	now       time.Time
+}
+
+func newRouterTest(t *testing.T, silencer stubSilencer) *routerTest {
+	t.Helper()
+	logger := zerolog.Nop()
+	rt := &routerTest{
+		notifiers: make(map[string]*recordingNotifier),
+		history:   &recordingHistory{},
+		now:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	notifiers := make(map[string]domain.AlertNotifier)
+	for _, receiver := range testRouting.Receivers {
+		rt.notifiers[receiver.Name] = &recordingNotifier{}
+		notifiers[receiver.Name] = rt.notifiers[receiver.Name]
+	}
+
+	router, err := NewRouter(testRouting, notifiers, silencer, rt.history, &logger)
+	require.NoError(t, err)
+	router.now = func() time.Time { return rt.now }
+	rt.router = router
+
+	return rt
+}
+
+// received returns the fingerprints of the alerts each receiver was notified about.
+func (rt *routerTest) received() map[string][]string {
+	received := make(map[string][]string)
+	for name, notifier := range rt.notifiers {
+		for _, notification := range notifier.notifications {
+			for _, alert := range notification.Alerts {
+				received[name] = append(received[name], alert.Fingerprint())
+			}
+		}
+	}
+	return received
+}
+
+func TestRouter_Routes(t *testing.T) {
+	rt := newRouterTest(t, stubSilencer{})
+
+	critical := firing("HighCPU", "CPUutilization1")
+	critical.Labels["severity"] = "critical"
+
+	err := rt.router.Notify(context.Background(), domain.Notification{
+		GroupKey: "{}",
+		Status:   domain.AlertStateFiring,
+		Alerts: []domain.Alert{
+			firing("HeapLeak", "HeapInuse"),
+			critical,
+			firing("AllocHigh", "Alloc"),
+			{Name: "AgentAbsent", Labels: map[string]string{"agent": "a1"}, State: domain.AlertStateFiring},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"runtime":  {"HeapLeak,metric=HeapInuse", "HighCPU,metric=CPUutilization1,severity=critical"},
+		"critical": {"HighCPU,metric=CPUutilization1,severity=critical"},
+		"ops":      {"AllocHigh,metric=Alloc", "AgentAbsent,agent=a1"},
+	}, rt.received())
+	assert.Equal(t, "{}", rt.notifiers["ops"].notifications[0].GroupKey)
+}
+
+func TestRouter_Escalation(t *testing.T) {
+	ctx := context.Background()
+	rt := newRouterTest(t, stubSilencer{})
+	alert := firing("HeapLeak", "HeapInuse")
+
+	require.NoError(t, rt.router.Notify(ctx, domain.Notification{Status: domain.AlertStateFiring, Alerts: []domain.Alert{alert}}))
+	escalations := rt.router.Escalations()
+	require.Len(t, escalations, 1)
+	assert.Equal(t, []string{"runtime"}, escalations[0].Notified)
+	assert.Equal(t, rt.now.Add(15*time.Minute), escalations[0].NextStepAt)
+
+	rt.now = rt.now.Add(14 * time.Minute)
+	rt.router.Escalate(ctx)
+	assert.Empty(t, rt.notifiers["runtime-lead"].notifications)
+
+	rt.now = rt.now.Add(time.Minute)
+	rt.router.Escalate(ctx)
+	require.Len(t, rt.notifiers["runtime-lead"].notifications, 1)
+	assert.Equal(t, domain.AlertStateFiring, rt.notifiers["runtime-lead"].notifications[0].Status)
+
+	escalations = rt.router.Escalations()
+	assert.Equal(t, []string{"runtime", "runtime-lead"}, escalations[0].Notified)
+	assert.Nil(t, escalations[0].NextStep, "no steps left")
+
+	rt.router.Escalate(ctx)
+	assert.Len(t, rt.notifiers["runtime-lead"].notifications, 1, "each step is notified once")
+
+	// The resolution reaches everyone who was told about the alert
+	require.NoError(t, rt.router.Notify(ctx, domain.Notification{Status: domain.AlertStateResolved, Alerts: []domain.Alert{resolved("HeapLeak", "HeapInuse")}}))
+	assert.Len(t, rt.notifiers["runtime"].notifications, 2)
+	assert.Len(t, rt.notifiers["runtime-lead"].notifications, 2)
+	assert.Empty(t, rt.router.Escalations())
+	assert.Equal(t, []domain.HistoryEventType{domain.HistoryAlertEscalated}, rt.history.types())
+}
+
+func TestRouter_ResolutionRetried(t *testing.T) {
+	ctx := context.Background()
+	rt := newRouterTest(t, stubSilencer{})
+	resolution := domain.Notification{Status: domain.AlertStateResolved, Alerts: []domain.Alert{resolved("HeapLeak", "HeapInuse")}}
+
+	require.NoError(t, rt.router.Notify(ctx, domain.Notification{Status: domain.AlertStateFiring, Alerts: []domain.Alert{firing("HeapLeak", "HeapInuse")}}))
+	rt.now = rt.now.Add(15 * time.Minute)
+	rt.router.Escalate(ctx)
+	require.Len(t, rt.notifiers["runtime-lead"].notifications, 1)
+
+	rt.notifiers["runtime-lead"].err = errors.New("connection refused")
+	assert.Error(t, rt.router.Notify(ctx, resolution))
+	assert.Empty(t, rt.router.Escalations(), "a resolved alert is no longer escalated")
+
+	// The retry of the resolution still reaches the escalation receiver, and only it
+	rt.notifiers["runtime-lead"].err = nil
+	require.NoError(t, rt.router.Notify(ctx, resolution))
+	assert.Len(t, rt.notifiers["runtime-lead"].notifications, 2)
+	assert.Len(t, rt.notifiers["runtime"].notifications, 2)
+
+	require.NoError(t, rt.router.Notify(ctx, resolution))
+	assert.Len(t, rt.notifiers["runtime-lead"].notifications, 2, "the state is dropped once the resolution was delivered")
+	assert.Len(t, rt.notifiers["runtime"].notifications, 3)
+}
+
+func TestRouter_Acknowledge(t *testing.T) {
+	ctx := context.Background()
+	rt := newRouterTest(t, stubSilencer{})
+	alert := firing("HeapLeak", "HeapInuse")
+	require.NoError(t, rt.router.Notify(ctx, domain.Notification{Status: domain.AlertStateFiring, Alerts: []domain.Alert{alert}}))
+
+	_, err := rt.router.Acknowledge(ctx, domain.Acknowledgement{AlertName: "HeapLeak", Labels: alert.Labels})
+	assert.ErrorIs(t, err, domain.ErrInvalidAck)
+
+	_, err = rt.router.Acknowledge(ctx, domain.Acknowledgement{AlertName: "HeapLeak", AcknowledgedBy: "alice"})
+	assert.ErrorIs(t, err, domain.ErrEscalationNotFound, "labels are part of the alert identity")
+
+	escalation, err := rt.router.Acknowledge(ctx, domain.Acknowledgement{
+		AlertName:      "HeapLeak",
+		Labels:         alert.Labels,
+		AcknowledgedBy: "alice",
+		Comment:        "looking into it",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", escalation.AcknowledgedBy)
+	assert.Nil(t, escalation.NextStep)
+
+	rt.now = rt.now.Add(time.Hour)
+	rt.router.Escalate(ctx)
+	assert.Empty(t, rt.notifiers["runtime-lead"].notifications, "acknowledged alerts are not escalated")
+
+	require.Len(t, rt.history.events, 1)
+	assert.Equal(t, domain.HistoryAlertAcknowledged, rt.history.events[0].Type)
+	assert.Equal(t, "alice", rt.history.events[0].Actor)
+}
+
+func TestRouter_SilencedAlertsAreNotEscalated(t *testing.T) {
+	ctx := context.Background()
+	rt := newRouterTest(t, stubSilencer{"HeapLeak": true})
+	require.NoError(t, rt.router.Notify(ctx, domain.Notification{Status: domain.AlertStateFiring, Alerts: []domain.Alert{firing("HeapLeak", "HeapInuse")}}))
+
+	rt.now = rt.now.Add(time.Hour)
+	rt.router.Escalate(ctx)
+	assert.Empty(t, rt.notifiers["runtime-lead"].notifications)
+}
+
+func TestNewRouter_Validation(t *testing.T) {
+	logger := zerolog.Nop()
+	notifiers := map[string]domain.AlertNotifier{"ops": &recordingNotifier{}}
+
+	tests := []struct {
+		name    string
+		routing domain.RoutingConfig
+	}{
+		{name: "no root receiver", routing: domain.RoutingConfig{Receivers: []domain.Receiver{{Name: "ops"}}}},
+		{name: "unknown receiver", routing: domain.RoutingConfig{
+			Receivers: []domain.Receiver{{Name: "ops"}},
+			Route:     domain.Route{Receiver: "ops", Routes: []domain.Route{{MetricPrefix: "CPU", Receiver: "dev"}}},
+		}},
+		{name: "decreasing escalation", routing: domain.RoutingConfig{
+			Receivers: []domain.Receiver{{Name: "ops"}},
+			Route: domain.Route{Receiver: "ops", Escalation: []domain.EscalationStep{
+				{After: domain.Duration(time.Hour), Receiver: "ops"},
+				{After: domain.Duration(time.Minute), Receiver: "ops"},
+			}},
+		}},
+		{name: "invalid matcher", routing: domain.RoutingConfig{
+			Receivers: []domain.Receiver{{Name: "ops"}},
+			Route:     domain.Route{Receiver: "ops", Routes: []domain.Route{{Matchers: []domain.Matcher{{Name: "a", Operator: "~"}}}}},
+		}},
+		{name: "invalid webhook", routing: domain.RoutingConfig{
+			Receivers: []domain.Receiver{{Name: "ops", WebhookURL: "ops.local"}},
+			Route:     domain.Route{Receiver: "ops"},
+		}},
+		{name: "missing notifier", routing: domain.RoutingConfig{
+			Receivers: []domain.Receiver{{Name: "ops"}, {Name: "dev"}},
+			Route:     domain.Route{Receiver: "ops"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(tt.routing, notifiers, stubSilencer{}, &recordingHistory{}, &logger)
+			assert.ErrorIs(t, err, domain.ErrInvalidRouting)
+		})
+	}
+
+	_, err := NewRouter(DefaultRouting, map[string]domain.AlertNotifier{DefaultReceiver: &recordingNotifier{}}, stubSilencer{}, &recordingHistory{}, &logger)
+	assert.NoError(t, err)
+}
+
+func TestLoadRouting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"receivers": [{"name": "ops"}, {"name": "lead", "webhook_url": "http://hooks.local/lead"}],
+		"route": {"receiver": "ops", "escalation": [{"after": "15m", "receiver": "lead"}]}
+	}`), 0o600))
+
+	routing, err := LoadRouting(path)
+	require.NoError(t, err)
+	require.NoError(t, routing.Validate())
+	assert.Equal(t, domain.Duration(15*time.Minute), routing.Route.Escalation[0].After)
+	assert.Equal(t, "http://hooks.local/lead", routing.Receivers[1].WebhookURL)
+
+	_, err = LoadRouting(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	logger := zerolog.Nop()
+
+	var received webhookPayload
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer stub.Close()
+
+	templates, err := NewTemplates(map[string]string{ReceiverWebhook: "{{len .Alerts}} alerts for {{.Receiver}}"}, "", nil, nil)
+	require.NoError(t, err)
+
+	notifier := NewWebhookNotifier("ops", stub.URL, templates, &logger)
+	err = notifier.Notify(context.Background(), domain.Notification{
+		GroupKey: "{alertname=HighCPU}",
+		Status:   domain.AlertStateFiring,
+		Alerts:   []domain.Alert{firing("HighCPU", "CPUutilization1")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ops", received.Receiver)
+	assert.Equal(t, "{alertname=HighCPU}", received.GroupKey)
+	assert.Equal(t, "1 alerts for webhook", received.Text)
+	require.Len(t, received.Alerts, 1)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	err = NewWebhookNotifier("ops", failing.URL, templates, &logger).Notify(context.Background(), domain.Notification{})
+	assert.ErrorContains(t, err, "down")
+}
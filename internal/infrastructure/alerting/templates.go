@@ -0,0 +1,378 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Receivers the notification templates are defined for.
+const (
+	ReceiverLog          = "log"
+	ReceiverAlertmanager = "alertmanager"
+	ReceiverWebhook      = "webhook"
+)
+
+// DefaultTemplates are used for the receivers without a configured template.
+var DefaultTemplates = map[string]string{
+	ReceiverLog: `[{{.Status | upper}}] {{labels .GroupLabels}}
+{{- range .Alerts}}
+{{.Name}} {{labels .Labels}} is {{.State}} for {{duration .Duration}}
+{{- with .Metric}}, {{.ID}} = {{.StringValue}}{{end}}
+{{- with .Rule}} ({{.Expr}}){{end}}
+{{- with .Annotations.summary}}: {{.}}{{end}} {{.Link}}
+{{- end}}`,
+	ReceiverAlertmanager: `{{range .Alerts}}{{.Name}} is {{.State}}
+{{- with .Metric}}: {{.ID}} = {{.StringValue}}{{end}}
+{{- with .Rule}}, rule {{.Expr}} for {{.For}}{{end}}{{end}}`,
+	ReceiverWebhook: `[{{.Status | upper}}] {{len .Firing}} firing, {{len .Resolved}} resolved
+{{- range .Alerts}}
+{{.Name}} {{labels .Labels}} is {{.State}} for {{duration .Duration}}
+{{- with .Metric}}, {{.ID}} = {{.StringValue}}{{end}}
+{{- with .Annotations.summary}}: {{.}}{{end}} {{.Link}}
+{{- end}}`,
+}
+
+// TemplateAlert is an alert as seen by notification templates.
+type TemplateAlert struct {
+	domain.Alert
+	// Rule is the rule that raised the alert, nil for alerts not raised by a rule.
+	Rule *domain.Rule
+	// Metric is the current state of the metric the alert is about, nil if unknown.
+	Metric *domain.Metric
+	// Duration is how long the alert has been firing, or was firing once resolved.
+	Duration time.Duration
+	// Link points at the page of the metric, or at the alert list.
+	Link string
+}
+
+// TemplateData is the data notification templates are executed with.
+type TemplateData struct {
+	Receiver    string
+	Status      domain.AlertState
+	GroupLabels map[string]string
+	Alerts      []TemplateAlert
+	ExternalURL string
+}
+
+func (d TemplateData) Firing() []TemplateAlert {
+	return d.withState(domain.AlertStateFiring)
+}
+
+func (d TemplateData) Resolved() []TemplateAlert {
+	return d.withState(domain.AlertStateResolved)
+}
+
+func (d TemplateData) withState(state domain.AlertState) []TemplateAlert {
+	var alerts []TemplateAlert
+	for _, alert := range d.Alerts {
+		if alert.State == state {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(s any) string { return strings.ToUpper(fmt.Sprint(s)) },
+	"lower": func(s any) string { return strings.ToLower(fmt.Sprint(s)) },
+	"join":  strings.Join,
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"labels": formatLabels,
+}
+
+// Templates renders notifications of each receiver with its text/template. Templates are validated
+// against a sample notification when they are created, so errors show up at startup.
+type Templates struct {
+	sources     map[string]string
+	templates   map[string]*template.Template
+	externalURL string
+	metrics     domain.MetricStorage
+	rules       domain.RuleStorage
+	now         func() time.Time
+}
+
+var _ domain.NotificationTemplates = (*Templates)(nil)
+
+// NewTemplates parses the templates, using DefaultTemplates for the receivers missing from sources.
+// Rules are looked up in the storage, so alerts can be rendered before the rule engine is running.
+func NewTemplates(sources map[string]string, externalURL string, metrics domain.MetricStorage, rules domain.RuleStorage) (*Templates, error) {
+	t := &Templates{
+		sources:     maps.Clone(DefaultTemplates),
+		templates:   make(map[string]*template.Template),
+		externalURL: strings.TrimRight(externalURL, "/"),
+		metrics:     metrics,
+		rules:       rules,
+		now:         time.Now,
+	}
+
+	for receiver, source := range sources {
+		if _, ok := DefaultTemplates[receiver]; !ok {
+			return nil, fmt.Errorf("%w: %s", domain.ErrUnknownReceiver, receiver)
+		}
+		t.sources[receiver] = source
+	}
+
+	for receiver, source := range t.sources {
+		tmpl, err := t.parse(receiver, source)
+		if err != nil {
+			return nil, err
+		}
+		t.templates[receiver] = tmpl
+	}
+
+	return t, nil
+}
+
+// LoadTemplateSources reads the templates of the receivers from a JSON file mapping receiver names to templates.
+func LoadTemplateSources(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification templates: %w", err)
+	}
+
+	var sources map[string]string
+	if err := json.Unmarshal(data, &sources); err != nil {
+		return nil, fmt.Errorf("failed to parse notification templates: %w", err)
+	}
+
+	return sources, nil
+}
+
+func (t *Templates) Templates() map[string]string {
+	return maps.Clone(t.sources)
+}
+
+// Render renders the notification with the template of the receiver. An empty template renders nothing.
+func (t *Templates) Render(ctx context.Context, receiver string, notification domain.Notification) (string, error) {
+	return t.Renderer(ctx, notification.Alerts).Render(receiver, notification)
+}
+
+func (t *Templates) Preview(ctx context.Context, receiver string, source string, alerts []domain.Alert) (string, error) {
+	tmpl, ok := t.templates[receiver]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownReceiver, receiver)
+	}
+
+	if source != "" {
+		var err error
+		if tmpl, err = t.parse(receiver, source); err != nil {
+			return "", err
+		}
+	}
+
+	if len(alerts) == 0 {
+		return execute(tmpl, t.sampleData(receiver))
+	}
+
+	notification := domain.Notification{
+		GroupLabels: map[string]string{domain.AlertNameLabel: alerts[0].Name},
+		Status:      domain.AlertStateResolved,
+		Alerts:      alerts,
+	}
+	for _, alert := range alerts {
+		if alert.State == domain.AlertStateFiring || alert.State == "" {
+			notification.Status = domain.AlertStateFiring
+		}
+	}
+
+	text, err := execute(tmpl, t.Renderer(ctx, alerts).data(receiver, notification))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", domain.ErrInvalidTemplate, err)
+	}
+	return text, nil
+}
+
+// Renderer renders notifications with the rules and metrics read by Templates.Renderer.
+type Renderer struct {
+	templates *Templates
+	rules     []domain.Rule
+	metrics   map[string]domain.Metric
+}
+
+// Renderer reads the rules and the metrics of the alerts once, so notifications about any of the alerts can be
+// rendered without further storage queries.
+func (t *Templates) Renderer(ctx context.Context, alerts []domain.Alert) *Renderer {
+	r := &Renderer{templates: t}
+	if t.rules != nil {
+		r.rules, _ = t.rules.ListRules(ctx)
+	}
+	if t.metrics == nil {
+		return r
+	}
+
+	// A metric that can not be read is left out, like the rules, so notifications are still sent during
+	// a storage outage
+	r.metrics = make(map[string]domain.Metric)
+	looked := make(map[string]struct{})
+	for _, alert := range alerts {
+		id, ok := alert.Labels[domain.MetricLabel]
+		if _, seen := looked[id]; !ok || seen {
+			continue
+		}
+		looked[id] = struct{}{}
+
+		for _, metricType := range domain.MetricTypes {
+			metric, found, err := t.metrics.GetMetric(ctx, metricType, id)
+			if err != nil {
+				break
+			}
+			if found {
+				r.metrics[id] = metric
+				break
+			}
+		}
+	}
+
+	return r
+}
+
+// Render renders the notification with the template of the receiver. An empty template renders nothing.
+func (r *Renderer) Render(receiver string, notification domain.Notification) (string, error) {
+	tmpl, ok := r.templates.templates[receiver]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownReceiver, receiver)
+	}
+
+	return execute(tmpl, r.data(receiver, notification))
+}
+
+// Link returns the page of the metric an alert is about, or the alert list for other alerts.
+func (r *Renderer) Link(alert domain.Alert) string {
+	externalURL := r.templates.externalURL
+	if externalURL == "" {
+		return ""
+	}
+
+	if metric, ok := r.metric(alert); ok {
+		return externalURL + "/value/" + string(metric.MType) + "/" + url.PathEscape(metric.ID)
+	}
+
+	if _, ok := alert.Labels[domain.MetricLabel]; ok {
+		return externalURL + "/"
+	}
+
+	return externalURL + "/api/v1/alerts"
+}
+
+func (r *Renderer) data(receiver string, notification domain.Notification) TemplateData {
+	data := TemplateData{
+		Receiver:    receiver,
+		Status:      notification.Status,
+		GroupLabels: notification.GroupLabels,
+		Alerts:      make([]TemplateAlert, len(notification.Alerts)),
+		ExternalURL: r.templates.externalURL,
+	}
+
+	now := r.templates.now()
+	for i, alert := range notification.Alerts {
+		templateAlert := TemplateAlert{
+			Alert:    alert,
+			Duration: alertDuration(alert, now),
+			Link:     r.Link(alert),
+		}
+
+		if metric, ok := r.metric(alert); ok {
+			templateAlert.Metric = &metric
+		}
+
+		if j := slices.IndexFunc(r.rules, func(rule domain.Rule) bool { return rule.Name == alert.Name }); j >= 0 {
+			templateAlert.Rule = &r.rules[j]
+		}
+
+		data.Alerts[i] = templateAlert
+	}
+
+	return data
+}
+
+// metric returns the metric named by the metric label of the alert, if it was read.
+func (r *Renderer) metric(alert domain.Alert) (domain.Metric, bool) {
+	id, ok := alert.Labels[domain.MetricLabel]
+	if !ok {
+		return domain.Metric{}, false
+	}
+
+	metric, ok := r.metrics[id]
+	return metric, ok
+}
+
+// parse parses the template and executes it with sample data, so references to unknown fields are rejected.
+func (t *Templates) parse(receiver, source string) (*template.Template, error) {
+	tmpl, err := template.New(receiver).Funcs(templateFuncs).Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	if _, err := execute(tmpl, t.sampleData(receiver)); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidTemplate, err)
+	}
+
+	return tmpl, nil
+}
+
+func (t *Templates) sampleData(receiver string) TemplateData {
+	now := t.now()
+	value := 95.5
+	rule := domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90", For: domain.Duration(5 * time.Minute)}
+	alert := domain.Alert{
+		Name:        rule.Name,
+		Labels:      map[string]string{domain.MetricLabel: "CPUutilization1", "severity": "critical"},
+		Annotations: map[string]string{"summary": "CPU is busy", "value": "95.5"},
+		State:       domain.AlertStateFiring,
+		StartsAt:    now.Add(-10 * time.Minute),
+	}
+
+	return TemplateData{
+		Receiver:    receiver,
+		Status:      domain.AlertStateFiring,
+		GroupLabels: map[string]string{domain.AlertNameLabel: alert.Name},
+		Alerts: []TemplateAlert{{
+			Alert:    alert,
+			Rule:     &rule,
+			Metric:   &domain.Metric{ID: "CPUutilization1", MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: now},
+			Duration: 10 * time.Minute,
+			Link:     t.externalURL + "/value/gauge/CPUutilization1",
+		}},
+		ExternalURL: t.externalURL,
+	}
+}
+
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func alertDuration(alert domain.Alert, now time.Time) time.Duration {
+	if alert.StartsAt.IsZero() {
+		return 0
+	}
+	if !alert.EndsAt.IsZero() {
+		return alert.EndsAt.Sub(alert.StartsAt)
+	}
+	return now.Sub(alert.StartsAt)
+}
+
+// formatLabels formats labels ordered by name, as in {agent="a1",severity="critical"}.
+func formatLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		parts = append(parts, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,181 @@
+package alerting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/rules"
+)
+
+func TestNewTemplates_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string]string
+		wantErr error
+	}{
+		{name: "defaults"},
+		{name: "custom", sources: map[string]string{ReceiverLog: `{{range .Firing}}{{.Name}} {{with .Rule}}{{.Expr}}{{end}}{{end}}`}},
+		{name: "empty disables text", sources: map[string]string{ReceiverAlertmanager: ""}},
+		{name: "syntax error", sources: map[string]string{ReceiverLog: `{{range .Alerts}}`}, wantErr: domain.ErrInvalidTemplate},
+		{name: "unknown field", sources: map[string]string{ReceiverLog: `{{.Severity}}`}, wantErr: domain.ErrInvalidTemplate},
+		{name: "unknown function", sources: map[string]string{ReceiverLog: `{{humanize .Status}}`}, wantErr: domain.ErrInvalidTemplate},
+		{name: "unknown receiver", sources: map[string]string{"pager": `{{.Status}}`}, wantErr: domain.ErrUnknownReceiver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTemplates(tt.sources, "http://metrics.local", nil, nil)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTemplates_Render(t *testing.T) {
+	ctx := context.Background()
+	metrics := metricstorage.NewMemoryMetricStorage()
+	value := 97.0
+	require.NoError(t, metrics.UpdateMetric(ctx, domain.Metric{ID: "CPUutilization1", MType: domain.MetricTypeGauge, Value: &value}))
+
+	ruleStore := rules.NewMemoryStorage()
+	require.NoError(t, ruleStore.SaveRule(ctx, domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90", For: domain.Duration(time.Minute)}))
+
+	templates, err := NewTemplates(nil, "http://metrics.local/", metrics, ruleStore)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 10, 0, 0, time.UTC)
+	templates.now = func() time.Time { return now }
+
+	notification := domain.Notification{
+		GroupLabels: map[string]string{domain.AlertNameLabel: "HighCPU"},
+		Status:      domain.AlertStateFiring,
+		Alerts: []domain.Alert{
+			{
+				Name:        "HighCPU",
+				Labels:      map[string]string{domain.MetricLabel: "CPUutilization1"},
+				Annotations: map[string]string{"summary": "CPU is busy"},
+				State:       domain.AlertStateFiring,
+				StartsAt:    now.Add(-5 * time.Minute),
+			},
+			{
+				Name:     "AgentAbsent",
+				Labels:   map[string]string{"agent": "a1"},
+				State:    domain.AlertStateResolved,
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(-30 * time.Minute),
+			},
+		},
+	}
+
+	text, err := templates.Render(ctx, ReceiverLog, notification)
+	require.NoError(t, err)
+	assert.Equal(t, `[FIRING] {alertname="HighCPU"}
+HighCPU {metric="CPUutilization1"} is firing for 5m0s, CPUutilization1 = 97 (CPUutilization* > 90): CPU is busy http://metrics.local/value/gauge/CPUutilization1
+AgentAbsent {agent="a1"} is resolved for 30m0s http://metrics.local/api/v1/alerts`, text)
+
+	_, err = templates.Render(ctx, "pager", notification)
+	assert.ErrorIs(t, err, domain.ErrUnknownReceiver)
+}
+
+// countingMetrics counts the metric lookups.
+type countingMetrics struct {
+	*metricstorage.MemoryMetricStorage
+	reads int
+}
+
+func (s *countingMetrics) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	s.reads++
+	return s.MemoryMetricStorage.GetMetric(ctx, metricType, metricName)
+}
+
+// countingRules counts the rule listings.
+type countingRules struct {
+	domain.RuleStorage
+	lists int
+}
+
+func (s *countingRules) ListRules(ctx context.Context) ([]domain.Rule, error) {
+	s.lists++
+	return s.RuleStorage.ListRules(ctx)
+}
+
+func TestTemplates_RendererReadsOnce(t *testing.T) {
+	ctx := context.Background()
+	metrics := &countingMetrics{MemoryMetricStorage: metricstorage.NewMemoryMetricStorage()}
+	value, delta := 97.0, int64(5)
+	require.NoError(t, metrics.UpdateMetrics(ctx, []domain.Metric{
+		{ID: "CPUutilization1", MType: domain.MetricTypeGauge, Value: &value},
+		{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: &delta},
+	}))
+	ruleStore := &countingRules{RuleStorage: rules.NewMemoryStorage()}
+	require.NoError(t, ruleStore.SaveRule(ctx, domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90"}))
+
+	templates, err := NewTemplates(nil, "http://metrics.local", metrics, ruleStore)
+	require.NoError(t, err)
+
+	alerts := []domain.Alert{
+		{Name: "HighCPU", Labels: map[string]string{domain.MetricLabel: "CPUutilization1"}, State: domain.AlertStateFiring},
+		{Name: "HighCPU", Labels: map[string]string{domain.MetricLabel: "CPUutilization1", "agent": "a2"}, State: domain.AlertStateFiring},
+		{Name: "PollStalled", Labels: map[string]string{domain.MetricLabel: "PollCount"}, State: domain.AlertStateFiring},
+	}
+	renderer := templates.Renderer(ctx, alerts)
+	for _, alert := range alerts {
+		_, err := renderer.Render(ReceiverAlertmanager, domain.Notification{Status: alert.State, Alerts: []domain.Alert{alert}})
+		require.NoError(t, err)
+		renderer.Link(alert)
+	}
+
+	assert.Equal(t, 1, ruleStore.lists, "the rules are listed once")
+	assert.Equal(t, 3, metrics.reads, "every metric is looked up once, trying the metric types in turn")
+
+	text, err := renderer.Render(ReceiverAlertmanager, domain.Notification{Status: domain.AlertStateFiring, Alerts: alerts[2:]})
+	require.NoError(t, err)
+	assert.Equal(t, "PollStalled is firing: PollCount = 5", text)
+	assert.Equal(t, "http://metrics.local/value/counter/PollCount", renderer.Link(alerts[2]))
+}
+
+func TestTemplates_Preview(t *testing.T) {
+	ctx := context.Background()
+	templates, err := NewTemplates(nil, "http://metrics.local", metricstorage.NewMemoryMetricStorage(), nil)
+	require.NoError(t, err)
+
+	text, err := templates.Preview(ctx, ReceiverAlertmanager, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "HighCPU is firing: CPUutilization1 = 95.5, rule CPUutilization* > 90 for 5m0s", text)
+
+	text, err = templates.Preview(ctx, ReceiverLog, `{{range .Alerts}}{{.Name}}/{{.Labels.agent}}{{end}}`, []domain.Alert{
+		{Name: "AgentAbsent", Labels: map[string]string{"agent": "a1"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "AgentAbsent/a1", text)
+
+	_, err = templates.Preview(ctx, ReceiverLog, `{{range .Alerts}}{{.Rule.Expr}}{{end}}`, []domain.Alert{{Name: "AgentAbsent"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidTemplate, "rules are nil for alerts not raised by a rule")
+
+	_, err = templates.Preview(ctx, ReceiverLog, `{{end}}`, nil)
+	assert.ErrorIs(t, err, domain.ErrInvalidTemplate)
+}
+
+func TestLoadTemplateSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"log": "{{.Status}}"}`), 0o644))
+
+	sources, err := LoadTemplateSources(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{ReceiverLog: "{{.Status}}"}, sources)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o644))
+	_, err = LoadTemplateSources(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// webhookTimeout limits a single webhook request.
+const webhookTimeout = 10 * time.Second
+
+// webhookPayload is the JSON body posted to webhook receivers.
+type webhookPayload struct {
+	Receiver    string            `json:"receiver"`
+	Status      domain.AlertState `json:"status"`
+	GroupKey    string            `json:"group_key"`
+	GroupLabels map[string]string `json:"group_labels"`
+	Alerts      []domain.Alert    `json:"alerts"`
+	Text        string            `json:"text"`
+}
+
+// WebhookNotifier posts notifications of a receiver as JSON, with the text rendered by the webhook receiver template.
+type WebhookNotifier struct {
+	receiver  string
+	url       string
+	templates *Templates
+	client    *http.Client
+	logger    *zerolog.Logger
+}
+
+var _ domain.AlertNotifier = (*WebhookNotifier)(nil)
+
+func NewWebhookNotifier(receiver string, url string, templates *Templates, logger *zerolog.Logger) *WebhookNotifier {
+	return &WebhookNotifier{
+		receiver:  receiver,
+		url:       url,
+		templates: templates,
+		client:    &http.Client{Timeout: webhookTimeout},
+		logger:    logger,
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	// A broken template must not block the notification, it is sent without text instead
+	text, err := n.templates.Render(ctx, ReceiverWebhook, notification)
+	if err != nil {
+		n.logger.Error().Err(err).Str("receiver", n.receiver).Msg("failed to render webhook notification template")
+	}
+
+	data, err := json.Marshal(webhookPayload{
+		Receiver:    n.receiver,
+		Status:      notification.Status,
+		GroupKey:    notification.GroupKey,
+		GroupLabels: notification.GroupLabels,
+		Alerts:      notification.Alerts,
+		Text:        text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification to %s: %w", n.receiver, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook of %s responded with %s: %s", n.receiver, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
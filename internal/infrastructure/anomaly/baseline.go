@@ -0,0 +1,35 @@
+package anomaly
+
+import "math"
+
+// baseline is an exponentially weighted moving mean and variance of a series.
+type baseline struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+func (b *baseline) observe(value, alpha float64) {
+	b.samples++
+	if b.samples == 1 {
+		b.mean = value
+		return
+	}
+
+	diff := value - b.mean
+	incr := alpha * diff
+	b.mean += incr
+	b.variance = (1 - alpha) * (b.variance + diff*incr)
+}
+
+func (b *baseline) stdDev() float64 {
+	return math.Sqrt(b.variance)
+}
+
+// zScore returns the distance of the value from the mean in standard deviations.
+// The deviation has a small floor relative to the mean, so that tiny changes of
+// an otherwise constant gauge are not reported as infinitely anomalous.
+func (b *baseline) zScore(value float64) float64 {
+	floor := 1e-6 * max(math.Abs(b.mean), 1)
+	return math.Abs(value-b.mean) / max(b.stdDev(), floor)
+}
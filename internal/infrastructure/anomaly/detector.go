@@ -0,0 +1,317 @@
+package anomaly
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// AlertMetricAnomaly is the name of the alert raised for anomalous gauge values.
+const AlertMetricAnomaly = "MetricAnomaly"
+
+// seasonBuckets splits the seasonal baseline by hour of day.
+const seasonBuckets = 24
+
+// Options configures the Detector.
+type Options struct {
+	// Threshold is the number of standard deviations a value may deviate from the mean.
+	Threshold float64
+	// Alpha is the EWMA smoothing factor, higher values adapt faster.
+	Alpha float64
+	// Seasonal additionally keeps a baseline per hour of day and prefers it once warmed up.
+	Seasonal bool
+}
+
+type series struct {
+	overall  baseline
+	seasonal []baseline
+	anomaly  *domain.Anomaly
+}
+
+// Detector is a domain.MetricStorage decorator maintaining an EWMA baseline of every gauge
+// and raising an alert when an ingested value deviates from it by more than the threshold.
+type Detector struct {
+	storage   domain.MetricStorage
+	opts      Options
+	warmup    int
+	publisher domain.AlertPublisher
+	logger    *zerolog.Logger
+	mu        sync.Mutex
+	series    map[string]*series
+}
+
+var _ domain.MetricStorage = (*Detector)(nil)
+var _ domain.AnomalyReporter = (*Detector)(nil)
+var _ domain.MetricReplica = (*Detector)(nil)
+var _ domain.StaleMetricDeleter = (*Detector)(nil)
+
+func New(storage domain.MetricStorage, opts Options, publisher domain.AlertPublisher, logger *zerolog.Logger) *Detector {
+	return &Detector{
+		storage: storage,
+		opts:    opts,
+		// An EWMA roughly averages the last 2/alpha samples, so the baseline is trusted after that many
+		warmup:    int(math.Ceil(2 / opts.Alpha)),
+		publisher: publisher,
+		logger:    logger,
+		series:    make(map[string]*series),
+	}
+}
+
+func (d *Detector) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	return d.storage.GetAllMetrics(ctx)
+}
+
+func (d *Detector) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	return d.storage.GetMetric(ctx, metricType, metricName)
+}
+
+func (d *Detector) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	if err := d.storage.UpdateMetric(ctx, metric); err != nil {
+		return err
+	}
+
+	d.observe(ctx, []domain.Metric{metric})
+	return nil
+}
+
+func (d *Detector) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	if err := d.storage.UpdateMetrics(ctx, metrics); err != nil {
+		return err
+	}
+
+	d.observe(ctx, metrics)
+	return nil
+}
+
+func (d *Detector) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	found, err := d.storage.DeleteMetric(ctx, metricType, metricName)
+	if err != nil {
+		return false, err
+	}
+
+	if metricType == domain.MetricTypeGauge {
+		d.forget(ctx, func(id string) bool { return id == metricName })
+	}
+
+	return found, nil
+}
+
+func (d *Detector) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	deleter, ok := d.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		return false, domain.ErrNotStaleDeleter
+	}
+
+	found, err := deleter.DeleteMetricIfStale(ctx, metricType, metricName, before)
+	if err != nil {
+		return false, err
+	}
+
+	if found && metricType == domain.MetricTypeGauge {
+		d.forget(ctx, func(id string) bool { return id == metricName })
+	}
+
+	return found, nil
+}
+
+func (d *Detector) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted, err := d.storage.DeleteByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	d.forget(ctx, func(id string) bool { return strings.HasPrefix(id, prefix) })
+
+	return deleted, nil
+}
+
+func (d *Detector) Ping(ctx context.Context) error {
+	return d.storage.Ping(ctx)
+}
+
+// Merge checks the gauge values replicated from peers like ingested ones.
+func (d *Detector) Merge(ctx context.Context, metrics []domain.ReplicatedMetric) ([]domain.MetricChange, error) {
+	replica, ok := d.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+
+	changes, err := replica.Merge(ctx, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []domain.Metric
+	deleted := make(map[string]bool)
+	for _, change := range changes {
+		switch {
+		case change.Metric.MType != domain.MetricTypeGauge:
+		case change.Deleted:
+			deleted[change.Metric.ID] = true
+		default:
+			updated = append(updated, change.Metric)
+		}
+	}
+
+	d.observe(ctx, updated)
+	if len(deleted) > 0 {
+		d.forget(ctx, func(id string) bool { return deleted[id] })
+	}
+
+	return changes, nil
+}
+
+func (d *Detector) ReplicatedMetrics(ctx context.Context) ([]domain.ReplicatedMetric, error) {
+	replica, ok := d.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+	return replica.ReplicatedMetrics(ctx)
+}
+
+// Anomalies returns the series whose latest value is anomalous, the most deviating first.
+func (d *Detector) Anomalies() []domain.Anomaly {
+	d.mu.Lock()
+	anomalies := make([]domain.Anomaly, 0)
+	for _, s := range d.series {
+		if s.anomaly != nil {
+			anomalies = append(anomalies, *s.anomaly)
+		}
+	}
+	d.mu.Unlock()
+
+	slices.SortFunc(anomalies, func(a, b domain.Anomaly) int {
+		if c := cmp.Compare(b.ZScore, a.ZScore); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.MetricID, b.MetricID)
+	})
+
+	return anomalies
+}
+
+func (d *Detector) observe(ctx context.Context, metrics []domain.Metric) {
+	var fired, resolved []domain.Alert
+
+	d.mu.Lock()
+	for _, metric := range metrics {
+		if metric.MType != domain.MetricTypeGauge || metric.Value == nil {
+			continue
+		}
+
+		at := metric.UpdatedAt
+		if at.IsZero() {
+			at = time.Now()
+		}
+
+		s, ok := d.series[metric.ID]
+		if !ok {
+			s = &series{}
+			if d.opts.Seasonal {
+				s.seasonal = make([]baseline, seasonBuckets)
+			}
+			d.series[metric.ID] = s
+		}
+
+		anomaly := d.check(s, metric.ID, *metric.Value, at)
+		switch {
+		case anomaly != nil:
+			s.anomaly = anomaly
+			fired = append(fired, anomalyAlert(*anomaly))
+		case s.anomaly != nil:
+			s.anomaly = nil
+			resolved = append(resolved, anomalyAlert(domain.Anomaly{MetricID: metric.ID}))
+		}
+
+		s.overall.observe(*metric.Value, d.opts.Alpha)
+		if d.opts.Seasonal {
+			s.seasonal[at.UTC().Hour()].observe(*metric.Value, d.opts.Alpha)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, alert := range fired {
+		d.publisher.Fire(ctx, alert)
+	}
+	for _, alert := range resolved {
+		d.publisher.Resolve(ctx, alert)
+	}
+}
+
+// check compares the value with the series baseline before the value is added to it.
+func (d *Detector) check(s *series, id string, value float64, at time.Time) *domain.Anomaly {
+	base := &s.overall
+	if d.opts.Seasonal {
+		if seasonal := &s.seasonal[at.UTC().Hour()]; seasonal.samples >= d.warmup {
+			base = seasonal
+		}
+	}
+
+	if base.samples < d.warmup {
+		return nil
+	}
+
+	z := base.zScore(value)
+	if z <= d.opts.Threshold {
+		return nil
+	}
+
+	d.logger.Debug().Str("metric_id", id).Float64("value", value).Float64("zscore", z).Msg("anomalous gauge value")
+
+	return &domain.Anomaly{
+		MetricID:   id,
+		Value:      value,
+		Mean:       base.mean,
+		StdDev:     base.stdDev(),
+		ZScore:     z,
+		DetectedAt: at,
+	}
+}
+
+func (d *Detector) forget(ctx context.Context, match func(id string) bool) {
+	var resolved []domain.Alert
+
+	d.mu.Lock()
+	for id, s := range d.series {
+		if !match(id) {
+			continue
+		}
+		if s.anomaly != nil {
+			resolved = append(resolved, anomalyAlert(*s.anomaly))
+		}
+		delete(d.series, id)
+	}
+	d.mu.Unlock()
+
+	for _, alert := range resolved {
+		d.publisher.Resolve(ctx, alert)
+	}
+}
+
+func anomalyAlert(anomaly domain.Anomaly) domain.Alert {
+	alert := domain.Alert{
+		Name:   AlertMetricAnomaly,
+		Labels: map[string]string{"metric": anomaly.MetricID},
+	}
+
+	if !anomaly.DetectedAt.IsZero() {
+		alert.StartsAt = anomaly.DetectedAt
+		alert.Annotations = map[string]string{
+			"summary": fmt.Sprintf("gauge %s is %.1f standard deviations from its mean", anomaly.MetricID, anomaly.ZScore),
+			"value":   fmt.Sprintf("%g", anomaly.Value),
+			"mean":    fmt.Sprintf("%g", anomaly.Mean),
+			"stddev":  fmt.Sprintf("%g", anomaly.StdDev),
+		}
+	}
+
+	return alert
+}
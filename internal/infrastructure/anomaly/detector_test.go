@@ -0,0 +1,149 @@
+package anomaly
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+type recordingPublisher struct {
+	fired    []domain.Alert
+	resolved []domain.Alert
+}
+
+func (p *recordingPublisher) Fire(_ context.Context, alert domain.Alert) {
+	p.fired = append(p.fired, alert)
+}
+
+func (p *recordingPublisher) Resolve(_ context.Context, alert domain.Alert) {
+	p.resolved = append(p.resolved, alert)
+}
+
+func gauge(id string, value float64, at time.Time) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: at}
+}
+
+// feed stores alternating values around the mean, one per minute starting at the given time.
+func feed(t *testing.T, detector *Detector, id string, mean float64, count int, start time.Time) time.Time {
+	for i := range count {
+		value := mean + 1
+		if i%2 == 0 {
+			value = mean - 1
+		}
+		require.NoError(t, detector.UpdateMetric(context.Background(), gauge(id, value, start)))
+		start = start.Add(time.Minute)
+	}
+	return start
+}
+
+func TestBaseline(t *testing.T) {
+	b := baseline{}
+	for _, v := range []float64{10, 10, 10, 10} {
+		b.observe(v, 0.5)
+	}
+
+	assert.Equal(t, 10.0, b.mean)
+	assert.Zero(t, b.variance)
+	assert.Equal(t, 4, b.samples)
+	assert.InDelta(t, 10, b.zScore(10.0001), 1e-6, "deviation has a floor for constant series")
+
+	b.observe(20, 0.5)
+	assert.Equal(t, 15.0, b.mean)
+	assert.Equal(t, 25.0, b.variance)
+	assert.Equal(t, 1.0, b.zScore(20))
+}
+
+func TestDetector_FiresAndResolves(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	detector := New(metricstorage.NewMemoryMetricStorage(), Options{Threshold: 3, Alpha: 0.1}, publisher, &logger)
+
+	now := feed(t, detector, "HeapInuse", 100, 30, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Empty(t, detector.Anomalies())
+	assert.Empty(t, publisher.fired)
+
+	require.NoError(t, detector.UpdateMetric(ctx, gauge("HeapInuse", 150, now)))
+
+	anomalies := detector.Anomalies()
+	require.Len(t, anomalies, 1)
+	assert.Equal(t, "HeapInuse", anomalies[0].MetricID)
+	assert.Equal(t, 150.0, anomalies[0].Value)
+	assert.Greater(t, anomalies[0].ZScore, 3.0)
+	assert.Equal(t, now, anomalies[0].DetectedAt)
+
+	require.Len(t, publisher.fired, 1)
+	assert.Equal(t, AlertMetricAnomaly, publisher.fired[0].Name)
+	assert.Equal(t, map[string]string{"metric": "HeapInuse"}, publisher.fired[0].Labels)
+
+	require.NoError(t, detector.UpdateMetric(ctx, gauge("HeapInuse", 105, now.Add(time.Minute))))
+
+	assert.Empty(t, detector.Anomalies())
+	require.Len(t, publisher.resolved, 1)
+	assert.Equal(t, publisher.fired[0].Fingerprint(), publisher.resolved[0].Fingerprint())
+}
+
+func TestDetector_WarmupAndCounters(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	detector := New(metricstorage.NewMemoryMetricStorage(), Options{Threshold: 3, Alpha: 0.1}, publisher, &logger)
+
+	now := feed(t, detector, "Alloc", 100, 10, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, detector.UpdateMetric(ctx, gauge("Alloc", 1000, now)))
+
+	delta := int64(1_000_000)
+	require.NoError(t, detector.UpdateMetric(ctx, domain.Metric{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: &delta}))
+
+	assert.Empty(t, detector.Anomalies())
+	assert.Empty(t, publisher.fired)
+}
+
+func TestDetector_Seasonal(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	detector := New(metricstorage.NewMemoryMetricStorage(), Options{Threshold: 3, Alpha: 0.5, Seasonal: true}, publisher, &logger)
+
+	// Nightly values are low, daytime values are high. The first day warms up the hourly baselines.
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		if i == 1 {
+			publisher.fired = nil
+		}
+		feed(t, detector, "GCCPUFraction", 10, 4, day.Add(2*time.Hour))
+		feed(t, detector, "GCCPUFraction", 100, 4, day.Add(14*time.Hour))
+		day = day.Add(24 * time.Hour)
+	}
+	require.Empty(t, publisher.fired, "both levels are normal for their hour")
+
+	require.NoError(t, detector.UpdateMetric(ctx, gauge("GCCPUFraction", 100, day.Add(2*time.Hour))))
+	require.Len(t, publisher.fired, 1, "a daytime value at night is anomalous")
+}
+
+func TestDetector_DeleteForgetsSeries(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	detector := New(metricstorage.NewMemoryMetricStorage(), Options{Threshold: 3, Alpha: 0.1}, publisher, &logger)
+
+	now := feed(t, detector, "CPUutilization1", 50, 30, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, detector.UpdateMetric(ctx, gauge("CPUutilization1", math.MaxFloat32, now)))
+	require.Len(t, detector.Anomalies(), 1)
+
+	deleted, err := detector.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	assert.Empty(t, detector.Anomalies())
+	assert.Len(t, publisher.resolved, 1)
+	assert.Empty(t, detector.series)
+}
@@ -0,0 +1,414 @@
+package cardinalitylimiter
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Limits configures the maximum number of series accepted by LimitedMetricStorage. Zero disables a limit.
+type Limits struct {
+	MaxSeries          int
+	MaxSeriesPerPrefix int
+	MaxSeriesPerTenant int
+}
+
+type seriesKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+// reservation counts the updates in flight that rely on a new series, it is released when the last of them fails.
+type reservation struct {
+	updates int
+}
+
+// reserved is a new series an update relies on until it is applied.
+type reserved struct {
+	key         seriesKey
+	reservation *reservation
+}
+
+// LimitedMetricStorage is a domain.MetricStorage decorator rejecting updates that would create new series
+// above the configured global, per-prefix and per-tenant limits. Updates of already known series are always accepted.
+// A series counts against the tenant of the update that created it, see domain.WithTenant. Series without a tenant,
+// like the ones already stored or replicated from peers, only count against the other limits. A new series counts while it is written, so concurrent updates reserve it once, and is released again only if
+// none of the updates writing it succeeds.
+type LimitedMetricStorage struct {
+	storage  domain.MetricStorage
+	limits   Limits
+	logger   *zerolog.Logger
+	mu       sync.Mutex
+	series   map[seriesKey]string
+	pending  map[seriesKey]*reservation
+	prefixes map[string]int
+	tenants  map[string]int
+	rejected map[string]int64
+
+	rejectedTenants map[string]int64
+}
+
+var _ domain.MetricStorage = (*LimitedMetricStorage)(nil)
+var _ domain.CardinalityReporter = (*LimitedMetricStorage)(nil)
+var _ domain.MetricReplica = (*LimitedMetricStorage)(nil)
+var _ domain.StoredStateUpdater = (*LimitedMetricStorage)(nil)
+var _ domain.StaleMetricDeleter = (*LimitedMetricStorage)(nil)
+
+// New reads the series already in the storage, so they stay accepted above the limits.
+func New(storage domain.MetricStorage, limits Limits, logger *zerolog.Logger) (*LimitedMetricStorage, error) {
+	s := &LimitedMetricStorage{
+		storage:  storage,
+		limits:   limits,
+		logger:   logger,
+		series:   make(map[seriesKey]string),
+		pending:  make(map[seriesKey]*reservation),
+		prefixes: make(map[string]int),
+		tenants:  make(map[string]int),
+		rejected: make(map[string]int64),
+
+		rejectedTenants: make(map[string]int64),
+	}
+
+	metrics, err := storage.GetAllMetrics(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read existing series: %w", err)
+	}
+	for _, metric := range metrics {
+		s.track(seriesKey{mType: metric.MType, id: metric.ID}, "")
+	}
+
+	return s, nil
+}
+
+func (s *LimitedMetricStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	return s.storage.GetAllMetrics(ctx)
+}
+
+func (s *LimitedMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	return s.storage.GetMetric(ctx, metricType, metricName)
+}
+
+func (s *LimitedMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	return s.UpdateMetrics(ctx, []domain.Metric{metric})
+}
+
+func (s *LimitedMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	return s.update(ctx, metrics, func() error {
+		if len(metrics) == 1 {
+			return s.storage.UpdateMetric(ctx, metrics[0])
+		}
+		return s.storage.UpdateMetrics(ctx, metrics)
+	})
+}
+
+func (s *LimitedMetricStorage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	updater, ok := s.storage.(domain.StoredStateUpdater)
+	if !ok {
+		return nil, domain.ErrNotStoredState
+	}
+
+	var stored []domain.Metric
+	err := s.update(ctx, metrics, func() error {
+		var err error
+		stored, err = updater.UpdateMetricsStored(ctx, metrics)
+		return err
+	})
+	return stored, err
+}
+
+// update admits the new series of the batch for the tenant of the context and applies it, releasing the new series
+// again if that fails.
+func (s *LimitedMetricStorage) update(ctx context.Context, metrics []domain.Metric, apply func() error) error {
+	s.mu.Lock()
+	newSeries, err := s.admit(metrics, domain.TenantFromContext(ctx))
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = apply()
+
+	s.mu.Lock()
+	s.settle(newSeries, err == nil)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *LimitedMetricStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	found, err := s.storage.DeleteMetric(ctx, metricType, metricName)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.untrack(seriesKey{mType: metricType, id: metricName})
+	s.mu.Unlock()
+
+	return found, nil
+}
+
+func (s *LimitedMetricStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	deleter, ok := s.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		return false, domain.ErrNotStaleDeleter
+	}
+
+	deleted, err := deleter.DeleteMetricIfStale(ctx, metricType, metricName, before)
+	if err != nil || !deleted {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.untrack(seriesKey{mType: metricType, id: metricName})
+	s.mu.Unlock()
+
+	return true, nil
+}
+
+func (s *LimitedMetricStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted, err := s.storage.DeleteByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	for key := range s.series {
+		if strings.HasPrefix(key.id, prefix) {
+			s.untrack(key)
+		}
+	}
+	s.mu.Unlock()
+
+	return deleted, nil
+}
+
+func (s *LimitedMetricStorage) Ping(ctx context.Context) error {
+	return s.storage.Ping(ctx)
+}
+
+// Merge tracks the series replicated from peers without limiting them, all servers of a cluster hold the same series.
+func (s *LimitedMetricStorage) Merge(ctx context.Context, metrics []domain.ReplicatedMetric) ([]domain.MetricChange, error) {
+	replica, ok := s.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+
+	changes, err := replica.Merge(ctx, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	for _, change := range changes {
+		key := seriesKey{mType: change.Metric.MType, id: change.Metric.ID}
+		if change.Deleted {
+			s.untrack(key)
+		} else {
+			delete(s.pending, key)
+			s.track(key, "")
+		}
+	}
+	s.mu.Unlock()
+
+	return changes, nil
+}
+
+func (s *LimitedMetricStorage) ReplicatedMetrics(ctx context.Context) ([]domain.ReplicatedMetric, error) {
+	replica, ok := s.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+	return replica.ReplicatedMetrics(ctx)
+}
+
+// CardinalityReport returns the current series counts and the top prefixes and tenants ordered by series count.
+// A non-positive top returns all prefixes and tenants.
+func (s *LimitedMetricStorage) CardinalityReport(top int) domain.CardinalityReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := domain.CardinalityReport{
+		TotalSeries:        len(s.series),
+		MaxSeries:          s.limits.MaxSeries,
+		MaxSeriesPerPrefix: s.limits.MaxSeriesPerPrefix,
+		MaxSeriesPerTenant: s.limits.MaxSeriesPerTenant,
+		TopPrefixes:        []domain.PrefixCardinality{},
+		TopTenants:         []domain.TenantCardinality{},
+	}
+
+	for _, rejected := range s.rejected {
+		report.RejectedTotal += rejected
+	}
+	for _, prefix := range rank(s.prefixes, s.rejected, top) {
+		report.TopPrefixes = append(report.TopPrefixes, domain.PrefixCardinality{
+			Prefix:   prefix,
+			Series:   s.prefixes[prefix],
+			Rejected: s.rejected[prefix],
+		})
+	}
+	for _, tenant := range rank(s.tenants, s.rejectedTenants, top) {
+		report.TopTenants = append(report.TopTenants, domain.TenantCardinality{
+			Tenant:   tenant,
+			Series:   s.tenants[tenant],
+			Rejected: s.rejectedTenants[tenant],
+		})
+	}
+
+	return report
+}
+
+// rank returns the names with series or rejected series ordered by the series count, then by the rejected count,
+// limited to the top ones unless top is not positive.
+func rank(series map[string]int, rejected map[string]int64, top int) []string {
+	names := make([]string, 0, len(series))
+	for name := range series {
+		names = append(names, name)
+	}
+	for name := range rejected {
+		if _, ok := series[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.SortFunc(names, func(a, b string) int {
+		if c := cmp.Compare(series[b], series[a]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(rejected[b], rejected[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	if top > 0 && len(names) > top {
+		names = names[:top]
+	}
+
+	return names
+}
+
+// admit checks the whole batch against the limits and reserves the series it creates, joining the reservations of
+// new series other updates are still writing. The batch is rejected as a whole if any of its new series does not fit.
+// It must be called with mu held.
+func (s *LimitedMetricStorage) admit(metrics []domain.Metric, tenant string) ([]reserved, error) {
+	var newSeries []reserved
+
+	for _, metric := range metrics {
+		key := seriesKey{mType: metric.MType, id: metric.ID}
+		if r, ok := s.pending[key]; ok {
+			r.updates++
+			newSeries = append(newSeries, reserved{key: key, reservation: r})
+			continue
+		}
+		if _, ok := s.series[key]; ok {
+			continue
+		}
+
+		prefix := domain.MetricPrefix(metric.ID)
+		err := s.checkLimits(prefix, tenant)
+		if err != nil {
+			s.settle(newSeries, false)
+			return nil, s.reject(metric, prefix, tenant, err)
+		}
+
+		s.track(key, tenant)
+		r := &reservation{updates: 1}
+		s.pending[key] = r
+		newSeries = append(newSeries, reserved{key: key, reservation: r})
+	}
+
+	return newSeries, nil
+}
+
+// settle ends the reservations of an update. A stored series is known from then on, a series is released when the
+// last update relying on it fails. Reservations that ended meanwhile, as the series was stored or deleted, are left
+// alone. It must be called with mu held.
+func (s *LimitedMetricStorage) settle(newSeries []reserved, stored bool) {
+	for _, series := range newSeries {
+		if s.pending[series.key] != series.reservation {
+			continue
+		}
+		if stored {
+			delete(s.pending, series.key)
+			continue
+		}
+
+		series.reservation.updates--
+		if series.reservation.updates == 0 {
+			s.untrack(series.key)
+		}
+	}
+}
+
+func (s *LimitedMetricStorage) checkLimits(prefix, tenant string) error {
+	if s.limits.MaxSeries > 0 && len(s.series) >= s.limits.MaxSeries {
+		return fmt.Errorf("%w: global limit of %d series reached", domain.ErrCardinalityLimitExceeded, s.limits.MaxSeries)
+	}
+
+	if s.limits.MaxSeriesPerPrefix > 0 && s.prefixes[prefix] >= s.limits.MaxSeriesPerPrefix {
+		return fmt.Errorf("%w: limit of %d series for prefix %q reached", domain.ErrCardinalityLimitExceeded, s.limits.MaxSeriesPerPrefix, prefix)
+	}
+
+	if tenant != "" && s.limits.MaxSeriesPerTenant > 0 && s.tenants[tenant] >= s.limits.MaxSeriesPerTenant {
+		return fmt.Errorf("%w: limit of %d series for tenant %q reached", domain.ErrCardinalityLimitExceeded, s.limits.MaxSeriesPerTenant, tenant)
+	}
+
+	return nil
+}
+
+func (s *LimitedMetricStorage) reject(metric domain.Metric, prefix, tenant string, err error) error {
+	s.rejected[prefix]++
+	if tenant != "" {
+		s.rejectedTenants[tenant]++
+	}
+	s.logger.Warn().
+		Str("metric_id", metric.ID).
+		Str("type", string(metric.MType)).
+		Str("prefix", prefix).
+		Str("tenant", tenant).
+		Msg("rejected new series")
+
+	return fmt.Errorf("series %s (%s) rejected: %w", metric.ID, metric.MType, err)
+}
+
+func (s *LimitedMetricStorage) track(key seriesKey, tenant string) {
+	if _, ok := s.series[key]; ok {
+		return
+	}
+	s.series[key] = tenant
+	s.prefixes[domain.MetricPrefix(key.id)]++
+	if tenant != "" {
+		s.tenants[tenant]++
+	}
+}
+
+func (s *LimitedMetricStorage) untrack(key seriesKey) {
+	tenant, ok := s.series[key]
+	if !ok {
+		return
+	}
+	delete(s.series, key)
+	delete(s.pending, key)
+
+	if tenant != "" {
+		s.tenants[tenant]--
+		if s.tenants[tenant] <= 0 {
+			delete(s.tenants, tenant)
+		}
+	}
+
+	prefix := domain.MetricPrefix(key.id)
+	s.prefixes[prefix]--
+	if s.prefixes[prefix] <= 0 {
+		delete(s.prefixes, prefix)
+	}
+}
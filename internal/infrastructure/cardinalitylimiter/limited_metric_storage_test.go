@@ -0,0 +1,246 @@
+package cardinalitylimiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func TestLimitedMetricStorage_UpdateMetrics(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+
+	tests := []struct {
+		name          string
+		limits        Limits
+		setupData     []domain.Metric
+		batch         []domain.Metric
+		expectError   bool
+		expectedTotal int
+	}{
+		{
+			name:          "no limits",
+			limits:        Limits{},
+			batch:         []domain.Metric{gauge("a", 1), gauge("b", 2), counter("c", 3)},
+			expectedTotal: 3,
+		},
+		{
+			name:          "global limit reached",
+			limits:        Limits{MaxSeries: 2},
+			setupData:     []domain.Metric{gauge("a", 1), gauge("b", 2)},
+			batch:         []domain.Metric{gauge("c", 3)},
+			expectError:   true,
+			expectedTotal: 2,
+		},
+		{
+			name:          "known series accepted above limit",
+			limits:        Limits{MaxSeries: 2},
+			setupData:     []domain.Metric{gauge("a", 1), gauge("b", 2)},
+			batch:         []domain.Metric{gauge("a", 3), gauge("b", 4)},
+			expectedTotal: 2,
+		},
+		{
+			name:          "prefix limit reached",
+			limits:        Limits{MaxSeriesPerPrefix: 2},
+			batch:         []domain.Metric{gauge("CPUutilization1", 1), gauge("CPUutilization2", 2), gauge("CPUutilization3", 3)},
+			expectError:   true,
+			expectedTotal: 0,
+		},
+		{
+			name:          "other prefixes are not affected",
+			limits:        Limits{MaxSeriesPerPrefix: 1},
+			setupData:     []domain.Metric{gauge("CPUutilization1", 1)},
+			batch:         []domain.Metric{gauge("HeapInuse", 1), counter("PollCount", 1)},
+			expectedTotal: 3,
+		},
+		{
+			name:          "duplicate series in batch counted once",
+			limits:        Limits{MaxSeries: 1},
+			batch:         []domain.Metric{counter("PollCount", 1), counter("PollCount", 2)},
+			expectedTotal: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memory := metricstorage.NewMemoryMetricStorage()
+			require.NoError(t, memory.UpdateMetrics(ctx, tt.setupData))
+			storage, err := New(memory, tt.limits, &logger)
+			require.NoError(t, err)
+
+			err = storage.UpdateMetrics(ctx, tt.batch)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, domain.ErrCardinalityLimitExceeded))
+				assert.Equal(t, int64(1), storage.CardinalityReport(0).RejectedTotal)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedTotal, storage.CardinalityReport(0).TotalSeries)
+			metrics, err := memory.GetAllMetrics(ctx)
+			require.NoError(t, err)
+			assert.Len(t, metrics, tt.expectedTotal)
+		})
+	}
+}
+
+func TestLimitedMetricStorage_CardinalityReport(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	storage, err := New(metricstorage.NewMemoryMetricStorage(), Limits{MaxSeries: 10, MaxSeriesPerPrefix: 3}, &logger)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		gauge("CPUutilization1", 1),
+		gauge("CPUutilization2", 2),
+		gauge("CPUutilization3", 3),
+		gauge("HeapInuse", 4),
+	}))
+	require.Error(t, storage.UpdateMetric(ctx, gauge("CPUutilization4", 5)))
+
+	report := storage.CardinalityReport(1)
+
+	assert.Equal(t, 4, report.TotalSeries)
+	assert.Equal(t, 10, report.MaxSeries)
+	assert.Equal(t, 3, report.MaxSeriesPerPrefix)
+	assert.Equal(t, int64(1), report.RejectedTotal)
+	assert.Equal(t, []domain.PrefixCardinality{{Prefix: "CPUutilization", Series: 3, Rejected: 1}}, report.TopPrefixes)
+}
+
+func TestLimitedMetricStorage_TenantLimit(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 1)))
+	storage, err := New(memory, Limits{MaxSeriesPerTenant: 2}, &logger)
+	require.NoError(t, err)
+
+	agent1 := domain.WithTenant(ctx, "agent-1")
+	agent2 := domain.WithTenant(ctx, "agent-2")
+	require.NoError(t, storage.UpdateMetrics(agent1, []domain.Metric{gauge("Frees", 1), gauge("HeapInuse", 2)}))
+	err = storage.UpdateMetric(agent1, gauge("Mallocs", 3))
+	require.ErrorIs(t, err, domain.ErrCardinalityLimitExceeded)
+	assert.NoError(t, storage.UpdateMetric(agent1, gauge("Alloc", 4)), "known series are accepted above the limit")
+	assert.NoError(t, storage.UpdateMetric(agent2, gauge("Mallocs", 3)), "other tenants are not affected")
+	assert.NoError(t, storage.UpdateMetric(ctx, gauge("Lookups", 5)), "updates without a tenant are not limited")
+
+	report := storage.CardinalityReport(0)
+	assert.Equal(t, 5, report.TotalSeries)
+	assert.Equal(t, 2, report.MaxSeriesPerTenant)
+	assert.Equal(t, []domain.TenantCardinality{
+		{Tenant: "agent-1", Series: 2, Rejected: 1},
+		{Tenant: "agent-2", Series: 1},
+	}, report.TopTenants)
+
+	_, err = storage.DeleteMetric(ctx, domain.MetricTypeGauge, "Frees")
+	require.NoError(t, err)
+	assert.NoError(t, storage.UpdateMetric(agent1, gauge("Sys", 6)), "deleted series free the tenant limit")
+}
+
+func TestLimitedMetricStorage_SeedsFromStorage(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 1)))
+
+	storage, err := New(memory, Limits{MaxSeries: 1}, &logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, storage.CardinalityReport(0).TotalSeries)
+	assert.Error(t, storage.UpdateMetric(ctx, gauge("Frees", 1)))
+	assert.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+}
+
+func TestLimitedMetricStorage_DeleteFreesSeries(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	storage, err := New(metricstorage.NewMemoryMetricStorage(), Limits{MaxSeriesPerPrefix: 2}, &logger)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{gauge("CPUutilization1", 1), gauge("CPUutilization2", 2)}))
+	require.Error(t, storage.UpdateMetric(ctx, gauge("CPUutilization3", 3)))
+
+	found, err := storage.DeleteMetric(ctx, domain.MetricTypeGauge, "CPUutilization1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("CPUutilization3", 3)))
+
+	deleted, err := storage.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Zero(t, storage.CardinalityReport(0).TotalSeries)
+}
+
+var errUpdateFailed = errors.New("update failed")
+
+// failingStorage blocks the updates of gauges set to 1 until released and fails them.
+type failingStorage struct {
+	*metricstorage.MemoryMetricStorage
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *failingStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	if metric.Value != nil && *metric.Value == 1 {
+		s.saving <- struct{}{}
+		<-s.release
+		return errUpdateFailed
+	}
+	return s.MemoryMetricStorage.UpdateMetric(ctx, metric)
+}
+
+func TestLimitedMetricStorage_FailedUpdateKeepsConcurrentSeries(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	failing := &failingStorage{
+		MemoryMetricStorage: metricstorage.NewMemoryMetricStorage(),
+		saving:              make(chan struct{}),
+		release:             make(chan struct{}),
+	}
+	storage, err := New(failing, Limits{MaxSeries: 1}, &logger)
+	require.NoError(t, err)
+
+	failed := make(chan error)
+	go func() { failed <- storage.UpdateMetric(ctx, gauge("Alloc", 1)) }()
+	<-failing.saving
+
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+	close(failing.release)
+	require.ErrorIs(t, <-failed, errUpdateFailed)
+
+	assert.Equal(t, 1, storage.CardinalityReport(0).TotalSeries, "the series stored meanwhile stays tracked")
+	assert.Error(t, storage.UpdateMetric(ctx, gauge("Frees", 2)))
+}
+
+func TestLimitedMetricStorage_FailedUpdateReleasesSeries(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	failing := &failingStorage{
+		MemoryMetricStorage: metricstorage.NewMemoryMetricStorage(),
+		saving:              make(chan struct{}, 1),
+		release:             make(chan struct{}),
+	}
+	close(failing.release)
+	storage, err := New(failing, Limits{MaxSeries: 1}, &logger)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, storage.UpdateMetric(ctx, gauge("Alloc", 1)), errUpdateFailed)
+	assert.Zero(t, storage.CardinalityReport(0).TotalSeries)
+	assert.NoError(t, storage.UpdateMetric(ctx, gauge("Frees", 2)))
+}
@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+var _ domain.Leadership = (*Election)(nil)
+
+// Election elects the leader of the cluster: the server with the lowest node name among this server and the peers
+// that answered a sync within the timeout. The servers agree on the leader while they see the same peers, so every
+// notification is sent once. While a partition lasts, each side elects its own leader.
+type Election struct {
+	node    string
+	timeout time.Duration
+	now     func() time.Time
+
+	mu sync.RWMutex
+	// ready is set once the peers were asked for the first time, no server leads before it knows its peers
+	ready bool
+	// seen holds the time of the last answer of every peer by node name
+	seen map[string]time.Time
+}
+
+func NewElection(node string, timeout time.Duration) *Election {
+	return &Election{
+		node:    node,
+		timeout: timeout,
+		now:     time.Now,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Node returns the node name of this server.
+func (e *Election) Node() string {
+	return e.node
+}
+
+// IsLeader reports whether this server leads the cluster.
+func (e *Election) IsLeader() bool {
+	return e.Leader() == e.node
+}
+
+// Leader returns the node name of the leader, empty before the peers were asked.
+func (e *Election) Leader() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if !e.ready {
+		return ""
+	}
+
+	leader := e.node
+	cutoff := e.now().Add(-e.timeout)
+	for peer, at := range e.seen {
+		if at.After(cutoff) && peer < leader {
+			leader = peer
+		}
+	}
+	return leader
+}
+
+// answered records an answer of the peer.
+func (e *Election) answered(peer string) {
+	if peer == "" {
+		return
+	}
+
+	e.mu.Lock()
+	e.seen[peer] = e.now()
+	e.mu.Unlock()
+}
+
+// asked marks the end of a round of syncs with all peers.
+func (e *Election) asked() {
+	e.mu.Lock()
+	e.ready = true
+	e.mu.Unlock()
+}
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/alerting"
+)
+
+type recordingNotifier struct {
+	notifications []domain.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, notification domain.Notification) error {
+	n.notifications = append(n.notifications, notification)
+	return nil
+}
+
+type noSilences struct{}
+
+func (noSilences) SilencedBy(domain.Alert) []string { return nil }
+
+type noHistory struct{}
+
+func (noHistory) Record(context.Context, domain.HistoryEvent) {}
+
+func TestElection_Leader(t *testing.T) {
+	c := newTestCluster(3)
+	for i, node := range c.replicators {
+		assert.False(t, node.election.IsLeader(), "node %d does not lead before it asked its peers", i)
+	}
+
+	c.syncAll()
+	for _, node := range c.replicators {
+		assert.Equal(t, "node0", node.election.Leader())
+	}
+
+	// The other nodes elect a new leader once node0 did not answer within the timeout
+	c.isolate(0, true)
+	later := time.Now().Add(2 * time.Minute)
+	for _, node := range c.replicators {
+		node.election.now = func() time.Time { return later }
+	}
+	c.syncAll()
+	assert.Equal(t, "node1", c.replicators[1].election.Leader())
+	assert.Equal(t, "node1", c.replicators[2].election.Leader())
+	assert.True(t, c.replicators[0].election.IsLeader(), "the isolated node leads its side of the partition")
+}
+
+func TestElection_NotifiesOnce(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	c := newTestCluster(2)
+	c.syncAll()
+
+	notifier := &recordingNotifier{}
+	dispatchers := make([]*alerting.Dispatcher, len(c.replicators))
+	for i, node := range c.replicators {
+		dispatchers[i] = alerting.NewDispatcher(notifier, noSilences{}, noHistory{}, node.election, alerting.GroupingOptions{}, &logger)
+	}
+
+	// Both nodes raise the alert from the replicated metrics
+	alert := domain.Alert{Name: "HighAlloc", State: domain.AlertStateFiring, Labels: map[string]string{"metric": "Alloc"}}
+	for _, dispatcher := range dispatchers {
+		dispatcher.Dispatch(alert)
+		dispatcher.Flush(ctx)
+	}
+	require.Len(t, notifier.notifications, 1)
+
+	// The follower takes over the alert state of the leader
+	c.isolate(0, true)
+	later := time.Now().Add(2 * time.Minute)
+	c.replicators[1].election.now = func() time.Time { return later }
+	c.replicators[1].sync()
+	require.True(t, c.replicators[1].election.IsLeader())
+
+	alert.State = domain.AlertStateResolved
+	dispatchers[1].Dispatch(alert)
+	dispatchers[1].Flush(ctx)
+	require.Len(t, notifier.notifications, 2)
+	assert.Equal(t, domain.AlertStateResolved, notifier.notifications[1].Status, "the new leader sends the resolution")
+}
+
+func TestNode_SharesAgents(t *testing.T) {
+	c := newTestCluster(2)
+	agents := c.replicators[0].agents.(domain.HeartbeatRecorder)
+	agents.RecordHeartbeat(domain.AgentInfo{ID: "agent-1"})
+
+	c.replicators[1].sync()
+	shared := c.replicators[1].agents.Agents()
+	require.Len(t, shared, 1)
+	assert.Equal(t, "agent-1", shared[0].ID)
+}
@@ -0,0 +1,138 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// peerTimeout limits a single push to or pull from a peer.
+const peerTimeout = 5 * time.Second
+
+var _ domain.ClusterMember = (*Node)(nil)
+
+// Node replicates the metrics of a Storage with the other servers of the cluster. It pushes the local changes to
+// all peers right away and pulls the state of every peer each sync interval, which brings back servers that missed
+// pushes while they or the network were down. The pulled states are merged through replica, the top of the metric
+// storage decorators, so history, anomaly detection and cardinality tracking see the replicated changes too.
+// The pulls also exchange the agents the servers track and tell the election which peers are up.
+type Node struct {
+	storage      *Storage
+	replica      domain.MetricReplica
+	peers        []domain.ReplicaPeer
+	election     *Election
+	agents       domain.SharedAgentRegistry
+	syncInterval time.Duration
+	logger       *zerolog.Logger
+}
+
+func NewNode(
+	storage *Storage,
+	replica domain.MetricReplica,
+	peers []domain.ReplicaPeer,
+	election *Election,
+	agents domain.SharedAgentRegistry,
+	syncInterval time.Duration,
+	logger *zerolog.Logger,
+) *Node {
+	return &Node{
+		storage:      storage,
+		replica:      replica,
+		peers:        peers,
+		election:     election,
+		agents:       agents,
+		syncInterval: syncInterval,
+		logger:       logger,
+	}
+}
+
+func (n *Node) Merge(ctx context.Context, metrics []domain.ReplicatedMetric) error {
+	_, err := n.replica.Merge(ctx, metrics)
+	return err
+}
+
+func (n *Node) State(ctx context.Context) (domain.ClusterState, error) {
+	metrics, err := n.replica.ReplicatedMetrics(ctx)
+	if err != nil {
+		return domain.ClusterState{}, err
+	}
+
+	return domain.ClusterState{
+		Node:    n.election.Node(),
+		Metrics: metrics,
+		Agents:  n.agents.Agents(),
+	}, nil
+}
+
+// Run pulls the state of the peers at the start and every sync interval, and pushes the changes as they happen,
+// until shutdownCh is closed.
+func (n *Node) Run(shutdownCh <-chan struct{}) {
+	n.sync()
+
+	ticker := time.NewTicker(n.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			n.push()
+			return
+		case <-n.storage.Changed():
+			n.push()
+		case <-ticker.C:
+			n.sync()
+		}
+	}
+}
+
+// push sends the metrics changed since the last push to all peers. A peer that misses them gets them with its
+// next sync.
+func (n *Node) push() {
+	states := n.storage.TakeChanges()
+	if len(states) == 0 {
+		return
+	}
+
+	n.eachPeer(func(ctx context.Context, peer domain.ReplicaPeer) error {
+		return peer.Replicate(ctx, states)
+	}, "failed to replicate metrics to peer")
+}
+
+// sync merges the states of all peers and expires the old tombstones.
+func (n *Node) sync() {
+	n.eachPeer(func(ctx context.Context, peer domain.ReplicaPeer) error {
+		state, err := peer.Sync(ctx)
+		if err != nil {
+			return err
+		}
+		n.election.answered(state.Node)
+		n.agents.MergeAgents(state.Agents)
+		return n.Merge(ctx, state.Metrics)
+	}, "failed to sync metrics from peer")
+	n.election.asked()
+	n.storage.join()
+	n.storage.expire()
+}
+
+// eachPeer calls op for all peers concurrently and logs the failures.
+func (n *Node) eachPeer(op func(ctx context.Context, peer domain.ReplicaPeer) error, failure string) {
+	var wg sync.WaitGroup
+	for _, peer := range n.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), peerTimeout)
+			defer cancel()
+
+			if err := op(ctx, peer); err != nil {
+				n.logger.Warn().Err(err).Str("peer", peer.Address()).Msg(failure)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,226 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type metricKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+type slot struct {
+	total   int64
+	version uint64
+}
+
+// record is the replicated state of a metric, see domain.ReplicatedMetric. The last write register of value,
+// updatedAt, timestamp and writer is shared by both metric types, counters only use it for the update times.
+// deletedAt is set by the deletions of counters too, to expire their tombstones.
+type record struct {
+	value     float64
+	updatedAt time.Time
+	timestamp time.Time
+	writer    string
+	deletedAt time.Time
+	slots     map[string]slot
+	deleted   map[string]slot
+	// absorbed is the latest slot of the previous runs of the local server merged into its current slot
+	absorbed slot
+}
+
+func newRecord() *record {
+	return &record{
+		slots:   make(map[string]slot),
+		deleted: make(map[string]slot),
+	}
+}
+
+// metric returns the metric the record currently holds, false if it was deleted or never written.
+func (r *record) metric(key metricKey) (domain.Metric, bool) {
+	metric := domain.Metric{
+		ID:        key.id,
+		MType:     key.mType,
+		UpdatedAt: r.updatedAt,
+		Timestamp: r.timestamp,
+	}
+
+	switch key.mType {
+	case domain.MetricTypeGauge:
+		if !r.updatedAt.After(r.deletedAt) {
+			return domain.Metric{}, false
+		}
+		value := r.value
+		metric.Value = &value
+
+	case domain.MetricTypeCounter:
+		var delta int64
+		exists := false
+		for replica, current := range r.slots {
+			base := r.deleted[replica]
+			delta += current.total - base.total
+			exists = exists || current.version > base.version
+		}
+		if !exists {
+			return domain.Metric{}, false
+		}
+		metric.Delta = &delta
+
+	default:
+		return domain.Metric{}, false
+	}
+
+	return metric, true
+}
+
+// write records a local update of the metric by the replica. The update time is moved past the last write and
+// deletion seen, so the update wins against them on every replica even if the clocks of the servers differ.
+// The versions of the counter slot start at floor, above the versions of the previous runs of the replica.
+func (r *record) write(replica string, floor uint64, metric domain.Metric, now time.Time) {
+	updatedAt := metric.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = now
+	}
+	if latest := maxTime(r.updatedAt, r.deletedAt); !updatedAt.After(latest) {
+		updatedAt = latest.Add(time.Nanosecond)
+	}
+
+	r.updatedAt = updatedAt
+	r.timestamp = metric.Timestamp
+	r.writer = replica
+
+	switch metric.MType {
+	case domain.MetricTypeGauge:
+		r.value = *metric.Value
+
+	case domain.MetricTypeCounter:
+		current := r.slots[replica]
+		r.slots[replica] = slot{total: current.total + *metric.Delta, version: max(current.version+1, floor)}
+	}
+}
+
+// remove records a local deletion of the metric. A counter drops the totals of all slots seen so far, increments
+// other replicas make concurrently survive the deletion.
+func (r *record) remove(mType domain.MetricType, now time.Time) {
+	r.deletedAt = maxTime(now, r.updatedAt)
+
+	if mType == domain.MetricTypeCounter {
+		for replica, current := range r.slots {
+			r.deleted[replica] = current
+		}
+	}
+}
+
+// merge merges the state of the metric received from a peer. The slots previous runs of the local replica own
+// left on the peers, the ones with versions below floor, are absorbed into its current slot instead, see absorb. merge reports whether that changed the current slot, which then has to be pushed.
+func (r *record) merge(state domain.ReplicatedMetric, own string, floor uint64) bool {
+	if state.UpdatedAt.After(r.updatedAt) || state.UpdatedAt.Equal(r.updatedAt) && state.Writer > r.writer {
+		r.value = state.Value
+		r.updatedAt = state.UpdatedAt
+		r.timestamp = state.Timestamp
+		r.writer = state.Writer
+	}
+	r.deletedAt = maxTime(r.deletedAt, state.DeletedAt)
+
+	var previous slot
+	for _, counterSlot := range state.Slots {
+		received := slot{total: counterSlot.Total, version: counterSlot.Version}
+		switch {
+		case counterSlot.Replica == own && received.version < floor:
+			previous = latest(previous, received)
+		case received.version > r.slots[counterSlot.Replica].version:
+			r.slots[counterSlot.Replica] = received
+		}
+	}
+	mergeSlots(r.deleted, state.Deleted)
+	// A replica's slot at a deletion is a state of the slot too, and the deletion has to be subtracted from it
+	for replica, base := range r.deleted {
+		switch {
+		case replica == own && base.version < floor:
+			previous = latest(previous, base)
+		case base.version > r.slots[replica].version:
+			r.slots[replica] = base
+		}
+	}
+
+	return r.absorb(own, floor, previous)
+}
+
+// absorb merges a slot of a previous run of the local replica. Until the replica adds to the counter the slot is
+// taken over as is, afterwards the increments of the previous run missing from the current slot are added to it,
+// so they survive the peers replacing the old slot with the current one.
+func (r *record) absorb(own string, floor uint64, previous slot) bool {
+	if previous.version <= r.absorbed.version {
+		return false
+	}
+
+	current := r.slots[own]
+	if current.version < floor {
+		r.slots[own] = previous
+		r.absorbed = previous
+		return false
+	}
+
+	r.slots[own] = slot{total: current.total + previous.total - r.absorbed.total, version: current.version + 1}
+	r.absorbed = previous
+	return true
+}
+
+// expired reports whether the record is a tombstone of a metric deleted before the given time.
+func (r *record) expired(key metricKey, before time.Time) bool {
+	if _, exists := r.metric(key); exists {
+		return false
+	}
+	return !r.deletedAt.IsZero() && r.deletedAt.Before(before)
+}
+
+// state returns the record in the replicated form.
+func (r *record) state(key metricKey) domain.ReplicatedMetric {
+	return domain.ReplicatedMetric{
+		ID:        key.id,
+		MType:     key.mType,
+		Value:     r.value,
+		UpdatedAt: r.updatedAt,
+		Timestamp: r.timestamp,
+		Writer:    r.writer,
+		DeletedAt: r.deletedAt,
+		Slots:     slotsToDomain(r.slots),
+		Deleted:   slotsToDomain(r.deleted),
+	}
+}
+
+func mergeSlots(slots map[string]slot, received []domain.CounterSlot) {
+	for _, counterSlot := range received {
+		if counterSlot.Version > slots[counterSlot.Replica].version {
+			slots[counterSlot.Replica] = slot{total: counterSlot.Total, version: counterSlot.Version}
+		}
+	}
+}
+
+func slotsToDomain(slots map[string]slot) []domain.CounterSlot {
+	if len(slots) == 0 {
+		return nil
+	}
+
+	result := make([]domain.CounterSlot, 0, len(slots))
+	for replica, current := range slots {
+		result = append(result, domain.CounterSlot{Replica: replica, Total: current.total, Version: current.version})
+	}
+	return result
+}
+
+func latest(a, b slot) slot {
+	if a.version > b.version {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,355 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// tombstoneTTL is how long deleted metrics are kept to replicate the deletion. A peer cut off for longer brings
+// the metrics it still has back.
+const tombstoneTTL = 24 * time.Hour
+
+var errUnsupportedMetricType = errors.New("unsupported metric type")
+
+var (
+	_ domain.MetricStorage = (*Storage)(nil)
+	_ domain.MetricReplica = (*Storage)(nil)
+
+	_ domain.StoredStateUpdater = (*Storage)(nil)
+	_ domain.StaleMetricDeleter = (*Storage)(nil)
+)
+
+// Storage keeps the metrics of a cluster server in memory in a form that merges with the states of the peers,
+// see domain.ReplicatedMetric, and collects the changes for Node to push. Counters keep a slot per replica, so
+// they converge to the sum of all increments however often the states are exchanged. Deleted metrics are kept as
+// tombstones, so the deletion replicates as well, until they expire.
+//
+// The replica ID is the name of the server, so a counter has a slot per server however often they restart. A restart
+// loses the in-memory slot of the server, its peers still have it. The versions of the new slot start at the start
+// time of the server, above the old ones, and the old slot is absorbed into the new one as soon as the server merges
+// it, so the peers do not lose the increments of the previous run when they take the new slot. Until the first sync
+// with the peers, the new slots are left out of ReplicatedMetrics for the same reason. Increments of the previous
+// run that only a peer unreachable at that sync had are lost if the peer takes the new slot from another server.
+type Storage struct {
+	replica string
+	// floor is the first version of the counter slots of this run of the server
+	floor uint64
+	now   func() time.Time
+
+	mu      sync.RWMutex
+	records map[metricKey]*record
+	changed map[metricKey]struct{}
+	joined  bool
+	// changedCh wakes up the node to push the changes
+	changedCh chan struct{}
+}
+
+func New(replica string) *Storage {
+	return &Storage{
+		replica:   replica,
+		floor:     uint64(time.Now().UnixNano()),
+		now:       time.Now,
+		records:   make(map[metricKey]*record),
+		changed:   make(map[metricKey]struct{}),
+		changedCh: make(chan struct{}, 1),
+	}
+}
+
+func (s *Storage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metrics := make([]domain.Metric, 0, len(s.records))
+	for key, r := range s.records {
+		if metric, ok := r.metric(key); ok {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	return metrics, nil
+}
+
+func (s *Storage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := metricKey{mType: metricType, id: metricName}
+	if r, ok := s.records[key]; ok {
+		if metric, ok := r.metric(key); ok {
+			return metric, true, nil
+		}
+	}
+
+	return domain.Metric{MType: metricType, ID: metricName}, false, nil
+}
+
+func (s *Storage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	return s.UpdateMetrics(ctx, []domain.Metric{metric})
+}
+
+// UpdateMetrics validates the whole batch first, so an invalid metric leaves the storage unchanged.
+func (s *Storage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	_, err := s.UpdateMetricsStored(ctx, metrics)
+	return err
+}
+
+// UpdateMetricsStored applies the batch like UpdateMetrics and returns the merged state of every updated metric,
+// once per metric.
+func (s *Storage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	for _, metric := range metrics {
+		if err := validateUpdate(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	now := s.now()
+	keys := make([]metricKey, 0, len(metrics))
+	updated := make(map[metricKey]struct{}, len(metrics))
+	for _, metric := range metrics {
+		key := metricKey{mType: metric.MType, id: metric.ID}
+		if _, ok := updated[key]; !ok {
+			updated[key] = struct{}{}
+			keys = append(keys, key)
+		}
+		s.record(key).write(s.replica, s.floor, metric, now)
+		s.changed[key] = struct{}{}
+	}
+	stored := make([]domain.Metric, 0, len(keys))
+	for _, key := range keys {
+		if metric, ok := s.records[key].metric(key); ok {
+			stored = append(stored, metric)
+		}
+	}
+	s.mu.Unlock()
+
+	s.notify()
+	return stored, nil
+}
+
+func (s *Storage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	if metricType != domain.MetricTypeGauge && metricType != domain.MetricTypeCounter {
+		return false, errUnsupportedMetricType
+	}
+
+	s.mu.Lock()
+	key := metricKey{mType: metricType, id: metricName}
+	found := s.remove(key)
+	s.mu.Unlock()
+
+	if found {
+		s.notify()
+	}
+	return found, nil
+}
+
+func (s *Storage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	if metricType != domain.MetricTypeGauge && metricType != domain.MetricTypeCounter {
+		return false, errUnsupportedMetricType
+	}
+
+	s.mu.Lock()
+	key := metricKey{mType: metricType, id: metricName}
+	found := false
+	if r, ok := s.records[key]; ok {
+		if metric, exists := r.metric(key); exists && !metric.UpdatedAt.IsZero() && metric.UpdatedAt.Before(before) {
+			found = s.remove(key)
+		}
+	}
+	s.mu.Unlock()
+
+	if found {
+		s.notify()
+	}
+	return found, nil
+}
+
+func (s *Storage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	deleted := 0
+	for key := range s.records {
+		if strings.HasPrefix(key.id, prefix) && s.remove(key) {
+			deleted++
+		}
+	}
+	s.mu.Unlock()
+
+	if deleted > 0 {
+		s.notify()
+	}
+	return deleted, nil
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Merge merges the states pushed or returned by a peer. They are not pushed on, every server pushes its own
+// changes to all peers and the periodic sync covers the pushes that were lost.
+func (s *Storage) Merge(ctx context.Context, metrics []domain.ReplicatedMetric) ([]domain.MetricChange, error) {
+	for _, state := range metrics {
+		if state.MType != domain.MetricTypeGauge && state.MType != domain.MetricTypeCounter {
+			return nil, errUnsupportedMetricType
+		}
+	}
+
+	s.mu.Lock()
+	var changes []domain.MetricChange
+	absorbed := false
+	for _, state := range metrics {
+		key := metricKey{mType: state.MType, id: state.ID}
+		r := s.record(key)
+		before, existed := r.metric(key)
+		if r.merge(state, s.replica, s.floor) {
+			s.changed[key] = struct{}{}
+			absorbed = true
+		}
+		after, exists := r.metric(key)
+
+		switch {
+		case exists && (!existed || !sameMetric(before, after)):
+			changes = append(changes, domain.MetricChange{Metric: after})
+		case existed && !exists:
+			changes = append(changes, domain.MetricChange{Metric: domain.Metric{ID: key.id, MType: key.mType}, Deleted: true})
+		}
+	}
+	s.mu.Unlock()
+
+	if absorbed {
+		s.notify()
+	}
+	return changes, nil
+}
+
+func (s *Storage) ReplicatedMetrics(ctx context.Context) ([]domain.ReplicatedMetric, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	states := make([]domain.ReplicatedMetric, 0, len(s.records))
+	for key, r := range s.records {
+		state := r.state(key)
+		if !s.joined {
+			state.Slots = s.withoutCurrentRun(state.Slots)
+			state.Deleted = s.withoutCurrentRun(state.Deleted)
+		}
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// withoutCurrentRun drops the slot this run of the server wrote.
+func (s *Storage) withoutCurrentRun(slots []domain.CounterSlot) []domain.CounterSlot {
+	result := slots[:0]
+	for _, counterSlot := range slots {
+		if counterSlot.Replica != s.replica || counterSlot.Version < s.floor {
+			result = append(result, counterSlot)
+		}
+	}
+	return result
+}
+
+// join marks the first sync with the peers done, see Storage.
+func (s *Storage) join() {
+	s.mu.Lock()
+	s.joined = true
+	s.mu.Unlock()
+}
+
+// expire drops the tombstones older than tombstoneTTL.
+func (s *Storage) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.now().Add(-tombstoneTTL)
+	for key, r := range s.records {
+		if r.expired(key, before) {
+			delete(s.records, key)
+			delete(s.changed, key)
+		}
+	}
+}
+
+// record returns the record of the metric, creating it if needed. It must be called with mu held.
+func (s *Storage) record(key metricKey) *record {
+	r, ok := s.records[key]
+	if !ok {
+		r = newRecord()
+		s.records[key] = r
+	}
+	return r
+}
+
+// remove deletes the metric if it exists, reporting whether it did. It must be called with mu held.
+func (s *Storage) remove(key metricKey) bool {
+	r, ok := s.records[key]
+	if !ok {
+		return false
+	}
+	if _, exists := r.metric(key); !exists {
+		return false
+	}
+
+	r.remove(key.mType, s.now())
+	s.changed[key] = struct{}{}
+	return true
+}
+
+// Changed is signalled when local updates are waiting to be pushed.
+func (s *Storage) Changed() <-chan struct{} {
+	return s.changedCh
+}
+
+// TakeChanges returns the states of the metrics changed locally since the last call.
+func (s *Storage) TakeChanges() []domain.ReplicatedMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]domain.ReplicatedMetric, 0, len(s.changed))
+	for key := range s.changed {
+		states = append(states, s.records[key].state(key))
+	}
+	clear(s.changed)
+	return states
+}
+
+func (s *Storage) notify() {
+	select {
+	case s.changedCh <- struct{}{}:
+	default:
+	}
+}
+
+func validateUpdate(metric domain.Metric) error {
+	switch metric.MType {
+	case domain.MetricTypeCounter:
+		if metric.Delta == nil {
+			return errors.New("counter delta is required")
+		}
+	case domain.MetricTypeGauge:
+		if metric.Value == nil {
+			return errors.New("gauge value is required")
+		}
+	default:
+		return errUnsupportedMetricType
+	}
+	return nil
+}
+
+// sameMetric reports whether a merge left the visible state of the metric unchanged.
+func sameMetric(a, b domain.Metric) bool {
+	if !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return false
+	}
+	switch a.MType {
+	case domain.MetricTypeGauge:
+		return *a.Value == *b.Value
+	default:
+		return *a.Delta == *b.Delta
+	}
+}
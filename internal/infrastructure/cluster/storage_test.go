@@ -0,0 +1,472 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/cardinalitylimiter"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/heartbeat"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metrichistory"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+var errPeerDown = errors.New("peer is down")
+
+// localPeer connects to a node in the same process, failing all calls while down.
+type localPeer struct {
+	node *Node
+	down *bool
+}
+
+func (p localPeer) Address() string {
+	return p.node.election.Node()
+}
+
+func (p localPeer) Replicate(ctx context.Context, metrics []domain.ReplicatedMetric) error {
+	if *p.down {
+		return errPeerDown
+	}
+	return p.node.Merge(ctx, metrics)
+}
+
+func (p localPeer) Sync(ctx context.Context) (domain.ClusterState, error) {
+	if *p.down {
+		return domain.ClusterState{}, errPeerDown
+	}
+	return p.node.State(ctx)
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Fire(context.Context, domain.Alert) {}
+
+func (nopPublisher) Resolve(context.Context, domain.Alert) {}
+
+// newTestNode returns the storage and the node of a server without peers, whose peers are considered up for a minute.
+func newTestNode(name string) (*Storage, *Node) {
+	logger := zerolog.Nop()
+	storage := New(name)
+	agents := heartbeat.New(10*time.Second, 3, nopPublisher{}, &logger)
+	return storage, NewNode(storage, storage, nil, NewElection(name, time.Minute), agents, time.Minute, &logger)
+}
+
+// testCluster is a cluster of storages that are all peers of each other and have synced with each other before.
+type testCluster struct {
+	nodes       []*Storage
+	replicators []*Node
+}
+
+func newTestCluster(size int) *testCluster {
+	c := &testCluster{}
+	for i := 0; i < size; i++ {
+		storage, node := newTestNode(fmt.Sprintf("node%d", i))
+		storage.join()
+		c.nodes = append(c.nodes, storage)
+		c.replicators = append(c.replicators, node)
+	}
+	for i, node := range c.replicators {
+		for j, peer := range c.replicators {
+			if i != j {
+				node.peers = append(node.peers, localPeer{node: peer, down: new(bool)})
+			}
+		}
+	}
+	return c
+}
+
+// isolate cuts the node off from all peers, or connects it again.
+func (c *testCluster) isolate(node int, isolated bool) {
+	for i, replicator := range c.replicators {
+		for _, peer := range replicator.peers {
+			if peer := peer.(localPeer); i == node || peer.node == c.replicators[node] {
+				*peer.down = isolated
+			}
+		}
+	}
+}
+
+func (c *testCluster) pushAll() {
+	for _, node := range c.replicators {
+		node.push()
+	}
+}
+
+func (c *testCluster) syncAll() {
+	for _, node := range c.replicators {
+		node.sync()
+	}
+}
+
+func counterValue(t *testing.T, storage *Storage, id string) (int64, bool) {
+	t.Helper()
+	metric, found := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, id)
+	if !found {
+		return 0, false
+	}
+	return *metric.Delta, true
+}
+
+func TestStorage_ReplicatesUpdates(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(3)
+
+	require.NoError(t, c.nodes[0].UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), gauge("Alloc", 1)}))
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, counter("PollCount", 3)))
+	require.NoError(t, c.nodes[2].UpdateMetric(ctx, counter("PollCount", 2)))
+	c.pushAll()
+
+	for i, node := range c.nodes {
+		value, found := counterValue(t, node, "PollCount")
+		require.True(t, found, "node %d", i)
+		assert.Equal(t, int64(10), value, "node %d", i)
+		alloc, found := domaintest.GetMetric(t, node, domain.MetricTypeGauge, "Alloc")
+		require.True(t, found, "node %d", i)
+		assert.Equal(t, 1.0, *alloc.Value)
+	}
+
+	// Exchanging the same states again does not count the increments twice
+	c.syncAll()
+	c.syncAll()
+	for _, node := range c.nodes {
+		value, _ := counterValue(t, node, "PollCount")
+		assert.Equal(t, int64(10), value)
+		all, err := node.GetAllMetrics(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	}
+}
+
+func TestStorage_SyncAfterOutage(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(3)
+
+	c.isolate(2, true)
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 1)))
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, counter("PollCount", 2)))
+	require.NoError(t, c.nodes[2].UpdateMetric(ctx, counter("PollCount", 4)))
+	c.pushAll()
+
+	value, _ := counterValue(t, c.nodes[0], "PollCount")
+	assert.Equal(t, int64(3), value)
+	value, _ = counterValue(t, c.nodes[2], "PollCount")
+	assert.Equal(t, int64(4), value, "the isolated node only has its own increments")
+
+	c.isolate(2, false)
+	c.replicators[2].sync()
+	value, _ = counterValue(t, c.nodes[2], "PollCount")
+	assert.Equal(t, int64(7), value)
+
+	c.syncAll()
+	for _, node := range c.nodes {
+		value, _ := counterValue(t, node, "PollCount")
+		assert.Equal(t, int64(7), value)
+	}
+}
+
+func TestStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[0].UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), gauge("Alloc", 1), gauge("CPU1", 1), gauge("CPU2", 2)}))
+	c.pushAll()
+
+	// The counter is deleted on one node while the other one increments it
+	found, err := c.nodes[0].DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	assert.True(t, found)
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, counter("PollCount", 2)))
+	deleted, err := c.nodes[1].DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	c.pushAll()
+
+	for i, node := range c.nodes {
+		value, found := counterValue(t, node, "PollCount")
+		require.True(t, found, "node %d", i)
+		assert.Equal(t, int64(2), value, "node %d keeps the concurrent increment only", i)
+		_, found = domaintest.GetMetric(t, node, domain.MetricTypeGauge, "CPU1")
+		assert.False(t, found, "node %d", i)
+		all, err := node.GetAllMetrics(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	}
+
+	found, err = c.nodes[1].DeleteMetric(ctx, domain.MetricTypeGauge, "CPU1")
+	require.NoError(t, err)
+	assert.False(t, found, "deleted metrics are not found again")
+
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, gauge("CPU1", 3)))
+	c.pushAll()
+	cpu, found := domaintest.GetMetric(t, c.nodes[0], domain.MetricTypeGauge, "CPU1")
+	require.True(t, found, "a write after the deletion creates the metric again")
+	assert.Equal(t, 3.0, *cpu.Value)
+}
+
+func TestStorage_DeleteMetricIfStale(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	alloc := gauge("Alloc", 1)
+	alloc.UpdatedAt = updatedAt
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, alloc))
+	c.pushAll()
+
+	found, err := c.nodes[0].DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "Alloc", updatedAt)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = c.nodes[0].DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "Alloc", updatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, found)
+	c.pushAll()
+
+	for i, node := range c.nodes {
+		_, found = domaintest.GetMetric(t, node, domain.MetricTypeGauge, "Alloc")
+		assert.False(t, found, "node %d", i)
+	}
+}
+
+func TestStorage_GaugeLastWriteWins(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	later := gauge("Alloc", 2)
+	later.UpdatedAt = now.Add(time.Second)
+	earlier := gauge("Alloc", 1)
+	earlier.UpdatedAt = now
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, later))
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, earlier))
+	c.pushAll()
+
+	for _, node := range c.nodes {
+		alloc, _ := domaintest.GetMetric(t, node, domain.MetricTypeGauge, "Alloc")
+		assert.Equal(t, 2.0, *alloc.Value)
+		assert.Equal(t, later.UpdatedAt, alloc.UpdatedAt)
+	}
+
+	// Writes at the same time are ordered by the replica
+	tie := now.Add(time.Hour)
+	first, second := gauge("Sys", 1), gauge("Sys", 2)
+	first.UpdatedAt, second.UpdatedAt = tie, tie
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, first))
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, second))
+	c.pushAll()
+
+	for _, node := range c.nodes {
+		sys, _ := domaintest.GetMetric(t, node, domain.MetricTypeGauge, "Sys")
+		assert.Equal(t, 2.0, *sys.Value)
+	}
+
+	// A local write wins against the writes the node has seen, also if its clock is behind
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, earlier))
+	c.pushAll()
+	alloc, _ := domaintest.GetMetric(t, c.nodes[1], domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 1.0, *alloc.Value)
+}
+
+// restart replaces the node with an empty one of the same name that has the same peers.
+func (c *testCluster) restart(i int) {
+	storage, node := newTestNode(c.replicators[i].election.Node())
+	node.peers = c.replicators[i].peers
+	for _, replicator := range c.replicators {
+		for j, peer := range replicator.peers {
+			if peer := peer.(localPeer); peer.node == c.replicators[i] {
+				replicator.peers[j] = localPeer{node: node, down: peer.down}
+			}
+		}
+	}
+	c.nodes[i], c.replicators[i] = storage, node
+}
+
+func TestStorage_Restart(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 5)))
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, counter("PollCount", 1)))
+	c.pushAll()
+
+	// The restarted node comes back empty and catches up from its peer
+	c.restart(0)
+	c.replicators[0].sync()
+
+	value, _ := counterValue(t, c.nodes[0], "PollCount")
+	assert.Equal(t, int64(6), value)
+
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 2)))
+	c.pushAll()
+	value, _ = counterValue(t, c.nodes[1], "PollCount")
+	assert.Equal(t, int64(8), value)
+
+	states, err := c.nodes[1].ReplicatedMetrics(ctx)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Len(t, states[0].Slots, 2, "a restart does not add a counter slot")
+}
+
+func TestStorage_RestartAbsorbsPreviousRun(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 5)))
+	c.pushAll()
+
+	// Updates before the first sync start a new slot, the old one is only on the peer
+	c.restart(0)
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 2)))
+	c.replicators[1].sync()
+	value, _ := counterValue(t, c.nodes[1], "PollCount")
+	assert.Equal(t, int64(5), value, "the new slot is not shared before the first sync")
+
+	c.replicators[0].sync()
+	value, _ = counterValue(t, c.nodes[0], "PollCount")
+	assert.Equal(t, int64(7), value)
+
+	c.pushAll()
+	value, _ = counterValue(t, c.nodes[1], "PollCount")
+	assert.Equal(t, int64(7), value)
+
+	// Merging the old slot again does not count it twice
+	c.syncAll()
+	value, _ = counterValue(t, c.nodes[0], "PollCount")
+	assert.Equal(t, int64(7), value)
+}
+
+func TestStorage_ExpiresTombstones(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[0].UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), gauge("Alloc", 1)}))
+	c.pushAll()
+	_, err := c.nodes[0].DeleteByPrefix(ctx, "")
+	require.NoError(t, err)
+	c.pushAll()
+
+	c.syncAll()
+	states, err := c.nodes[1].ReplicatedMetrics(ctx)
+	require.NoError(t, err)
+	assert.Len(t, states, 2, "fresh tombstones are kept")
+
+	for _, node := range c.nodes {
+		node.now = func() time.Time { return time.Now().Add(tombstoneTTL + time.Minute) }
+	}
+	c.syncAll()
+	for _, node := range c.nodes {
+		states, err := node.ReplicatedMetrics(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, states)
+	}
+}
+
+func TestStorage_InvalidUpdates(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(1)
+	node := c.nodes[0]
+
+	err := node.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), {ID: "PollCount", MType: domain.MetricTypeCounter}})
+	assert.Error(t, err)
+	_, found := domaintest.GetMetric(t, node, domain.MetricTypeGauge, "Alloc")
+	assert.False(t, found, "an invalid batch is not applied")
+
+	_, err = node.DeleteMetric(ctx, "histogram", "Alloc")
+	assert.Error(t, err)
+	_, err = node.Merge(ctx, []domain.ReplicatedMetric{{ID: "Alloc", MType: "histogram"}})
+	assert.Error(t, err)
+}
+
+func TestStorage_Run(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[1].UpdateMetric(ctx, counter("PollCount", 1)))
+
+	shutdownCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.replicators[0].Run(shutdownCh)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		value, found, err := c.nodes[0].GetMetric(ctx, domain.MetricTypeCounter, "PollCount")
+		return err == nil && found && *value.Delta == 1
+	}, time.Second, 10*time.Millisecond, "the state of the peers is pulled at the start")
+
+	require.NoError(t, c.nodes[0].UpdateMetric(ctx, counter("PollCount", 2)))
+	assert.Eventually(t, func() bool {
+		value, found, err := c.nodes[1].GetMetric(ctx, domain.MetricTypeCounter, "PollCount")
+		return err == nil && found && *value.Delta == 3
+	}, time.Second, 10*time.Millisecond, "changes are pushed")
+
+	close(shutdownCh)
+	<-done
+}
+
+func TestStorage_MergeChanges(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	require.NoError(t, c.nodes[0].UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), gauge("Alloc", 1)}))
+
+	changes, err := c.nodes[1].Merge(ctx, c.nodes[0].TakeChanges())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	for _, change := range changes {
+		assert.False(t, change.Deleted)
+		if change.Metric.MType == domain.MetricTypeCounter {
+			assert.Equal(t, int64(5), *change.Metric.Delta, "counters change to their merged total")
+		}
+	}
+
+	states, err := c.nodes[0].ReplicatedMetrics(ctx)
+	require.NoError(t, err)
+	changes, err = c.nodes[1].Merge(ctx, states)
+	require.NoError(t, err)
+	assert.Empty(t, changes, "states merged before change nothing")
+
+	_, err = c.nodes[0].DeleteMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	changes, err = c.nodes[1].Merge(ctx, c.nodes[0].TakeChanges())
+	require.NoError(t, err)
+	assert.Equal(t, []domain.MetricChange{{Metric: domain.Metric{ID: "Alloc", MType: domain.MetricTypeGauge}, Deleted: true}}, changes)
+}
+
+func TestNode_MergesThroughDecorators(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCluster(2)
+	logger := zerolog.Nop()
+
+	limited, err := cardinalitylimiter.New(c.nodes[1], cardinalitylimiter.Limits{MaxSeries: 1}, &logger)
+	require.NoError(t, err)
+	history := metrichistory.New(limited, time.Hour)
+	c.replicators[1].replica = history
+
+	require.NoError(t, c.nodes[0].UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), gauge("Alloc", 1)}))
+	c.replicators[1].sync()
+
+	samples := history.Samples(ctx, domain.MetricTypeCounter, "PollCount", time.Time{})
+	require.Len(t, samples, 1, "replicated updates are recorded in the history")
+	assert.Equal(t, 5.0, samples[0].Value)
+	assert.Equal(t, 2, limited.CardinalityReport(0).TotalSeries, "replicated series are tracked above the limit")
+
+	_, err = c.nodes[0].DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	c.replicators[1].sync()
+	assert.Empty(t, history.Samples(ctx, domain.MetricTypeCounter, "PollCount", time.Time{}))
+	assert.Equal(t, 1, limited.CardinalityReport(0).TotalSeries)
+
+	_, err = metrichistory.New(metricstorage.NewMemoryMetricStorage(), time.Hour).Merge(ctx, nil)
+	assert.ErrorIs(t, err, domain.ErrNotReplicated)
+}
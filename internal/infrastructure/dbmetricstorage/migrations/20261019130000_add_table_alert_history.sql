@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS alert_history (
+    id BIGSERIAL PRIMARY KEY,
+    time TIMESTAMPTZ NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    alert_name VARCHAR(255) NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}',
+    silences JSONB NOT NULL DEFAULT '[]',
+    group_key TEXT NOT NULL DEFAULT '',
+    silence_id VARCHAR(36) NOT NULL DEFAULT '',
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS alert_history_time_idx ON alert_history (time);
+CREATE INDEX IF NOT EXISTS alert_history_alert_name_idx ON alert_history (alert_name, time);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS alert_history;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_metric_change() RETURNS trigger AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    PERFORM pg_notify('metric_changes', json_build_object('type', changed.type, 'id', changed.id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER metrics_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON metrics
+    FOR EACH ROW EXECUTE FUNCTION notify_metric_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS metrics_notify_change ON metrics;
+DROP FUNCTION IF EXISTS notify_metric_change();
+-- +goose StatementEnd
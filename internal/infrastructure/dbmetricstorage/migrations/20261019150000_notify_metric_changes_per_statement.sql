@@ -0,0 +1,82 @@
+-- +goose Up
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS metrics_notify_change ON metrics;
+DROP FUNCTION IF EXISTS notify_metric_change();
+-- +goose StatementEnd
+
+-- Sends the metrics changed by a statement as JSON arrays, split to stay below the 8000 byte payload limit.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_metric_changes() RETURNS trigger AS $$
+DECLARE
+    batch text := '';
+    item text;
+BEGIN
+    FOR item IN SELECT json_build_object('type', m.type, 'id', m.id)::text FROM changed_metrics m LOOP
+        IF batch <> '' AND octet_length(batch) + octet_length(item) > 7900 THEN
+            PERFORM pg_notify('metric_changes', '[' || batch || ']');
+            batch := '';
+        END IF;
+        IF batch <> '' THEN
+            batch := batch || ',';
+        END IF;
+        batch := batch || item;
+    END LOOP;
+
+    IF batch <> '' THEN
+        PERFORM pg_notify('metric_changes', '[' || batch || ']');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER metrics_notify_insert
+    AFTER INSERT ON metrics
+    REFERENCING NEW TABLE AS changed_metrics
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_metric_changes();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER metrics_notify_update
+    AFTER UPDATE ON metrics
+    REFERENCING NEW TABLE AS changed_metrics
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_metric_changes();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER metrics_notify_delete
+    AFTER DELETE ON metrics
+    REFERENCING OLD TABLE AS changed_metrics
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_metric_changes();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS metrics_notify_insert ON metrics;
+DROP TRIGGER IF EXISTS metrics_notify_update ON metrics;
+DROP TRIGGER IF EXISTS metrics_notify_delete ON metrics;
+DROP FUNCTION IF EXISTS notify_metric_changes();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_metric_change() RETURNS trigger AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    PERFORM pg_notify('metric_changes', json_build_object('type', changed.type, 'id', changed.id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER metrics_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON metrics
+    FOR EACH ROW EXECUTE FUNCTION notify_metric_change();
+-- +goose StatementEnd
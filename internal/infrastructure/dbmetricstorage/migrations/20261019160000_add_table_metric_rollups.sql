@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS metric_rollups (
+    type VARCHAR(50) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    resolution_seconds BIGINT NOT NULL,
+    start TIMESTAMPTZ NOT NULL,
+    count INTEGER NOT NULL,
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    avg DOUBLE PRECISION NOT NULL,
+    sum DOUBLE PRECISION NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (type, id, resolution_seconds, start)
+);
+CREATE INDEX IF NOT EXISTS metric_rollups_start_idx ON metric_rollups (resolution_seconds, start);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS metric_rollups;
+-- +goose StatementEnd
@@ -0,0 +1,110 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// metricChangesChannel is notified by triggers on the metrics table with the rows changed by each statement.
+const metricChangesChannel = "metric_changes"
+
+// listenRetryInterval is the wait before reconnecting after the listening connection failed.
+const listenRetryInterval = 5 * time.Second
+
+// metricChange is a changed metric in the payload of a metric_changes notification, which holds an array of
+// them, or a single one as sent by the row level trigger of earlier versions.
+type metricChange struct {
+	MType domain.MetricType `json:"type"`
+	ID    string            `json:"id"`
+}
+
+// ChangeListener evicts the metrics changed in the database, by this or any other replica, from a cache.
+type ChangeListener struct {
+	dsn         string
+	invalidator domain.MetricInvalidator
+	logger      *zerolog.Logger
+}
+
+func NewChangeListener(dsn string, invalidator domain.MetricInvalidator, logger *zerolog.Logger) *ChangeListener {
+	return &ChangeListener{
+		dsn:         dsn,
+		invalidator: invalidator,
+		logger:      logger,
+	}
+}
+
+// Run listens to the metric changes on a dedicated connection until shutdownCh is closed. The cache is
+// suspended whenever the connection is down, as the changes made meanwhile are not delivered.
+func (l *ChangeListener) Run(shutdownCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-shutdownCh
+		cancel()
+	}()
+
+	for {
+		l.invalidator.Suspend()
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger.Error().Err(err).Msg("metric change listener disconnected, serving metrics without the cache")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryInterval):
+		}
+	}
+}
+
+func (l *ChangeListener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() { _ = conn.Close(context.Background()) }()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+metricChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	l.invalidator.Resume()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		l.apply(notification.Payload)
+	}
+}
+
+// apply evicts the metrics of the notification payload, or everything if the payload cannot be parsed.
+func (l *ChangeListener) apply(payload string) {
+	var changes []metricChange
+	err := json.Unmarshal([]byte(payload), &changes)
+	if err != nil {
+		var change metricChange
+		err = json.Unmarshal([]byte(payload), &change)
+		changes = []metricChange{change}
+	}
+	if err != nil || slices.ContainsFunc(changes, func(change metricChange) bool { return change.ID == "" }) {
+		l.logger.Error().Str("payload", payload).Msg("unexpected metric change notification, dropping the cache")
+		l.invalidator.Suspend()
+		l.invalidator.Resume()
+		return
+	}
+
+	for _, change := range changes {
+		l.invalidator.Invalidate(change.MType, change.ID)
+	}
+}
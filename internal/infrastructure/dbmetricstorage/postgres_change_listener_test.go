@@ -0,0 +1,48 @@
+package dbmetricstorage
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// recordingInvalidator records the calls of the listener.
+type recordingInvalidator struct {
+	calls []string
+}
+
+func (r *recordingInvalidator) Invalidate(metricType domain.MetricType, metricName string) {
+	r.calls = append(r.calls, "invalidate "+string(metricType)+" "+metricName)
+}
+
+func (r *recordingInvalidator) Suspend() {
+	r.calls = append(r.calls, "suspend")
+}
+
+func (r *recordingInvalidator) Resume() {
+	r.calls = append(r.calls, "resume")
+}
+
+func TestChangeListener_Apply(t *testing.T) {
+	invalidator := &recordingInvalidator{}
+	logger := zerolog.Nop()
+	listener := NewChangeListener("", invalidator, &logger)
+
+	listener.apply(`[{"type": "counter", "id": "PollCount"}, {"type": "gauge", "id": "Alloc"}]`)
+	listener.apply(`{"type": "counter", "id": "PollCount"}`)
+	listener.apply(`not json`)
+	listener.apply(`{"type": "gauge"}`)
+	listener.apply(`[{"type": "gauge", "id": "Alloc"}, {"type": "gauge"}]`)
+
+	assert.Equal(t, []string{
+		"invalidate counter PollCount",
+		"invalidate gauge Alloc",
+		"invalidate counter PollCount",
+		"suspend", "resume",
+		"suspend", "resume",
+		"suspend", "resume",
+	}, invalidator.calls)
+}
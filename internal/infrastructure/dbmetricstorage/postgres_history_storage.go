@@ -0,0 +1,137 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// PostgresHistoryStorage appends alert history events to the alert_history table.
+type PostgresHistoryStorage struct {
+	pool *pgxpool.Pool
+}
+
+var _ domain.HistoryStorage = (*PostgresHistoryStorage)(nil)
+
+func NewHistoryStorage(dsn string) (*PostgresHistoryStorage, error) {
+	pool, err := newPool(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresHistoryStorage{pool: pool}, nil
+}
+
+func (s PostgresHistoryStorage) AppendEvent(ctx context.Context, event domain.HistoryEvent) error {
+	labels, err := json.Marshal(event.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
+	silences, err := json.Marshal(event.Silences)
+	if err != nil {
+		return fmt.Errorf("failed to marshal silences: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, insertHistoryEvent,
+		event.Time, string(event.Type), event.AlertName, labels, silences,
+		event.GroupKey, event.SilenceID, event.Actor, event.Message,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert history event: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresHistoryStorage) QueryEvents(ctx context.Context, filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	query, args := historyQuery(filter)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+
+	defer rows.Close()
+
+	events := make([]domain.HistoryEvent, 0)
+	for rows.Next() {
+		var event domain.HistoryEvent
+		var eventType string
+		var labels, silences []byte
+		err := rows.Scan(&event.Time, &eventType, &event.AlertName, &labels, &silences,
+			&event.GroupKey, &event.SilenceID, &event.Actor, &event.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan history event: %w", err)
+		}
+		event.Type = domain.HistoryEventType(eventType)
+
+		if err := json.Unmarshal(labels, &event.Labels); err != nil {
+			return nil, fmt.Errorf("failed to parse history event labels: %w", err)
+		}
+		if err := json.Unmarshal(silences, &event.Silences); err != nil {
+			return nil, fmt.Errorf("failed to parse history event silences: %w", err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return events, nil
+}
+
+// historyQuery builds the query selecting the events matching the filter, newest first.
+func historyQuery(filter domain.HistoryFilter) (string, []any) {
+	var sb strings.Builder
+	sb.WriteString(selectHistoryEvents)
+
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		fmt.Fprintf(&sb, "\tAND "+condition+"\n", len(args))
+	}
+
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		where("type = ANY($%d)", types)
+	}
+	if filter.AlertName != "" {
+		where("alert_name = $%d", filter.AlertName)
+	}
+	if len(filter.Labels) > 0 {
+		labels, _ := json.Marshal(filter.Labels)
+		where("labels @> $%d::jsonb", labels)
+	}
+	if filter.SilenceID != "" {
+		args = append(args, filter.SilenceID)
+		fmt.Fprintf(&sb, "\tAND (silence_id = $%d OR silences ? $%d)\n", len(args), len(args))
+	}
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if !filter.Since.IsZero() {
+		where("time >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("time < $%d", filter.Until)
+	}
+
+	sb.WriteString("\tORDER BY time DESC, id DESC\n")
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		fmt.Fprintf(&sb, "\tLIMIT $%d\n", len(args))
+	}
+
+	return sb.String(), args
+}
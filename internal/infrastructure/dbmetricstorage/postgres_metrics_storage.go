@@ -18,9 +18,22 @@ type PostgresMetricsStorage struct {
 	logger *zerolog.Logger
 }
 
-var _ domain.MetricStorage = (*PostgresMetricsStorage)(nil)
+var (
+	_ domain.MetricStorage      = (*PostgresMetricsStorage)(nil)
+	_ domain.StoredStateUpdater = (*PostgresMetricsStorage)(nil)
+	_ domain.StaleMetricDeleter = (*PostgresMetricsStorage)(nil)
+)
 
 func New(dsn string, logger *zerolog.Logger) (*PostgresMetricsStorage, error) {
+	pool, err := newPool(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresMetricsStorage{pool: pool, logger: logger}, nil
+}
+
+func newPool(dsn string) (*pgxpool.Pool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -38,38 +51,44 @@ func New(dsn string, logger *zerolog.Logger) (*PostgresMetricsStorage, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &PostgresMetricsStorage{pool: pool, logger: logger}, nil
+	return pool, nil
 }
 
-func (s PostgresMetricsStorage) GetAllMetrics(ctx context.Context) []domain.Metric {
-	metrics := make([]domain.Metric, 0)
-
+func (s PostgresMetricsStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
 	rows, err := s.pool.Query(ctx, selectAllMetrics)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to query metrics")
-		return metrics
+		return nil, fmt.Errorf("%w: failed to query metrics: %w", domain.ErrStorageUnavailable, err)
 	}
 
+	return scanMetrics(rows)
+}
+
+// scanMetrics reads the metric rows and closes them.
+func scanMetrics(rows pgx.Rows) ([]domain.Metric, error) {
 	defer rows.Close()
 
+	metrics := make([]domain.Metric, 0)
 	for rows.Next() {
 		var metric domain.Metric
-		err := rows.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value)
-		if err != nil {
-			panic(err)
+		var timestamp *time.Time
+		if err := rows.Scan(&metric.ID, &metric.MType, &metric.Delta, &metric.Value, &metric.UpdatedAt, &timestamp); err != nil {
+			return nil, fmt.Errorf("%w: failed to scan metric: %w", domain.ErrStorageUnavailable, err)
+		}
+		if timestamp != nil {
+			metric.Timestamp = *timestamp
 		}
 		metrics = append(metrics, metric)
 	}
 
 	if err := rows.Err(); err != nil {
-		s.logger.Error().Err(err).Msg("failed to scan metrics")
+		return nil, fmt.Errorf("%w: failed to read metrics: %w", domain.ErrStorageUnavailable, err)
 	}
 
-	return metrics
+	return metrics, nil
 }
 
 func (s PostgresMetricsStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
-	_, err := s.pool.Exec(ctx, upsertMetric, metric.ID, metric.MType, metric.Delta, metric.Value)
+	_, err := s.pool.Exec(ctx, upsertMetric, upsertArgs(metric)...)
 	if err != nil {
 		return fmt.Errorf("failed to update metric: %w", err)
 	}
@@ -77,45 +96,95 @@ func (s PostgresMetricsStorage) UpdateMetric(ctx context.Context, metric domain.
 	return nil
 }
 
+// UpdateMetrics upserts the batch with a single statement, so any batch size takes one round trip and is
+// applied atomically. Repeated metrics are merged first, adding up counter deltas.
 func (s PostgresMetricsStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
-	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	_, err := s.pool.Exec(ctx, upsertMetrics, newUpsertBatch(metrics, time.Now()).args()...)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to update metrics: %w", err)
 	}
 
-	defer func(tx pgx.Tx, ctx context.Context) {
-		_ = tx.Rollback(ctx)
-	}(tx, ctx)
+	return nil
+}
 
-	for _, metric := range metrics {
-		_, err = tx.Exec(ctx, upsertMetric,
-			metric.ID, metric.MType, metric.Delta, metric.Value,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to update metric: %w", err)
-		}
+// UpdateMetricsStored is UpdateMetrics returning the stored rows from the same statement.
+func (s PostgresMetricsStorage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	if len(metrics) == 0 {
+		return nil, nil
 	}
 
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	rows, err := s.pool.Query(ctx, upsertMetricsReturning, newUpsertBatch(metrics, time.Now()).args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update metrics: %w", err)
 	}
 
-	return nil
+	return scanMetrics(rows)
 }
 
-func (s PostgresMetricsStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
+func (s PostgresMetricsStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
 	row := s.pool.QueryRow(ctx, selectMetric, metricName, metricType)
 	metric := domain.Metric{ID: metricName, MType: metricType}
-	err := row.Scan(&metric.Delta, &metric.Value)
+	var timestamp *time.Time
+	err := row.Scan(&metric.Delta, &metric.Value, &metric.UpdatedAt, &timestamp)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Metric{}, false, nil
+	}
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return domain.Metric{}, false
-		}
+		return domain.Metric{}, false, fmt.Errorf("%w: failed to query metric: %w", domain.ErrStorageUnavailable, err)
+	}
+	if timestamp != nil {
+		metric.Timestamp = *timestamp
+	}
+
+	return metric, true, nil
+}
+
+func (s PostgresMetricsStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, deleteMetric, metricName, metricType)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metric: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s PostgresMetricsStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	tag, err := s.pool.Exec(ctx, deleteStaleMetric, metricName, metricType, before)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metric: %w", err)
 	}
 
-	return metric, true
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s PostgresMetricsStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	tag, err := s.pool.Exec(ctx, deleteMetricsByPrefix, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete metrics: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
 }
 
 func (s PostgresMetricsStorage) Ping(ctx context.Context) error {
 	return s.pool.Ping(ctx)
 }
+
+// upsertArgs returns the upsertMetric arguments for the metric, using the current time if the receive time is unknown.
+func upsertArgs(metric domain.Metric) []any {
+	updatedAt := metric.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = time.Now()
+	}
+
+	var timestamp *time.Time
+	if !metric.Timestamp.IsZero() {
+		timestamp = &metric.Timestamp
+	}
+
+	return []any{metric.ID, metric.MType, metric.Delta, metric.Value, updatedAt, timestamp}
+}
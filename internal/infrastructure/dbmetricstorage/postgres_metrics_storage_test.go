@@ -0,0 +1,138 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// testDSNEnv names the database the tests run against, they are skipped without it. The database is migrated and
+// the tests only touch metrics with IDs of their own.
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+func newTestPostgresStorage(t *testing.T) (*PostgresMetricsStorage, string) {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	require.NoError(t, Migrate(dsn))
+
+	logger := zerolog.Nop()
+	storage, err := New(dsn, &logger)
+	require.NoError(t, err)
+
+	prefix := t.Name() + strconv.FormatInt(time.Now().UnixNano(), 36) + "/"
+	t.Cleanup(func() {
+		_, _ = storage.DeleteByPrefix(context.Background(), prefix)
+		storage.pool.Close()
+	})
+
+	return storage, prefix
+}
+
+func TestPostgresMetricsStorage_UpdateMetrics(t *testing.T) {
+	ctx := context.Background()
+	storage, prefix := newTestPostgresStorage(t)
+
+	delta := func(v int64) *int64 { return &v }
+	value := func(v float64) *float64 { return &v }
+	reportedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, storage.UpdateMetric(ctx, domain.Metric{ID: prefix + "PollCount", MType: domain.MetricTypeCounter, Delta: delta(10)}))
+	stored, err := storage.UpdateMetricsStored(ctx, []domain.Metric{
+		{ID: prefix + "PollCount", MType: domain.MetricTypeCounter, Delta: delta(5)},
+		{ID: prefix + "Alloc", MType: domain.MetricTypeGauge, Value: value(1)},
+		{ID: prefix + "PollCount", MType: domain.MetricTypeCounter, Delta: delta(2)},
+		{ID: prefix + "Alloc", MType: domain.MetricTypeGauge, Value: value(2), Timestamp: reportedAt},
+	})
+	require.NoError(t, err)
+	require.Len(t, stored, 2, "repeated metrics are merged")
+
+	pollCount, found, err := storage.GetMetric(ctx, domain.MetricTypeCounter, prefix+"PollCount")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, int64(17), *pollCount.Delta, "counter deltas add up to the stored total")
+
+	alloc, found, err := storage.GetMetric(ctx, domain.MetricTypeGauge, prefix+"Alloc")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, 2.0, *alloc.Value, "the last gauge value wins")
+	assert.True(t, alloc.Timestamp.Equal(reportedAt))
+
+	for _, metric := range stored {
+		if metric.MType == domain.MetricTypeCounter {
+			assert.Equal(t, int64(17), *metric.Delta, "the stored total is returned")
+		}
+	}
+}
+
+func TestPostgresMetricsStorage_DeleteMetricIfStale(t *testing.T) {
+	ctx := context.Background()
+	storage, prefix := newTestPostgresStorage(t)
+
+	value := 1.0
+	updatedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.UpdateMetric(ctx, domain.Metric{ID: prefix + "Alloc", MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: updatedAt}))
+
+	found, err := storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, prefix+"Alloc", updatedAt)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, prefix+"Alloc", updatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestPostgresMetricsStorage_ConcurrentBatches(t *testing.T) {
+	ctx := context.Background()
+	storage, prefix := newTestPostgresStorage(t)
+
+	// Batches sharing the same metrics in opposite orders would deadlock if their rows were not ordered
+	ids := make([]string, 50)
+	for i := range ids {
+		ids[i] = prefix + "Counter" + strconv.Itoa(i)
+	}
+
+	const workers, rounds = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				batch := make([]domain.Metric, len(ids))
+				for i, id := range ids {
+					if w%2 == 1 {
+						id = ids[len(ids)-1-i]
+					}
+					one := int64(1)
+					batch[i] = domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &one}
+				}
+				errs <- storage.UpdateMetrics(ctx, batch)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	for _, id := range ids {
+		metric, found, err := storage.GetMetric(ctx, domain.MetricTypeCounter, id)
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, int64(workers*rounds), *metric.Delta)
+	}
+}
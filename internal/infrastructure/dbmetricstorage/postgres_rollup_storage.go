@@ -0,0 +1,122 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// PostgresRollupStorage keeps the rollups of the metric history in the metric_rollups table next to the metrics.
+type PostgresRollupStorage struct {
+	pool *pgxpool.Pool
+}
+
+var _ domain.RollupStorage = (*PostgresRollupStorage)(nil)
+
+func NewRollupStorage(dsn string) (*PostgresRollupStorage, error) {
+	pool, err := newPool(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresRollupStorage{pool: pool}, nil
+}
+
+func (s PostgresRollupStorage) LoadRollups(ctx context.Context) ([]domain.RollupSeries, error) {
+	rows, err := s.pool.Query(ctx, selectAllRollups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rollups: %w", err)
+	}
+
+	defer rows.Close()
+
+	var result []domain.RollupSeries
+	for rows.Next() {
+		var mType, id string
+		var seconds int64
+		var rollup domain.Rollup
+		err := rows.Scan(&mType, &id, &seconds, &rollup.Start, &rollup.Count,
+			&rollup.Min, &rollup.Max, &rollup.Avg, &rollup.Sum, &rollup.Last)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rollup: %w", err)
+		}
+
+		// The rows are ordered by series, a new series starts whenever the key changes
+		resolution := domain.Resolution(time.Duration(seconds) * time.Second)
+		n := len(result)
+		if n == 0 || result[n-1].MType != domain.MetricType(mType) || result[n-1].ID != id || result[n-1].Resolution != resolution {
+			result = append(result, domain.RollupSeries{MType: domain.MetricType(mType), ID: id, Resolution: resolution})
+			n++
+		}
+		result[n-1].Rollups = append(result[n-1].Rollups, rollup)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rollups: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s PostgresRollupStorage) AppendRollups(ctx context.Context, series []domain.RollupSeries) error {
+	var types, ids []string
+	var resolutions []int64
+	var starts []time.Time
+	var counts []int32
+	var mins, maxes, avgs, sums, lasts []float64
+	for _, item := range series {
+		for _, rollup := range item.Rollups {
+			types = append(types, string(item.MType))
+			ids = append(ids, item.ID)
+			resolutions = append(resolutions, int64(time.Duration(item.Resolution)/time.Second))
+			starts = append(starts, rollup.Start)
+			counts = append(counts, int32(rollup.Count))
+			mins = append(mins, rollup.Min)
+			maxes = append(maxes, rollup.Max)
+			avgs = append(avgs, rollup.Avg)
+			sums = append(sums, rollup.Sum)
+			lasts = append(lasts, rollup.Last)
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	_, err := s.pool.Exec(ctx, insertRollups, types, ids, resolutions, starts, counts, mins, maxes, avgs, sums, lasts)
+	if err != nil {
+		return fmt.Errorf("failed to insert rollups: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresRollupStorage) PruneRollups(ctx context.Context, resolution domain.Resolution, before time.Time) error {
+	_, err := s.pool.Exec(ctx, pruneRollups, int64(time.Duration(resolution)/time.Second), before)
+	if err != nil {
+		return fmt.Errorf("failed to prune rollups: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresRollupStorage) DeleteRollups(ctx context.Context, metricType domain.MetricType, metricName string) error {
+	_, err := s.pool.Exec(ctx, deleteRollups, metricType, metricName)
+	if err != nil {
+		return fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresRollupStorage) DeleteRollupsByPrefix(ctx context.Context, prefix string) error {
+	_, err := s.pool.Exec(ctx, deleteRollupsByPrefix, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestPostgresRollupStorage(t *testing.T) {
+	ctx := context.Background()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	require.NoError(t, Migrate(dsn))
+
+	storage, err := NewRollupStorage(dsn)
+	require.NoError(t, err)
+	prefix := t.Name() + strconv.FormatInt(time.Now().UnixNano(), 36) + "/"
+	t.Cleanup(func() {
+		_ = storage.DeleteRollupsByPrefix(context.Background(), prefix)
+		storage.pool.Close()
+	})
+
+	// Other tests may have rollups of their own, only the ones of this test are compared
+	load := func() []domain.RollupSeries {
+		stored, err := storage.LoadRollups(ctx)
+		require.NoError(t, err)
+		stored = slices.DeleteFunc(stored, func(series domain.RollupSeries) bool {
+			return !strings.HasPrefix(series.ID, prefix)
+		})
+		slices.SortFunc(stored, func(a, b domain.RollupSeries) int {
+			if a.Resolution != b.Resolution {
+				return int(a.Resolution - b.Resolution)
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+		for _, series := range stored {
+			for i := range series.Rollups {
+				series.Rollups[i].Start = series.Rollups[i].Start.UTC()
+			}
+		}
+		return stored
+	}
+
+	// Far in the past, so the prune below does not touch the rollups of other tests
+	start := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := domain.Resolution(time.Minute)
+	hour := domain.Resolution(time.Hour)
+	rollup := func(offset time.Duration, last float64) domain.Rollup {
+		return domain.Rollup{Start: start.Add(offset), Count: 1, Min: last, Max: last, Avg: last, Last: last}
+	}
+
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: prefix + "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(0, 1), rollup(time.Minute, 2)}},
+		{MType: domain.MetricTypeGauge, ID: prefix + "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+		{MType: domain.MetricTypeCounter, ID: prefix + "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start, Count: 2, Sum: 5, Last: 5}}},
+	}))
+	// A retried write keeps the rollups stored already
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: prefix + "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+	}))
+	require.NoError(t, storage.PruneRollups(ctx, minute, start.Add(time.Minute)))
+
+	want := []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: prefix + "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+		{MType: domain.MetricTypeGauge, ID: prefix + "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+		{MType: domain.MetricTypeCounter, ID: prefix + "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start, Count: 2, Sum: 5, Last: 5}}},
+	}
+	assert.Equal(t, want, load())
+
+	require.NoError(t, storage.DeleteRollups(ctx, domain.MetricTypeGauge, prefix+"Alloc"))
+	assert.Equal(t, want[2:], load())
+}
@@ -0,0 +1,93 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// PostgresRuleStorage keeps alert rules in the rules table next to the metrics.
+type PostgresRuleStorage struct {
+	pool *pgxpool.Pool
+}
+
+var _ domain.RuleStorage = (*PostgresRuleStorage)(nil)
+
+func NewRuleStorage(dsn string) (*PostgresRuleStorage, error) {
+	pool, err := newPool(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresRuleStorage{pool: pool}, nil
+}
+
+func (s PostgresRuleStorage) ListRules(ctx context.Context) ([]domain.Rule, error) {
+	rows, err := s.pool.Query(ctx, selectAllRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rules: %w", err)
+	}
+
+	defer rows.Close()
+
+	var rules []domain.Rule
+	for rows.Next() {
+		var rule domain.Rule
+		var forDuration int64
+		var labels, annotations []byte
+		err := rows.Scan(&rule.Name, &rule.Expr, &forDuration, &labels, &annotations, &rule.CreatedAt, &rule.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rule: %w", err)
+		}
+		rule.For = domain.Duration(forDuration)
+
+		if err := json.Unmarshal(labels, &rule.Labels); err != nil {
+			return nil, fmt.Errorf("failed to parse labels of rule %s: %w", rule.Name, err)
+		}
+		if err := json.Unmarshal(annotations, &rule.Annotations); err != nil {
+			return nil, fmt.Errorf("failed to parse annotations of rule %s: %w", rule.Name, err)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (s PostgresRuleStorage) SaveRule(ctx context.Context, rule domain.Rule) error {
+	labels, err := json.Marshal(rule.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels: %w", err)
+	}
+
+	annotations, err := json.Marshal(rule.Annotations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, upsertRule,
+		rule.Name, rule.Expr, int64(rule.For), labels, annotations, rule.CreatedAt, rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save rule: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresRuleStorage) DeleteRule(ctx context.Context, name string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, deleteRule, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete rule: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
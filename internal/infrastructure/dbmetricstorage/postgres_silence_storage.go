@@ -0,0 +1,83 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// PostgresSilenceStorage keeps silences in the silences table next to the metrics.
+type PostgresSilenceStorage struct {
+	pool *pgxpool.Pool
+}
+
+var _ domain.SilenceStorage = (*PostgresSilenceStorage)(nil)
+
+func NewSilenceStorage(dsn string) (*PostgresSilenceStorage, error) {
+	pool, err := newPool(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PostgresSilenceStorage{pool: pool}, nil
+}
+
+func (s PostgresSilenceStorage) ListSilences(ctx context.Context) ([]domain.Silence, error) {
+	rows, err := s.pool.Query(ctx, selectAllSilences)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query silences: %w", err)
+	}
+
+	defer rows.Close()
+
+	var silences []domain.Silence
+	for rows.Next() {
+		var silence domain.Silence
+		var matchers []byte
+		err := rows.Scan(&silence.ID, &matchers, &silence.StartsAt, &silence.EndsAt, &silence.CreatedBy, &silence.Comment, &silence.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+
+		if err := json.Unmarshal(matchers, &silence.Matchers); err != nil {
+			return nil, fmt.Errorf("failed to parse matchers of silence %s: %w", silence.ID, err)
+		}
+
+		silences = append(silences, silence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read silences: %w", err)
+	}
+
+	return silences, nil
+}
+
+func (s PostgresSilenceStorage) SaveSilence(ctx context.Context, silence domain.Silence) error {
+	matchers, err := json.Marshal(silence.Matchers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal matchers: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, upsertSilence,
+		silence.ID, matchers, silence.StartsAt, silence.EndsAt, silence.CreatedBy, silence.Comment, silence.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save silence: %w", err)
+	}
+
+	return nil
+}
+
+func (s PostgresSilenceStorage) DeleteSilence(ctx context.Context, id string) (bool, error) {
+	tag, err := s.pool.Exec(ctx, deleteSilence, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete silence: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -1,11 +1,12 @@
 package dbmetricstorage
 
 const selectAllMetrics = `
-	SELECT * FROM metrics
+	SELECT id, type, value_delta, value_gauge, updated_at, client_timestamp
+	FROM metrics
 `
 
 const selectMetric = `
-	SELECT value_delta, value_gauge 
+	SELECT value_delta, value_gauge, updated_at, client_timestamp
 	FROM metrics 
 	WHERE 
 		id = $1 
@@ -14,13 +15,153 @@ const selectMetric = `
 `
 
 const upsertMetric = `
-    INSERT INTO metrics (id, type, value_delta, value_gauge)
-	VALUES ($1, $2, $3, $4)
+    INSERT INTO metrics (id, type, value_delta, value_gauge, updated_at, client_timestamp)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	ON CONFLICT (id, type) DO UPDATE SET
 		value_delta = CASE 
 			WHEN metrics.type = 'counter' 
 			THEN metrics.value_delta + EXCLUDED.value_delta
 			ELSE EXCLUDED.value_delta
 		END,
-		value_gauge = EXCLUDED.value_gauge
+		value_gauge = EXCLUDED.value_gauge,
+		updated_at = EXCLUDED.updated_at,
+		client_timestamp = EXCLUDED.client_timestamp
+`
+
+// upsertMetrics is upsertMetric for a whole batch passed as column arrays. A statement cannot update a row
+// twice, so the batch must not contain the same metric more than once.
+const upsertMetrics = `
+    INSERT INTO metrics (id, type, value_delta, value_gauge, updated_at, client_timestamp)
+	SELECT * FROM unnest(
+		$1::varchar[], $2::varchar[], $3::bigint[], $4::double precision[], $5::timestamptz[], $6::timestamptz[]
+	)
+	ON CONFLICT (id, type) DO UPDATE SET
+		value_delta = CASE 
+			WHEN metrics.type = 'counter' 
+			THEN metrics.value_delta + EXCLUDED.value_delta
+			ELSE EXCLUDED.value_delta
+		END,
+		value_gauge = EXCLUDED.value_gauge,
+		updated_at = EXCLUDED.updated_at,
+		client_timestamp = EXCLUDED.client_timestamp
+`
+
+// upsertMetricsReturning is upsertMetrics returning the stored rows.
+const upsertMetricsReturning = upsertMetrics + `
+	RETURNING id, type, value_delta, value_gauge, updated_at, client_timestamp
+`
+
+const deleteMetric = `
+	DELETE FROM metrics
+	WHERE
+		id = $1
+	  AND
+		type = $2
+`
+
+const deleteStaleMetric = `
+	DELETE FROM metrics
+	WHERE
+		id = $1
+	  AND
+		type = $2
+	  AND
+		updated_at < $3
+`
+
+const deleteMetricsByPrefix = `
+	DELETE FROM metrics
+	WHERE left(id, length($1)) = $1
+`
+
+const selectAllSilences = `
+	SELECT id, matchers, starts_at, ends_at, created_by, comment, created_at
+	FROM silences
+`
+
+const upsertSilence = `
+	INSERT INTO silences (id, matchers, starts_at, ends_at, created_by, comment, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (id) DO UPDATE SET
+		matchers = EXCLUDED.matchers,
+		starts_at = EXCLUDED.starts_at,
+		ends_at = EXCLUDED.ends_at,
+		created_by = EXCLUDED.created_by,
+		comment = EXCLUDED.comment
+`
+
+const deleteSilence = `
+	DELETE FROM silences
+	WHERE id = $1
+`
+
+const selectAllRules = `
+	SELECT name, expr, for_duration, labels, annotations, created_at, updated_at
+	FROM rules
+`
+
+const upsertRule = `
+	INSERT INTO rules (name, expr, for_duration, labels, annotations, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (name) DO UPDATE SET
+		expr = EXCLUDED.expr,
+		for_duration = EXCLUDED.for_duration,
+		labels = EXCLUDED.labels,
+		annotations = EXCLUDED.annotations,
+		updated_at = EXCLUDED.updated_at
+`
+
+const deleteRule = `
+	DELETE FROM rules
+	WHERE name = $1
+`
+
+const insertHistoryEvent = `
+	INSERT INTO alert_history (time, type, alert_name, labels, silences, group_key, silence_id, actor, message)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+
+// selectHistoryEvents is completed with the conditions of the filter, ordering and limit.
+const selectHistoryEvents = `
+	SELECT time, type, alert_name, labels, silences, group_key, silence_id, actor, message
+	FROM alert_history
+	WHERE true
+`
+
+const selectAllRollups = `
+	SELECT type, id, resolution_seconds, start, count, min, max, avg, sum, last
+	FROM metric_rollups
+	ORDER BY type, id, resolution_seconds, start
+`
+
+// insertRollups inserts a batch of rollups passed as column arrays. Rollups stored already by an earlier
+// attempt of the same batch are kept.
+const insertRollups = `
+	INSERT INTO metric_rollups (type, id, resolution_seconds, start, count, min, max, avg, sum, last)
+	SELECT * FROM unnest(
+		$1::varchar[], $2::varchar[], $3::bigint[], $4::timestamptz[], $5::integer[],
+		$6::double precision[], $7::double precision[], $8::double precision[], $9::double precision[], $10::double precision[]
+	)
+	ON CONFLICT (type, id, resolution_seconds, start) DO NOTHING
+`
+
+const pruneRollups = `
+	DELETE FROM metric_rollups
+	WHERE
+		resolution_seconds = $1
+	  AND
+		start < $2
+`
+
+const deleteRollups = `
+	DELETE FROM metric_rollups
+	WHERE
+		type = $1
+	  AND
+		id = $2
+`
+
+const deleteRollupsByPrefix = `
+	DELETE FROM metric_rollups
+	WHERE left(id, length($1)) = $1
 `
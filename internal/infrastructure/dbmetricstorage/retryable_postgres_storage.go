@@ -2,73 +2,149 @@ package dbmetricstorage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
+// RetryablePostgresStorage is a domain.MetricStorage decorator retrying the statements that failed because of
+// the database, behind a circuit breaker. Errors of an unavailable database wrap domain.ErrStorageUnavailable.
 type RetryablePostgresStorage struct {
-	storage        domain.MetricStorage
-	retryIntervals []time.Duration
-	logger         *zerolog.Logger
+	storage domain.MetricStorage
+	// updates retries only statements that were not applied, as counter deltas must not be added twice
+	updates *resilience.Retrier
+	// idempotent retries also statements that may have been applied before the connection was lost
+	idempotent *resilience.Retrier
 }
 
-var _ domain.MetricStorage = (*RetryablePostgresStorage)(nil)
+var (
+	_ domain.MetricStorage      = (*RetryablePostgresStorage)(nil)
+	_ domain.StoredStateUpdater = (*RetryablePostgresStorage)(nil)
+	_ domain.StaleMetricDeleter = (*RetryablePostgresStorage)(nil)
+)
 
 func NewRetryableDBStorage(
 	storage domain.MetricStorage,
-	retryAttempts []time.Duration,
+	backoff resilience.Backoff,
+	breaker *resilience.Breaker,
 	logger *zerolog.Logger,
 ) *RetryablePostgresStorage {
 	return &RetryablePostgresStorage{
-		storage:        storage,
-		retryIntervals: append([]time.Duration{0}, retryAttempts...),
-		logger:         logger,
+		storage:    storage,
+		updates:    resilience.NewRetrier(backoff, breaker, resilience.ClassifyPgError, logger),
+		idempotent: resilience.NewRetrier(backoff, breaker, resilience.Idempotent(resilience.ClassifyPgError), logger),
 	}
 }
 
 func (s *RetryablePostgresStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
-	return s.withRetry(func() error {
+	return s.do(ctx, s.updates, func() error {
 		return s.storage.UpdateMetric(ctx, metric)
 	})
 }
 
-func (s *RetryablePostgresStorage) GetAllMetrics(ctx context.Context) []domain.Metric {
-	return s.storage.GetAllMetrics(ctx)
+func (s *RetryablePostgresStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	var metrics []domain.Metric
+	err := s.do(ctx, s.idempotent, func() error {
+		var err error
+		metrics, err = s.storage.GetAllMetrics(ctx)
+		return err
+	})
+
+	return metrics, err
 }
 
 func (s *RetryablePostgresStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
-	return s.withRetry(func() error {
+	return s.do(ctx, s.updates, func() error {
 		return s.storage.UpdateMetrics(ctx, metrics)
 	})
 }
 
-func (s *RetryablePostgresStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
-	return s.storage.GetMetric(ctx, metricType, metricName)
+func (s *RetryablePostgresStorage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	updater, ok := s.storage.(domain.StoredStateUpdater)
+	if !ok {
+		return nil, domain.ErrNotStoredState
+	}
+
+	var stored []domain.Metric
+	err := s.do(ctx, s.updates, func() error {
+		var err error
+		stored, err = updater.UpdateMetricsStored(ctx, metrics)
+		return err
+	})
+
+	return stored, err
+}
+
+func (s *RetryablePostgresStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	var metric domain.Metric
+	var found bool
+	err := s.do(ctx, s.idempotent, func() error {
+		var err error
+		metric, found, err = s.storage.GetMetric(ctx, metricType, metricName)
+		return err
+	})
+
+	return metric, found, err
+}
+
+func (s *RetryablePostgresStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	var found bool
+	err := s.do(ctx, s.idempotent, func() error {
+		var err error
+		found, err = s.storage.DeleteMetric(ctx, metricType, metricName)
+		return err
+	})
+
+	return found, err
+}
+
+func (s *RetryablePostgresStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	deleter, ok := s.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		return false, domain.ErrNotStaleDeleter
+	}
+
+	var found bool
+	err := s.do(ctx, s.idempotent, func() error {
+		var err error
+		found, err = deleter.DeleteMetricIfStale(ctx, metricType, metricName, before)
+		return err
+	})
+
+	return found, err
+}
+
+func (s *RetryablePostgresStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	var deleted int
+	err := s.do(ctx, s.idempotent, func() error {
+		var err error
+		deleted, err = s.storage.DeleteByPrefix(ctx, prefix)
+		return err
+	})
+
+	return deleted, err
 }
 
 func (s *RetryablePostgresStorage) Ping(ctx context.Context) error {
-	return s.withRetry(func() error {
+	return s.do(ctx, s.idempotent, func() error {
 		return s.storage.Ping(ctx)
 	})
 }
 
-func (s *RetryablePostgresStorage) withRetry(target func() error) error {
-	var err error
-	for n, interval := range s.retryIntervals {
-		if n > 0 {
-			s.logger.Warn().Msgf("retrying connect %d in %f", n, interval.Seconds())
-			time.Sleep(interval)
-		}
-
-		err = target()
-		if err == nil {
-			return nil
-		}
-		s.logger.Error().Err(err).Msgf("failed to connect %s", err)
+// do runs the statement with the retrier, marking the errors of an unavailable database.
+func (s *RetryablePostgresStorage) do(ctx context.Context, retrier *resilience.Retrier, op func() error) error {
+	err := retrier.Do(ctx, op)
+	if err == nil || errors.Is(err, domain.ErrStorageUnavailable) {
+		return err
 	}
 
+	if errors.Is(err, resilience.ErrCircuitOpen) || resilience.ClassifyPgError(err) != resilience.Permanent {
+		return fmt.Errorf("%w: %w", domain.ErrStorageUnavailable, err)
+	}
 	return err
 }
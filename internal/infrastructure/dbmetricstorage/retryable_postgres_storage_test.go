@@ -0,0 +1,100 @@
+package dbmetricstorage
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
+)
+
+// flakyStorage fails the calls with the queued errors before passing them to the memory storage.
+type flakyStorage struct {
+	*metricstorage.MemoryMetricStorage
+	errs  []error
+	calls int
+}
+
+func (s *flakyStorage) fail() error {
+	s.calls++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func (s *flakyStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.MemoryMetricStorage.UpdateMetric(ctx, metric)
+}
+
+func (s *flakyStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	if err := s.fail(); err != nil {
+		return nil, err
+	}
+	return s.MemoryMetricStorage.GetAllMetrics(ctx)
+}
+
+func newTestRetryableStorage(breaker *resilience.Breaker, errs ...error) (*RetryablePostgresStorage, *flakyStorage) {
+	logger := zerolog.Nop()
+	flaky := &flakyStorage{MemoryMetricStorage: metricstorage.NewMemoryMetricStorage(), errs: errs}
+	backoff := resilience.Backoff{Retries: 2, Initial: time.Millisecond}
+	return NewRetryableDBStorage(flaky, backoff, breaker, &logger), flaky
+}
+
+func TestRetryablePostgresStorage_Updates(t *testing.T) {
+	ctx := context.Background()
+	delta := int64(1)
+	pollCount := domain.Metric{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: &delta}
+
+	storage, flaky := newTestRetryableStorage(nil, &pgconn.ConnectError{}, &pgconn.PgError{Code: "40001"})
+	require.NoError(t, storage.UpdateMetric(ctx, pollCount))
+	assert.Equal(t, 3, flaky.calls, "statements that were not applied are retried")
+
+	storage, flaky = newTestRetryableStorage(nil, io.ErrUnexpectedEOF)
+	err := storage.UpdateMetric(ctx, pollCount)
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+	assert.Equal(t, 1, flaky.calls, "a counter update that may have been applied is not repeated")
+
+	storage, flaky = newTestRetryableStorage(nil, &pgconn.PgError{Code: "23502"})
+	err = storage.UpdateMetric(ctx, pollCount)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrStorageUnavailable)
+	assert.Equal(t, 1, flaky.calls)
+}
+
+func TestRetryablePostgresStorage_Reads(t *testing.T) {
+	ctx := context.Background()
+
+	storage, flaky := newTestRetryableStorage(nil, io.ErrUnexpectedEOF, io.ErrUnexpectedEOF)
+	_, err := storage.GetAllMetrics(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, flaky.calls, "reads are retried after a lost connection")
+}
+
+func TestRetryablePostgresStorage_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	down := &pgconn.ConnectError{}
+
+	storage, flaky := newTestRetryableStorage(resilience.NewBreaker(3, time.Hour), down, down, down, down)
+	_, err := storage.GetAllMetrics(ctx)
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+	assert.Equal(t, 3, flaky.calls)
+
+	_, err = storage.GetAllMetrics(ctx)
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+	assert.ErrorIs(t, err, resilience.ErrCircuitOpen)
+	assert.Equal(t, 3, flaky.calls, "the database is not called while the breaker is open")
+}
@@ -0,0 +1,111 @@
+package dbmetricstorage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// upsertBatch holds the columns of the upsertMetrics arguments, a metric per index.
+type upsertBatch struct {
+	ids        []string
+	types      []string
+	deltas     []*int64
+	values     []*float64
+	updatedAt  []time.Time
+	timestamps []*time.Time
+}
+
+// newUpsertBatch merges the repeated metrics of the batch: counter deltas add up, while the value and the times
+// of the last update win. Metrics without a receive time get now. The rows are ordered by type and ID, so
+// concurrent batches lock the rows they share in the same order and do not deadlock.
+func newUpsertBatch(metrics []domain.Metric, now time.Time) upsertBatch {
+	type key struct {
+		id    string
+		mType domain.MetricType
+	}
+
+	batch := upsertBatch{
+		ids:        make([]string, 0, len(metrics)),
+		types:      make([]string, 0, len(metrics)),
+		deltas:     make([]*int64, 0, len(metrics)),
+		values:     make([]*float64, 0, len(metrics)),
+		updatedAt:  make([]time.Time, 0, len(metrics)),
+		timestamps: make([]*time.Time, 0, len(metrics)),
+	}
+	index := make(map[key]int, len(metrics))
+
+	for _, metric := range metrics {
+		updatedAt := metric.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = now
+		}
+
+		var timestamp *time.Time
+		if !metric.Timestamp.IsZero() {
+			timestamp = &metric.Timestamp
+		}
+
+		k := key{id: metric.ID, mType: metric.MType}
+		i, found := index[k]
+		if !found {
+			index[k] = len(batch.ids)
+			batch.ids = append(batch.ids, metric.ID)
+			batch.types = append(batch.types, string(metric.MType))
+			batch.deltas = append(batch.deltas, copyOf(metric.Delta))
+			batch.values = append(batch.values, copyOf(metric.Value))
+			batch.updatedAt = append(batch.updatedAt, updatedAt)
+			batch.timestamps = append(batch.timestamps, timestamp)
+			continue
+		}
+
+		switch {
+		case metric.MType != domain.MetricTypeCounter:
+			batch.deltas[i] = copyOf(metric.Delta)
+			batch.values[i] = copyOf(metric.Value)
+		case batch.deltas[i] == nil:
+			batch.deltas[i] = copyOf(metric.Delta)
+		case metric.Delta != nil:
+			*batch.deltas[i] += *metric.Delta
+		}
+		batch.updatedAt[i] = updatedAt
+		batch.timestamps[i] = timestamp
+	}
+
+	sort.Sort(batch)
+	return batch
+}
+
+func (b upsertBatch) Len() int {
+	return len(b.ids)
+}
+
+func (b upsertBatch) Less(i, j int) bool {
+	if b.types[i] != b.types[j] {
+		return b.types[i] < b.types[j]
+	}
+	return b.ids[i] < b.ids[j]
+}
+
+func (b upsertBatch) Swap(i, j int) {
+	b.ids[i], b.ids[j] = b.ids[j], b.ids[i]
+	b.types[i], b.types[j] = b.types[j], b.types[i]
+	b.deltas[i], b.deltas[j] = b.deltas[j], b.deltas[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.updatedAt[i], b.updatedAt[j] = b.updatedAt[j], b.updatedAt[i]
+	b.timestamps[i], b.timestamps[j] = b.timestamps[j], b.timestamps[i]
+}
+
+func (b upsertBatch) args() []any {
+	return []any{b.ids, b.types, b.deltas, b.values, b.updatedAt, b.timestamps}
+}
+
+// copyOf keeps the merged values from changing the metrics of the caller.
+func copyOf[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
@@ -0,0 +1,40 @@
+package dbmetricstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestNewUpsertBatch(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	receivedAt := now.Add(-time.Minute)
+	reportedAt := now.Add(-2 * time.Minute)
+
+	delta := func(v int64) *int64 { return &v }
+	value := func(v float64) *float64 { return &v }
+
+	first := delta(5)
+	metrics := []domain.Metric{
+		{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: first},
+		{ID: "Alloc", MType: domain.MetricTypeGauge, Value: value(1)},
+		{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: delta(3), UpdatedAt: receivedAt},
+		{ID: "Alloc", MType: domain.MetricTypeGauge, Value: value(2), Timestamp: reportedAt},
+		{ID: "Alloc", MType: domain.MetricTypeCounter, Delta: delta(1)},
+		{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: delta(2), UpdatedAt: receivedAt},
+	}
+
+	batch := newUpsertBatch(metrics, now)
+
+	assert.Equal(t, []string{"Alloc", "PollCount", "Alloc"}, batch.ids, "the rows are ordered by type and ID")
+	assert.Equal(t, []string{"counter", "counter", "gauge"}, batch.types)
+	assert.Equal(t, []*int64{delta(1), delta(10), nil}, batch.deltas, "counter deltas add up")
+	assert.Equal(t, []*float64{nil, nil, value(2)}, batch.values, "the last gauge value wins")
+	assert.Equal(t, []time.Time{now, receivedAt, now}, batch.updatedAt)
+	assert.Equal(t, []*time.Time{nil, nil, &reportedAt}, batch.timestamps)
+	assert.Equal(t, int64(5), *first, "the metrics of the caller are not changed")
+	assert.Len(t, batch.args(), 6)
+}
@@ -0,0 +1,229 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// forwardTimeout limits a forwarding round, including the batches sent from the spool.
+const forwardTimeout = 30 * time.Second
+
+// Options configure what a server forwards to its upstream server.
+type Options struct {
+	// Prefix is prepended to the metric names, so the upstream server tells the metrics of the edges apart.
+	Prefix string
+	// BatchSize is the maximum number of metrics sent in a single request.
+	BatchSize int
+	// SpoolPath is the file keeping the batches while the upstream server is unavailable, if empty they are dropped.
+	// The counter totals already forwarded are kept next to it, so a restart continues where the last round stopped.
+	SpoolPath string
+	// SpoolMaxSize limits the spool file in bytes, no limit if not positive.
+	SpoolMaxSize int64
+}
+
+// Forwarder makes an edge server an agent of an upstream server. Every interval it sends the aggregate of the
+// updates since the last round: the increments of the counters and the gauges that were updated.
+// Batches the upstream server does not accept are spooled to disk and sent again, in order, before the next
+// round. A batch that reached the upstream server but failed with a lost response is sent again and its
+// counter increments count twice.
+type Forwarder struct {
+	storage domain.MetricStorage
+	sender  domain.MetricBatchSender
+	options Options
+	spool   *spool
+	logger  *zerolog.Logger
+	now     func() time.Time
+
+	// forwarded holds the counter totals that were already forwarded, nil before the first round
+	forwarded map[string]int64
+	// since is the start of the last round, gauges updated later are forwarded
+	since time.Time
+}
+
+func New(storage domain.MetricStorage, sender domain.MetricBatchSender, options Options, logger *zerolog.Logger) (*Forwarder, error) {
+	if options.BatchSize <= 0 {
+		return nil, errors.New("forwarding batch size must be positive")
+	}
+
+	f := &Forwarder{
+		storage: storage,
+		sender:  sender,
+		options: options,
+		logger:  logger,
+		now:     time.Now,
+	}
+
+	if options.SpoolPath != "" {
+		var err error
+		if f.spool, err = openSpool(options.SpoolPath, options.SpoolMaxSize); err != nil {
+			return nil, err
+		}
+		if f.forwarded, err = f.spool.readForwarded(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Run forwards at the start and every interval until shutdownCh is closed, then forwards a last time.
+// Without saved totals, the first round only takes the current counter totals as forwarded.
+func (f *Forwarder) Run(interval time.Duration, shutdownCh <-chan struct{}) {
+	f.Forward(context.Background())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			f.Forward(context.Background())
+			return
+		case <-ticker.C:
+			f.Forward(context.Background())
+		}
+	}
+}
+
+// Forward sends the spooled batches and the updates since the last round.
+func (f *Forwarder) Forward(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+
+	startedAt := f.now()
+	metrics, err := f.storage.GetAllMetrics(ctx)
+	if err != nil {
+		f.logger.Error().Err(err).Msg("failed to read metrics to forward")
+		return
+	}
+
+	f.send(ctx, f.batches(f.aggregate(metrics, startedAt)))
+
+	// The updates of the round are sent or spooled now
+	if f.spool != nil {
+		if err := f.spool.writeForwarded(f.forwarded); err != nil {
+			f.logger.Error().Err(err).Msg("failed to save forwarded totals")
+		}
+	}
+}
+
+// aggregate returns the updates since the last round, named for the upstream server, and starts a new round.
+func (f *Forwarder) aggregate(metrics []domain.Metric, startedAt time.Time) []domain.Metric {
+	first := f.forwarded == nil
+	totals := make(map[string]int64)
+	var updates []domain.Metric
+
+	for _, metric := range metrics {
+		update := domain.Metric{
+			ID:        f.options.Prefix + metric.ID,
+			MType:     metric.MType,
+			Timestamp: metric.UpdatedAt,
+		}
+		if update.Timestamp.IsZero() {
+			update.Timestamp = startedAt
+		}
+
+		switch metric.MType {
+		case domain.MetricTypeGauge:
+			if !metric.UpdatedAt.IsZero() && !metric.UpdatedAt.After(f.since) {
+				continue
+			}
+			value := *metric.Value
+			update.Value = &value
+
+		case domain.MetricTypeCounter:
+			// A deleted counter is gone from the totals, once it is updated again it is forwarded in full.
+			// A total below the forwarded one is a counter deleted and updated again within the round.
+			totals[metric.ID] = *metric.Delta
+			delta := *metric.Delta - f.forwarded[metric.ID]
+			if delta < 0 {
+				delta = *metric.Delta
+			}
+			if first || delta == 0 {
+				continue
+			}
+			update.Delta = &delta
+
+		default:
+			continue
+		}
+
+		updates = append(updates, update)
+	}
+
+	f.forwarded = totals
+	f.since = startedAt
+	return updates
+}
+
+func (f *Forwarder) batches(metrics []domain.Metric) [][]domain.Metric {
+	var batches [][]domain.Metric
+	for len(metrics) > 0 {
+		size := min(f.options.BatchSize, len(metrics))
+		batches = append(batches, metrics[:size])
+		metrics = metrics[size:]
+	}
+	return batches
+}
+
+// send sends the spooled batches first, then the new ones. Once a batch fails, it and all later ones are spooled,
+// so the upstream server gets the updates in order.
+func (f *Forwarder) send(ctx context.Context, batches [][]domain.Metric) {
+	if f.spool != nil && !f.spool.empty() {
+		spooled, err := f.spool.read()
+		if err != nil {
+			f.logger.Error().Err(err).Int("batches", len(spooled)).Msg("spool is damaged, later batches are lost")
+		}
+
+		sent := f.sendAll(ctx, spooled)
+		if sent > 0 || sent == len(spooled) || err != nil {
+			if err := f.spool.replace(spooled[sent:]); err != nil {
+				f.logger.Error().Err(err).Msg("failed to update spool")
+			}
+		}
+		if sent < len(spooled) {
+			// The upstream server is still unavailable, the new batches are spooled after the old ones
+			f.spoolBatches(batches)
+			return
+		}
+	}
+
+	if sent := f.sendAll(ctx, batches); sent < len(batches) {
+		f.spoolBatches(batches[sent:])
+	}
+}
+
+// sendAll sends the batches until one fails and returns the number of batches sent.
+func (f *Forwarder) sendAll(ctx context.Context, batches [][]domain.Metric) int {
+	for i, batch := range batches {
+		if err := f.sender.SendBatch(ctx, batch); err != nil {
+			f.logger.Warn().Err(err).Int("batches", len(batches)-i).Msg("failed to forward metrics to upstream server")
+			return i
+		}
+	}
+	return len(batches)
+}
+
+func (f *Forwarder) spoolBatches(batches [][]domain.Metric) {
+	if len(batches) == 0 {
+		return
+	}
+	if f.spool == nil {
+		f.logger.Error().Int("batches", len(batches)).Msg("no spool, dropping metrics not forwarded")
+		return
+	}
+
+	dropped, err := f.spool.append(batches)
+	if err != nil {
+		f.logger.Error().Err(err).Int("batches", dropped).Msg("failed to spool metrics, dropping them")
+		return
+	}
+	if dropped > 0 {
+		f.logger.Error().Int("batches", dropped).Msg("spool is full, dropping metrics not forwarded")
+	}
+}
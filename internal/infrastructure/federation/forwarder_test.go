@@ -0,0 +1,255 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+var errUpstreamDown = errors.New("upstream server is down")
+
+var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func gauge(id string, value float64, updatedAt time.Time) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: updatedAt}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+// recordingSender records the accepted batches and fails all batches while down.
+type recordingSender struct {
+	batches [][]domain.Metric
+	down    bool
+}
+
+func (s *recordingSender) SendBatch(ctx context.Context, metrics []domain.Metric) error {
+	if s.down {
+		return errUpstreamDown
+	}
+	s.batches = append(s.batches, metrics)
+	return nil
+}
+
+// forwardedValues sums up the forwarded counter increments and keeps the last forwarded gauge values.
+func (s *recordingSender) forwardedValues() (map[string]int64, map[string]float64) {
+	counters := make(map[string]int64)
+	gauges := make(map[string]float64)
+	for _, batch := range s.batches {
+		for _, metric := range batch {
+			if metric.MType == domain.MetricTypeCounter {
+				counters[metric.ID] += *metric.Delta
+			} else {
+				gauges[metric.ID] = *metric.Value
+			}
+		}
+	}
+	return counters, gauges
+}
+
+func newTestForwarder(t *testing.T, options Options) (*Forwarder, *metricstorage.MemoryMetricStorage, *recordingSender, *time.Time) {
+	t.Helper()
+	storage := metricstorage.NewMemoryMetricStorage()
+	sender := &recordingSender{}
+	logger := zerolog.Nop()
+	forwarder, err := New(storage, sender, options, &logger)
+	require.NoError(t, err)
+
+	now := start
+	forwarder.now = func() time.Time { return now }
+	return forwarder, storage, sender, &now
+}
+
+func TestForwarder_Forward(t *testing.T) {
+	ctx := context.Background()
+	forwarder, storage, sender, now := newTestForwarder(t, Options{Prefix: "dc1.", BatchSize: 100})
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		gauge("Alloc", 1, start.Add(-time.Second)),
+		counter("PollCount", 5),
+	}))
+
+	forwarder.Forward(ctx)
+	require.Len(t, sender.batches, 1)
+	require.Len(t, sender.batches[0], 1, "counters restored at the start count as forwarded")
+	alloc := sender.batches[0][0]
+	assert.Equal(t, "dc1.Alloc", alloc.ID)
+	assert.Equal(t, 1.0, *alloc.Value)
+	assert.Equal(t, start.Add(-time.Second), alloc.Timestamp, "the update time on the edge is the client time upstream")
+
+	*now = start.Add(10 * time.Second)
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		counter("PollCount", 3),
+		counter("PollCount", 4),
+		gauge("Sys", 2, start.Add(5*time.Second)),
+	}))
+	forwarder.Forward(ctx)
+	require.Len(t, sender.batches, 2)
+	counters, gauges := sender.forwardedValues()
+	assert.Equal(t, map[string]int64{"dc1.PollCount": 7}, counters, "counter increments are aggregated")
+	assert.Equal(t, map[string]float64{"dc1.Alloc": 1, "dc1.Sys": 2}, gauges)
+	assert.Len(t, sender.batches[1], 2, "unchanged gauges are not forwarded again")
+
+	*now = start.Add(20 * time.Second)
+	forwarder.Forward(ctx)
+	assert.Len(t, sender.batches, 2, "nothing changed, nothing is sent")
+
+	// A deleted and recreated counter is forwarded in full
+	_, err := storage.DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	forwarder.Forward(ctx)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 2)))
+	forwarder.Forward(ctx)
+	counters, _ = sender.forwardedValues()
+	assert.Equal(t, int64(9), counters["dc1.PollCount"])
+
+	// Deleted and recreated within a round, the lower total is forwarded in full
+	_, err = storage.DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 1)))
+	forwarder.Forward(ctx)
+	counters, _ = sender.forwardedValues()
+	assert.Equal(t, int64(10), counters["dc1.PollCount"])
+}
+
+func TestForwarder_Batches(t *testing.T) {
+	ctx := context.Background()
+	forwarder, storage, sender, _ := newTestForwarder(t, Options{BatchSize: 2})
+	for _, id := range []string{"CPU1", "CPU2", "CPU3", "CPU4", "CPU5"} {
+		require.NoError(t, storage.UpdateMetric(ctx, gauge(id, 1, start)))
+	}
+
+	forwarder.Forward(ctx)
+	require.Len(t, sender.batches, 3)
+	assert.Len(t, sender.batches[0], 2)
+	assert.Len(t, sender.batches[2], 1)
+
+	_, err := New(storage, sender, Options{}, forwarder.logger)
+	assert.Error(t, err, "the batch size is required")
+}
+
+func TestForwarder_Spool(t *testing.T) {
+	ctx := context.Background()
+	spoolPath := filepath.Join(t.TempDir(), "forward.spool")
+	options := Options{BatchSize: 1, SpoolPath: spoolPath}
+	forwarder, storage, sender, now := newTestForwarder(t, options)
+	forwarder.Forward(ctx)
+
+	// The upstream server goes down for two rounds
+	sender.down = true
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 1), gauge("Alloc", 1, start.Add(time.Second))}))
+	*now = start.Add(10 * time.Second)
+	forwarder.Forward(ctx)
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 2), gauge("Alloc", 2, start.Add(11*time.Second))}))
+	*now = start.Add(20 * time.Second)
+	forwarder.Forward(ctx)
+	assert.Empty(t, sender.batches)
+
+	spooled, err := forwarder.spool.read()
+	require.NoError(t, err)
+	assert.Len(t, spooled, 4)
+
+	// The edge restarts and forwards the spooled batches once the upstream server is back
+	sender.down = false
+	restarted, err := New(storage, sender, options, forwarder.logger)
+	require.NoError(t, err)
+	restarted.now = forwarder.now
+	restarted.Forward(ctx)
+
+	counters, gauges := sender.forwardedValues()
+	assert.Equal(t, map[string]int64{"PollCount": 3}, counters)
+	assert.Equal(t, map[string]float64{"Alloc": 2}, gauges, "the spooled updates are sent in order")
+	assert.Len(t, sender.batches, 5, "the spooled batches and the gauges of the first round after the restart")
+	_, err = os.Stat(spoolPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the sent spool is removed")
+}
+
+func TestForwarder_RestartWithSavedTotals(t *testing.T) {
+	ctx := context.Background()
+	options := Options{BatchSize: 10, SpoolPath: filepath.Join(t.TempDir(), "forward.spool")}
+	forwarder, storage, sender, _ := newTestForwarder(t, options)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 5)))
+	forwarder.Forward(ctx)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 2)))
+	forwarder.Forward(ctx)
+
+	// The edge crashes before forwarding the last increments
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 3)))
+	restarted, err := New(storage, sender, options, forwarder.logger)
+	require.NoError(t, err)
+	restarted.now = forwarder.now
+	restarted.Forward(ctx)
+
+	counters, _ := sender.forwardedValues()
+	assert.Equal(t, int64(5), counters["PollCount"], "the increments since the last saved round are forwarded")
+}
+
+func TestForwarder_SpoolPartiallySent(t *testing.T) {
+	ctx := context.Background()
+	options := Options{BatchSize: 1, SpoolPath: filepath.Join(t.TempDir(), "forward.spool")}
+	forwarder, storage, _, now := newTestForwarder(t, options)
+	forwarder.Forward(ctx)
+
+	// The upstream server accepts a single batch, then fails again
+	sender := &limitedSender{accept: 1}
+	forwarder.sender = sender
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 1), counter("Sys", 1)}))
+	*now = start.Add(10 * time.Second)
+	forwarder.Forward(ctx)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 2)))
+	*now = start.Add(20 * time.Second)
+	forwarder.Forward(ctx)
+
+	sender.accept = 10
+	forwarder.Forward(ctx)
+	total := int64(0)
+	for _, metric := range sender.sent {
+		total += *metric.Delta
+	}
+	assert.Equal(t, int64(4), total, "every increment is forwarded exactly once")
+	assert.True(t, forwarder.spool.empty())
+}
+
+// limitedSender accepts the given number of batches and fails the later ones.
+type limitedSender struct {
+	accept int
+	sent   []domain.Metric
+}
+
+func (s *limitedSender) SendBatch(ctx context.Context, metrics []domain.Metric) error {
+	if s.accept == 0 {
+		return errUpstreamDown
+	}
+	s.accept--
+	s.sent = append(s.sent, metrics...)
+	return nil
+}
+
+func TestForwarder_WithoutSpool(t *testing.T) {
+	ctx := context.Background()
+	forwarder, storage, sender, now := newTestForwarder(t, Options{BatchSize: 10})
+	forwarder.Forward(ctx)
+
+	sender.down = true
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 1)))
+	*now = start.Add(10 * time.Second)
+	forwarder.Forward(ctx)
+
+	sender.down = false
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 2)))
+	*now = start.Add(20 * time.Second)
+	forwarder.Forward(ctx)
+
+	counters, _ := sender.forwardedValues()
+	assert.Equal(t, int64(2), counters["PollCount"], "the failed increments are dropped")
+}
@@ -0,0 +1,205 @@
+package federation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// spool keeps the batches the upstream server did not accept in a file of JSON lines, one batch per line and
+// the oldest first, so they are sent in order once the upstream server is back, also after a restart.
+// Next to it, in the .forwarded file, it keeps the counter totals of the last round.
+type spool struct {
+	path    string
+	maxSize int64
+	size    int64
+}
+
+func openSpool(path string, maxSize int64) (*spool, error) {
+	s := &spool{path: path, maxSize: maxSize}
+
+	info, err := os.Stat(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to stat spool: %w", err)
+	}
+	if err == nil {
+		s.size = info.Size()
+	}
+
+	if err := s.trimTornLine(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// trimTornLine cuts off a last line without a newline, left by a crash during an append, so the next batch
+// is appended on a line of its own.
+func (s *spool) trimTornLine() error {
+	if s.size == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read spool: %w", err)
+	}
+	if bytes.HasSuffix(data, []byte("\n")) {
+		return nil
+	}
+
+	s.size = int64(bytes.LastIndexByte(data, '\n') + 1)
+	if err := os.Truncate(s.path, s.size); err != nil {
+		return fmt.Errorf("failed to truncate spool: %w", err)
+	}
+	return nil
+}
+
+func (s *spool) empty() bool {
+	return s.size == 0
+}
+
+// read returns the spooled batches. A broken line stops the reading there and is reported.
+func (s *spool) read() ([][]domain.Metric, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool: %w", err)
+	}
+
+	var batches [][]domain.Metric
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return batches, nil
+		}
+
+		var batch []domain.Metric
+		if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &batch); err != nil {
+			return batches, fmt.Errorf("broken spool batch at line %d: %w", line, err)
+		}
+		batches = append(batches, batch)
+	}
+}
+
+// append adds the batches to the end of the spool and returns the number of batches dropped because the spool
+// is full. Once a batch does not fit, the later ones are dropped too, the spooled updates stay in order.
+func (s *spool) append(batches [][]domain.Metric) (int, error) {
+	data, kept, err := s.encode(batches, s.size)
+	if err != nil {
+		return len(batches), err
+	}
+	if len(data) == 0 {
+		return len(batches) - kept, nil
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return len(batches), fmt.Errorf("failed to open spool: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	n, err := file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return len(batches), fmt.Errorf("failed to append to spool: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return len(batches), fmt.Errorf("failed to sync spool: %w", err)
+	}
+
+	return len(batches) - kept, nil
+}
+
+// replace atomically replaces the spooled batches with the ones that still have to be sent.
+func (s *spool) replace(batches [][]domain.Metric) error {
+	if len(batches) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool: %w", err)
+		}
+		s.size = 0
+		return nil
+	}
+
+	// The batches were in the spool before, they fit
+	data, _, err := s.encode(batches, 0)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write spool: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace spool: %w", err)
+	}
+
+	s.size = int64(len(data))
+	return nil
+}
+
+// readForwarded returns the saved counter totals of the last round, nil if there are none.
+func (s *spool) readForwarded() (map[string]int64, error) {
+	data, err := os.ReadFile(s.forwardedPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read forwarded totals: %w", err)
+	}
+
+	totals := make(map[string]int64)
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal forwarded totals: %w", err)
+	}
+	return totals, nil
+}
+
+// writeForwarded atomically replaces the saved counter totals.
+func (s *spool) writeForwarded(totals map[string]int64) error {
+	data, err := json.Marshal(totals)
+	if err != nil {
+		return fmt.Errorf("failed to marshal forwarded totals: %w", err)
+	}
+
+	tmpPath := s.forwardedPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write forwarded totals: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.forwardedPath()); err != nil {
+		return fmt.Errorf("failed to replace forwarded totals: %w", err)
+	}
+	return nil
+}
+
+func (s *spool) forwardedPath() string {
+	return s.path + ".forwarded"
+}
+
+// encode returns the lines of the batches that fit into the spool of the given size, and their number.
+func (s *spool) encode(batches [][]domain.Metric, size int64) ([]byte, int, error) {
+	var data []byte
+	for i, batch := range batches {
+		line, err := json.Marshal(batch)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to marshal batch: %w", err)
+		}
+		if s.maxSize > 0 && size+int64(len(data)+len(line)+1) > s.maxSize {
+			return data, i, nil
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	return data, len(batches), nil
+}
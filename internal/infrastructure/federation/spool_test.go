@@ -0,0 +1,78 @@
+package federation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestSpool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forward.spool")
+	s, err := openSpool(path, 0)
+	require.NoError(t, err)
+	assert.True(t, s.empty())
+
+	first := []domain.Metric{counter("PollCount", 1)}
+	second := []domain.Metric{counter("PollCount", 2), counter("Sys", 3)}
+	dropped, err := s.append([][]domain.Metric{first, second})
+	require.NoError(t, err)
+	assert.Zero(t, dropped)
+	assert.False(t, s.empty())
+
+	batches, err := s.read()
+	require.NoError(t, err)
+	assert.Equal(t, [][]domain.Metric{first, second}, batches)
+
+	require.NoError(t, s.replace([][]domain.Metric{second}))
+	batches, err = s.read()
+	require.NoError(t, err)
+	assert.Equal(t, [][]domain.Metric{second}, batches)
+
+	require.NoError(t, s.replace(nil))
+	assert.True(t, s.empty())
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSpool_MaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forward.spool")
+	batch := []domain.Metric{counter("PollCount", 1)}
+	s, err := openSpool(path, 100)
+	require.NoError(t, err)
+
+	dropped, err := s.append([][]domain.Metric{batch, batch, batch})
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped, "the batches that do not fit are dropped")
+	dropped, err = s.append([][]domain.Metric{batch})
+	require.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+
+	batches, err := s.read()
+	require.NoError(t, err)
+	assert.Len(t, batches, 2)
+}
+
+func TestSpool_Damaged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forward.spool")
+	batch := `[{"id":"PollCount","type":"counter","delta":1}]`
+
+	// A crash during an append leaves a torn last line
+	require.NoError(t, os.WriteFile(path, []byte(batch+"\n"+`[{"id":"Sys"`), 0o644))
+	s, err := openSpool(path, 0)
+	require.NoError(t, err)
+	_, err = s.append([][]domain.Metric{{counter("Sys", 2)}})
+	require.NoError(t, err)
+	batches, err := s.read()
+	require.NoError(t, err)
+	assert.Len(t, batches, 2, "the torn line is cut off")
+
+	require.NoError(t, os.WriteFile(path, []byte(batch+"\nbroken\n"+batch+"\n"), 0o644))
+	batches, err = s.read()
+	assert.Error(t, err)
+	assert.Len(t, batches, 1, "the batches before a broken line are read")
+}
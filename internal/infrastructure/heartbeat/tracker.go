@@ -0,0 +1,226 @@
+package heartbeat
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// AlertAgentAbsent is the name of the alert raised for agents that stopped reporting.
+const AlertAgentAbsent = "AgentAbsent"
+
+// rateWindow is the period the report rate is averaged over.
+const rateWindow = 5 * time.Minute
+
+type agentState struct {
+	status domain.AgentStatus
+	recent []time.Time
+}
+
+// Tracker records agent reports and raises an alert when an agent misses
+// the configured number of report intervals in a row.
+type Tracker struct {
+	interval  time.Duration
+	missed    int
+	publisher domain.AlertPublisher
+	logger    *zerolog.Logger
+	now       func() time.Time
+	mu        sync.Mutex
+	agents    map[string]*agentState
+}
+
+var _ domain.HeartbeatRecorder = (*Tracker)(nil)
+var _ domain.SharedAgentRegistry = (*Tracker)(nil)
+
+// New creates a tracker expecting a report every interval. A non-positive missed disables alerting.
+func New(interval time.Duration, missed int, publisher domain.AlertPublisher, logger *zerolog.Logger) *Tracker {
+	return &Tracker{
+		interval:  interval,
+		missed:    missed,
+		publisher: publisher,
+		logger:    logger,
+		now:       time.Now,
+		agents:    make(map[string]*agentState),
+	}
+}
+
+// RecordHeartbeat registers a report of the agent. Agents without an explicit ID are identified by their address.
+func (t *Tracker) RecordHeartbeat(agent domain.AgentInfo) {
+	id := cmp.Or(agent.ID, agent.Address)
+	if id == "" {
+		return
+	}
+
+	now := t.now()
+
+	t.mu.Lock()
+	state, ok := t.agents[id]
+	if !ok {
+		state = &agentState{status: domain.AgentStatus{ID: id, FirstSeen: now}}
+		t.agents[id] = state
+		t.logger.Info().Str("agent", id).Str("version", agent.Version).Msg("new agent registered")
+	}
+
+	state.status.Address = cmp.Or(agent.Address, state.status.Address)
+	state.status.Version = cmp.Or(agent.Version, state.status.Version)
+	state.status.LastSeen = now
+	state.status.Reports++
+	state.recent = append(trimRecent(state.recent, now), now)
+
+	wasAbsent := state.status.Absent
+	state.status.Absent = false
+	t.mu.Unlock()
+
+	if wasAbsent {
+		t.logger.Info().Str("agent", id).Msg("agent is reporting again")
+		t.publisher.Resolve(context.Background(), absentAlert(id))
+	}
+}
+
+// MergeAgents merges the agents tracked by another server of the cluster. An agent that reports to the other server
+// counts as reporting, the report counts and rates stay the ones seen by this server.
+func (t *Tracker) MergeAgents(agents []domain.AgentStatus) {
+	now := t.now()
+	deadline := time.Duration(t.missed) * t.interval
+
+	var back []string
+	t.mu.Lock()
+	for _, agent := range agents {
+		if agent.ID == "" {
+			continue
+		}
+
+		state, ok := t.agents[agent.ID]
+		if !ok {
+			state = &agentState{status: domain.AgentStatus{ID: agent.ID, FirstSeen: agent.FirstSeen}}
+			t.agents[agent.ID] = state
+		}
+		if agent.FirstSeen.Before(state.status.FirstSeen) {
+			state.status.FirstSeen = agent.FirstSeen
+		}
+		if !agent.LastSeen.After(state.status.LastSeen) {
+			continue
+		}
+
+		state.status.Address = cmp.Or(agent.Address, state.status.Address)
+		state.status.Version = cmp.Or(agent.Version, state.status.Version)
+		state.status.LastSeen = agent.LastSeen
+		if state.status.Absent && now.Sub(agent.LastSeen) <= deadline {
+			state.status.Absent = false
+			back = append(back, agent.ID)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, id := range back {
+		t.logger.Info().Str("agent", id).Msg("agent is reporting to another server again")
+		t.publisher.Resolve(context.Background(), absentAlert(id))
+	}
+}
+
+// Agents returns the status of all known agents ordered by ID.
+func (t *Tracker) Agents() []domain.AgentStatus {
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	agents := make([]domain.AgentStatus, 0, len(t.agents))
+	for _, state := range t.agents {
+		state.recent = trimRecent(state.recent, now)
+
+		status := state.status
+		status.ReportsPerMinute = reportsPerMinute(len(state.recent), now.Sub(status.FirstSeen))
+		agents = append(agents, status)
+	}
+
+	slices.SortFunc(agents, func(a, b domain.AgentStatus) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return agents
+}
+
+// Check raises an alert for every agent that has not reported within the allowed number of intervals.
+func (t *Tracker) Check(ctx context.Context) {
+	if t.missed <= 0 || t.interval <= 0 {
+		return
+	}
+
+	now := t.now()
+	deadline := time.Duration(t.missed) * t.interval
+
+	var absent []domain.Alert
+	t.mu.Lock()
+	for id, state := range t.agents {
+		silence := now.Sub(state.status.LastSeen)
+		if state.status.Absent || silence <= deadline {
+			continue
+		}
+
+		state.status.Absent = true
+		alert := absentAlert(id)
+		alert.StartsAt = now
+		alert.Annotations = map[string]string{
+			"summary":   fmt.Sprintf("agent %s has not reported for %s", id, silence.Round(time.Second)),
+			"address":   state.status.Address,
+			"version":   state.status.Version,
+			"last_seen": state.status.LastSeen.UTC().Format(time.RFC3339),
+		}
+		absent = append(absent, alert)
+	}
+	t.mu.Unlock()
+
+	for _, alert := range absent {
+		t.logger.Warn().Str("agent", alert.Labels["agent"]).Msg("agent stopped reporting")
+		t.publisher.Fire(ctx, alert)
+	}
+}
+
+// Run periodically checks agents for missed reports until shutdownCh is closed.
+func (t *Tracker) Run(shutdownCh <-chan struct{}) {
+	if t.missed <= 0 || t.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			t.Check(context.Background())
+		}
+	}
+}
+
+func absentAlert(id string) domain.Alert {
+	return domain.Alert{
+		Name:   AlertAgentAbsent,
+		Labels: map[string]string{"agent": id},
+	}
+}
+
+func trimRecent(recent []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-rateWindow)
+	i, _ := slices.BinarySearchFunc(recent, cutoff, func(t time.Time, target time.Time) int {
+		return t.Compare(target)
+	})
+	return recent[i:]
+}
+
+// reportsPerMinute averages the reports over the rate window, or over the agent lifetime
+// if it is shorter, but never less than a minute to keep the first reports from spiking the rate.
+func reportsPerMinute(reports int, lifetime time.Duration) float64 {
+	span := max(min(lifetime, rateWindow), time.Minute)
+	return float64(reports) / span.Minutes()
+}
@@ -0,0 +1,131 @@
+package heartbeat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type recordingPublisher struct {
+	fired    []domain.Alert
+	resolved []domain.Alert
+}
+
+func (p *recordingPublisher) Fire(_ context.Context, alert domain.Alert) {
+	p.fired = append(p.fired, alert)
+}
+
+func (p *recordingPublisher) Resolve(_ context.Context, alert domain.Alert) {
+	p.resolved = append(p.resolved, alert)
+}
+
+func TestTracker_AbsentAgentAlert(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	tracker := New(10*time.Second, 3, publisher, &logger)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	tracker.RecordHeartbeat(domain.AgentInfo{ID: "agent-1", Address: "10.0.0.1", Version: "v1.0.0"})
+	tracker.RecordHeartbeat(domain.AgentInfo{Address: "10.0.0.2"})
+
+	now = now.Add(25 * time.Second)
+	tracker.RecordHeartbeat(domain.AgentInfo{Address: "10.0.0.2"})
+	now = now.Add(10 * time.Second)
+	tracker.Check(ctx)
+
+	require.Len(t, publisher.fired, 1)
+	assert.Equal(t, AlertAgentAbsent, publisher.fired[0].Name)
+	assert.Equal(t, map[string]string{"agent": "agent-1"}, publisher.fired[0].Labels)
+	assert.Equal(t, "v1.0.0", publisher.fired[0].Annotations["version"])
+
+	// The alert is raised only once per absence
+	now = now.Add(time.Minute)
+	tracker.Check(ctx)
+	require.Len(t, publisher.fired, 2)
+	assert.Equal(t, "10.0.0.2", publisher.fired[1].Labels["agent"])
+
+	tracker.RecordHeartbeat(domain.AgentInfo{ID: "agent-1", Address: "10.0.0.1"})
+	require.Len(t, publisher.resolved, 1)
+	assert.Equal(t, publisher.fired[0].Fingerprint(), publisher.resolved[0].Fingerprint())
+}
+
+func TestTracker_Agents(t *testing.T) {
+	logger := zerolog.Nop()
+	tracker := New(10*time.Second, 0, &recordingPublisher{}, &logger)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	tracker.now = func() time.Time { return now }
+
+	for range 20 {
+		tracker.RecordHeartbeat(domain.AgentInfo{ID: "b", Address: "10.0.0.2", Version: "v2"})
+		now = now.Add(30 * time.Second)
+	}
+	tracker.RecordHeartbeat(domain.AgentInfo{ID: "a", Address: "10.0.0.1"})
+
+	agents := tracker.Agents()
+
+	require.Len(t, agents, 2)
+	assert.Equal(t, "a", agents[0].ID)
+	assert.Equal(t, 1.0, agents[0].ReportsPerMinute)
+
+	assert.Equal(t, domain.AgentStatus{
+		ID:               "b",
+		Address:          "10.0.0.2",
+		Version:          "v2",
+		FirstSeen:        start,
+		LastSeen:         start.Add(19 * 30 * time.Second),
+		Reports:          20,
+		ReportsPerMinute: 2,
+	}, agents[1])
+}
+
+func TestTracker_IgnoresUnidentifiedAgents(t *testing.T) {
+	logger := zerolog.Nop()
+	tracker := New(10*time.Second, 3, &recordingPublisher{}, &logger)
+
+	tracker.RecordHeartbeat(domain.AgentInfo{Version: "v1"})
+
+	assert.Empty(t, tracker.Agents())
+}
+
+func TestTracker_MergeAgents(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	publisher := &recordingPublisher{}
+	tracker := New(10*time.Second, 3, publisher, &logger)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	tracker.now = func() time.Time { return now }
+	tracker.RecordHeartbeat(domain.AgentInfo{ID: "agent-1", Address: "10.0.0.1"})
+
+	// The agent fails over to another server, which keeps this one from taking it for absent
+	now = start.Add(35 * time.Second)
+	tracker.MergeAgents([]domain.AgentStatus{{ID: "agent-1", Address: "10.0.0.1", FirstSeen: start, LastSeen: start.Add(30 * time.Second)}})
+	tracker.Check(ctx)
+	assert.Empty(t, publisher.fired)
+
+	// An older report of the other server changes nothing
+	tracker.MergeAgents([]domain.AgentStatus{{ID: "agent-1", LastSeen: start}})
+	agents := tracker.Agents()
+	require.Len(t, agents, 1)
+	assert.Equal(t, start.Add(30*time.Second), agents[0].LastSeen)
+	assert.Equal(t, int64(1), agents[0].Reports, "reports are counted per server")
+
+	now = start.Add(70 * time.Second)
+	tracker.Check(ctx)
+	require.Len(t, publisher.fired, 1)
+
+	tracker.MergeAgents([]domain.AgentStatus{{ID: "agent-1", LastSeen: start.Add(65 * time.Second)}})
+	require.Len(t, publisher.resolved, 1, "the agent reporting to another server again resolves the alert")
+}
@@ -0,0 +1,41 @@
+package history
+
+import (
+	"context"
+	"sync"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// memoryCapacity is the number of events the memory storage keeps, older events are dropped.
+const memoryCapacity = 10000
+
+// MemoryStorage keeps the most recent events in memory only, they are lost on restart.
+type MemoryStorage struct {
+	mu     sync.Mutex
+	events []domain.HistoryEvent
+}
+
+var _ domain.HistoryStorage = (*MemoryStorage)(nil)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) AppendEvent(_ context.Context, event domain.HistoryEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.events) == memoryCapacity {
+		s.events = s.events[1:]
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *MemoryStorage) QueryEvents(_ context.Context, filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return newestMatching(s.events, filter), nil
+}
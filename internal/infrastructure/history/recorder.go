@@ -0,0 +1,63 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// DefaultLimit is the number of events returned by a query without a limit.
+const DefaultLimit = 100
+
+// Recorder appends events to the history storage, stamping events without a time with the current time.
+type Recorder struct {
+	storage domain.HistoryStorage
+	logger  *zerolog.Logger
+	now     func() time.Time
+}
+
+var _ domain.HistoryRecorder = (*Recorder)(nil)
+var _ domain.HistoryReader = (*Recorder)(nil)
+
+func NewRecorder(storage domain.HistoryStorage, logger *zerolog.Logger) *Recorder {
+	return &Recorder{
+		storage: storage,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+func (r *Recorder) Record(ctx context.Context, event domain.HistoryEvent) {
+	if event.Time.IsZero() {
+		event.Time = r.now()
+	}
+
+	if err := r.storage.AppendEvent(ctx, event); err != nil {
+		r.logger.Error().Err(err).Str("type", string(event.Type)).Msg("failed to record history event")
+	}
+}
+
+func (r *Recorder) QueryHistory(ctx context.Context, filter domain.HistoryFilter) ([]domain.HistoryEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultLimit
+	}
+
+	return r.storage.QueryEvents(ctx, filter)
+}
+
+// newestMatching returns the events matching the filter, newest first, from events ordered oldest first.
+func newestMatching(events []domain.HistoryEvent, filter domain.HistoryFilter) []domain.HistoryEvent {
+	result := make([]domain.HistoryEvent, 0)
+	for i := len(events) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(result) == filter.Limit {
+			break
+		}
+		if filter.Matches(events[i]) {
+			result = append(result, events[i])
+		}
+	}
+	return result
+}
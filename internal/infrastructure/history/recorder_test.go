@@ -0,0 +1,96 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func TestRecorder_QueryHistory(t *testing.T) {
+	storages := map[string]func(t *testing.T) domain.HistoryStorage{
+		"memory": func(*testing.T) domain.HistoryStorage { return NewMemoryStorage() },
+		"file": func(t *testing.T) domain.HistoryStorage {
+			return NewFileStorage(filepath.Join(t.TempDir(), "metrics.json.history.jsonl"))
+		},
+	}
+
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := zerolog.Nop()
+			recorder := NewRecorder(newStorage(t), &logger)
+
+			start := time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC)
+			now := start
+			recorder.now = func() time.Time { return now }
+
+			agent := map[string]string{"agent": "a1"}
+			recorder.Record(ctx, domain.HistoryEvent{Type: domain.HistorySilenceCreated, SilenceID: "s1", Actor: "ops"})
+			now = now.Add(time.Minute)
+			recorder.Record(ctx, domain.HistoryEvent{Type: domain.HistoryAlertFiring, AlertName: "AgentAbsent", Labels: agent, Silences: []string{"s1"}})
+			now = now.Add(time.Minute)
+			recorder.Record(ctx, domain.HistoryEvent{Type: domain.HistoryAlertFiring, AlertName: "HighCPU", Labels: map[string]string{"metric": "CPU"}})
+			now = now.Add(time.Minute)
+			recorder.Record(ctx, domain.HistoryEvent{Type: domain.HistoryAlertResolved, AlertName: "AgentAbsent", Labels: agent})
+
+			tests := []struct {
+				name   string
+				filter domain.HistoryFilter
+				want   []domain.HistoryEventType
+			}{
+				{name: "all newest first", want: []domain.HistoryEventType{domain.HistoryAlertResolved, domain.HistoryAlertFiring, domain.HistoryAlertFiring, domain.HistorySilenceCreated}},
+				{name: "alert name", filter: domain.HistoryFilter{AlertName: "AgentAbsent"}, want: []domain.HistoryEventType{domain.HistoryAlertResolved, domain.HistoryAlertFiring}},
+				{name: "type", filter: domain.HistoryFilter{Types: []domain.HistoryEventType{domain.HistoryAlertFiring}}, want: []domain.HistoryEventType{domain.HistoryAlertFiring, domain.HistoryAlertFiring}},
+				{name: "label", filter: domain.HistoryFilter{Labels: map[string]string{"metric": "CPU"}}, want: []domain.HistoryEventType{domain.HistoryAlertFiring}},
+				{name: "silence", filter: domain.HistoryFilter{SilenceID: "s1"}, want: []domain.HistoryEventType{domain.HistoryAlertFiring, domain.HistorySilenceCreated}},
+				{name: "actor", filter: domain.HistoryFilter{Actor: "ops"}, want: []domain.HistoryEventType{domain.HistorySilenceCreated}},
+				{name: "time range", filter: domain.HistoryFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, want: []domain.HistoryEventType{domain.HistoryAlertFiring, domain.HistoryAlertFiring}},
+				{name: "limit", filter: domain.HistoryFilter{Limit: 1}, want: []domain.HistoryEventType{domain.HistoryAlertResolved}},
+				{name: "no match", filter: domain.HistoryFilter{AlertName: "Missing"}, want: []domain.HistoryEventType{}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					events, err := recorder.QueryHistory(ctx, tt.filter)
+					require.NoError(t, err)
+
+					types := make([]domain.HistoryEventType, len(events))
+					for i, event := range events {
+						types[i] = event.Type
+					}
+					assert.Equal(t, tt.want, types)
+				})
+			}
+		})
+	}
+}
+
+func TestFileStorage_SkipsTruncatedLine(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	storage := NewFileStorage(path)
+
+	require.NoError(t, storage.AppendEvent(ctx, domain.HistoryEvent{Type: domain.HistoryAlertFiring, AlertName: "AgentAbsent"}))
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"type":"alert_res`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	require.NoError(t, storage.AppendEvent(ctx, domain.HistoryEvent{Type: domain.HistoryAlertResolved, AlertName: "AgentAbsent"}))
+
+	events, err := storage.QueryEvents(ctx, domain.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, domain.HistoryAlertResolved, events[0].Type)
+	assert.Equal(t, domain.HistoryAlertFiring, events[1].Type)
+}
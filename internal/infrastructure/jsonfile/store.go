@@ -0,0 +1,148 @@
+// Package jsonfile keeps small collections in JSON files, rewritten atomically on every change.
+package jsonfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps the items in a JSON array in a single file, identified by the ID function. Every change reads
+// and rewrites the whole file, which suits a few hundred items changed by hand.
+type Store[T any] struct {
+	mu   sync.Mutex
+	path string
+	// name is what the items are called in the errors
+	name string
+	id   func(T) string
+}
+
+func New[T any](path, name string, id func(T) string) *Store[T] {
+	return &Store[T]{
+		path: path,
+		name: name,
+		id:   id,
+	}
+}
+
+// List returns all items in the order they were first saved, none without a file.
+func (s *Store[T]) List() ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+// Save replaces the item with the same ID or adds it at the end.
+func (s *Store[T]) Save(item T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range items {
+		if s.id(items[i]) == s.id(item) {
+			items[i] = item
+			replaced = true
+		}
+	}
+	if !replaced {
+		items = append(items, item)
+	}
+
+	return s.write(items)
+}
+
+// Delete removes the item with the ID, reporting whether it was there.
+func (s *Store[T]) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	kept := items[:0]
+	for _, item := range items {
+		if s.id(item) != id {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == len(items) {
+		return false, nil
+	}
+
+	return true, s.write(kept)
+}
+
+func (s *Store[T]) read() ([]T, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s file: %w", s.name, err)
+	}
+
+	var items []T
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse %s file: %w", s.name, err)
+	}
+
+	return items, nil
+}
+
+func (s *Store[T]) write(items []T) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", s.name, err)
+	}
+
+	if err := WriteFile(s.path, data); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", s.name, err)
+	}
+
+	return nil
+}
+
+// WriteFile replaces the file through a temporary file flushed to disk before the rename, and flushes the
+// directory after it, so neither a crash nor a power loss leaves a partially written or lost file behind.
+func WriteFile(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dir.Close() }()
+
+	return dir.Sync()
+}
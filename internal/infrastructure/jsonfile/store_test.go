@@ -0,0 +1,61 @@
+package jsonfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID    string `json:"id"`
+	Value int    `json:"value"`
+}
+
+func newItemStore(path string) *Store[item] {
+	return New(path, "items", func(i item) string { return i.ID })
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "items.json")
+	store := newItemStore(path)
+
+	items, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, items, "a missing file holds no items")
+
+	require.NoError(t, store.Save(item{ID: "a", Value: 1}))
+	require.NoError(t, store.Save(item{ID: "b", Value: 2}))
+	require.NoError(t, store.Save(item{ID: "a", Value: 3}))
+
+	items, err = newItemStore(path).List()
+	require.NoError(t, err)
+	assert.Equal(t, []item{{ID: "a", Value: 3}, {ID: "b", Value: 2}}, items, "saving replaces the item with the same ID in place")
+
+	deleted, err := store.Delete("a")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = store.Delete("a")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	items, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []item{{ID: "b", Value: 2}}, items)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	require.NoError(t, os.WriteFile(path, []byte("[{"), 0o644))
+
+	_, err := newItemStore(path).List()
+	assert.ErrorContains(t, err, "failed to parse items file")
+	assert.Error(t, newItemStore(path).Save(item{ID: "a"}), "a corrupt file is not overwritten")
+}
@@ -0,0 +1,293 @@
+package kvmetricstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// openTimeout bounds the wait for the file lock held by another process using the same database.
+const openTimeout = 5 * time.Second
+
+// BoltMetricStorage keeps the metrics in an embedded bbolt database, a bucket per metric type keyed by the metric
+// ID. Every update is a single transaction written in place, so the storage neither rewrites all metrics
+// like FileMetricStorage nor needs a database server like PostgresMetricsStorage.
+type BoltMetricStorage struct {
+	db     *bolt.DB
+	logger *zerolog.Logger
+}
+
+var _ domain.MetricStorage = (*BoltMetricStorage)(nil)
+var _ domain.StaleMetricDeleter = (*BoltMetricStorage)(nil)
+
+// storedMetric is the value of a metric in its bucket, the type and ID are given by the bucket and the key.
+type storedMetric struct {
+	Delta     *int64    `json:"delta,omitempty"`
+	Value     *float64  `json:"value,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Timestamp time.Time `json:"timestamp,omitzero"`
+}
+
+var metricTypes = []domain.MetricType{domain.MetricTypeGauge, domain.MetricTypeCounter}
+
+func New(path string, logger *zerolog.Logger) (*BoltMetricStorage, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metrics database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, metricType := range metricTypes {
+			if _, err := tx.CreateBucketIfNotExists([]byte(metricType)); err != nil {
+				return err
+			}
+		}
+		_, err := tx.CreateBucketIfNotExists(rollupBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create metric buckets: %w", err)
+	}
+
+	return &BoltMetricStorage{db: db, logger: logger}, nil
+}
+
+// Close releases the database file.
+func (s *BoltMetricStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltMetricStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	metrics := make([]domain.Metric, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, metricType := range metricTypes {
+			err := tx.Bucket([]byte(metricType)).ForEach(func(key, value []byte) error {
+				metric, err := decodeMetric(metricType, key, value)
+				if err != nil {
+					return err
+				}
+				metrics = append(metrics, metric)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read metrics: %w", domain.ErrStorageUnavailable, err)
+	}
+
+	return metrics, nil
+}
+
+func (s *BoltMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	metric := domain.Metric{MType: metricType, ID: metricName}
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metricType))
+		if bucket == nil {
+			return nil
+		}
+
+		value := bucket.Get([]byte(metricName))
+		if value == nil {
+			return nil
+		}
+
+		decoded, err := decodeMetric(metricType, []byte(metricName), value)
+		if err != nil {
+			return err
+		}
+		metric, found = decoded, true
+		return nil
+	})
+	if err != nil {
+		return domain.Metric{MType: metricType, ID: metricName}, false, fmt.Errorf("%w: failed to read metric: %w", domain.ErrStorageUnavailable, err)
+	}
+
+	return metric, found, nil
+}
+
+func (s *BoltMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	return s.UpdateMetrics(ctx, []domain.Metric{metric})
+}
+
+// UpdateMetrics applies the batch in one transaction: either all metrics are stored or none. Gauges take
+// the new value and counters add the delta to the stored total, like the upsert of the Postgres storage.
+func (s *BoltMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, metric := range metrics {
+			if err := update(tx, metric, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltMetricStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	found := false
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metricType))
+		if bucket == nil {
+			return errors.New("unsupported metric type")
+		}
+
+		key := []byte(metricName)
+		if bucket.Get(key) == nil {
+			return nil
+		}
+		found = true
+		return bucket.Delete(key)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metric: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *BoltMetricStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	found := false
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(metricType))
+		if bucket == nil {
+			return errors.New("unsupported metric type")
+		}
+
+		key := []byte(metricName)
+		value := bucket.Get(key)
+		if value == nil {
+			return nil
+		}
+		metric, err := decodeMetric(metricType, key, value)
+		if err != nil {
+			return err
+		}
+		if metric.UpdatedAt.IsZero() || !metric.UpdatedAt.Before(before) {
+			return nil
+		}
+		found = true
+		return bucket.Delete(key)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete metric: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *BoltMetricStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, metricType := range metricTypes {
+			// Keys are sorted, the matching ones follow the prefix itself
+			cursor := tx.Bucket([]byte(metricType)).Cursor()
+			for key, _ := cursor.Seek([]byte(prefix)); key != nil && bytes.HasPrefix(key, []byte(prefix)); key, _ = cursor.Next() {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				deleted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete metrics: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func (s *BoltMetricStorage) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// update stores the metric within the transaction, using now if the receive time is unknown.
+func update(tx *bolt.Tx, metric domain.Metric, now time.Time) error {
+	bucket := tx.Bucket([]byte(metric.MType))
+	if bucket == nil {
+		return errors.New("unsupported metric type")
+	}
+
+	record := storedMetric{UpdatedAt: metric.UpdatedAt, Timestamp: metric.Timestamp}
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = now
+	}
+
+	key := []byte(metric.ID)
+	switch metric.MType {
+	case domain.MetricTypeCounter:
+		if metric.Delta == nil {
+			return fmt.Errorf("counter %s has no delta", metric.ID)
+		}
+		delta := *metric.Delta
+		if value := bucket.Get(key); value != nil {
+			var current storedMetric
+			if err := json.Unmarshal(value, &current); err != nil {
+				return fmt.Errorf("failed to decode counter %s: %w", metric.ID, err)
+			}
+			if current.Delta != nil {
+				delta += *current.Delta
+			}
+		}
+		record.Delta = &delta
+
+	case domain.MetricTypeGauge:
+		if metric.Value == nil {
+			return fmt.Errorf("gauge %s has no value", metric.ID)
+		}
+		record.Value = metric.Value
+	}
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode metric %s: %w", metric.ID, err)
+	}
+
+	return bucket.Put(key, value)
+}
+
+func decodeMetric(metricType domain.MetricType, key, value []byte) (domain.Metric, error) {
+	var record storedMetric
+	if err := json.Unmarshal(value, &record); err != nil {
+		return domain.Metric{}, fmt.Errorf("failed to decode metric %s: %w", key, err)
+	}
+
+	return domain.Metric{
+		ID:        string(key),
+		MType:     metricType,
+		Delta:     record.Delta,
+		Value:     record.Value,
+		UpdatedAt: record.UpdatedAt,
+		Timestamp: record.Timestamp,
+	}, nil
+}
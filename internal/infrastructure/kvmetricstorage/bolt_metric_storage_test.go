@@ -0,0 +1,165 @@
+package kvmetricstorage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func newTestStorage(t *testing.T, path string) *BoltMetricStorage {
+	t.Helper()
+	logger := zerolog.Nop()
+	storage, err := New(path, &logger)
+	require.NoError(t, err)
+	return storage
+}
+
+func TestBoltMetricStorage_UpdateMetrics(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	storage := newTestStorage(t, path)
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), counter("PollCount", 5), counter("PollCount", 2)}))
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 3)))
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2.5)))
+
+	alloc, found := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "Alloc")
+	require.True(t, found)
+	assert.Equal(t, 2.5, *alloc.Value)
+	assert.False(t, alloc.UpdatedAt.IsZero(), "the receive time defaults to now")
+
+	pollCount, found := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	require.True(t, found)
+	assert.Equal(t, int64(10), *pollCount.Delta, "counters add up, also within a batch")
+
+	_, found = domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "Alloc")
+	assert.False(t, found, "metrics of different types are kept apart")
+
+	require.NoError(t, storage.Close())
+	reopened := newTestStorage(t, path)
+	defer func() { _ = reopened.Close() }()
+	assert.Len(t, domaintest.AllMetrics(t, reopened), 2, "the metrics survive a restart")
+}
+
+func TestBoltMetricStorage_UpdateMetricsIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer func() { _ = storage.Close() }()
+
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 5)))
+
+	broken := domain.Metric{ID: "Sys", MType: domain.MetricTypeGauge}
+	err := storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 3), gauge("Alloc", 1), broken})
+	require.Error(t, err)
+
+	pollCount, _ := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(5), *pollCount.Delta, "a failed batch changes nothing")
+	_, found := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "Alloc")
+	assert.False(t, found)
+
+	err = storage.UpdateMetric(ctx, domain.Metric{ID: "Alloc", MType: "histogram"})
+	assert.Error(t, err)
+}
+
+func TestBoltMetricStorage_Delete(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer func() { _ = storage.Close() }()
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		gauge("CPU1", 1), gauge("CPU2", 2), counter("CPUCount", 2), gauge("C", 3), gauge("Alloc", 4),
+	}))
+
+	found, err := storage.DeleteMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = storage.DeleteMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	deleted, err := storage.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	metrics := domaintest.AllMetrics(t, storage)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "C", metrics[0].ID)
+}
+
+func TestBoltMetricStorage_DeleteMetricIfStale(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer func() { _ = storage.Close() }()
+
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	alloc := gauge("Alloc", 1)
+	alloc.UpdatedAt = updatedAt
+	require.NoError(t, storage.UpdateMetric(ctx, alloc))
+
+	found, err := storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "Alloc", updatedAt)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "Alloc", updatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, domaintest.AllMetrics(t, storage))
+}
+
+func TestBoltMetricStorage_KeepsTimes(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t, filepath.Join(t.TempDir(), "metrics.db"))
+	defer func() { _ = storage.Close() }()
+
+	updatedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	timestamp := updatedAt.Add(-time.Second)
+	metric := gauge("Alloc", 1)
+	metric.UpdatedAt = updatedAt
+	metric.Timestamp = timestamp
+	require.NoError(t, storage.UpdateMetric(ctx, metric))
+
+	stored, _ := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "Alloc")
+	assert.True(t, updatedAt.Equal(stored.UpdatedAt))
+	assert.True(t, timestamp.Equal(stored.Timestamp))
+	assert.NoError(t, storage.Ping(ctx))
+
+	require.NoError(t, storage.Close())
+	assert.Error(t, storage.Ping(ctx))
+}
+
+func TestBoltMetricStorage_ReadErrors(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t, filepath.Join(t.TempDir(), "metrics.db"))
+
+	require.NoError(t, storage.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(domain.MetricTypeGauge)).Put([]byte("Alloc"), []byte("{"))
+	}))
+
+	_, found, err := storage.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable, "a corrupt record is not reported as missing")
+	assert.False(t, found)
+	_, err = storage.GetAllMetrics(ctx)
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+
+	require.NoError(t, storage.Close())
+	_, _, err = storage.GetMetric(ctx, domain.MetricTypeGauge, "Sys")
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+}
@@ -0,0 +1,221 @@
+package kvmetricstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// rollupBucket keeps the rollups of the metric history keyed by resolution, start, type and ID, so the expired
+// rollups of a resolution are a range at the start of its keys.
+var rollupBucket = []byte("rollups")
+
+var _ domain.RollupStorage = (*BoltMetricStorage)(nil)
+
+// storedRollup is the value of a rollup in its bucket, the start is given by the key.
+type storedRollup struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Sum   float64 `json:"sum"`
+	Last  float64 `json:"last"`
+}
+
+type rollupSeriesKey struct {
+	mType      domain.MetricType
+	id         string
+	resolution domain.Resolution
+}
+
+func (s *BoltMetricStorage) LoadRollups(ctx context.Context) ([]domain.RollupSeries, error) {
+	var result []domain.RollupSeries
+	index := make(map[rollupSeriesKey]int)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// The keys of a series follow each other in start order
+		return tx.Bucket(rollupBucket).ForEach(func(key, value []byte) error {
+			series, rollup, err := decodeRollup(key, value)
+			if err != nil {
+				return err
+			}
+
+			i, ok := index[series]
+			if !ok {
+				i = len(result)
+				index[series] = i
+				result = append(result, domain.RollupSeries{MType: series.mType, ID: series.id, Resolution: series.resolution})
+			}
+			result[i].Rollups = append(result[i].Rollups, rollup)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rollups: %w", err)
+	}
+
+	return result, nil
+}
+
+func (s *BoltMetricStorage) AppendRollups(ctx context.Context, series []domain.RollupSeries) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rollupBucket)
+		for _, item := range series {
+			for _, rollup := range item.Rollups {
+				value, err := json.Marshal(storedRollup{
+					Count: rollup.Count,
+					Min:   rollup.Min,
+					Max:   rollup.Max,
+					Avg:   rollup.Avg,
+					Sum:   rollup.Sum,
+					Last:  rollup.Last,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to encode rollup of %s: %w", item.ID, err)
+				}
+
+				key := rollupKey(rollupSeriesKey{mType: item.MType, id: item.ID, resolution: item.Resolution}, rollup.Start)
+				if err := bucket.Put(key, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store rollups: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltMetricStorage) PruneRollups(ctx context.Context, resolution domain.Resolution, before time.Time) error {
+	prefix := binary.BigEndian.AppendUint64(nil, uint64(resolution))
+	end := append(bytes.Clone(prefix), rollupStart(before)...)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rollupBucket)
+		var expired [][]byte
+		cursor := bucket.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.Compare(key, end) < 0; key, _ = cursor.Next() {
+			expired = append(expired, bytes.Clone(key))
+		}
+		return deleteKeys(bucket, expired)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune rollups: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BoltMetricStorage) DeleteRollups(ctx context.Context, metricType domain.MetricType, metricName string) error {
+	return s.deleteRollups(func(series rollupSeriesKey) bool {
+		return series.mType == metricType && series.id == metricName
+	})
+}
+
+func (s *BoltMetricStorage) DeleteRollupsByPrefix(ctx context.Context, prefix string) error {
+	return s.deleteRollups(func(series rollupSeriesKey) bool {
+		return strings.HasPrefix(series.id, prefix)
+	})
+}
+
+// deleteRollups drops the rollups of the matching series, scanning the whole bucket as it is not keyed by metric.
+func (s *BoltMetricStorage) deleteRollups(matches func(series rollupSeriesKey) bool) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rollupBucket)
+		var deleted [][]byte
+		err := bucket.ForEach(func(key, _ []byte) error {
+			series, err := decodeRollupKey(key)
+			if err != nil {
+				return err
+			}
+			if matches(series) {
+				deleted = append(deleted, bytes.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return deleteKeys(bucket, deleted)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	return nil
+}
+
+// deleteKeys deletes the keys collected before, as deleting while iterating a cursor skips keys.
+func deleteKeys(bucket *bolt.Bucket, keys [][]byte) error {
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupKey returns the resolution and start as big-endian numbers, which sort like the values, followed by the
+// type and ID separated by a zero byte.
+func rollupKey(series rollupSeriesKey, start time.Time) []byte {
+	key := binary.BigEndian.AppendUint64(nil, uint64(series.resolution))
+	key = append(key, rollupStart(start)...)
+	key = append(key, series.mType...)
+	key = append(key, 0)
+	return append(key, series.id...)
+}
+
+// rollupStart encodes the start with the sign bit flipped, so times before 1970 sort first as well.
+func rollupStart(start time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(start.UnixNano())^(1<<63))
+}
+
+func decodeRollupKey(key []byte) (rollupSeriesKey, error) {
+	if len(key) < 16 {
+		return rollupSeriesKey{}, fmt.Errorf("invalid rollup key %x", key)
+	}
+	mType, id, found := bytes.Cut(key[16:], []byte{0})
+	if !found {
+		return rollupSeriesKey{}, fmt.Errorf("invalid rollup key %x", key)
+	}
+
+	return rollupSeriesKey{
+		mType:      domain.MetricType(mType),
+		id:         string(id),
+		resolution: domain.Resolution(binary.BigEndian.Uint64(key)),
+	}, nil
+}
+
+func decodeRollup(key, value []byte) (rollupSeriesKey, domain.Rollup, error) {
+	series, err := decodeRollupKey(key)
+	if err != nil {
+		return rollupSeriesKey{}, domain.Rollup{}, err
+	}
+
+	var record storedRollup
+	if err := json.Unmarshal(value, &record); err != nil {
+		return rollupSeriesKey{}, domain.Rollup{}, fmt.Errorf("failed to decode rollup of %s: %w", series.id, err)
+	}
+
+	start := time.Unix(0, int64(binary.BigEndian.Uint64(key[8:16])^(1<<63)))
+	return series, domain.Rollup{
+		Start: start,
+		Count: record.Count,
+		Min:   record.Min,
+		Max:   record.Max,
+		Avg:   record.Avg,
+		Sum:   record.Sum,
+		Last:  record.Last,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package kvmetricstorage
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// loadRollups returns the stored rollups ordered by resolution and ID.
+func loadRollups(t *testing.T, storage domain.RollupStorage) []domain.RollupSeries {
+	t.Helper()
+	stored, err := storage.LoadRollups(context.Background())
+	require.NoError(t, err)
+	slices.SortFunc(stored, func(a, b domain.RollupSeries) int {
+		if a.Resolution != b.Resolution {
+			return int(a.Resolution - b.Resolution)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return stored
+}
+
+func TestBoltMetricStorage_Rollups(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	storage := newTestStorage(t, path)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := domain.Resolution(time.Minute)
+	hour := domain.Resolution(time.Hour)
+	rollup := func(offset time.Duration, last float64) domain.Rollup {
+		return domain.Rollup{Start: start.Add(offset).Local(), Count: 1, Min: last, Max: last, Avg: last, Last: last}
+	}
+
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(0, 1), rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+		{MType: domain.MetricTypeGauge, ID: "agent1/Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(0, 1)}},
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+		{MType: domain.MetricTypeCounter, ID: "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start.Local(), Count: 2, Sum: 5, Last: 5}}},
+	}))
+	require.NoError(t, storage.PruneRollups(ctx, minute, start.Add(time.Minute)))
+	require.NoError(t, storage.DeleteRollupsByPrefix(ctx, "agent1/"))
+
+	want := []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+		{MType: domain.MetricTypeCounter, ID: "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start.Local(), Count: 2, Sum: 5, Last: 5}}},
+	}
+	require.NoError(t, storage.Close())
+	storage = newTestStorage(t, path)
+	assert.Equal(t, want, loadRollups(t, storage), "the rollups are kept in the database")
+
+	require.NoError(t, storage.DeleteRollups(ctx, domain.MetricTypeGauge, "Alloc"))
+	assert.Equal(t, want[2:], loadRollups(t, storage))
+}
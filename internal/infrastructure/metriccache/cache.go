@@ -0,0 +1,317 @@
+package metriccache
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+type metricKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+// entry is a cached lookup of a metric, including a miss. An invalidation leaves an entry that is not loaded
+// with a new version, so a lookup that started before it does not store its outdated result.
+type entry struct {
+	metric  domain.Metric
+	found   bool
+	loaded  bool
+	expires time.Time
+	version uint64
+}
+
+// staleRefreshLimit is the most changed metrics GetAllMetrics reads one by one to bring the cached list up to
+// date, with more it reads the whole list again.
+const staleRefreshLimit = 64
+
+// Cache is a domain.MetricStorage decorator serving reads from memory. Writes go through to the decorated
+// storage and evict the written metrics, which are read again on the next lookup. Changes made by other
+// replicas sharing the storage are evicted through the domain.MetricInvalidator methods, entries expire
+// after the TTL in case a change is missed anyway. The list of all metrics is kept up to date by reading
+// only the metrics changed since it was read.
+type Cache struct {
+	storage domain.MetricStorage
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	suspended bool
+	// epoch changes when all entries are dropped, lookups started before are not stored
+	epoch   uint64
+	changes uint64
+	entries map[metricKey]entry
+	// all is the cached result of GetAllMetrics, valid while allLoaded except for the metrics in stale, which
+	// holds the version of their last change. Changes are tracked while the list is loaded or being read.
+	all        map[metricKey]domain.Metric
+	allLoaded  bool
+	allExpires time.Time
+	listing    int
+	stale      map[metricKey]uint64
+}
+
+var _ domain.MetricStorage = (*Cache)(nil)
+var _ domain.MetricInvalidator = (*Cache)(nil)
+var _ domain.StoredStateUpdater = (*Cache)(nil)
+var _ domain.StaleMetricDeleter = (*Cache)(nil)
+
+func New(storage domain.MetricStorage, ttl time.Duration) *Cache {
+	return &Cache{
+		storage: storage,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[metricKey]entry),
+		stale:   make(map[metricKey]uint64),
+	}
+}
+
+// GetAllMetrics serves the cached list, reading the metrics changed since it was read, or reads the whole list
+// from the storage. Failed reads are not cached.
+func (c *Cache) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	c.mu.Lock()
+	if !c.suspended && c.allLoaded && c.now().Before(c.allExpires) && len(c.stale) <= staleRefreshLimit {
+		if len(c.stale) == 0 {
+			all := c.list()
+			c.mu.Unlock()
+			return all, nil
+		}
+		stale := maps.Clone(c.stale)
+		epoch := c.epoch
+		c.mu.Unlock()
+		return c.refresh(ctx, stale, epoch)
+	}
+	epoch, changes := c.epoch, c.changes
+	c.listing++
+	c.mu.Unlock()
+
+	metrics, err := c.storage.GetAllMetrics(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listing--
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.suspended && c.epoch == epoch {
+		c.all = make(map[metricKey]domain.Metric, len(metrics))
+		for _, metric := range metrics {
+			c.all[metricKey{mType: metric.MType, id: metric.ID}] = metric
+		}
+		c.allLoaded = true
+		c.allExpires = c.now().Add(c.ttl)
+		// The metrics changed while the list was read stay stale
+		maps.DeleteFunc(c.stale, func(_ metricKey, version uint64) bool { return version <= changes })
+	}
+
+	return metrics, nil
+}
+
+// refresh reads the stale metrics into the cached list and returns it, or reads the whole list if it was
+// dropped meanwhile. A metric changed again meanwhile stays stale.
+func (c *Cache) refresh(ctx context.Context, stale map[metricKey]uint64, epoch uint64) ([]domain.Metric, error) {
+	type lookup struct {
+		metric domain.Metric
+		found  bool
+	}
+	lookups := make(map[metricKey]lookup, len(stale))
+	for key := range stale {
+		metric, found, err := c.GetMetric(ctx, key.mType, key.id)
+		if err != nil {
+			return nil, err
+		}
+		lookups[key] = lookup{metric: metric, found: found}
+	}
+
+	c.mu.Lock()
+	if c.suspended || c.epoch != epoch || !c.allLoaded {
+		c.mu.Unlock()
+		return c.GetAllMetrics(ctx)
+	}
+
+	for key, l := range lookups {
+		if l.found {
+			c.all[key] = l.metric
+		} else {
+			delete(c.all, key)
+		}
+		if c.stale[key] == stale[key] {
+			delete(c.stale, key)
+		}
+	}
+	all := c.list()
+	c.mu.Unlock()
+
+	return all, nil
+}
+
+// GetMetric serves the cached lookup, including a miss, or reads the metric from the storage. Failed reads are
+// not cached.
+func (c *Cache) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	key := metricKey{mType: metricType, id: metricName}
+
+	c.mu.Lock()
+	cached := c.entries[key]
+	if !c.suspended && cached.loaded && c.now().Before(cached.expires) {
+		c.mu.Unlock()
+		return cached.metric, cached.found, nil
+	}
+	epoch := c.epoch
+	c.mu.Unlock()
+
+	metric, found, err := c.storage.GetMetric(ctx, metricType, metricName)
+	if err != nil {
+		return metric, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.suspended && c.epoch == epoch && c.entries[key].version == cached.version {
+		c.entries[key] = entry{
+			metric:  metric,
+			found:   found,
+			loaded:  true,
+			expires: c.now().Add(c.ttl),
+			version: cached.version,
+		}
+	}
+
+	return metric, found, nil
+}
+
+func (c *Cache) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	return c.UpdateMetrics(ctx, []domain.Metric{metric})
+}
+
+// UpdateMetrics writes the metrics to the storage and evicts them, also when the write failed, as it may have
+// been applied in part.
+func (c *Cache) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	err := c.storage.UpdateMetrics(ctx, metrics)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, metric := range metrics {
+		c.invalidate(metricKey{mType: metric.MType, id: metric.ID})
+	}
+
+	return err
+}
+
+func (c *Cache) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	updater, ok := c.storage.(domain.StoredStateUpdater)
+	if !ok {
+		return nil, domain.ErrNotStoredState
+	}
+
+	stored, err := updater.UpdateMetricsStored(ctx, metrics)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, metric := range metrics {
+		c.invalidate(metricKey{mType: metric.MType, id: metric.ID})
+	}
+
+	return stored, err
+}
+
+func (c *Cache) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	found, err := c.storage.DeleteMetric(ctx, metricType, metricName)
+
+	c.Invalidate(metricType, metricName)
+	return found, err
+}
+
+func (c *Cache) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	deleter, ok := c.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		return false, domain.ErrNotStaleDeleter
+	}
+
+	found, err := deleter.DeleteMetricIfStale(ctx, metricType, metricName, before)
+
+	c.Invalidate(metricType, metricName)
+	return found, err
+}
+
+// DeleteByPrefix deletes the metrics from the storage and drops all entries, as a lookup of any of the deleted
+// metrics may be in flight.
+func (c *Cache) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted, err := c.storage.DeleteByPrefix(ctx, prefix)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key.id, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	for key := range c.all {
+		if strings.HasPrefix(key.id, prefix) {
+			delete(c.all, key)
+			delete(c.stale, key)
+		}
+	}
+	c.epoch++
+	c.changes++
+
+	return deleted, err
+}
+
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.storage.Ping(ctx)
+}
+
+// Invalidate evicts the metric, changed by this or another replica.
+func (c *Cache) Invalidate(metricType domain.MetricType, metricName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.invalidate(metricKey{mType: metricType, id: metricName})
+}
+
+// Suspend drops all entries and passes reads through to the storage until Resume.
+func (c *Cache) Suspend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.suspended = true
+	c.reset()
+}
+
+// Resume starts caching again after Suspend.
+func (c *Cache) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.suspended = false
+}
+
+// invalidate replaces the entry with a newer version that is not loaded and marks the metric stale in the list
+// of all metrics. It must be called with mu held.
+func (c *Cache) invalidate(key metricKey) {
+	c.changes++
+	c.entries[key] = entry{version: c.changes}
+	if !c.suspended && (c.allLoaded || c.listing > 0) {
+		c.stale[key] = c.changes
+	}
+}
+
+// reset drops all entries. It must be called with mu held.
+func (c *Cache) reset() {
+	c.entries = make(map[metricKey]entry)
+	c.all = nil
+	c.allLoaded = false
+	c.stale = make(map[metricKey]uint64)
+	c.epoch++
+	c.changes++
+}
+
+// list returns the cached list of all metrics. It must be called with mu held.
+func (c *Cache) list() []domain.Metric {
+	return slices.Collect(maps.Values(c.all))
+}
@@ -0,0 +1,248 @@
+package metriccache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+// countingStorage counts the reads reaching the storage and runs beforeGet inside every GetMetric and beforeList
+// inside every GetAllMetrics.
+type countingStorage struct {
+	*metricstorage.MemoryMetricStorage
+	gets       int
+	lists      int
+	beforeGet  func()
+	beforeList func()
+	// err fails all reads when set
+	err error
+}
+
+func (s *countingStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	s.gets++
+	if s.err != nil {
+		return domain.Metric{}, false, s.err
+	}
+	metric, found, err := s.MemoryMetricStorage.GetMetric(ctx, metricType, metricName)
+	if s.beforeGet != nil {
+		s.beforeGet()
+	}
+	return metric, found, err
+}
+
+func (s *countingStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	s.lists++
+	if s.err != nil {
+		return nil, s.err
+	}
+	metrics, err := s.MemoryMetricStorage.GetAllMetrics(ctx)
+	if s.beforeList != nil {
+		s.beforeList()
+	}
+	return metrics, err
+}
+
+func newTestCache() (*Cache, *countingStorage, *time.Time) {
+	storage := &countingStorage{MemoryMetricStorage: metricstorage.NewMemoryMetricStorage()}
+	cache := New(storage, time.Minute)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	return cache, storage, &now
+}
+
+func TestCache_GetMetric(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, now := newTestCache()
+	require.NoError(t, cache.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), counter("PollCount", 5)}))
+
+	for i := 0; i < 3; i++ {
+		alloc, found := domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+		require.True(t, found)
+		assert.Equal(t, 1.0, *alloc.Value)
+		_, found = domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Sys")
+		assert.False(t, found)
+	}
+	assert.Equal(t, 2, storage.gets, "hits and misses are cached")
+
+	require.NoError(t, cache.UpdateMetric(ctx, counter("PollCount", 3)))
+	pollCount, _ := domaintest.GetMetric(t, cache, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(8), *pollCount.Delta, "writes evict the metric")
+
+	found, err := cache.DeleteMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	assert.True(t, found)
+	_, found = domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	assert.False(t, found)
+
+	gets := storage.gets
+	*now = now.Add(2 * time.Minute)
+	cache.GetMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, gets+1, storage.gets, "entries expire after the TTL")
+}
+
+func TestCache_GetAllMetrics(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetrics(ctx, []domain.Metric{gauge("CPU1", 1), gauge("CPU2", 2), gauge("Alloc", 3)}))
+
+	assert.Len(t, domaintest.AllMetrics(t, cache), 3)
+	all := domaintest.AllMetrics(t, cache)
+	assert.Len(t, all, 3)
+	assert.Equal(t, 1, storage.lists)
+	all[0] = domain.Metric{}
+	assert.NotContains(t, domaintest.AllMetrics(t, cache), domain.Metric{}, "callers get their own slice")
+
+	require.NoError(t, cache.UpdateMetrics(ctx, []domain.Metric{gauge("Sys", 4), gauge("Alloc", 5)}))
+	gets := storage.gets
+	all = domaintest.AllMetrics(t, cache)
+	assert.Len(t, all, 4)
+	for _, metric := range all {
+		if metric.ID == "Alloc" {
+			assert.Equal(t, 5.0, *metric.Value, "the changed metrics are read again")
+		}
+	}
+	assert.Equal(t, gets+2, storage.gets, "only the changed metrics are read")
+	assert.Equal(t, 1, storage.lists)
+
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "CPU1")
+	deleted, err := cache.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Len(t, domaintest.AllMetrics(t, cache), 2)
+	_, found := domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "CPU1")
+	assert.False(t, found)
+	assert.Equal(t, 1, storage.lists, "deleted metrics are dropped from the list")
+
+	batch := make([]domain.Metric, staleRefreshLimit+1)
+	for i := range batch {
+		batch[i] = counter(fmt.Sprintf("Counter%d", i), 1)
+	}
+	require.NoError(t, cache.UpdateMetrics(ctx, batch))
+	assert.Len(t, domaintest.AllMetrics(t, cache), 2+len(batch))
+	assert.Equal(t, 2, storage.lists, "the whole list is read again after many changes")
+}
+
+func TestCache_ReadErrors(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetric(ctx, gauge("Alloc", 1)))
+
+	storage.err = fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable)
+	_, found, err := cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+	assert.False(t, found)
+	_, err = cache.GetAllMetrics(ctx)
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+
+	storage.err = nil
+	alloc, found := domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	require.True(t, found, "failed reads are not cached")
+	assert.Equal(t, 1.0, *alloc.Value)
+	assert.Len(t, domaintest.AllMetrics(t, cache), 1)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetric(ctx, gauge("Alloc", 1)))
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	cache.GetAllMetrics(ctx)
+
+	// Another replica changes the shared storage
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+	alloc, _ := domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 1.0, *alloc.Value)
+
+	cache.Invalidate(domain.MetricTypeGauge, "Alloc")
+	alloc, _ = domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 2.0, *alloc.Value)
+	assert.Equal(t, 2.0, *domaintest.AllMetrics(t, cache)[0].Value, "the list of all metrics is evicted too")
+}
+
+func TestCache_Suspend(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetric(ctx, gauge("Alloc", 1)))
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+
+	cache.Suspend()
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	cache.GetAllMetrics(ctx)
+	cache.GetAllMetrics(ctx)
+	assert.Equal(t, 3, storage.gets, "reads pass through while suspended")
+	assert.Equal(t, 2, storage.lists)
+
+	cache.Resume()
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 4, storage.gets)
+}
+
+func TestCache_InvalidationDuringLookup(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetric(ctx, gauge("Alloc", 1)))
+
+	// The metric changes after the storage was read, but before the result is cached
+	storage.beforeGet = func() {
+		storage.beforeGet = nil
+		require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+		cache.Invalidate(domain.MetricTypeGauge, "Alloc")
+	}
+	alloc, _ := domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 1.0, *alloc.Value)
+
+	alloc, _ = domaintest.GetMetric(t, cache, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 2.0, *alloc.Value, "the outdated lookup is not cached")
+
+	storage.beforeGet = func() {
+		storage.beforeGet = nil
+		cache.Suspend()
+		cache.Resume()
+	}
+	cache.Invalidate(domain.MetricTypeGauge, "Alloc")
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	gets := storage.gets
+	cache.GetMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, gets+1, storage.gets, "lookups started before a suspension are not cached")
+}
+
+func TestCache_InvalidationDuringList(t *testing.T) {
+	ctx := context.Background()
+	cache, storage, _ := newTestCache()
+	require.NoError(t, cache.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), gauge("Sys", 1)}))
+
+	// The metric changes after the storage was read, but before the list is cached
+	storage.beforeList = func() {
+		storage.beforeList = nil
+		require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+		cache.Invalidate(domain.MetricTypeGauge, "Alloc")
+	}
+	cache.GetAllMetrics(ctx)
+
+	gets := storage.gets
+	for _, metric := range domaintest.AllMetrics(t, cache) {
+		if metric.ID == "Alloc" {
+			assert.Equal(t, 2.0, *metric.Value, "a metric changed during the read stays stale")
+		}
+	}
+	assert.Equal(t, gets+1, storage.gets)
+	assert.Equal(t, 1, storage.lists)
+}
@@ -0,0 +1,249 @@
+package metrichistory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/jsonfile"
+)
+
+// minCompactSize is the log size below which the rollup log is not compacted.
+const minCompactSize = 1 << 20
+
+const (
+	opAppend       = "append"
+	opPrune        = "prune"
+	opDelete       = "delete"
+	opDeletePrefix = "delete_prefix"
+)
+
+// FileStorage keeps the rollups in a JSON Lines log of the appended rollups, prunes and deletions, replayed on
+// load. Once the log has grown to twice its size after the last compaction, it is rewritten with the rollups
+// still kept, so it stays proportional to them.
+type FileStorage struct {
+	mu   sync.Mutex
+	path string
+	// compacted is the size of the log after the last compaction, written the bytes appended since
+	compacted int64
+	written   int64
+}
+
+var _ domain.RollupStorage = (*FileStorage)(nil)
+
+// logEntry is a line of the rollup log.
+type logEntry struct {
+	Op         string            `json:"op"`
+	MType      domain.MetricType `json:"type,omitempty"`
+	ID         string            `json:"id,omitempty"`
+	Resolution time.Duration     `json:"resolution,omitempty"`
+	Rollups    []domain.Rollup   `json:"rollups,omitempty"`
+	Before     time.Time         `json:"before,omitzero"`
+	Prefix     string            `json:"prefix,omitempty"`
+}
+
+type storedSeriesKey struct {
+	seriesKey
+	resolution time.Duration
+}
+
+func NewFileStorage(path string) *FileStorage {
+	s := &FileStorage{path: path}
+	if info, err := os.Stat(path); err == nil {
+		s.compacted = info.Size()
+	}
+	return s
+}
+
+func (s *FileStorage) LoadRollups(_ context.Context) ([]domain.RollupSeries, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.replay()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.RollupSeries, 0, len(stored))
+	for key, rollups := range stored {
+		result = append(result, domain.RollupSeries{
+			MType:      key.mType,
+			ID:         key.id,
+			Resolution: domain.Resolution(key.resolution),
+			Rollups:    rollups,
+		})
+	}
+	return result, nil
+}
+
+func (s *FileStorage) AppendRollups(_ context.Context, series []domain.RollupSeries) error {
+	entries := make([]logEntry, 0, len(series))
+	for _, item := range series {
+		entries = append(entries, logEntry{
+			Op:         opAppend,
+			MType:      item.MType,
+			ID:         item.ID,
+			Resolution: time.Duration(item.Resolution),
+			Rollups:    item.Rollups,
+		})
+	}
+	return s.write(entries...)
+}
+
+func (s *FileStorage) PruneRollups(_ context.Context, resolution domain.Resolution, before time.Time) error {
+	return s.write(logEntry{Op: opPrune, Resolution: time.Duration(resolution), Before: before})
+}
+
+func (s *FileStorage) DeleteRollups(_ context.Context, metricType domain.MetricType, metricName string) error {
+	return s.write(logEntry{Op: opDelete, MType: metricType, ID: metricName})
+}
+
+func (s *FileStorage) DeleteRollupsByPrefix(_ context.Context, prefix string) error {
+	return s.write(logEntry{Op: opDeletePrefix, Prefix: prefix})
+}
+
+// write appends the entries to the log, compacting it once it has doubled.
+func (s *FileStorage) write(entries ...logEntry) error {
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rollups: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open rollups file: %w", err)
+	}
+
+	// A line truncated by a crash is terminated first, so it does not swallow the new entries
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write rollups file: %w", err)
+	}
+
+	s.written += int64(len(data))
+	if s.written < max(s.compacted, minCompactSize) {
+		return nil
+	}
+	return s.compact()
+}
+
+// compact rewrites the log with an append entry per series still kept. It must be called with mu held.
+func (s *FileStorage) compact() error {
+	stored, err := s.replay()
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for key, rollups := range stored {
+		line, err := json.Marshal(logEntry{Op: opAppend, MType: key.mType, ID: key.id, Resolution: key.resolution, Rollups: rollups})
+		if err != nil {
+			return fmt.Errorf("failed to marshal rollups: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := jsonfile.WriteFile(s.path, data); err != nil {
+		return fmt.Errorf("failed to compact rollups file: %w", err)
+	}
+
+	s.compacted, s.written = int64(len(data)), 0
+	return nil
+}
+
+// replay applies the log, skipping a line truncated by a crash during a write. It must be called with mu held.
+func (s *FileStorage) replay() (map[storedSeriesKey][]domain.Rollup, error) {
+	stored := make(map[storedSeriesKey][]domain.Rollup)
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rollups file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		var entry logEntry
+		if len(line) == 0 || json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		entry.apply(stored)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rollups file: %w", err)
+	}
+
+	return stored, nil
+}
+
+func (e logEntry) apply(stored map[storedSeriesKey][]domain.Rollup) {
+	switch e.Op {
+	case opAppend:
+		key := storedSeriesKey{seriesKey: seriesKey{mType: e.MType, id: e.ID}, resolution: e.Resolution}
+		rollups := stored[key]
+		for _, rollup := range e.Rollups {
+			// Rollups stored by an earlier attempt of a failed write are not added twice
+			if n := len(rollups); n == 0 || rollup.Start.After(rollups[n-1].Start) {
+				rollups = append(rollups, rollup)
+			}
+		}
+		stored[key] = rollups
+
+	case opPrune:
+		for key, rollups := range stored {
+			if key.resolution != e.Resolution {
+				continue
+			}
+			if rollups = rollups[searchRollups(rollups, e.Before):]; len(rollups) == 0 {
+				delete(stored, key)
+				continue
+			}
+			stored[key] = rollups
+		}
+
+	case opDelete:
+		for key := range stored {
+			if key.mType == e.MType && key.id == e.ID {
+				delete(stored, key)
+			}
+		}
+
+	case opDeletePrefix:
+		for key := range stored {
+			if strings.HasPrefix(key.id, e.Prefix) {
+				delete(stored, key)
+			}
+		}
+	}
+}
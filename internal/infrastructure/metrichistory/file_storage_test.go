@@ -0,0 +1,102 @@
+package metrichistory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// loadRollups returns the stored rollups ordered by resolution and ID.
+func loadRollups(t *testing.T, storage domain.RollupStorage) []domain.RollupSeries {
+	t.Helper()
+	stored, err := storage.LoadRollups(context.Background())
+	require.NoError(t, err)
+	slices.SortFunc(stored, func(a, b domain.RollupSeries) int {
+		if a.Resolution != b.Resolution {
+			return int(a.Resolution - b.Resolution)
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return stored
+}
+
+func TestFileStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json.rollups")
+	storage := NewFileStorage(path)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := domain.Resolution(time.Minute)
+	hour := domain.Resolution(time.Hour)
+	rollup := func(offset time.Duration, last float64) domain.Rollup {
+		return domain.Rollup{Start: start.Add(offset), Count: 1, Min: last, Max: last, Avg: last, Last: last}
+	}
+
+	assert.Empty(t, loadRollups(t, storage), "a missing file holds no rollups")
+
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(0, 1), rollup(time.Minute, 2)}},
+		{MType: domain.MetricTypeGauge, ID: "agent1/Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(0, 1)}},
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+	}))
+	// A retried write adds the rollups stored already once
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+	}))
+	require.NoError(t, storage.PruneRollups(ctx, minute, start.Add(time.Minute)))
+	require.NoError(t, storage.DeleteRollupsByPrefix(ctx, "agent1/"))
+
+	// A line truncated by a crash is skipped, the next entries still apply
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"op":"delete","type":"gauge","id":"Al`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+		{MType: domain.MetricTypeCounter, ID: "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start, Count: 2, Sum: 5, Last: 5}}},
+	}))
+
+	want := []domain.RollupSeries{
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: minute, Rollups: []domain.Rollup{rollup(time.Minute, 2), rollup(2*time.Minute, 3)}},
+		{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: hour, Rollups: []domain.Rollup{rollup(0, 2)}},
+		{MType: domain.MetricTypeCounter, ID: "PollCount", Resolution: hour, Rollups: []domain.Rollup{{Start: start, Count: 2, Sum: 5, Last: 5}}},
+	}
+	assert.Equal(t, want, loadRollups(t, NewFileStorage(path)))
+
+	require.NoError(t, storage.DeleteRollups(ctx, domain.MetricTypeGauge, "Alloc"))
+	assert.Equal(t, want[2:], loadRollups(t, storage))
+}
+
+func TestFileStorage_Compact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json.rollups")
+	storage := NewFileStorage(path)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Every minute adds a rollup and drops the one before, only the last one is kept
+	var size int64
+	for i := range 20000 {
+		at := start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, storage.AppendRollups(ctx, []domain.RollupSeries{
+			{MType: domain.MetricTypeGauge, ID: "Alloc", Resolution: domain.Resolution(time.Minute), Rollups: []domain.Rollup{{Start: at, Count: 1, Last: 1}}},
+		}))
+		require.NoError(t, storage.PruneRollups(ctx, domain.Resolution(time.Minute), at))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		size = max(size, info.Size())
+	}
+
+	assert.LessOrEqual(t, size, int64(2*minCompactSize+1024), "the log is compacted once it doubled")
+	stored := loadRollups(t, NewFileStorage(path))
+	require.Len(t, stored, 1)
+	assert.Equal(t, []domain.Rollup{{Start: start.Add(19999 * time.Minute), Count: 1, Last: 1}}, stored[0].Rollups)
+}
@@ -0,0 +1,439 @@
+package metrichistory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// defaultMaxPoints limits the points of a range query that does not give a limit.
+const defaultMaxPoints = 1000
+
+// defaultQueryRange is how far back a range query without a start looks.
+const defaultQueryRange = time.Hour
+
+// tier holds the rollups of one resolution. All intervals before mark are rolled up, the later ones
+// are still being filled.
+type tier struct {
+	resolution time.Duration
+	retention  time.Duration
+	mark       time.Time
+	series     map[seriesKey][]domain.Rollup
+}
+
+func newTiers(policies []domain.RetentionPolicy) []*tier {
+	tiers := make([]*tier, len(policies))
+	for i, policy := range policies {
+		tiers[i] = &tier{
+			resolution: policy.Resolution,
+			retention:  policy.Retention,
+			series:     make(map[seriesKey][]domain.Rollup),
+		}
+	}
+	return tiers
+}
+
+// Rollup aggregates the intervals that ended since the last run into every rollup tier, each from the finer
+// data before it, and drops the rollups older than their retention. It takes the lock for a single series at
+// a time, so updates and queries of the other series go on meanwhile. The new rollups are then stored in the
+// rollup storage and the expired ones dropped from it.
+func (s *Store) Rollup() {
+	now := s.now()
+
+	for i, t := range s.tiers {
+		s.mu.Lock()
+		end := now.Truncate(t.resolution)
+		if t.mark.IsZero() {
+			t.mark = s.oldest(i, end).Truncate(t.resolution)
+		}
+		// Samples appended from now on start after the mark, see append
+		from := t.mark
+		t.mark = maxTime(from, end)
+		keys := s.sourceKeys(i)
+		s.mu.Unlock()
+
+		if end.After(from) {
+			for _, key := range keys {
+				s.mu.Lock()
+				var rollups []domain.Rollup
+				if i == 0 {
+					rollups = rollupSamples(key, s.series[key], t, from, end)
+				} else {
+					rollups = rollupRollups(key, s.tiers[i-1].series[key], t, from, end)
+				}
+				if s.rollups != nil && len(rollups) > 0 {
+					s.unsaved = append(s.unsaved, domain.RollupSeries{
+						MType:      key.mType,
+						ID:         key.id,
+						Resolution: domain.Resolution(t.resolution),
+						Rollups:    rollups,
+					})
+				}
+				s.mu.Unlock()
+			}
+		}
+
+		s.prune(t, now.Add(-t.retention))
+	}
+
+	s.save(context.Background(), now)
+}
+
+// save stores the new rollups and drops the expired ones from the rollup storage. Rollups failing to be stored
+// are kept for the next run, expired ones failing to be dropped are dropped with the next ones.
+func (s *Store) save(ctx context.Context, now time.Time) {
+	if s.rollups == nil {
+		return
+	}
+
+	s.saves.Lock()
+	defer s.saves.Unlock()
+
+	s.mu.Lock()
+	unsaved := s.unsaved
+	s.unsaved = nil
+	s.mu.Unlock()
+
+	if len(unsaved) > 0 {
+		if err := s.rollups.AppendRollups(ctx, unsaved); err != nil {
+			s.logger.Error().Err(err).Msg("failed to store rollups, retrying with the next rollup")
+			s.mu.Lock()
+			s.unsaved = append(unsaved, s.unsaved...)
+			s.mu.Unlock()
+		}
+	}
+
+	for _, t := range s.tiers {
+		if err := s.rollups.PruneRollups(ctx, domain.Resolution(t.resolution), now.Add(-t.retention)); err != nil {
+			s.logger.Error().Err(err).Dur("resolution", t.resolution).Msg("failed to drop expired rollups")
+		}
+	}
+}
+
+// restore adds the stored rollups to their tiers and marks each tier as rolled up until the end of its latest
+// rollup. The intervals after it are rolled up from the finer data, which holds nothing up to there: a coarser
+// rollup is built from every finer one before the mark. Rollups of resolutions no longer kept are skipped,
+// the expired ones are dropped by the next Rollup.
+func (s *Store) restore(stored []domain.RollupSeries) {
+	s.mu.Lock()
+	for _, series := range stored {
+		t := s.tier(series.Resolution)
+		if t == nil || len(series.Rollups) == 0 {
+			continue
+		}
+
+		key := seriesKey{mType: series.MType, id: series.ID}
+		rollups := append(t.series[key], series.Rollups...)
+		slices.SortFunc(rollups, func(a, b domain.Rollup) int {
+			return a.Start.Compare(b.Start)
+		})
+		t.series[key] = rollups
+		t.mark = maxTime(t.mark, rollups[len(rollups)-1].Start.Add(t.resolution))
+	}
+	s.mu.Unlock()
+}
+
+// QueryRange returns the values of the metric in the query range at the requested or automatically picked
+// resolution. Rollups are only returned for intervals that ended before the last Rollup.
+func (s *Store) QueryRange(_ context.Context, query domain.RangeQuery) (domain.MetricRange, error) {
+	now := s.now()
+	if query.To.IsZero() {
+		query.To = now
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultQueryRange)
+	}
+	if !query.From.Before(query.To) {
+		return domain.MetricRange{}, fmt.Errorf("%w: from must be before to", domain.ErrInvalidRange)
+	}
+	if query.MaxPoints <= 0 {
+		query.MaxPoints = defaultMaxPoints
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := seriesKey{mType: query.MType, id: query.ID}
+	resolution, err := s.resolution(key, query, now)
+	if err != nil {
+		return domain.MetricRange{}, err
+	}
+
+	result := domain.MetricRange{
+		ID:         query.ID,
+		MType:      query.MType,
+		Resolution: resolution,
+		From:       query.From,
+		To:         query.To,
+		Points:     []domain.Rollup{},
+	}
+
+	if resolution == domain.ResolutionRaw {
+		samples := s.series[key]
+		i, j := searchSamples(samples, query.From), searchSamples(samples, query.To)
+		var prev *float64
+		if i > 0 {
+			prev = &samples[i-1].Value
+		}
+		for _, sample := range samples[i:j] {
+			result.Points = append(result.Points, sampleRollup(query.MType, sample, prev))
+			prev = &sample.Value
+		}
+		return result, nil
+	}
+
+	rollups := s.tier(resolution).series[key]
+	// The rollups overlapping the range, the first one may start before it
+	i := searchRollups(rollups, query.From.Add(-time.Duration(resolution)+1))
+	j := searchRollups(rollups, query.To)
+	result.Points = append(result.Points, rollups[i:j]...)
+	return result, nil
+}
+
+// resolution returns the requested resolution, or the finest one that keeps the whole range and has at most
+// the maximum number of points, falling back to the coarsest one. It must be called with mu held.
+func (s *Store) resolution(key seriesKey, query domain.RangeQuery, now time.Time) (domain.Resolution, error) {
+	if query.Resolution != nil {
+		if *query.Resolution == domain.ResolutionRaw || s.tier(*query.Resolution) != nil {
+			return *query.Resolution, nil
+		}
+		return 0, fmt.Errorf("%w: no data is kept at resolution %s", domain.ErrInvalidRange, query.Resolution)
+	}
+
+	samples := s.series[key]
+	raw := searchSamples(samples, query.To) - searchSamples(samples, query.From)
+	if !query.From.Before(now.Add(-s.retention)) && raw <= query.MaxPoints {
+		return domain.ResolutionRaw, nil
+	}
+
+	for _, t := range s.tiers {
+		points := int(query.To.Sub(query.From)/t.resolution) + 1
+		if !query.From.Before(now.Add(-t.retention)) && points <= query.MaxPoints {
+			return domain.Resolution(t.resolution), nil
+		}
+	}
+
+	if len(s.tiers) == 0 {
+		return domain.ResolutionRaw, nil
+	}
+	return domain.Resolution(s.tiers[len(s.tiers)-1].resolution), nil
+}
+
+func (s *Store) tier(resolution domain.Resolution) *tier {
+	for _, t := range s.tiers {
+		if t.resolution == time.Duration(resolution) {
+			return t
+		}
+	}
+	return nil
+}
+
+// oldest returns the time of the oldest data the tier is built from, or end without any.
+func (s *Store) oldest(tierIndex int, end time.Time) time.Time {
+	oldest := end
+	if tierIndex == 0 {
+		for _, samples := range s.series {
+			if len(samples) > 0 && samples[0].Time.Before(oldest) {
+				oldest = samples[0].Time
+			}
+		}
+		return oldest
+	}
+
+	for _, rollups := range s.tiers[tierIndex-1].series {
+		if len(rollups) > 0 && rollups[0].Start.Before(oldest) {
+			oldest = rollups[0].Start
+		}
+	}
+	return oldest
+}
+
+// sourceKeys returns the series the tier is built from. It must be called with mu held.
+func (s *Store) sourceKeys(tierIndex int) []seriesKey {
+	var keys []seriesKey
+	if tierIndex == 0 {
+		for key := range s.series {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+
+	for key := range s.tiers[tierIndex-1].series {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// rollupSamples aggregates the raw samples of the series between from and end into the tier, returning the
+// new rollups. It must be called with mu held.
+func rollupSamples(key seriesKey, samples []domain.Sample, t *tier, from, end time.Time) []domain.Rollup {
+	i, j := searchSamples(samples, from), searchSamples(samples, end)
+	if i == j {
+		return nil
+	}
+
+	// Counter increases continue from the sample before the interval, or the total of the last rollup
+	var prev *float64
+	if i > 0 {
+		prev = &samples[i-1].Value
+	} else if rollups := t.series[key]; len(rollups) > 0 {
+		prev = &rollups[len(rollups)-1].Last
+	}
+
+	b := rollupBuilder{mType: key.mType}
+	for _, sample := range samples[i:j] {
+		b.addSample(sample.Time.Truncate(t.resolution), sample.Value, prev)
+		prev = &sample.Value
+	}
+	rollups := b.result()
+	t.series[key] = append(t.series[key], rollups...)
+	return rollups
+}
+
+// rollupRollups aggregates the rollups of the series in the finer tier between from and end into the target tier,
+// returning the new rollups. It must be called with mu held.
+func rollupRollups(key seriesKey, rollups []domain.Rollup, target *tier, from, end time.Time) []domain.Rollup {
+	i, j := searchRollups(rollups, from), searchRollups(rollups, end)
+	if i == j {
+		return nil
+	}
+
+	b := rollupBuilder{mType: key.mType}
+	for _, rollup := range rollups[i:j] {
+		b.addRollup(rollup.Start.Truncate(target.resolution), rollup)
+	}
+	result := b.result()
+	target.series[key] = append(target.series[key], result...)
+	return result
+}
+
+// prune drops the rollups of the tier starting before the cutoff, a series at a time.
+func (s *Store) prune(t *tier, cutoff time.Time) {
+	s.mu.RLock()
+	keys := make([]seriesKey, 0, len(t.series))
+	for key := range t.series {
+		keys = append(keys, key)
+	}
+	s.mu.RUnlock()
+
+	for _, key := range keys {
+		s.mu.Lock()
+		rollups := t.series[key]
+		i := searchRollups(rollups, cutoff)
+		switch {
+		case i == len(rollups):
+			delete(t.series, key)
+		case i > 0:
+			t.series[key] = append(rollups[:0], rollups[i:]...)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// rollupBuilder aggregates values in time order into consecutive rollups.
+type rollupBuilder struct {
+	mType   domain.MetricType
+	rollups []domain.Rollup
+	// total is the sum of the gauge values of the last rollup, its average once divided by the count
+	total float64
+}
+
+func (b *rollupBuilder) addSample(start time.Time, value float64, prev *float64) {
+	r := b.current(start)
+	if b.mType == domain.MetricTypeCounter {
+		r.Sum += increase(prev, value)
+	} else {
+		b.addRange(r, value, value)
+		b.total += value
+	}
+	r.Count++
+	r.Last = value
+}
+
+func (b *rollupBuilder) addRollup(start time.Time, rollup domain.Rollup) {
+	r := b.current(start)
+	if b.mType == domain.MetricTypeCounter {
+		r.Sum += rollup.Sum
+	} else {
+		b.addRange(r, rollup.Min, rollup.Max)
+		b.total += rollup.Avg * float64(rollup.Count)
+	}
+	r.Count += rollup.Count
+	r.Last = rollup.Last
+}
+
+func (b *rollupBuilder) addRange(r *domain.Rollup, minValue, maxValue float64) {
+	if r.Count == 0 {
+		r.Min, r.Max = minValue, maxValue
+		return
+	}
+	r.Min = min(r.Min, minValue)
+	r.Max = max(r.Max, maxValue)
+}
+
+// current returns the rollup starting at start, finishing the previous one when a new interval begins.
+func (b *rollupBuilder) current(start time.Time) *domain.Rollup {
+	if n := len(b.rollups); n > 0 && b.rollups[n-1].Start.Equal(start) {
+		return &b.rollups[n-1]
+	}
+
+	b.finish()
+	b.rollups = append(b.rollups, domain.Rollup{Start: start})
+	return &b.rollups[len(b.rollups)-1]
+}
+
+func (b *rollupBuilder) finish() {
+	if n := len(b.rollups); n > 0 && b.mType == domain.MetricTypeGauge {
+		b.rollups[n-1].Avg = b.total / float64(b.rollups[n-1].Count)
+	}
+	b.total = 0
+}
+
+func (b *rollupBuilder) result() []domain.Rollup {
+	b.finish()
+	return b.rollups
+}
+
+// sampleRollup returns a raw sample as a rollup of one value.
+func sampleRollup(mType domain.MetricType, sample domain.Sample, prev *float64) domain.Rollup {
+	b := rollupBuilder{mType: mType}
+	b.addSample(sample.Time, sample.Value, prev)
+	return b.result()[0]
+}
+
+// increase returns how much a counter grew from the previous total, which is unknown for the first sample.
+// A smaller total means the counter was reset and counted up from zero again.
+func increase(prev *float64, value float64) float64 {
+	switch {
+	case prev == nil:
+		return 0
+	case value < *prev:
+		return value
+	default:
+		return value - *prev
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func searchSamples(samples []domain.Sample, t time.Time) int {
+	i, _ := slices.BinarySearchFunc(samples, t, func(sample domain.Sample, t time.Time) int {
+		return sample.Time.Compare(t)
+	})
+	return i
+}
+
+func searchRollups(rollups []domain.Rollup, t time.Time) int {
+	i, _ := slices.BinarySearchFunc(rollups, t, func(rollup domain.Rollup, t time.Time) int {
+		return rollup.Start.Compare(t)
+	})
+	return i
+}
@@ -0,0 +1,337 @@
+package metrichistory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func newRollupStore() (*Store, *time.Time) {
+	store := New(
+		metricstorage.NewMemoryMetricStorage(),
+		2*time.Hour,
+		domain.RetentionPolicy{Resolution: time.Minute, Retention: 24 * time.Hour},
+		domain.RetentionPolicy{Resolution: time.Hour, Retention: 7 * 24 * time.Hour},
+	)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func resolution(r time.Duration) *domain.Resolution {
+	res := domain.Resolution(r)
+	return &res
+}
+
+// updateAt applies the updates at the given offset from midnight.
+func updateAt(t *testing.T, store *Store, now *time.Time, offset time.Duration, metrics ...domain.Metric) {
+	t.Helper()
+	*now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset)
+	require.NoError(t, store.UpdateMetrics(context.Background(), metrics))
+}
+
+func TestStore_Rollup(t *testing.T) {
+	ctx := context.Background()
+	store, now := newRollupStore()
+	start := *now
+
+	updateAt(t, store, now, 10*time.Second, gauge("Alloc", 1), counter("PollCount", 5))
+	updateAt(t, store, now, 40*time.Second, gauge("Alloc", 3), counter("PollCount", 5))
+	updateAt(t, store, now, 70*time.Second, gauge("Alloc", 5), counter("PollCount", 2))
+
+	*now = start.Add(2 * time.Minute)
+	store.Rollup()
+
+	minutes, err := store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: *now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.Resolution(time.Minute), minutes.Resolution)
+	assert.Equal(t, []domain.Rollup{
+		{Start: start, Count: 2, Min: 1, Max: 3, Avg: 2, Last: 3},
+		{Start: start.Add(time.Minute), Count: 1, Min: 5, Max: 5, Avg: 5, Last: 5},
+	}, minutes.Points)
+
+	minutes, err = store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeCounter, ID: "PollCount", From: start, To: *now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{
+		{Start: start, Count: 2, Sum: 5, Last: 10},
+		{Start: start.Add(time.Minute), Count: 1, Sum: 2, Last: 12},
+	}, minutes.Points, "counters sum up their increases")
+
+	updateAt(t, store, now, 30*time.Minute, gauge("Alloc", 7), counter("PollCount", 8))
+	*now = start.Add(time.Hour + 30*time.Second)
+	store.Rollup()
+
+	hours, err := store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: *now, Resolution: resolution(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{{Start: start, Count: 4, Min: 1, Max: 7, Avg: 4, Last: 7}}, hours.Points)
+
+	hours, err = store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeCounter, ID: "PollCount", From: start, To: *now, Resolution: resolution(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{{Start: start, Count: 4, Sum: 15, Last: 20}}, hours.Points)
+}
+
+func TestStore_RollupCounterReset(t *testing.T) {
+	ctx := context.Background()
+	store, now := newRollupStore()
+	start := *now
+
+	updateAt(t, store, now, 10*time.Second, counter("PollCount", 10))
+	*now = start.Add(time.Minute)
+	store.Rollup()
+
+	// The counter restarts from zero, as after an agent restart with the storage reset
+	_, err := store.DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	require.NoError(t, err)
+	updateAt(t, store, now, 70*time.Second, counter("PollCount", 10))
+	updateAt(t, store, now, 80*time.Second, counter("PollCount", 5))
+	*now = start.Add(2 * time.Minute)
+	store.Rollup()
+
+	result, err := store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeCounter, ID: "PollCount", From: start, To: *now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{{Start: start.Add(time.Minute), Count: 2, Sum: 5, Last: 15}}, result.Points,
+		"deleting a metric forgets its rollups")
+
+	assert.Equal(t, 3.0, increase(ptr(12.0), 3), "a smaller total is a reset")
+	assert.Equal(t, 0.0, increase(nil, 3))
+}
+
+func TestStore_RollupLateSample(t *testing.T) {
+	ctx := context.Background()
+	store, now := newRollupStore()
+	start := *now
+
+	updateAt(t, store, now, 10*time.Second, gauge("Alloc", 1))
+	*now = start.Add(2 * time.Minute)
+	store.Rollup()
+
+	// Read at 1:30, the sample is appended after the rollup of its interval
+	updateAt(t, store, now, 90*time.Second, gauge("Alloc", 2))
+	*now = start.Add(3 * time.Minute)
+	store.Rollup()
+
+	result, err := store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: *now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{
+		{Start: start, Count: 1, Min: 1, Max: 1, Avg: 1, Last: 1},
+		{Start: start.Add(2 * time.Minute), Count: 1, Min: 2, Max: 2, Avg: 2, Last: 2},
+	}, result.Points, "a late sample is rolled up with the first open interval")
+}
+
+func TestStore_QueryRangeResolution(t *testing.T) {
+	ctx := context.Background()
+	store, now := newRollupStore()
+	start := *now
+
+	for i := 0; i < 120; i++ {
+		updateAt(t, store, now, time.Duration(i)*30*time.Second, gauge("Alloc", float64(i)))
+	}
+	*now = start.Add(time.Hour + time.Minute)
+	store.Rollup()
+
+	tests := []struct {
+		name       string
+		query      domain.RangeQuery
+		resolution domain.Resolution
+		points     int
+	}{
+		{
+			name:       "raw samples fit",
+			query:      domain.RangeQuery{From: start, To: start.Add(10 * time.Minute)},
+			resolution: domain.ResolutionRaw,
+			points:     20,
+		},
+		{
+			name:       "too many raw samples",
+			query:      domain.RangeQuery{From: start, To: start.Add(time.Hour), MaxPoints: 100},
+			resolution: domain.Resolution(time.Minute),
+			points:     60,
+		},
+		{
+			name:       "too many minutes",
+			query:      domain.RangeQuery{From: start, To: start.Add(time.Hour), MaxPoints: 10},
+			resolution: domain.Resolution(time.Hour),
+			points:     1,
+		},
+		{
+			name:       "older than the raw retention",
+			query:      domain.RangeQuery{From: start.Add(-3 * time.Hour), To: start.Add(time.Hour)},
+			resolution: domain.Resolution(time.Minute),
+			points:     60,
+		},
+		{
+			name:       "older than all retentions",
+			query:      domain.RangeQuery{From: start.Add(-30 * 24 * time.Hour), To: start.Add(time.Hour)},
+			resolution: domain.Resolution(time.Hour),
+			points:     1,
+		},
+		{
+			name:       "rollups overlapping the start",
+			query:      domain.RangeQuery{From: start.Add(90 * time.Second), To: start.Add(3 * time.Minute), Resolution: resolution(time.Minute)},
+			resolution: domain.Resolution(time.Minute),
+			points:     2,
+		},
+		{
+			name:       "explicit raw",
+			query:      domain.RangeQuery{From: start, To: start.Add(time.Hour), Resolution: resolution(0), MaxPoints: 10},
+			resolution: domain.ResolutionRaw,
+			points:     120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.MType, tt.query.ID = domain.MetricTypeGauge, "Alloc"
+			result, err := store.QueryRange(ctx, tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resolution, result.Resolution)
+			assert.Len(t, result.Points, tt.points)
+		})
+	}
+
+	_, err := store.QueryRange(ctx, domain.RangeQuery{From: start, To: start})
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+	_, err = store.QueryRange(ctx, domain.RangeQuery{Resolution: resolution(5 * time.Minute)})
+	assert.ErrorIs(t, err, domain.ErrInvalidRange)
+
+	result, err := store.QueryRange(ctx, domain.RangeQuery{MType: domain.MetricTypeGauge, ID: "Sys"})
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Minute), result.From, "the range defaults to the last hour")
+	assert.Empty(t, result.Points)
+}
+
+func TestStore_RollupRetention(t *testing.T) {
+	ctx := context.Background()
+	store, now := newRollupStore()
+	start := *now
+
+	updateAt(t, store, now, 10*time.Second, gauge("Alloc", 1))
+	*now = start.Add(time.Hour)
+	store.Rollup()
+
+	*now = start.Add(25 * time.Hour)
+	store.Rollup()
+	store.Prune()
+
+	result, err := store.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: *now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Points, "minute rollups expire after a day")
+
+	result, err = store.QueryRange(ctx, domain.RangeQuery{MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: *now})
+	require.NoError(t, err)
+	assert.Equal(t, domain.Resolution(time.Hour), result.Resolution)
+	assert.Equal(t, []domain.Rollup{{Start: start, Count: 1, Min: 1, Max: 1, Avg: 1, Last: 1}}, result.Points)
+}
+
+// failingRollupStorage fails the given number of appends before passing them on.
+type failingRollupStorage struct {
+	domain.RollupStorage
+	failures int
+}
+
+func (s *failingRollupStorage) AppendRollups(ctx context.Context, series []domain.RollupSeries) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("disk full")
+	}
+	return s.RollupStorage.AppendRollups(ctx, series)
+}
+
+func TestStore_PersistentRollups(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	path := filepath.Join(t.TempDir(), "metrics.json.rollups")
+	policies := []domain.RetentionPolicy{
+		{Resolution: time.Minute, Retention: 24 * time.Hour},
+		{Resolution: time.Hour, Retention: 7 * 24 * time.Hour},
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	newStore := func(rollups domain.RollupStorage) *Store {
+		t.Helper()
+		store, err := NewPersistent(ctx, metricstorage.NewMemoryMetricStorage(), rollups, &logger, 2*time.Hour, policies...)
+		require.NoError(t, err)
+		store.now = func() time.Time { return now }
+		return store
+	}
+
+	rollups := &failingRollupStorage{RollupStorage: NewFileStorage(path), failures: 1}
+	store := newStore(rollups)
+	updateAt(t, store, &now, 10*time.Second, gauge("Alloc", 1), gauge("Sys", 1))
+	updateAt(t, store, &now, 70*time.Second, gauge("Alloc", 3))
+	now = start.Add(2 * time.Minute)
+	store.Rollup()
+
+	stored, err := rollups.LoadRollups(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stored, "the first write failed")
+
+	updateAt(t, store, &now, 65*time.Minute, gauge("Alloc", 5))
+	now = start.Add(66 * time.Minute)
+	store.Rollup()
+	_, err = store.DeleteMetric(ctx, domain.MetricTypeGauge, "Sys")
+	require.NoError(t, err)
+
+	// A restarted store gets the rollups back and carries on from the last one
+	restored := newStore(NewFileStorage(path))
+	result, err := restored.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{
+		{Start: start, Count: 1, Min: 1, Max: 1, Avg: 1, Last: 1},
+		{Start: start.Add(time.Minute), Count: 1, Min: 3, Max: 3, Avg: 3, Last: 3},
+		{Start: start.Add(65 * time.Minute), Count: 1, Min: 5, Max: 5, Avg: 5, Last: 5},
+	}, result.Points, "the rollups of the failed write were stored with the next ones")
+
+	result, err = restored.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: now, Resolution: resolution(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{{Start: start, Count: 2, Min: 1, Max: 3, Avg: 2, Last: 3}}, result.Points)
+
+	result, err = restored.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Sys", From: start, To: now, Resolution: resolution(time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, result.Points, "the rollups of a deleted metric are deleted")
+
+	now = start.Add(2 * time.Hour)
+	restored.Rollup()
+	result, err = restored.QueryRange(ctx, domain.RangeQuery{
+		MType: domain.MetricTypeGauge, ID: "Alloc", From: start, To: now, Resolution: resolution(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Rollup{
+		{Start: start, Count: 2, Min: 1, Max: 3, Avg: 2, Last: 3},
+		{Start: start.Add(time.Hour), Count: 1, Min: 5, Max: 5, Avg: 5, Last: 5},
+	}, result.Points, "the restored minute rollups are rolled up into hours once")
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,384 @@
+package metrichistory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// pruneInterval is how often the ended intervals are rolled up and the expired samples and rollups dropped.
+const pruneInterval = time.Minute
+
+type seriesKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+// Store is a domain.MetricStorage decorator keeping the values of every metric in memory for the retention period.
+// Counter samples hold the total after each update, as returned by the decorated storage if it is a
+// domain.StoredStateUpdater and read back otherwise. Older values are kept as rollups of coarser resolutions,
+// each with its own retention. The raw samples are not persisted, a restart starts them over; the rollups are
+// kept in a domain.RollupStorage if one is given. A sample takes 32 bytes and a rollup 72, the retentions bound
+// the memory per series.
+type Store struct {
+	storage   domain.MetricStorage
+	rollups   domain.RollupStorage
+	logger    *zerolog.Logger
+	retention time.Duration
+	now       func() time.Time
+	// saves serializes the writes to the rollup storage, so the rollups of a deleted series are not stored
+	// after they were deleted
+	saves  sync.Mutex
+	mu     sync.RWMutex
+	series map[seriesKey][]domain.Sample
+	tiers  []*tier
+	// unsaved holds the rollups not in the rollup storage yet, retried after a failed write
+	unsaved []domain.RollupSeries
+}
+
+var _ domain.MetricStorage = (*Store)(nil)
+var _ domain.MetricHistory = (*Store)(nil)
+var _ domain.MetricRangeReader = (*Store)(nil)
+var _ domain.MetricReplica = (*Store)(nil)
+var _ domain.StaleMetricDeleter = (*Store)(nil)
+
+// New keeps the raw samples for the retention period and the given rollups, which have to pass
+// domain.ValidateRetentionPolicies, in memory only.
+func New(storage domain.MetricStorage, retention time.Duration, rollups ...domain.RetentionPolicy) *Store {
+	logger := zerolog.Nop()
+	return &Store{
+		storage:   storage,
+		logger:    &logger,
+		retention: retention,
+		now:       time.Now,
+		series:    make(map[seriesKey][]domain.Sample),
+		tiers:     newTiers(rollups),
+	}
+}
+
+// NewPersistent is New keeping the rollups in the rollup storage as well, and restores the stored ones.
+func NewPersistent(
+	ctx context.Context,
+	storage domain.MetricStorage,
+	rollupStorage domain.RollupStorage,
+	logger *zerolog.Logger,
+	retention time.Duration,
+	rollups ...domain.RetentionPolicy,
+) (*Store, error) {
+	s := New(storage, retention, rollups...)
+	s.rollups = rollupStorage
+	s.logger = logger
+
+	stored, err := rollupStorage.LoadRollups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rollups: %w", err)
+	}
+	s.restore(stored)
+
+	return s, nil
+}
+
+func (s *Store) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	return s.storage.GetAllMetrics(ctx)
+}
+
+func (s *Store) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	return s.storage.GetMetric(ctx, metricType, metricName)
+}
+
+func (s *Store) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	metrics := []domain.Metric{metric}
+	return s.update(ctx, metrics, func() error {
+		return s.storage.UpdateMetric(ctx, metric)
+	})
+}
+
+func (s *Store) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	return s.update(ctx, metrics, func() error {
+		return s.storage.UpdateMetrics(ctx, metrics)
+	})
+}
+
+// update applies the batch and records the stored states returned by the decorated storage. A storage not
+// returning them is updated with apply and the counters are read back.
+func (s *Store) update(ctx context.Context, metrics []domain.Metric, apply func() error) error {
+	if updater, ok := s.storage.(domain.StoredStateUpdater); ok {
+		stored, err := updater.UpdateMetricsStored(ctx, metrics)
+		if !errors.Is(err, domain.ErrNotStoredState) {
+			if err != nil {
+				return err
+			}
+			s.append(values(stored))
+			return nil
+		}
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	s.record(ctx, metrics)
+	return nil
+}
+
+func (s *Store) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	found, err := s.storage.DeleteMetric(ctx, metricType, metricName)
+	if err != nil {
+		return false, err
+	}
+
+	deleted := seriesKey{mType: metricType, id: metricName}
+	err = s.drop(func(key seriesKey) bool { return key == deleted }, func(rollups domain.RollupStorage) error {
+		return rollups.DeleteRollups(ctx, metricType, metricName)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+func (s *Store) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	deleter, ok := s.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		return false, domain.ErrNotStaleDeleter
+	}
+
+	found, err := deleter.DeleteMetricIfStale(ctx, metricType, metricName, before)
+	if err != nil || !found {
+		return false, err
+	}
+
+	deleted := seriesKey{mType: metricType, id: metricName}
+	err = s.drop(func(key seriesKey) bool { return key == deleted }, func(rollups domain.RollupStorage) error {
+		return rollups.DeleteRollups(ctx, metricType, metricName)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted, err := s.storage.DeleteByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.drop(func(key seriesKey) bool { return strings.HasPrefix(key.id, prefix) }, func(rollups domain.RollupStorage) error {
+		return rollups.DeleteRollupsByPrefix(ctx, prefix)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+func (s *Store) Ping(ctx context.Context) error {
+	return s.storage.Ping(ctx)
+}
+
+// Merge records the values of the metrics replicated from peers, the counters with their merged totals.
+func (s *Store) Merge(ctx context.Context, metrics []domain.ReplicatedMetric) ([]domain.MetricChange, error) {
+	replica, ok := s.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+
+	changes, err := replica.Merge(ctx, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []domain.Metric
+	deleted := make(map[seriesKey]bool)
+	for _, change := range changes {
+		if change.Deleted {
+			deleted[seriesKey{mType: change.Metric.MType, id: change.Metric.ID}] = true
+			continue
+		}
+		merged = append(merged, change.Metric)
+	}
+
+	if len(deleted) > 0 {
+		err := s.drop(func(key seriesKey) bool { return deleted[key] }, func(rollups domain.RollupStorage) error {
+			for key := range deleted {
+				if err := rollups.DeleteRollups(ctx, key.mType, key.id); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.append(values(merged))
+
+	return changes, nil
+}
+
+func (s *Store) ReplicatedMetrics(ctx context.Context) ([]domain.ReplicatedMetric, error) {
+	replica, ok := s.storage.(domain.MetricReplica)
+	if !ok {
+		return nil, domain.ErrNotReplicated
+	}
+	return replica.ReplicatedMetrics(ctx)
+}
+
+func (s *Store) Samples(_ context.Context, metricType domain.MetricType, metricName string, since time.Time) []domain.Sample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	samples := s.series[seriesKey{mType: metricType, id: metricName}]
+	return slices.Clone(samples[searchSamples(samples, since):])
+}
+
+func (s *Store) Retention() time.Duration {
+	return s.retention
+}
+
+// Run periodically rolls up the ended intervals and drops expired samples until shutdownCh is closed.
+func (s *Store) Run(shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			s.Rollup()
+			s.Prune()
+		}
+	}
+}
+
+// Prune drops the samples older than the retention and forgets series without samples left.
+func (s *Store) Prune() {
+	cutoff := s.now().Add(-s.retention)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, samples := range s.series {
+		if samples = trim(samples, cutoff); len(samples) == 0 {
+			delete(s.series, key)
+			continue
+		}
+		s.series[key] = samples
+	}
+}
+
+// record appends the current value of the updated metrics. Counters are read back, as updates only carry the delta.
+func (s *Store) record(ctx context.Context, metrics []domain.Metric) {
+	values := make(map[seriesKey]float64, len(metrics))
+	for _, metric := range metrics {
+		key := seriesKey{mType: metric.MType, id: metric.ID}
+		switch {
+		case metric.MType == domain.MetricTypeGauge && metric.Value != nil:
+			values[key] = *metric.Value
+		case metric.MType == domain.MetricTypeCounter:
+			if _, ok := values[key]; ok {
+				continue
+			}
+			// A counter whose total can not be read back misses this sample, the update itself succeeded
+			stored, found, err := s.storage.GetMetric(ctx, metric.MType, metric.ID)
+			if err == nil && found && stored.Delta != nil {
+				values[key] = float64(*stored.Delta)
+			}
+		}
+	}
+
+	s.append(values)
+}
+
+// values returns the value of every stored metric.
+func values(metrics []domain.Metric) map[seriesKey]float64 {
+	values := make(map[seriesKey]float64, len(metrics))
+	for _, metric := range metrics {
+		key := seriesKey{mType: metric.MType, id: metric.ID}
+		switch {
+		case metric.Value != nil:
+			values[key] = *metric.Value
+		case metric.Delta != nil:
+			values[key] = float64(*metric.Delta)
+		}
+	}
+	return values
+}
+
+// append adds a sample of the current time to every series. The time is taken under the lock and never before
+// the last sample of the series, so the samples stay ordered. A sample late for an interval that was rolled up
+// already is counted in the first interval still open.
+func (s *Store) append(values map[seriesKey]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	cutoff := now.Add(-s.retention)
+
+	if len(s.tiers) > 0 {
+		now = maxTime(now, s.tiers[0].mark)
+	}
+	for key, value := range values {
+		at := now
+		samples := trim(s.series[key], cutoff)
+		if len(samples) > 0 {
+			at = maxTime(at, samples[len(samples)-1].Time)
+		}
+		s.series[key] = append(samples, domain.Sample{Time: at, Value: value})
+	}
+}
+
+// drop forgets the samples and rollups of the deleted series matching and deletes their stored rollups with
+// remove. The rollups being stored are waited for, and those not stored yet are dropped, so none come back.
+func (s *Store) drop(matches func(key seriesKey) bool, remove func(rollups domain.RollupStorage) error) error {
+	s.saves.Lock()
+	defer s.saves.Unlock()
+
+	s.mu.Lock()
+	for key := range s.series {
+		if matches(key) {
+			delete(s.series, key)
+		}
+	}
+	for _, t := range s.tiers {
+		for key := range t.series {
+			if matches(key) {
+				delete(t.series, key)
+			}
+		}
+	}
+	s.unsaved = slices.DeleteFunc(s.unsaved, func(series domain.RollupSeries) bool {
+		return matches(seriesKey{mType: series.MType, id: series.ID})
+	})
+	s.mu.Unlock()
+
+	if s.rollups == nil {
+		return nil
+	}
+	if err := remove(s.rollups); err != nil {
+		return fmt.Errorf("failed to delete the stored rollups: %w", err)
+	}
+	return nil
+}
+
+// trim drops the samples before the cutoff, reusing the slice.
+func trim(samples []domain.Sample, cutoff time.Time) []domain.Sample {
+	i := searchSamples(samples, cutoff)
+	if i == 0 {
+		return samples
+	}
+	return append(samples[:0], samples[i:]...)
+}
@@ -0,0 +1,155 @@
+package metrichistory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func newTestStore() (*Store, *time.Time) {
+	store := New(metricstorage.NewMemoryMetricStorage(), 10*time.Minute)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestStore_Samples(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore()
+	start := *now
+
+	require.NoError(t, store.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), counter("PollCount", 5)}))
+	*now = now.Add(time.Minute)
+	require.NoError(t, store.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), counter("PollCount", 2)}))
+	*now = now.Add(time.Minute)
+	require.NoError(t, store.UpdateMetric(ctx, gauge("Alloc", 3)))
+
+	assert.Equal(t, []domain.Sample{
+		{Time: start, Value: 5},
+		{Time: start.Add(time.Minute), Value: 12},
+	}, store.Samples(ctx, domain.MetricTypeCounter, "PollCount", start), "counters record the accumulated total")
+
+	assert.Equal(t, []domain.Sample{
+		{Time: start.Add(2 * time.Minute), Value: 3},
+	}, store.Samples(ctx, domain.MetricTypeGauge, "Alloc", start.Add(time.Second)))
+
+	assert.Empty(t, store.Samples(ctx, domain.MetricTypeCounter, "Alloc", start), "series are kept per metric type")
+	assert.Equal(t, 10*time.Minute, store.Retention())
+}
+
+func TestStore_Retention(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore()
+	start := *now
+
+	require.NoError(t, store.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), gauge("Sys", 1)}))
+	*now = now.Add(8 * time.Minute)
+	require.NoError(t, store.UpdateMetric(ctx, gauge("Alloc", 2)))
+	*now = now.Add(4 * time.Minute)
+	require.NoError(t, store.UpdateMetric(ctx, gauge("Alloc", 3)))
+
+	samples := store.Samples(ctx, domain.MetricTypeGauge, "Alloc", start)
+	require.Len(t, samples, 2, "updates drop the expired samples of their series")
+	assert.Equal(t, 2.0, samples[0].Value)
+
+	assert.Len(t, store.Samples(ctx, domain.MetricTypeGauge, "Sys", start), 1)
+	store.Prune()
+	assert.Empty(t, store.Samples(ctx, domain.MetricTypeGauge, "Sys", start))
+	assert.Len(t, store.Samples(ctx, domain.MetricTypeGauge, "Alloc", start), 2)
+}
+
+func TestStore_Delete(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore()
+	start := *now
+
+	require.NoError(t, store.UpdateMetrics(ctx, []domain.Metric{gauge("CPUutilization1", 1), gauge("CPUutilization2", 2), gauge("Alloc", 3)}))
+
+	found, err := store.DeleteMetric(ctx, domain.MetricTypeGauge, "Alloc")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, store.Samples(ctx, domain.MetricTypeGauge, "Alloc", start))
+
+	deleted, err := store.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Empty(t, store.Samples(ctx, domain.MetricTypeGauge, "CPUutilization1", start))
+	assert.Empty(t, domaintest.AllMetrics(t, store))
+}
+
+// readCountingStorage counts the metrics read back from the storage.
+type readCountingStorage struct {
+	*metricstorage.MemoryMetricStorage
+	reads int
+}
+
+func (s *readCountingStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	s.reads++
+	return s.MemoryMetricStorage.GetMetric(ctx, metricType, metricName)
+}
+
+// plainStorage hides the optional capabilities of the storage.
+type plainStorage struct {
+	domain.MetricStorage
+}
+
+func TestStore_StoredState(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		wrap  func(domain.MetricStorage) domain.MetricStorage
+		reads int
+	}{
+		{name: "returned by the storage", wrap: func(s domain.MetricStorage) domain.MetricStorage { return s }, reads: 0},
+		{name: "read back", wrap: func(s domain.MetricStorage) domain.MetricStorage { return plainStorage{s} }, reads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &readCountingStorage{MemoryMetricStorage: metricstorage.NewMemoryMetricStorage()}
+			store := New(tt.wrap(storage), 10*time.Minute)
+			store.now = func() time.Time { return start }
+
+			require.NoError(t, store.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), counter("PollCount", 2), gauge("Alloc", 1)}))
+			require.NoError(t, store.UpdateMetric(ctx, counter("PollCount", 3)))
+
+			assert.Equal(t, tt.reads, storage.reads)
+			assert.Equal(t, []domain.Sample{
+				{Time: start, Value: 7},
+				{Time: start, Value: 10},
+			}, store.Samples(ctx, domain.MetricTypeCounter, "PollCount", start))
+		})
+	}
+}
+
+func TestStore_SamplesStayOrdered(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore()
+	start := *now
+
+	require.NoError(t, store.UpdateMetric(ctx, gauge("Alloc", 1)))
+	*now = now.Add(-time.Second)
+	require.NoError(t, store.UpdateMetric(ctx, gauge("Alloc", 2)))
+
+	assert.Equal(t, []domain.Sample{
+		{Time: start, Value: 1},
+		{Time: start, Value: 2},
+	}, store.Samples(ctx, domain.MetricTypeGauge, "Alloc", start), "a sample is never older than the one before")
+}
@@ -0,0 +1,89 @@
+package metricreaper
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Reaper periodically deletes metrics that have not been updated within the TTL. The storage must implement
+// domain.StaleMetricDeleter, so a metric updated after the snapshot of all metrics was taken is not deleted.
+type Reaper struct {
+	storage domain.MetricStorage
+	ttl     time.Duration
+	logger  *zerolog.Logger
+	now     func() time.Time
+}
+
+func New(storage domain.MetricStorage, ttl time.Duration, logger *zerolog.Logger) *Reaper {
+	return &Reaper{
+		storage: storage,
+		ttl:     ttl,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Run periodically expires stale metrics until shutdownCh is closed.
+func (r *Reaper) Run(shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(r.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			r.Expire(context.Background())
+		}
+	}
+}
+
+// Expire deletes all metrics not updated within the TTL and returns the number of deleted metrics.
+func (r *Reaper) Expire(ctx context.Context) int {
+	now := r.now()
+
+	metrics, err := r.storage.GetAllMetrics(ctx)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("failed to read metrics to expire")
+		return 0
+	}
+
+	deleter, ok := r.storage.(domain.StaleMetricDeleter)
+	if !ok {
+		r.logger.Error().Err(domain.ErrNotStaleDeleter).Msg("failed to expire metrics")
+		return 0
+	}
+
+	deleted := 0
+	for _, metric := range metrics {
+		if !metric.IsStale(now, r.ttl) {
+			continue
+		}
+
+		// The metric may have been updated since the snapshot was taken, the storage checks it again
+		found, err := deleter.DeleteMetricIfStale(ctx, metric.MType, metric.ID, now.Add(-r.ttl))
+		if err != nil {
+			r.logger.Error().Err(err).Str("metric_id", metric.ID).Msg("failed to expire metric")
+			continue
+		}
+
+		if found {
+			deleted++
+		}
+	}
+
+	if deleted > 0 {
+		r.logger.Info().Int("count", deleted).Dur("ttl", r.ttl).Msg("expired stale metrics")
+	}
+
+	return deleted
+}
+
+// checkInterval makes a stale metric live at most 10% longer than the TTL, but never checks more than once a second.
+func (r *Reaper) checkInterval() time.Duration {
+	return max(r.ttl/10, time.Second)
+}
@@ -0,0 +1,120 @@
+package metricreaper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64, updatedAt time.Time) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: updatedAt}
+}
+
+func TestReaper_Expire(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	reaper := New(memory, time.Minute, &logger)
+	reaper.now = func() time.Time { return now }
+
+	require.NoError(t, memory.UpdateMetrics(ctx, []domain.Metric{
+		gauge("CPUutilization1", 1, now),
+		gauge("CPUutilization2", 2, now),
+	}))
+
+	now = now.Add(45 * time.Second)
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("CPUutilization2", 3, now)))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 1, reaper.Expire(ctx))
+
+	_, found := domaintest.GetMetric(t, memory, domain.MetricTypeGauge, "CPUutilization1")
+	assert.False(t, found)
+	_, found = domaintest.GetMetric(t, memory, domain.MetricTypeGauge, "CPUutilization2")
+	assert.True(t, found)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, reaper.Expire(ctx))
+	assert.Empty(t, domaintest.AllMetrics(t, memory))
+	assert.Zero(t, reaper.Expire(ctx))
+}
+
+func TestReaper_Run(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 1, time.Now().Add(-time.Hour))))
+
+	reaper := New(memory, time.Second, &logger)
+	shutdownCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		reaper.Run(shutdownCh)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		metrics, err := memory.GetAllMetrics(ctx)
+		return err == nil && len(metrics) == 0
+	}, 3*time.Second, 50*time.Millisecond)
+
+	close(shutdownCh)
+	<-done
+}
+
+// staleSnapshotStorage returns the metrics read before they were updated again.
+type staleSnapshotStorage struct {
+	*metricstorage.MemoryMetricStorage
+	snapshot []domain.Metric
+}
+
+func (s *staleSnapshotStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	return s.snapshot, nil
+}
+
+func TestReaper_ExpireUpdatedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 1, now)))
+	storage := &staleSnapshotStorage{MemoryMetricStorage: memory, snapshot: domaintest.AllMetrics(t, memory)}
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 2, now)))
+
+	reaper := New(storage, time.Minute, &logger)
+	reaper.now = func() time.Time { return now }
+	assert.Zero(t, reaper.Expire(ctx))
+
+	metric, found := domaintest.GetMetric(t, memory, domain.MetricTypeGauge, "Alloc")
+	require.True(t, found)
+	assert.Equal(t, 2.0, *metric.Value)
+}
+
+// plainStorage hides the optional capabilities of the wrapped storage.
+type plainStorage struct {
+	domain.MetricStorage
+}
+
+func TestReaper_ExpireUnsupported(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	memory := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, memory.UpdateMetric(ctx, gauge("Alloc", 1, time.Now().Add(-time.Hour))))
+
+	reaper := New(plainStorage{memory}, time.Minute, &logger)
+	assert.Zero(t, reaper.Expire(ctx))
+	assert.Len(t, domaintest.AllMetrics(t, memory), 1)
+}
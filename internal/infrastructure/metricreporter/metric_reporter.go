@@ -2,13 +2,16 @@ package metricreporter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/resilience"
 )
 
 type HTTPMetricReporter struct {
@@ -17,7 +20,10 @@ type HTTPMetricReporter struct {
 	logger  *slog.Logger
 }
 
-var _ domain.MetricReporter = (*HTTPMetricReporter)(nil)
+var (
+	_ domain.MetricReporter    = (*HTTPMetricReporter)(nil)
+	_ domain.MetricBatchSender = (*HTTPMetricReporter)(nil)
+)
 
 func NewHTTPMetricReporter(baseURL string, client *http.Client) *HTTPMetricReporter {
 	return &HTTPMetricReporter{
@@ -69,31 +75,35 @@ func (mr *HTTPMetricReporter) ReportMetric(metrics domain.Metric) {
 func (mr *HTTPMetricReporter) ReportBatch(metrics []domain.Metric) {
 	mr.logger.Info("report metric request", "metrics", metrics)
 
+	if err := mr.SendBatch(context.Background(), metrics); err != nil {
+		mr.logger.Error("failed to report metrics", "metrics", metrics, "error", err)
+		return
+	}
+	mr.logger.Info("metrics reported", "count", len(metrics))
+}
+
+// SendBatch posts the metrics to the batch endpoint, failing unless the server answers with 200 OK.
+func (mr *HTTPMetricReporter) SendBatch(ctx context.Context, metrics []domain.Metric) error {
 	bodyBytes, err := json.Marshal(metrics)
 	if err != nil {
-		mr.logger.Error("failed to convert metrics to json", "metrics", metrics)
-		return
+		return fmt.Errorf("failed to convert metrics to json: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, mr.baseURL+"/updates/", bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, mr.baseURL+"/updates/", bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		mr.logger.Error("failed to build post request", "json", bodyBytes, "error", err)
-		return
+		return fmt.Errorf("failed to build post request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := mr.client.Do(req)
 	if err != nil {
-		mr.logger.Error("failed to report metrics", "metrics", metrics, "error", err)
-		return
+		return err
 	}
-	_ = resp.Body.Close()
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
 
-	var responseBody []byte
-	responseBody, _ = io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		mr.logger.Error("received error", "status", resp.Status, "body", string(responseBody))
-		return
+		return resilience.NewStatusError(resp)
 	}
-	mr.logger.Info("received response", "status", resp.Status, "body", string(responseBody))
+	return nil
 }
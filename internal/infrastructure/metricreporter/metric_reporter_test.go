@@ -1,21 +1,25 @@
 package metricreporter
 
 import (
+	"cmp"
+	"context"
 	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
 )
 
 type MockRoundTripper struct {
 	lastRequest *http.Request
+	statusCode  int
 }
 
 func (m *MockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	m.lastRequest = req
-	return &http.Response{StatusCode: http.StatusOK}, nil
+	return &http.Response{StatusCode: cmp.Or(m.statusCode, http.StatusOK), Body: http.NoBody}, nil
 }
 
 func Test_HTTPMetricReporter_ReportRawMetric(t *testing.T) {
@@ -82,3 +86,20 @@ func Test_HTTPMetricReporter_ReportBatch(t *testing.T) {
 	assert.Equal(t, "POST", transport.lastRequest.Method)
 	assert.Equal(t, "application/json", transport.lastRequest.Header.Get("Content-Type"))
 }
+
+func Test_HTTPMetricReporter_SendBatch(t *testing.T) {
+	// Arrange
+	transport := &MockRoundTripper{}
+	mockClient := &http.Client{Transport: transport}
+	reporter := NewHTTPMetricReporter("http://example.com", mockClient)
+
+	counterValue := int64(10)
+	metrics := []domain.Metric{{ID: "test_counter", MType: domain.MetricTypeCounter, Delta: &counterValue}}
+
+	// Act & Assert
+	require.NoError(t, reporter.SendBatch(context.Background(), metrics))
+	assert.Equal(t, "http://example.com/updates/", transport.lastRequest.URL.String())
+
+	transport.statusCode = http.StatusServiceUnavailable
+	assert.Error(t, reporter.SendBatch(context.Background(), metrics), "a batch the server did not accept is reported")
+}
@@ -2,8 +2,10 @@ package metricstorage
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -11,15 +13,34 @@ import (
 	"github.com/angryscorp/alert-metrics/internal/domain"
 )
 
+// walSyncInterval is how often the WAL is flushed with the interval fsync policy.
+const walSyncInterval = time.Second
+
+// walCompactSize is the WAL size that triggers a compaction when snapshots are not taken periodically.
+const walCompactSize = 16 << 20
+
+// FileMetricStorage persists the metrics of the decorated storage in a checksummed snapshot and a write-ahead log
+// next to it. Every change is appended to the log before it is applied, and the log is compacted into
+// a new snapshot every write interval, or once it grows large when the interval is 0. The previous snapshots
+// are kept, so a damaged snapshot can be restored from an older one.
 type FileMetricStorage struct {
 	storage            domain.MetricStorage
 	logger             zerolog.Logger
 	writeInterval      time.Duration
 	fileStoragePath    string
+	walPath            string
+	fsync              FsyncPolicy
+	keepSnapshots      int
 	fileStorageContext context.Context
+	mu                 sync.Mutex
+	wal                *wal
 }
 
-var _ domain.MetricStorage = (*FileMetricStorage)(nil)
+var (
+	_ domain.MetricStorage      = (*FileMetricStorage)(nil)
+	_ domain.StoredStateUpdater = (*FileMetricStorage)(nil)
+	_ domain.StaleMetricDeleter = (*FileMetricStorage)(nil)
+)
 
 func NewFileMetricStorage(
 	storage domain.MetricStorage,
@@ -27,114 +48,362 @@ func NewFileMetricStorage(
 	writeInterval time.Duration,
 	fileStoragePath string,
 	shouldRestore bool,
-) *FileMetricStorage {
+	fsync FsyncPolicy,
+	keepSnapshots int,
+) (*FileMetricStorage, error) {
+	if err := fsync.Validate(); err != nil {
+		return nil, err
+	}
+	if keepSnapshots < 1 {
+		return nil, fmt.Errorf("at least one snapshot has to be kept, got %d", keepSnapshots)
+	}
+
 	m := &FileMetricStorage{
 		storage:            storage,
 		logger:             logger,
 		writeInterval:      writeInterval,
 		fileStoragePath:    fileStoragePath,
+		walPath:            fileStoragePath + ".wal",
+		fsync:              fsync,
+		keepSnapshots:      keepSnapshots,
 		fileStorageContext: context.TODO(),
 	}
 
-	if shouldRestore {
-		m.RestoreFromFile()
+	w, err := openWAL(m.walPath)
+	if err != nil {
+		return nil, err
+	}
+	m.wal = w
+
+	// Without restoring, the files are left as they are until the first compaction replaces them with the new
+	// state. Updates logged before that are replayed on top of the old state by a restore.
+	if !shouldRestore {
+		return m, nil
 	}
 
-	go m.saveCurrentMetrics()
+	if err := m.RestoreFromFile(); err != nil {
+		return nil, err
+	}
+
+	// Starts from a snapshot of the restored state and an empty log
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.compact(); err != nil {
+		return nil, err
+	}
 
-	return m
+	return m, nil
 }
 
-func (s FileMetricStorage) RestoreFromFile() {
-	data, err := os.ReadFile(s.fileStoragePath)
+// RestoreFromFile loads the newest valid snapshot and replays the WAL on top of it. Having no snapshot is not
+// an error, having only damaged ones is. A broken log is replayed up to the broken record.
+func (s *FileMetricStorage) RestoreFromFile() error {
+	restored, records, err := readFiles(s.fileStoragePath, s.walPath, s.keepSnapshots, s.logger)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to read metrics file")
-		return
+		return err
+	}
+
+	if err := s.storage.UpdateMetrics(s.fileStorageContext, restored); err != nil {
+		return fmt.Errorf("failed to restore metrics: %w", err)
+	}
+
+	s.logger.Info().Int("metrics", len(restored)).Int("wal_records", records).Msg("metrics restored")
+	return nil
+}
+
+// ReadFileMetricStorage returns the metrics of a file storage in a memory storage, restored like RestoreFromFile
+// does, without compacting or otherwise changing the files. It fails if there is neither a snapshot nor a WAL.
+func ReadFileMetricStorage(fileStoragePath string, keepSnapshots int, logger zerolog.Logger) (*MemoryMetricStorage, error) {
+	walPath := fileStoragePath + ".wal"
+	exists := false
+	for _, path := range append(snapshotPaths(fileStoragePath, keepSnapshots), walPath) {
+		if _, err := os.Stat(path); err == nil {
+			exists = true
+			break
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+	}
+	if !exists {
+		return nil, fmt.Errorf("no file storage at %s: %w", fileStoragePath, os.ErrNotExist)
 	}
 
-	var metrics []domain.Metric
-	err = json.Unmarshal(data, &metrics)
+	metrics, _, err := readFiles(fileStoragePath, walPath, keepSnapshots, logger)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to unmarshal metrics file")
-		return
+		return nil, err
+	}
+
+	storage := NewMemoryMetricStorage()
+	if err := storage.UpdateMetrics(context.Background(), metrics); err != nil {
+		return nil, fmt.Errorf("failed to load metrics: %w", err)
+	}
+	return storage, nil
+}
+
+// readFiles returns the metrics of the newest valid snapshot with the WAL replayed on top of it, and the number
+// of replayed records.
+func readFiles(fileStoragePath, walPath string, keepSnapshots int, logger zerolog.Logger) ([]domain.Metric, int, error) {
+	snapshot, err := readSnapshot(fileStoragePath, keepSnapshots, logger)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	metrics := make(map[metricKey]domain.Metric, len(snapshot))
+	for _, metric := range snapshot {
+		metrics[metricKey{mType: metric.MType, id: metric.ID}] = metric
+	}
+
+	records, err := readWAL(walPath)
+	if err != nil {
+		logger.Error().Err(err).Int("replayed", len(records)).Msg("WAL is damaged, later updates are lost")
+	}
+	replay(metrics, records)
+
+	restored := make([]domain.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		restored = append(restored, metric)
+	}
+	return restored, len(records), nil
+}
+
+// readSnapshot returns the metrics of the newest snapshot that is intact.
+func readSnapshot(fileStoragePath string, keepSnapshots int, logger zerolog.Logger) ([]domain.Metric, error) {
+	var errs []error
+	for i, path := range snapshotPaths(fileStoragePath, keepSnapshots) {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var metrics []domain.Metric
+			if metrics, err = decodeSnapshot(data); err == nil {
+				if i > 0 {
+					// The WAL only holds the changes since the newest snapshot, the ones in between are lost
+					logger.Warn().Str("snapshot", path).Errs("skipped", errs).Msg("restoring from an older snapshot")
+				}
+				return metrics, nil
+			}
+		}
+
+		logger.Error().Err(err).Str("snapshot", path).Msg("skipping damaged snapshot")
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("no valid snapshot to restore: %w", errors.Join(errs...))
+	}
+	return nil, nil
+}
+
+// Run compacts the WAL every write interval and flushes it with the interval fsync policy until shutdownCh is
+// closed, then takes a final snapshot.
+func (s *FileMetricStorage) Run(shutdownCh <-chan struct{}) {
+	var compactCh, syncCh <-chan time.Time
+	if s.writeInterval > 0 {
+		ticker := time.NewTicker(s.writeInterval)
+		defer ticker.Stop()
+		compactCh = ticker.C
+	}
+	if s.fsync == FsyncInterval {
+		ticker := time.NewTicker(walSyncInterval)
+		defer ticker.Stop()
+		syncCh = ticker.C
 	}
 
-	for _, v := range metrics {
-		if err := s.storage.UpdateMetric(s.fileStorageContext, v); err != nil {
-			s.logger.Error().Err(err).Msg("failed to update metric")
+	for {
+		select {
+		case <-shutdownCh:
+			s.Compact()
 			return
+		case <-compactCh:
+			s.Compact()
+		case <-syncCh:
+			s.mu.Lock()
+			if err := s.wal.sync(); err != nil {
+				s.logger.Error().Err(err).Msg("failed to flush WAL")
+			}
+			s.mu.Unlock()
 		}
 	}
 }
 
-func (s FileMetricStorage) GetAllMetrics(ctx context.Context) []domain.Metric {
+// Compact writes a snapshot of all metrics and empties the WAL.
+func (s *FileMetricStorage) Compact() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.compact(); err != nil {
+		s.logger.Error().Err(err).Msg("failed to compact WAL")
+	}
+}
+
+func (s *FileMetricStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
 	return s.storage.GetAllMetrics(ctx)
 }
 
-func (s FileMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
+func (s *FileMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
 	return s.storage.GetMetric(ctx, metricType, metricName)
 }
 
-func (s FileMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
-	err := s.storage.UpdateMetric(ctx, metric)
+func (s *FileMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	return s.UpdateMetrics(ctx, []domain.Metric{metric})
+}
+
+// UpdateMetrics logs the batch before applying it, so an acknowledged update is always in the WAL. The stored state
+// is logged rather than the update, counters only carry the increment.
+func (s *FileMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	_, err := s.UpdateMetricsStored(ctx, metrics)
+	return err
+}
+
+// UpdateMetricsStored applies the batch like UpdateMetrics and returns the logged stored states.
+func (s *FileMetricStorage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	for _, metric := range metrics {
+		if err := validateUpdate(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The receive time is set here, so the logged state and the applied one agree
+	now := time.Now()
+	updates := make([]domain.Metric, len(metrics))
+	stored := make(map[metricKey]int, len(metrics))
+	var states []domain.Metric
+	for i, metric := range metrics {
+		if metric.UpdatedAt.IsZero() {
+			metric.UpdatedAt = now
+		}
+		updates[i] = metric
+
+		key := metricKey{mType: metric.MType, id: metric.ID}
+		index, seen := stored[key]
+		if !seen {
+			index = len(states)
+			stored[key] = index
+			states = append(states, domain.Metric{})
+		}
+
+		state := metric
+		if metric.MType == domain.MetricTypeCounter {
+			total := *metric.Delta
+			if seen {
+				total += *states[index].Delta
+			} else {
+				current, found, err := s.storage.GetMetric(ctx, metric.MType, metric.ID)
+				if err != nil {
+					return nil, err
+				}
+				if found {
+					total += *current.Delta
+				}
+			}
+			state.Delta = &total
+		}
+		states[index] = state
+	}
+
+	if err := s.log(walRecord{Op: walUpdate, Metrics: states}); err != nil {
+		return nil, err
+	}
+
+	err := s.storage.UpdateMetrics(ctx, updates)
+	s.compactIfLarge()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return states, nil
+}
 
-	if s.writeInterval == 0 {
-		s.saveCurrentMetrics()
+func (s *FileMetricStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, found, err := s.storage.GetMetric(ctx, metricType, metricName); err != nil || !found {
+		return false, err
+	}
+	if err := s.log(walRecord{Op: walDelete, MType: metricType, ID: metricName}); err != nil {
+		return false, err
 	}
 
-	return nil
+	found, err := s.storage.DeleteMetric(ctx, metricType, metricName)
+	s.compactIfLarge()
+	return found, err
 }
 
-func (s FileMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
-	err := s.storage.UpdateMetrics(ctx, metrics)
-	if err != nil {
-		return err
+// DeleteMetricIfStale checks the update time under the same lock as the updates, so none is missed.
+func (s *FileMetricStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metric, found, err := s.storage.GetMetric(ctx, metricType, metricName)
+	if err != nil || !found || !isStale(metric.UpdatedAt, before) {
+		return false, err
 	}
+	if err := s.log(walRecord{Op: walDelete, MType: metricType, ID: metricName}); err != nil {
+		return false, err
+	}
+
+	found, err = s.storage.DeleteMetric(ctx, metricType, metricName)
+	s.compactIfLarge()
+	return found, err
+}
 
-	if s.writeInterval == 0 {
-		s.saveCurrentMetrics()
+// DeleteByPrefix logs the deletion even if nothing matches, replaying it deletes nothing either.
+func (s *FileMetricStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.log(walRecord{Op: walDeletePrefix, Prefix: prefix}); err != nil {
+		return 0, err
 	}
 
-	return nil
+	deleted, err := s.storage.DeleteByPrefix(ctx, prefix)
+	s.compactIfLarge()
+	return deleted, err
 }
 
-func (s FileMetricStorage) Ping(ctx context.Context) error {
+func (s *FileMetricStorage) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (s FileMetricStorage) saveCurrentMetrics() {
-	allMetrics := s.storage.GetAllMetrics(s.fileStorageContext)
-	s.writeToFile(allMetrics)
+// log appends the record to the WAL. It must be called with mu held.
+func (s *FileMetricStorage) log(record walRecord) error {
+	if err := s.wal.append(record, s.fsync == FsyncAlways); err != nil {
+		s.logger.Error().Err(err).Msg("failed to write WAL")
+		return err
+	}
+	return nil
+}
 
-	if s.writeInterval > 0 {
-		time.Sleep(s.writeInterval)
-		go s.saveCurrentMetrics()
+// compactIfLarge compacts the WAL once it outgrew the size limit when snapshots are not taken periodically. It must
+// be called with mu held, after the logged change was applied.
+func (s *FileMetricStorage) compactIfLarge() {
+	if s.writeInterval == 0 && s.wal.size >= walCompactSize {
+		if err := s.compact(); err != nil {
+			s.logger.Error().Err(err).Msg("failed to compact WAL")
+		}
 	}
 }
 
-func (s FileMetricStorage) writeToFile(metrics []domain.Metric) {
-	data, err := json.Marshal(metrics)
+// compact replaces the snapshot atomically and empties the WAL. A crash in between leaves records that are
+// already in the snapshot, which replay again to the same state. It must be called with mu held.
+func (s *FileMetricStorage) compact() error {
+	metrics, err := s.storage.GetAllMetrics(s.fileStorageContext)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to marshal metrics")
-		return
+		return err
 	}
 
-	file, err := os.Create(s.fileStoragePath)
+	data, err := encodeSnapshot(metrics)
 	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to create metrics file")
-		return
+		return err
 	}
 
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	_, err = file.Write(data)
-	if err != nil {
-		s.logger.Error().Err(err).Msg("failed to write metrics to file")
-		return
+	if err := writeSnapshot(s.fileStoragePath, data, s.keepSnapshots); err != nil {
+		return err
 	}
+
+	return s.wal.reset()
 }
@@ -0,0 +1,173 @@
+package metricstorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func newTestFileStorage(t *testing.T, path string, interval time.Duration, fsync FsyncPolicy) *FileMetricStorage {
+	t.Helper()
+	storage, err := NewFileMetricStorage(NewMemoryMetricStorage(), zerolog.Nop(), interval, path, true, fsync, 3)
+	require.NoError(t, err)
+	return storage
+}
+
+func TestFileMetricStorage_ReplaysWAL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{gauge("Alloc", 1), counter("PollCount", 5), gauge("CPU1", 10), gauge("CPU2", 20)}))
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 3)))
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 2)))
+	_, err := storage.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+
+	// Nothing was compacted, the updates are only in the WAL
+	snapshot, err := os.ReadFile(path)
+	require.NoError(t, err)
+	metrics, err := decodeSnapshot(snapshot)
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+
+	restored := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	assert.ElementsMatch(t, []string{"Alloc", "PollCount"}, ids(domaintest.AllMetrics(t, restored)))
+
+	alloc, _ := domaintest.GetMetric(t, restored, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 2.0, *alloc.Value)
+	pollCount, _ := domaintest.GetMetric(t, restored, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(8), *pollCount.Delta, "counters are restored with their total")
+
+	info, err := os.Stat(path + ".wal")
+	require.NoError(t, err)
+	assert.Zero(t, info.Size(), "the restored state is compacted into the snapshot")
+}
+
+func TestFileMetricStorage_CompactionIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncNever)
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 5)))
+	wal, err := os.ReadFile(path + ".wal")
+	require.NoError(t, err)
+
+	storage.Compact()
+	// A crash between writing the snapshot and emptying the WAL leaves records already in the snapshot
+	require.NoError(t, os.WriteFile(path+".wal", wal, 0o644))
+
+	restored := newTestFileStorage(t, path, time.Hour, FsyncNever)
+	pollCount, _ := domaintest.GetMetric(t, restored, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(5), *pollCount.Delta)
+}
+
+func TestFileMetricStorage_TornWAL(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 1)))
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Sys", 2)))
+
+	wal, err := os.ReadFile(path + ".wal")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".wal", wal[:len(wal)-5], 0o644))
+
+	restored := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	assert.Equal(t, []string{"Alloc"}, ids(domaintest.AllMetrics(t, restored)), "the torn last record is dropped")
+}
+
+func TestFileMetricStorage_RestoreDisabled(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, 0, FsyncAlways)
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 1)))
+
+	fresh, err := NewFileMetricStorage(NewMemoryMetricStorage(), zerolog.Nop(), 0, path, false, FsyncAlways, 3)
+	require.NoError(t, err)
+	assert.Empty(t, domaintest.AllMetrics(t, fresh))
+
+	restored := newTestFileStorage(t, path, 0, FsyncAlways)
+	assert.Equal(t, []string{"Alloc"}, ids(domaintest.AllMetrics(t, restored)), "the old state is kept until the first compaction")
+
+	fresh.Compact()
+	restored = newTestFileStorage(t, path, 0, FsyncAlways)
+	assert.Empty(t, domaintest.AllMetrics(t, restored), "the compaction replaces the old state")
+}
+
+func TestFileMetricStorage_WriteAhead(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 5), counter("PollCount", 2)}))
+
+	// Once the WAL cannot be written, nothing is applied
+	require.NoError(t, storage.wal.file.Close())
+	assert.Error(t, storage.UpdateMetric(ctx, counter("PollCount", 3)))
+	_, err := storage.DeleteMetric(ctx, domain.MetricTypeCounter, "PollCount")
+	assert.Error(t, err)
+	pollCount, _ := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(7), *pollCount.Delta)
+
+	restored := newTestFileStorage(t, path, time.Hour, FsyncAlways)
+	restoredCount, _ := domaintest.GetMetric(t, restored, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(7), *restoredCount.Delta)
+	assert.True(t, pollCount.UpdatedAt.Equal(restoredCount.UpdatedAt), "the logged state is the applied one")
+}
+
+func TestFileMetricStorage_Run(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncInterval)
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 1)))
+
+	shutdownCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		storage.Run(shutdownCh)
+		close(done)
+	}()
+	close(shutdownCh)
+	<-done
+
+	snapshot, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(snapshot), "Alloc", "shutdown takes a final snapshot")
+}
+
+func TestNewFileMetricStorage_InvalidOptions(t *testing.T) {
+	_, err := NewFileMetricStorage(NewMemoryMetricStorage(), zerolog.Nop(), 0, filepath.Join(t.TempDir(), "metrics.json"), false, "sometimes", 3)
+	assert.Error(t, err)
+
+	_, err = NewFileMetricStorage(NewMemoryMetricStorage(), zerolog.Nop(), 0, filepath.Join(t.TempDir(), "metrics.json"), false, FsyncNever, 0)
+	assert.Error(t, err)
+}
+
+func ids(metrics []domain.Metric) []string {
+	result := make([]string, len(metrics))
+	for i, metric := range metrics {
+		result[i] = metric.ID
+	}
+	return result
+}
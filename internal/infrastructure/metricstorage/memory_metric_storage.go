@@ -3,106 +3,362 @@ package metricstorage
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
 )
 
-var _ domain.MetricStorage = (*MemoryMetricStorage)(nil)
+var (
+	_ domain.MetricStorage      = (*MemoryMetricStorage)(nil)
+	_ domain.StoredStateUpdater = (*MemoryMetricStorage)(nil)
+	_ domain.StaleMetricDeleter = (*MemoryMetricStorage)(nil)
+)
 
-type MemoryMetricStorage struct {
+// shardCount is the number of independently locked parts of the storage, a power of two.
+const shardCount = 64
+
+var errUnsupportedMetricType = errors.New("unsupported metric type")
+
+// gaugeRecord and counterRecord hold their value behind a pointer that is replaced, never written to, on update.
+// Readers hand the pointer out as is, so reading the metrics does not allocate a value per metric.
+type gaugeRecord struct {
+	value     *float64
+	updatedAt time.Time
+	timestamp time.Time
+}
+
+type counterRecord struct {
+	delta     *int64
+	updatedAt time.Time
+	timestamp time.Time
+}
+
+type shard struct {
 	mu       sync.RWMutex
-	gauges   map[string]float64
-	counters map[string]int64
+	gauges   map[string]gaugeRecord
+	counters map[string]counterRecord
+}
+
+// MemoryMetricStorage keeps the metrics in maps split into shards by the hash of the metric ID, each with its
+// own lock, so concurrent updates of different metrics rarely wait for each other.
+// The values of the returned metrics are shared with the storage and must not be modified.
+type MemoryMetricStorage struct {
+	shards [shardCount]shard
 }
 
 func NewMemoryMetricStorage() *MemoryMetricStorage {
-	return &MemoryMetricStorage{
-		gauges:   make(map[string]float64),
-		counters: make(map[string]int64),
+	m := &MemoryMetricStorage{}
+	for i := range m.shards {
+		m.shards[i].gauges = make(map[string]gaugeRecord)
+		m.shards[i].counters = make(map[string]counterRecord)
 	}
+	return m
 }
 
-func (m *MemoryMetricStorage) GetAllMetrics(ctx context.Context) []domain.Metric {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// shardIndex returns the shard of the metric ID, using the FNV-1a hash.
+func shardIndex(id string) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(id); i++ {
+		hash ^= uint32(id[i])
+		hash *= 16777619
+	}
+	return int(hash & (shardCount - 1))
+}
 
-	res := make([]domain.Metric, 0)
-	for key, value := range m.gauges {
-		res = append(res, domain.Metric{
-			ID:    key,
-			MType: domain.MetricTypeGauge,
-			Value: &value,
-		})
+// GetAllMetrics returns a consistent view of all metrics, allocating only the returned slice.
+func (m *MemoryMetricStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	for i := range m.shards {
+		m.shards[i].mu.RLock()
 	}
-	for key, value := range m.counters {
-		res = append(res, domain.Metric{
-			ID:    key,
-			MType: domain.MetricTypeCounter,
-			Delta: &value,
-		})
+
+	count := 0
+	for i := range m.shards {
+		count += len(m.shards[i].gauges) + len(m.shards[i].counters)
+	}
+
+	res := make([]domain.Metric, 0, count)
+	for i := range m.shards {
+		s := &m.shards[i]
+		for key, record := range s.gauges {
+			res = append(res, record.metric(key))
+		}
+		for key, record := range s.counters {
+			res = append(res, record.metric(key))
+		}
+		s.mu.RUnlock()
 	}
 
-	return res
+	return res, nil
 }
 
-func (m *MemoryMetricStorage) UpdateMetric(ctx context.Context, metrics domain.Metric) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *MemoryMetricStorage) UpdateMetric(ctx context.Context, metric domain.Metric) error {
+	if err := validateUpdate(metric); err != nil {
+		return err
+	}
 
-	switch metrics.MType {
-	case domain.MetricTypeCounter:
-		m.counters[metrics.ID] += *metrics.Delta
+	s := &m.shards[shardIndex(metric.ID)]
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	case domain.MetricTypeGauge:
-		m.gauges[metrics.ID] = *metrics.Value
+	s.update(metric, time.Now())
+	return nil
+}
 
-	default:
-		return errors.New("unsupported metric type")
+// UpdateMetrics validates the whole batch first, so an invalid metric leaves the storage unchanged, then applies
+// it taking the lock of every affected shard once. The updates of a metric are applied in the batch order.
+func (m *MemoryMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	if len(metrics) == 1 {
+		return m.UpdateMetric(ctx, metrics[0])
 	}
 
-	return nil
+	return m.update(metrics, nil)
 }
 
-func (m *MemoryMetricStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
-	for _, v := range metrics {
-		if err := m.UpdateMetric(ctx, v); err != nil {
+// UpdateMetricsStored applies the batch like UpdateMetrics and returns the stored state of every updated metric,
+// once per metric.
+func (m *MemoryMetricStorage) UpdateMetricsStored(ctx context.Context, metrics []domain.Metric) ([]domain.Metric, error) {
+	states := make([]domain.Metric, len(metrics))
+	err := m.update(metrics, func(i int, state domain.Metric) {
+		states[i] = state
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keeps the last state of a metric updated more than once
+	indexes := make(map[metricKey]int, len(metrics))
+	stored := make([]domain.Metric, 0, len(metrics))
+	for _, state := range states {
+		key := metricKey{mType: state.MType, id: state.ID}
+		if index, ok := indexes[key]; ok {
+			stored[index] = state
+			continue
+		}
+		indexes[key] = len(stored)
+		stored = append(stored, state)
+	}
+
+	return stored, nil
+}
+
+// update validates and applies the batch, passing the stored state after each update of the batch to stored
+// unless it is nil.
+func (m *MemoryMetricStorage) update(metrics []domain.Metric, stored func(i int, state domain.Metric)) error {
+	for _, metric := range metrics {
+		if err := validateUpdate(metric); err != nil {
 			return err
 		}
 	}
 
+	// Orders the metrics by shard with a counting sort, which keeps the batch order within a shard
+	var starts [shardCount + 1]int
+	indexes := make([]uint8, len(metrics))
+	for i, metric := range metrics {
+		index := shardIndex(metric.ID)
+		indexes[i] = uint8(index)
+		starts[index+1]++
+	}
+	for i := 1; i <= shardCount; i++ {
+		starts[i] += starts[i-1]
+	}
+	next := starts
+	order := make([]int, len(metrics))
+	for i, index := range indexes {
+		order[next[index]] = i
+		next[index]++
+	}
+
+	now := time.Now()
+	for index := 0; index < shardCount; index++ {
+		if starts[index] == starts[index+1] {
+			continue
+		}
+
+		s := &m.shards[index]
+		s.mu.Lock()
+		for _, i := range order[starts[index]:starts[index+1]] {
+			state := s.update(metrics[i], now)
+			if stored != nil {
+				stored(i, state)
+			}
+		}
+		s.mu.Unlock()
+	}
+
 	return nil
 }
 
-func (m *MemoryMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+func (m *MemoryMetricStorage) GetMetric(ctx context.Context, metricType domain.MetricType, metricName string) (domain.Metric, bool, error) {
+	s := &m.shards[shardIndex(metricName)]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch metricType {
+	case domain.MetricTypeCounter:
+		if record, ok := s.counters[metricName]; ok {
+			return record.metric(metricName), true, nil
+		}
 
-	res := domain.Metric{
-		MType: metricType,
-		ID:    metricName,
+	case domain.MetricTypeGauge:
+		if record, ok := s.gauges[metricName]; ok {
+			return record.metric(metricName), true, nil
+		}
 	}
 
+	return domain.Metric{MType: metricType, ID: metricName}, false, nil
+}
+
+func (m *MemoryMetricStorage) DeleteMetric(ctx context.Context, metricType domain.MetricType, metricName string) (bool, error) {
+	s := &m.shards[shardIndex(metricName)]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	found := false
 	switch metricType {
 	case domain.MetricTypeCounter:
-		v, ok := m.counters[metricName]
-		if ok {
-			res.Delta = &v
-			found = true
+		_, found = s.counters[metricName]
+		delete(s.counters, metricName)
+
+	case domain.MetricTypeGauge:
+		_, found = s.gauges[metricName]
+		delete(s.gauges, metricName)
+
+	default:
+		return false, errUnsupportedMetricType
+	}
+
+	return found, nil
+}
+
+func (m *MemoryMetricStorage) DeleteMetricIfStale(ctx context.Context, metricType domain.MetricType, metricName string, before time.Time) (bool, error) {
+	s := &m.shards[shardIndex(metricName)]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch metricType {
+	case domain.MetricTypeCounter:
+		record, found := s.counters[metricName]
+		if !found || !isStale(record.updatedAt, before) {
+			return false, nil
 		}
+		delete(s.counters, metricName)
 
 	case domain.MetricTypeGauge:
-		v, ok := m.gauges[metricName]
-		if ok {
-			res.Value = &v
-			found = true
+		record, found := s.gauges[metricName]
+		if !found || !isStale(record.updatedAt, before) {
+			return false, nil
 		}
+		delete(s.gauges, metricName)
+
+	default:
+		return false, errUnsupportedMetricType
+	}
+
+	return true, nil
+}
+
+func (m *MemoryMetricStorage) DeleteByPrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	for i := range m.shards {
+		s := &m.shards[i]
+		s.mu.Lock()
+		for key := range s.gauges {
+			if strings.HasPrefix(key, prefix) {
+				delete(s.gauges, key)
+				deleted++
+			}
+		}
+		for key := range s.counters {
+			if strings.HasPrefix(key, prefix) {
+				delete(s.counters, key)
+				deleted++
+			}
+		}
+		s.mu.Unlock()
 	}
 
-	return res, found
+	return deleted, nil
 }
 
 func (m *MemoryMetricStorage) Ping(ctx context.Context) error {
 	return nil
 }
+
+// isStale reports whether a metric updated at updatedAt went stale before the given time, like domain.Metric.IsStale.
+func isStale(updatedAt, before time.Time) bool {
+	return !updatedAt.IsZero() && updatedAt.Before(before)
+}
+
+func validateUpdate(metric domain.Metric) error {
+	switch metric.MType {
+	case domain.MetricTypeCounter:
+		if metric.Delta == nil {
+			return errors.New("counter delta is required")
+		}
+	case domain.MetricTypeGauge:
+		if metric.Value == nil {
+			return errors.New("gauge value is required")
+		}
+	default:
+		return errUnsupportedMetricType
+	}
+	return nil
+}
+
+// update stores the validated metric, using now if the receive time is unknown, and returns the stored state.
+// It must be called with mu held.
+func (s *shard) update(metric domain.Metric, now time.Time) domain.Metric {
+	updatedAt := metric.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = now
+	}
+
+	switch metric.MType {
+	case domain.MetricTypeCounter:
+		delta := *metric.Delta
+		if current, ok := s.counters[metric.ID]; ok {
+			delta += *current.delta
+		}
+		record := counterRecord{
+			delta:     &delta,
+			updatedAt: updatedAt,
+			timestamp: metric.Timestamp,
+		}
+		s.counters[metric.ID] = record
+		return record.metric(metric.ID)
+
+	case domain.MetricTypeGauge:
+		value := *metric.Value
+		record := gaugeRecord{
+			value:     &value,
+			updatedAt: updatedAt,
+			timestamp: metric.Timestamp,
+		}
+		s.gauges[metric.ID] = record
+		return record.metric(metric.ID)
+	}
+
+	return domain.Metric{}
+}
+
+func (r gaugeRecord) metric(id string) domain.Metric {
+	return domain.Metric{
+		ID:        id,
+		MType:     domain.MetricTypeGauge,
+		Value:     r.value,
+		UpdatedAt: r.updatedAt,
+		Timestamp: r.timestamp,
+	}
+}
+
+func (r counterRecord) metric(id string) domain.Metric {
+	return domain.Metric{
+		ID:        id,
+		MType:     domain.MetricTypeCounter,
+		Delta:     r.delta,
+		UpdatedAt: r.updatedAt,
+		Timestamp: r.timestamp,
+	}
+}
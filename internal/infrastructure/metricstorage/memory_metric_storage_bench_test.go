@@ -0,0 +1,143 @@
+package metricstorage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// singleLockStorage is the previous design of MemoryMetricStorage, one lock for all metrics taken once per metric,
+// kept as the baseline of the benchmarks.
+type singleLockStorage struct {
+	mu       sync.RWMutex
+	gauges   map[string]gaugeRecord
+	counters map[string]counterRecord
+}
+
+func newSingleLockStorage() *singleLockStorage {
+	return &singleLockStorage{gauges: make(map[string]gaugeRecord), counters: make(map[string]counterRecord)}
+}
+
+func (m *singleLockStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	for _, metric := range metrics {
+		m.mu.Lock()
+		now := time.Now()
+		switch metric.MType {
+		case domain.MetricTypeCounter:
+			delta := *metric.Delta
+			if current, ok := m.counters[metric.ID]; ok {
+				delta += *current.delta
+			}
+			m.counters[metric.ID] = counterRecord{delta: &delta, updatedAt: now}
+		case domain.MetricTypeGauge:
+			value := *metric.Value
+			m.gauges[metric.ID] = gaugeRecord{value: &value, updatedAt: now}
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+func (m *singleLockStorage) GetAllMetrics(ctx context.Context) ([]domain.Metric, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res := make([]domain.Metric, 0)
+	for key, record := range m.gauges {
+		value := *record.value
+		res = append(res, domain.Metric{ID: key, MType: domain.MetricTypeGauge, Value: &value, UpdatedAt: record.updatedAt})
+	}
+	for key, record := range m.counters {
+		delta := *record.delta
+		res = append(res, domain.Metric{ID: key, MType: domain.MetricTypeCounter, Delta: &delta, UpdatedAt: record.updatedAt})
+	}
+	return res, nil
+}
+
+type benchStorage interface {
+	UpdateMetrics(ctx context.Context, metrics []domain.Metric) error
+	GetAllMetrics(ctx context.Context) ([]domain.Metric, error)
+}
+
+var benchStorages = []struct {
+	name string
+	new  func() benchStorage
+}{
+	{"single_lock", func() benchStorage { return newSingleLockStorage() }},
+	{"sharded", func() benchStorage { return NewMemoryMetricStorage() }},
+}
+
+// agentBatch returns a report of an agent like the ones of the runtime monitor: gauges and a poll counter.
+func agentBatch(agent int) []domain.Metric {
+	prefix := "agent" + strconv.Itoa(agent) + "."
+	batch := make([]domain.Metric, 0, 31)
+	for i := 0; i < 30; i++ {
+		batch = append(batch, gauge(prefix+"gauge"+strconv.Itoa(i), float64(i)))
+	}
+	return append(batch, counter(prefix+"PollCount", 1))
+}
+
+// BenchmarkMemoryMetricStorage_UpdateMetrics reports batches of many agents concurrently.
+func BenchmarkMemoryMetricStorage_UpdateMetrics(b *testing.B) {
+	ctx := context.Background()
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			storage := bs.new()
+			var agents atomic.Int64
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				batch := agentBatch(int(agents.Add(1)))
+				for pb.Next() {
+					_ = storage.UpdateMetrics(ctx, batch)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkMemoryMetricStorage_GetAllMetrics reads 100 agents worth of metrics.
+func BenchmarkMemoryMetricStorage_GetAllMetrics(b *testing.B) {
+	ctx := context.Background()
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			storage := bs.new()
+			for agent := 0; agent < 100; agent++ {
+				_ = storage.UpdateMetrics(ctx, agentBatch(agent))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = storage.GetAllMetrics(ctx)
+			}
+		})
+	}
+}
+
+// BenchmarkMemoryMetricStorage_Mixed reads all metrics while agents keep reporting, like the UI and the rule
+// engine do.
+func BenchmarkMemoryMetricStorage_Mixed(b *testing.B) {
+	ctx := context.Background()
+	for _, bs := range benchStorages {
+		b.Run(bs.name, func(b *testing.B) {
+			storage := bs.new()
+			var agents atomic.Int64
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				agent := int(agents.Add(1))
+				batch := agentBatch(agent)
+				for i := 0; pb.Next(); i++ {
+					if agent%8 == 0 && i%16 == 0 {
+						_, _ = storage.GetAllMetrics(ctx)
+						continue
+					}
+					_ = storage.UpdateMetrics(ctx, batch)
+				}
+			})
+		})
+	}
+}
@@ -2,12 +2,16 @@ package metricstorage
 
 import (
 	"context"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
 )
 
 func TestMemoryMetricStorage_UpdateMetric(t *testing.T) {
@@ -70,6 +74,7 @@ func TestMemoryMetricStorage_GetMetric(t *testing.T) {
 	ctx := context.Background()
 	counterValue := int64(10)
 	gaugeValue := float64(3.14)
+	updatedAt := time.Date(2025, 5, 6, 19, 29, 12, 0, time.UTC)
 
 	tests := []struct {
 		name        string
@@ -83,36 +88,40 @@ func TestMemoryMetricStorage_GetMetric(t *testing.T) {
 			name: "get existing counter metric",
 			setupData: []domain.Metric{
 				{
-					ID:    "test_counter",
-					MType: domain.MetricTypeCounter,
-					Delta: &counterValue,
+					ID:        "test_counter",
+					MType:     domain.MetricTypeCounter,
+					Delta:     &counterValue,
+					UpdatedAt: updatedAt,
 				},
 			},
 			metricType:  domain.MetricTypeCounter,
 			metricName:  "test_counter",
 			expectFound: true,
 			expected: domain.Metric{
-				ID:    "test_counter",
-				MType: domain.MetricTypeCounter,
-				Delta: &counterValue,
+				ID:        "test_counter",
+				MType:     domain.MetricTypeCounter,
+				Delta:     &counterValue,
+				UpdatedAt: updatedAt,
 			},
 		},
 		{
 			name: "get existing gauge metric",
 			setupData: []domain.Metric{
 				{
-					ID:    "test_gauge",
-					MType: domain.MetricTypeGauge,
-					Value: &gaugeValue,
+					ID:        "test_gauge",
+					MType:     domain.MetricTypeGauge,
+					Value:     &gaugeValue,
+					UpdatedAt: updatedAt,
 				},
 			},
 			metricType:  domain.MetricTypeGauge,
 			metricName:  "test_gauge",
 			expectFound: true,
 			expected: domain.Metric{
-				ID:    "test_gauge",
-				MType: domain.MetricTypeGauge,
-				Value: &gaugeValue,
+				ID:        "test_gauge",
+				MType:     domain.MetricTypeGauge,
+				Value:     &gaugeValue,
+				UpdatedAt: updatedAt,
 			},
 		},
 		{
@@ -137,7 +146,7 @@ func TestMemoryMetricStorage_GetMetric(t *testing.T) {
 				require.NoError(t, err)
 			}
 
-			result, found := storage.GetMetric(ctx, tt.metricType, tt.metricName)
+			result, found := domaintest.GetMetric(t, storage, tt.metricType, tt.metricName)
 
 			assert.Equal(t, tt.expectFound, found)
 			assert.Equal(t, tt.expected, result)
@@ -151,17 +160,20 @@ func TestMemoryMetricStorage_GetAllMetrics(t *testing.T) {
 
 	counterValue := int64(10)
 	gaugeValue := float64(3.14)
+	updatedAt := time.Date(2025, 5, 6, 19, 29, 12, 0, time.UTC)
 
 	metrics := []domain.Metric{
 		{
-			ID:    "counter1",
-			MType: domain.MetricTypeCounter,
-			Delta: &counterValue,
+			ID:        "counter1",
+			MType:     domain.MetricTypeCounter,
+			Delta:     &counterValue,
+			UpdatedAt: updatedAt,
 		},
 		{
-			ID:    "gauge1",
-			MType: domain.MetricTypeGauge,
-			Value: &gaugeValue,
+			ID:        "gauge1",
+			MType:     domain.MetricTypeGauge,
+			Value:     &gaugeValue,
+			UpdatedAt: updatedAt,
 		},
 	}
 
@@ -170,7 +182,7 @@ func TestMemoryMetricStorage_GetAllMetrics(t *testing.T) {
 		require.NoError(t, err)
 	}
 
-	result := storage.GetAllMetrics(ctx)
+	result := domaintest.AllMetrics(t, storage)
 	assert.Len(t, result, 2)
 	assert.ElementsMatch(t, metrics, result)
 }
@@ -252,7 +264,7 @@ func TestMemoryMetricStorage_CounterAccumulation(t *testing.T) {
 	err := storage.UpdateMetric(ctx, metric)
 	require.NoError(t, err)
 
-	result, found := storage.GetMetric(ctx, domain.MetricTypeCounter, "test_counter")
+	result, found := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "test_counter")
 	require.True(t, found)
 	assert.Equal(t, int64(5), *result.Delta)
 
@@ -261,7 +273,7 @@ func TestMemoryMetricStorage_CounterAccumulation(t *testing.T) {
 	err = storage.UpdateMetric(ctx, metric)
 	require.NoError(t, err)
 
-	result, found = storage.GetMetric(ctx, domain.MetricTypeCounter, "test_counter")
+	result, found = domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "test_counter")
 	require.True(t, found)
 	assert.Equal(t, int64(15), *result.Delta)
 }
@@ -282,7 +294,7 @@ func TestMemoryMetricStorage_GaugeReplacement(t *testing.T) {
 	err := storage.UpdateMetric(ctx, metric)
 	require.NoError(t, err)
 
-	result, found := storage.GetMetric(ctx, domain.MetricTypeGauge, "test_gauge")
+	result, found := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "test_gauge")
 	require.True(t, found)
 	assert.Equal(t, 3.14, *result.Value)
 
@@ -291,11 +303,121 @@ func TestMemoryMetricStorage_GaugeReplacement(t *testing.T) {
 	err = storage.UpdateMetric(ctx, metric)
 	require.NoError(t, err)
 
-	result, found = storage.GetMetric(ctx, domain.MetricTypeGauge, "test_gauge")
+	result, found = domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "test_gauge")
 	require.True(t, found)
 	assert.Equal(t, 2.71, *result.Value)
 }
 
+func TestMemoryMetricStorage_Timestamps(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	value := float64(3.14)
+	clientTime := time.Date(2025, 5, 6, 19, 29, 12, 0, time.UTC)
+
+	before := time.Now()
+	err := storage.UpdateMetric(ctx, domain.Metric{
+		ID:        "test_gauge",
+		MType:     domain.MetricTypeGauge,
+		Value:     &value,
+		Timestamp: clientTime,
+	})
+	require.NoError(t, err)
+
+	result, found := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "test_gauge")
+	require.True(t, found)
+	assert.False(t, result.UpdatedAt.Before(before))
+	assert.Equal(t, clientTime, result.Timestamp)
+
+	receivedAt := time.Date(2025, 5, 6, 19, 30, 0, 0, time.UTC)
+	err = storage.UpdateMetric(ctx, domain.Metric{
+		ID:        "test_gauge",
+		MType:     domain.MetricTypeGauge,
+		Value:     &value,
+		UpdatedAt: receivedAt,
+	})
+	require.NoError(t, err)
+
+	result, found = domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "test_gauge")
+	require.True(t, found)
+	assert.Equal(t, receivedAt, result.UpdatedAt)
+	assert.True(t, result.Timestamp.IsZero())
+}
+
+func TestMemoryMetricStorage_DeleteMetric(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	counterValue := int64(10)
+	gaugeValue := float64(3.14)
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		{ID: "metric", MType: domain.MetricTypeCounter, Delta: &counterValue},
+		{ID: "metric", MType: domain.MetricTypeGauge, Value: &gaugeValue},
+	}))
+
+	found, err := storage.DeleteMetric(ctx, domain.MetricTypeGauge, "metric")
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = storage.DeleteMetric(ctx, domain.MetricTypeGauge, "metric")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = storage.DeleteMetric(ctx, "invalid", "metric")
+	assert.Error(t, err)
+
+	_, found = domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "metric")
+	assert.True(t, found)
+}
+
+func TestMemoryMetricStorage_DeleteMetricIfStale(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	gaugeValue := float64(3.14)
+	require.NoError(t, storage.UpdateMetric(ctx, domain.Metric{ID: "metric", MType: domain.MetricTypeGauge, Value: &gaugeValue, UpdatedAt: updatedAt}))
+
+	found, err := storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "metric", updatedAt)
+	require.NoError(t, err)
+	assert.False(t, found, "a metric updated at the deadline is not stale")
+
+	found, err = storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "metric", updatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = storage.DeleteMetricIfStale(ctx, domain.MetricTypeGauge, "metric", updatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = storage.DeleteMetricIfStale(ctx, "invalid", "metric", updatedAt)
+	assert.Error(t, err)
+}
+
+func TestMemoryMetricStorage_DeleteByPrefix(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	counterValue := int64(10)
+	gaugeValue := float64(3.14)
+
+	require.NoError(t, storage.UpdateMetrics(ctx, []domain.Metric{
+		{ID: "CPUutilization1", MType: domain.MetricTypeGauge, Value: &gaugeValue},
+		{ID: "CPUutilization2", MType: domain.MetricTypeGauge, Value: &gaugeValue},
+		{ID: "CPUcount", MType: domain.MetricTypeCounter, Delta: &counterValue},
+		{ID: "PollCount", MType: domain.MetricTypeCounter, Delta: &counterValue},
+	}))
+
+	deleted, err := storage.DeleteByPrefix(ctx, "CPU")
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+
+	result := domaintest.AllMetrics(t, storage)
+	require.Len(t, result, 1)
+	assert.Equal(t, "PollCount", result[0].ID)
+}
+
 func TestMemoryMetricStorage_Ping(t *testing.T) {
 	ctx := context.Background()
 	storage := NewMemoryMetricStorage()
@@ -303,3 +425,64 @@ func TestMemoryMetricStorage_Ping(t *testing.T) {
 	err := storage.Ping(ctx)
 	assert.NoError(t, err)
 }
+
+func TestMemoryMetricStorage_UpdateMetricsBatch(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	batch := []domain.Metric{counter("PollCount", 1)}
+	for i := 0; i < 200; i++ {
+		batch = append(batch, gauge("gauge"+strconv.Itoa(i), float64(i)), counter("PollCount", 1))
+	}
+	batch = append(batch, gauge("gauge0", -1))
+	require.NoError(t, storage.UpdateMetrics(ctx, batch))
+
+	assert.Len(t, domaintest.AllMetrics(t, storage), 201)
+	pollCount, _ := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(201), *pollCount.Delta)
+	gauge0, _ := domaintest.GetMetric(t, storage, domain.MetricTypeGauge, "gauge0")
+	assert.Equal(t, -1.0, *gauge0.Value, "the last update of a metric in the batch wins")
+
+	err := storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 1), {ID: "Alloc", MType: domain.MetricTypeGauge}})
+	require.Error(t, err)
+	pollCount, _ = domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(201), *pollCount.Delta, "an invalid batch changes nothing")
+}
+
+func TestMemoryMetricStorage_UpdateMetricsStored(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+	require.NoError(t, storage.UpdateMetric(ctx, counter("PollCount", 10)))
+
+	stored, err := storage.UpdateMetricsStored(ctx, []domain.Metric{counter("PollCount", 1), gauge("Alloc", 1), counter("PollCount", 2), gauge("Alloc", 2)})
+	require.NoError(t, err)
+	require.Len(t, stored, 2, "a metric updated more than once is returned once")
+	assert.Equal(t, "PollCount", stored[0].ID)
+	assert.Equal(t, int64(13), *stored[0].Delta, "counters are returned with their total")
+	assert.Equal(t, "Alloc", stored[1].ID)
+	assert.Equal(t, 2.0, *stored[1].Value)
+
+	_, err = storage.UpdateMetricsStored(ctx, []domain.Metric{{ID: "Alloc", MType: domain.MetricTypeGauge}})
+	assert.Error(t, err)
+}
+
+func TestMemoryMetricStorage_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryMetricStorage()
+
+	var wg sync.WaitGroup
+	for agent := 0; agent < 8; agent++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_ = storage.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 1), gauge("Alloc", float64(i))})
+				_ = domaintest.AllMetrics(t, storage)
+			}
+		}()
+	}
+	wg.Wait()
+
+	pollCount, _ := domaintest.GetMetric(t, storage, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(800), *pollCount.Delta)
+}
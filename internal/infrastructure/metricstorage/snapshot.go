@@ -0,0 +1,136 @@
+package metricstorage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// snapshotVersion is the current snapshot format: a header line with the version and the SHA-256 of the body,
+// followed by the metrics as a JSON array. Version 1 was the bare JSON array, which is still restored.
+const snapshotVersion = 2
+
+const snapshotMagic = "alert-metrics-snapshot"
+
+var errCorruptSnapshot = errors.New("corrupt snapshot")
+
+func encodeSnapshot(metrics []domain.Metric) ([]byte, error) {
+	body, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	header := fmt.Sprintf("%s v%d sha256:%s\n", snapshotMagic, snapshotVersion, hex.EncodeToString(sum[:]))
+	return append([]byte(header), body...), nil
+}
+
+func decodeSnapshot(data []byte) ([]domain.Metric, error) {
+	body := data
+	if bytes.HasPrefix(data, []byte(snapshotMagic)) {
+		header, rest, ok := bytes.Cut(data, []byte("\n"))
+		if !ok {
+			return nil, fmt.Errorf("%w: missing body", errCorruptSnapshot)
+		}
+
+		var version int
+		var checksum string
+		if _, err := fmt.Sscanf(string(header), snapshotMagic+" v%d sha256:%s", &version, &checksum); err != nil {
+			return nil, fmt.Errorf("%w: bad header: %w", errCorruptSnapshot, err)
+		}
+		if version != snapshotVersion {
+			return nil, fmt.Errorf("%w: unsupported version %d", errCorruptSnapshot, version)
+		}
+
+		sum := sha256.Sum256(rest)
+		if hex.EncodeToString(sum[:]) != checksum {
+			return nil, fmt.Errorf("%w: checksum mismatch", errCorruptSnapshot)
+		}
+		body = rest
+	}
+
+	var metrics []domain.Metric
+	if err := json.Unmarshal(body, &metrics); err != nil {
+		return nil, fmt.Errorf("%w: %w", errCorruptSnapshot, err)
+	}
+	return metrics, nil
+}
+
+// snapshotPaths returns the snapshot files from the newest to the oldest: path itself, then path.1, path.2 and so on.
+func snapshotPaths(path string, keep int) []string {
+	paths := []string{path}
+	for i := 1; i < keep; i++ {
+		paths = append(paths, path+"."+strconv.Itoa(i))
+	}
+	return paths
+}
+
+// writeSnapshot writes the snapshot atomically, keeping the previous keep-1 snapshots as path.1, path.2 and so on.
+func writeSnapshot(path string, data []byte, keep int) error {
+	tmp, err := writeTemp(path, data)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp) }()
+
+	// Shifts the older snapshots, a missing one leaves a gap that restore skips
+	paths := snapshotPaths(path, keep)
+	for i := len(paths) - 1; i > 0; i-- {
+		if err := os.Rename(paths[i-1], paths[i]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate snapshot: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace metrics file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// writeTemp writes the data to a temporary file next to path and flushes it to disk.
+func writeTemp(path string, data []byte) (string, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create metrics file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write metrics to file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to sync metrics file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("failed to close metrics file: %w", err)
+	}
+
+	return file.Name(), nil
+}
+
+// syncDir flushes the directory entry changes made by renames.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot directory: %w", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package metricstorage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+)
+
+func TestDecodeSnapshot(t *testing.T) {
+	encoded, err := encodeSnapshot([]domain.Metric{gauge("Alloc", 1)})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(encoded, []byte("alert-metrics-snapshot v2 sha256:")))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []string
+		wantErr bool
+	}{
+		{name: "current format", data: encoded, want: []string{"Alloc"}},
+		{name: "legacy format", data: []byte(`[{"id":"PollCount","type":"counter","delta":3}]`), want: []string{"PollCount"}},
+		{name: "checksum mismatch", data: bytes.Replace(encoded, []byte(`"value":1`), []byte(`"value":2`), 1), wantErr: true},
+		{name: "truncated", data: encoded[:len(encoded)-3], wantErr: true},
+		{name: "unknown version", data: bytes.Replace(encoded, []byte(" v2 "), []byte(" v9 "), 1), wantErr: true},
+		{name: "header only", data: []byte("alert-metrics-snapshot v2 sha256:00"), wantErr: true},
+		{name: "empty file", data: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := decodeSnapshot(tt.data)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errCorruptSnapshot)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(metrics))
+		})
+	}
+}
+
+func TestFileMetricStorage_KeepsSnapshots(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncNever)
+	for i := range 5 {
+		require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", float64(i))))
+		storage.Compact()
+	}
+
+	matches, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{path, path + ".1", path + ".2", path + ".wal"}, matches)
+
+	data, err := os.ReadFile(path + ".2")
+	require.NoError(t, err)
+	metrics, err := decodeSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, *metrics[0].Value, "the oldest kept snapshot is two compactions behind")
+}
+
+func TestFileMetricStorage_RestoreFallsBack(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	storage := newTestFileStorage(t, path, time.Hour, FsyncNever)
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Alloc", 1)))
+	storage.Compact()
+	require.NoError(t, storage.UpdateMetric(ctx, gauge("Sys", 2)))
+	storage.Compact()
+
+	// A torn write of the newest snapshot
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0o644))
+
+	restored := newTestFileStorage(t, path, time.Hour, FsyncNever)
+	assert.Equal(t, []string{"Alloc"}, ids(domaintest.AllMetrics(t, restored)))
+
+	for _, p := range snapshotPaths(path, 3) {
+		require.NoError(t, os.WriteFile(p, []byte("garbage"), 0o644))
+	}
+	_, err = NewFileMetricStorage(NewMemoryMetricStorage(), zerolog.Nop(), time.Hour, path, true, FsyncNever, 3)
+	assert.ErrorIs(t, err, errCorruptSnapshot, "damaged snapshots must not be overwritten with an empty state")
+}
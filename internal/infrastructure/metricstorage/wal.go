@@ -0,0 +1,169 @@
+package metricstorage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// FsyncPolicy tells when appended WAL records are flushed to disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways flushes every record before the update returns.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval flushes once a second, a crash loses at most the last second of updates.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+func (p FsyncPolicy) Validate() error {
+	switch p {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+		return nil
+	default:
+		return fmt.Errorf("unknown fsync policy %q, expected always, interval or never", p)
+	}
+}
+
+type walOp string
+
+const (
+	walUpdate       walOp = "update"
+	walDelete       walOp = "delete"
+	walDeletePrefix walOp = "delete_prefix"
+)
+
+// walRecord is a line of the write-ahead log. Updates hold the stored state of the metrics after the update,
+// so counters carry their total and replaying a record more than once gives the same result.
+type walRecord struct {
+	Op      walOp             `json:"op"`
+	Metrics []domain.Metric   `json:"metrics,omitempty"`
+	MType   domain.MetricType `json:"type,omitempty"`
+	ID      string            `json:"id,omitempty"`
+	Prefix  string            `json:"prefix,omitempty"`
+}
+
+// wal is an append-only file of JSON lines.
+type wal struct {
+	file     *os.File
+	size     int64
+	unsynced bool
+}
+
+func openWAL(path string) (*wal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to stat WAL: %w", err)
+	}
+
+	return &wal{file: file, size: info.Size()}, nil
+}
+
+func (w *wal) append(record walRecord, sync bool) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal WAL record: %w", err)
+	}
+
+	n, err := w.file.Write(append(data, '\n'))
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to append to WAL: %w", err)
+	}
+
+	w.unsynced = true
+	if sync {
+		return w.sync()
+	}
+	return nil
+}
+
+func (w *wal) sync() error {
+	if !w.unsynced {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	w.unsynced = false
+	return nil
+}
+
+// reset empties the log once its records are part of a snapshot.
+func (w *wal) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	w.size = 0
+	w.unsynced = true
+	return w.sync()
+}
+
+// readWAL reads the records of the log. A torn last line, left by a crash during an append, is ignored,
+// while a broken record in the middle of the log stops the replay there and is reported.
+func readWAL(path string) ([]walRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	var records []walRecord
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for line := 1; ; line++ {
+		text, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			// Without the trailing newline the last record may be incomplete
+			return records, nil
+		}
+
+		var record walRecord
+		if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &record); err != nil {
+			return records, fmt.Errorf("broken WAL record at line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// metricKey identifies a metric by type and ID while the state is rebuilt from the snapshot and the log.
+type metricKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+// replay applies the records to the metrics restored from the snapshot.
+func replay(metrics map[metricKey]domain.Metric, records []walRecord) {
+	for _, record := range records {
+		switch record.Op {
+		case walUpdate:
+			for _, metric := range record.Metrics {
+				metrics[metricKey{mType: metric.MType, id: metric.ID}] = metric
+			}
+		case walDelete:
+			delete(metrics, metricKey{mType: record.MType, id: record.ID})
+		case walDeletePrefix:
+			for key := range metrics {
+				if strings.HasPrefix(key.id, record.Prefix) {
+					delete(metrics, key)
+				}
+			}
+		}
+	}
+}
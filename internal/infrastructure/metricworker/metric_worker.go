@@ -53,13 +53,15 @@ func (mw *MetricWorker) Stop() {
 func (mw *MetricWorker) sendBatch() {
 	buf := make([]domain.Metric, 0)
 	rawMetrics := mw.metricMonitor.GetMetrics()
+	collectedAt := time.Now()
 
 	// Send Gauge metrics
 	for key, value := range rawMetrics.Gauges {
 		metric := domain.Metric{
-			ID:    key,
-			MType: domain.MetricTypeGauge,
-			Value: &value,
+			ID:        key,
+			MType:     domain.MetricTypeGauge,
+			Value:     &value,
+			Timestamp: collectedAt,
 		}
 		buf = append(buf, metric)
 		if len(buf) >= batchSize {
@@ -71,9 +73,10 @@ func (mw *MetricWorker) sendBatch() {
 	// Send Counter metrics
 	for key, value := range rawMetrics.Counters {
 		metric := domain.Metric{
-			ID:    key,
-			MType: domain.MetricTypeCounter,
-			Delta: &value,
+			ID:        key,
+			MType:     domain.MetricTypeCounter,
+			Delta:     &value,
+			Timestamp: collectedAt,
 		}
 		buf = append(buf, metric)
 		if len(buf) >= batchSize {
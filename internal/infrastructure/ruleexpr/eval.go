@@ -0,0 +1,288 @@
+package ruleexpr
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Value is the result of an evaluation, either a Scalar or a Vector.
+type Value interface {
+	value()
+}
+
+// Scalar is a single number not tied to a metric.
+type Scalar float64
+
+// Vector holds one sample per metric, ordered by metric ID.
+type Vector []domain.RuleSample
+
+func (Scalar) value() {}
+func (Vector) value() {}
+
+// Env provides the data an expression is evaluated against.
+type Env struct {
+	Metrics []domain.Metric
+	// History provides the samples of range selectors, it is only needed by expressions calling functions.
+	History domain.MetricHistory
+	// Now is the end of the ranges of range selectors.
+	Now time.Time
+}
+
+// Eval evaluates the expression. Comparisons filter vectors like PromQL does: a vector compared with a scalar
+// keeps the samples for which the comparison is true, two vectors are compared sample by sample by metric ID,
+// and two scalars yield 1 or 0. The set operators and, or and unless also match vector samples by metric ID.
+func Eval(ctx context.Context, expr Expr, env Env) (Value, error) {
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		return Scalar(e.Value), nil
+
+	case *Selector:
+		return env.selectSamples(e), nil
+
+	case *Call:
+		return env.call(ctx, e)
+
+	case *BinaryExpr:
+		lhs, err := Eval(ctx, e.LHS, env)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := Eval(ctx, e.RHS, env)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(setOperators, e.Op) {
+			return combine(e.Op, lhs, rhs)
+		}
+		return compare(e.Op, lhs, rhs)
+
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+// EvalVector evaluates the expression and returns its result as a vector, a scalar result is rejected.
+func EvalVector(ctx context.Context, expr Expr, env Env) (Vector, error) {
+	result, err := Eval(ctx, expr, env)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, ok := result.(Vector)
+	if !ok {
+		return nil, fmt.Errorf("expression %s does not select any metric", expr)
+	}
+	return vector, nil
+}
+
+func (env Env) selectSamples(selector *Selector) Vector {
+	var vector Vector
+	for _, metric := range env.Metrics {
+		if !selector.Matches(metric.ID) {
+			continue
+		}
+
+		switch {
+		case metric.MType == domain.MetricTypeGauge && metric.Value != nil:
+			vector = append(vector, domain.RuleSample{MetricID: metric.ID, Value: *metric.Value})
+		case metric.MType == domain.MetricTypeCounter && metric.Delta != nil:
+			vector = append(vector, domain.RuleSample{MetricID: metric.ID, Value: float64(*metric.Delta)})
+		}
+	}
+
+	slices.SortFunc(vector, func(a, b domain.RuleSample) int {
+		return cmp.Compare(a.MetricID, b.MetricID)
+	})
+	return vector
+}
+
+// call applies the function to the samples of every matching metric in the range. Metrics without enough
+// samples for the function are left out of the result.
+func (env Env) call(ctx context.Context, call *Call) (Vector, error) {
+	if env.History == nil {
+		return nil, errors.New("metric history is not available")
+	}
+
+	since := env.Now.Add(-call.Arg.Range)
+	var vector Vector
+	for _, metric := range env.Metrics {
+		if !call.Arg.Selector.Matches(metric.ID) {
+			continue
+		}
+
+		samples := env.History.Samples(ctx, metric.MType, metric.ID, since)
+		value, ok := apply(call.Func, samples)
+		if ok {
+			vector = append(vector, domain.RuleSample{MetricID: metric.ID, Value: value})
+		}
+	}
+
+	slices.SortFunc(vector, func(a, b domain.RuleSample) int {
+		return cmp.Compare(a.MetricID, b.MetricID)
+	})
+	return vector, nil
+}
+
+// apply computes the function over samples ordered by time, reporting false when there are too few of them.
+func apply(function string, samples []domain.Sample) (float64, bool) {
+	switch function {
+	case FuncRate:
+		// Per-second increase, a decreasing value is a counter reset and counts from zero
+		if len(samples) < 2 {
+			return 0, false
+		}
+		seconds := samples[len(samples)-1].Time.Sub(samples[0].Time).Seconds()
+		if seconds <= 0 {
+			return 0, false
+		}
+		var increase float64
+		for i := 1; i < len(samples); i++ {
+			if samples[i].Value < samples[i-1].Value {
+				increase += samples[i].Value
+				continue
+			}
+			increase += samples[i].Value - samples[i-1].Value
+		}
+		return increase / seconds, true
+
+	case FuncDelta:
+		if len(samples) < 2 {
+			return 0, false
+		}
+		return samples[len(samples)-1].Value - samples[0].Value, true
+
+	case FuncAvgOverTime:
+		if len(samples) == 0 {
+			return 0, false
+		}
+		var sum float64
+		for _, sample := range samples {
+			sum += sample.Value
+		}
+		return sum / float64(len(samples)), true
+
+	default:
+		return 0, false
+	}
+}
+
+// combine applies a set operator to two vectors: and keeps the left samples whose metric is on the right,
+// unless keeps the ones whose metric is not, and or adds the right samples of metrics missing on the left.
+func combine(op string, lhs, rhs Value) (Value, error) {
+	l, lok := lhs.(Vector)
+	r, rok := rhs.(Vector)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s requires metric operands on both sides", op)
+	}
+
+	right := make(map[string]bool, len(r))
+	for _, sample := range r {
+		right[sample.MetricID] = true
+	}
+
+	var result Vector
+	switch op {
+	case "and", "unless":
+		for _, sample := range l {
+			if right[sample.MetricID] == (op == "and") {
+				result = append(result, sample)
+			}
+		}
+	case "or":
+		left := make(map[string]bool, len(l))
+		result = append(result, l...)
+		for _, sample := range l {
+			left[sample.MetricID] = true
+		}
+		for _, sample := range r {
+			if !left[sample.MetricID] {
+				result = append(result, sample)
+			}
+		}
+		slices.SortFunc(result, func(a, b domain.RuleSample) int {
+			return cmp.Compare(a.MetricID, b.MetricID)
+		})
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+	return result, nil
+}
+
+func compare(op string, lhs, rhs Value) (Value, error) {
+	switch l := lhs.(type) {
+	case Scalar:
+		switch r := rhs.(type) {
+		case Scalar:
+			ok, err := compareValues(op, float64(l), float64(r))
+			if err != nil || !ok {
+				return Scalar(0), err
+			}
+			return Scalar(1), nil
+		case Vector:
+			return filter(r, func(sample domain.RuleSample) (bool, error) {
+				return compareValues(op, float64(l), sample.Value)
+			})
+		}
+
+	case Vector:
+		switch r := rhs.(type) {
+		case Scalar:
+			return filter(l, func(sample domain.RuleSample) (bool, error) {
+				return compareValues(op, sample.Value, float64(r))
+			})
+		case Vector:
+			byID := make(map[string]float64, len(r))
+			for _, sample := range r {
+				byID[sample.MetricID] = sample.Value
+			}
+			return filter(l, func(sample domain.RuleSample) (bool, error) {
+				other, ok := byID[sample.MetricID]
+				if !ok {
+					return false, nil
+				}
+				return compareValues(op, sample.Value, other)
+			})
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported operands for %s", op)
+}
+
+func filter(vector Vector, keep func(domain.RuleSample) (bool, error)) (Vector, error) {
+	var result Vector
+	for _, sample := range vector {
+		ok, err := keep(sample)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, sample)
+		}
+	}
+	return result, nil
+}
+
+func compareValues(op string, a, b float64) (bool, error) {
+	switch op {
+	case ">":
+		return a > b, nil
+	case "<":
+		return a < b, nil
+	case ">=":
+		return a >= b, nil
+	case "<=":
+		return a <= b, nil
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	default:
+		return false, fmt.Errorf("unknown operator %q", op)
+	}
+}
@@ -0,0 +1,218 @@
+package ruleexpr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "comparison", input: "HeapInuse > 1e9", want: "HeapInuse > 1e+09"},
+		{name: "wildcard", input: "CPUutilization* >= 90", want: "CPUutilization* >= 90"},
+		{name: "negative number", input: "Temperature< -10.5", want: "Temperature < -10.5"},
+		{name: "parentheses", input: "(Alloc) != (0)", want: "Alloc != 0"},
+		{name: "selector only", input: "Alloc", want: "Alloc"},
+		{name: "missing operand", input: "Alloc >", wantErr: true},
+		{name: "trailing token", input: "Alloc > 1 2", wantErr: true},
+		{name: "unclosed parenthesis", input: "(Alloc > 1", wantErr: true},
+		{name: "unknown character", input: "Alloc # 1", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "rate", input: "rate(PollCount[5m]) > 0.5", want: "rate(PollCount[5m]) > 0.5"},
+		{name: "range units", input: "delta(Alloc[ 1h30m ]) < 0", want: "delta(Alloc[1h30m]) < 0"},
+		{name: "set operators", input: "Alloc > 1 and HeapInuse > 2 or Alloc unless Sys",
+			want: "((Alloc > 1) and (HeapInuse > 2)) or (Alloc unless Sys)"},
+		{name: "keyword as metric", input: "and > 1", want: "and > 1"},
+		{name: "sign after set operator", input: "Alloc and -1 < Alloc", want: "Alloc and (-1 < Alloc)"},
+		{name: "bare range selector", input: "PollCount[5m] > 1", wantErr: true},
+		{name: "unknown function", input: "sum(PollCount[5m])", wantErr: true},
+		{name: "function without range", input: "rate(PollCount)", wantErr: true},
+		{name: "invalid range", input: "rate(PollCount[5x])", wantErr: true},
+		{name: "unclosed range", input: "rate(PollCount[5m)", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr.String())
+		})
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	expr, err := Parse("CPU* > Limit")
+	require.NoError(t, err)
+
+	selectors := Selectors(expr)
+	require.Len(t, selectors, 2)
+	assert.Equal(t, "CPU*", selectors[0].Pattern)
+	assert.True(t, selectors[0].Matches("CPUutilization1"))
+	assert.False(t, selectors[0].Matches("GPU"))
+	assert.Equal(t, "Limit", selectors[1].Pattern)
+	assert.True(t, IsCondition(expr))
+
+	expr, err = Parse("avg_over_time(CPU*[10m]) > 90 or rate(PollCount[1m]) == 0")
+	require.NoError(t, err)
+	require.Len(t, Selectors(expr), 2)
+
+	ranges := MatrixSelectors(expr)
+	require.Len(t, ranges, 2)
+	assert.Equal(t, "CPU*", ranges[0].Selector.Pattern)
+	assert.Equal(t, 10*time.Minute, ranges[0].Range)
+	assert.Equal(t, time.Minute, ranges[1].Range)
+}
+
+func TestEval(t *testing.T) {
+	env := Env{Metrics: []domain.Metric{
+		gauge("CPUutilization2", 95),
+		gauge("CPUutilization1", 40),
+		counter("PollCount", 7),
+		gauge("Limit", 50),
+	}}
+
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{name: "selector", input: "CPU*", want: Vector{{MetricID: "CPUutilization1", Value: 40}, {MetricID: "CPUutilization2", Value: 95}}},
+		{name: "vector filter", input: "CPU* > 90", want: Vector{{MetricID: "CPUutilization2", Value: 95}}},
+		{name: "scalar on the left", input: "90 < CPU*", want: Vector{{MetricID: "CPUutilization2", Value: 95}}},
+		{name: "counter", input: "PollCount == 7", want: Vector{{MetricID: "PollCount", Value: 7}}},
+		{name: "no match", input: "PollCount > 7", want: Vector(nil)},
+		{name: "vectors join by metric", input: "CPU* > Limit", want: Vector(nil)},
+		{name: "scalar true", input: "2 >= 1", want: Scalar(1)},
+		{name: "scalar false", input: "2 <= 1", want: Scalar(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			require.NoError(t, err)
+
+			got, err := Eval(context.Background(), expr, env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvalVector(t *testing.T) {
+	expr, err := Parse("1 > 0")
+	require.NoError(t, err)
+
+	_, err = EvalVector(context.Background(), expr, Env{})
+	assert.Error(t, err)
+}
+
+type fakeHistory map[string][]domain.Sample
+
+func (h fakeHistory) Samples(_ context.Context, _ domain.MetricType, metricName string, since time.Time) []domain.Sample {
+	var samples []domain.Sample
+	for _, sample := range h[metricName] {
+		if !sample.Time.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+func (h fakeHistory) Retention() time.Duration {
+	return time.Hour
+}
+
+func TestEval_Functions(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration, value float64) domain.Sample {
+		return domain.Sample{Time: now.Add(-ago), Value: value}
+	}
+
+	env := Env{
+		Metrics: []domain.Metric{
+			counter("PollCount", 130),
+			counter("Restarted", 20),
+			gauge("HeapAlloc", 400),
+			gauge("Alloc", 10),
+			gauge("Idle", 1),
+		},
+		History: fakeHistory{
+			// The sample before the range is ignored
+			"PollCount": {at(10*time.Minute, 0), at(5*time.Minute, 10), at(2*time.Minute, 70), at(0, 130)},
+			// The reset from 60 to 30 counts as an increase of 30
+			"Restarted": {at(5*time.Minute, 0), at(2*time.Minute, 60), at(0, 30)},
+			"HeapAlloc": {at(9*time.Minute, 100), at(3*time.Minute, 300), at(0, 400)},
+			"Alloc":     {at(time.Minute, 10)},
+		},
+		Now: now,
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{name: "rate", input: "rate(PollCount[5m])", want: Vector{{MetricID: "PollCount", Value: 0.4}}},
+		{name: "rate with reset", input: "rate(Restarted[5m])", want: Vector{{MetricID: "Restarted", Value: 0.3}}},
+		{name: "delta", input: "delta(HeapAlloc[10m])", want: Vector{{MetricID: "HeapAlloc", Value: 300}}},
+		{name: "avg_over_time", input: "avg_over_time(*Alloc[5m])", want: Vector{
+			{MetricID: "Alloc", Value: 10},
+			{MetricID: "HeapAlloc", Value: 350},
+		}},
+		{name: "too few samples", input: "rate(Alloc[5m])", want: Vector(nil)},
+		{name: "no samples", input: "avg_over_time(Idle[5m])", want: Vector(nil)},
+		{name: "comparison", input: "rate(PollCount[5m]) > 0.3", want: Vector{{MetricID: "PollCount", Value: 0.4}}},
+		{name: "and", input: "delta(HeapAlloc[10m]) > 0 and HeapAlloc > 200", want: Vector{{MetricID: "HeapAlloc", Value: 300}}},
+		{name: "and different metrics", input: "Alloc > 0 and HeapAlloc > 0", want: Vector(nil)},
+		{name: "or", input: "Idle or Alloc > 5", want: Vector{{MetricID: "Alloc", Value: 10}, {MetricID: "Idle", Value: 1}}},
+		{name: "unless", input: "*Alloc unless Alloc", want: Vector{{MetricID: "HeapAlloc", Value: 400}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			require.NoError(t, err)
+
+			got, err := Eval(context.Background(), expr, env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEval_FunctionErrors(t *testing.T) {
+	ctx := context.Background()
+	env := Env{Metrics: []domain.Metric{counter("PollCount", 1)}}
+
+	expr, err := Parse("rate(PollCount[5m]) > 1")
+	require.NoError(t, err)
+	_, err = Eval(ctx, expr, env)
+	assert.Error(t, err, "no history")
+
+	expr, err = Parse("PollCount and 1")
+	require.NoError(t, err)
+	_, err = Eval(ctx, expr, env)
+	assert.Error(t, err, "scalar operand")
+}
@@ -0,0 +1,134 @@
+package ruleexpr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenRange
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// setOperators combine vectors by metric ID. They are lexed as identifiers and only recognized in operator position.
+var setOperators = []string{"and", "or", "unless"}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	for pos := 0; pos < len(input); {
+		c := rune(input[pos])
+
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: pos})
+			pos++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: pos})
+			pos++
+
+		case c == '[':
+			end := strings.IndexByte(input[pos:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed \"[\" at position %d", pos)
+			}
+			tokens = append(tokens, token{kind: tokenRange, text: strings.TrimSpace(input[pos+1 : pos+end]), pos: pos})
+			pos += end + 1
+
+		case isDigit(c) || c == '.' || ((c == '-' || c == '+') && pos+1 < len(input) && isDigit(rune(input[pos+1])) && expectsOperand(tokens)):
+			end := pos + 1
+			for end < len(input) && isNumberChar(input, end) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[pos:end], pos: pos})
+			pos = end
+
+		case isIdentStart(c):
+			end := pos + 1
+			for end < len(input) && isIdentChar(rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[pos:end], pos: pos})
+			pos = end
+
+		default:
+			op := matchOperator(input[pos:])
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// expectsOperand reports whether a sign at the current position starts a number rather than being an operator.
+func expectsOperand(tokens []token) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenOperator || last.kind == tokenLeftParen ||
+		(last.kind == tokenIdent && slices.Contains(setOperators, last.text))
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumberChar(input string, i int) bool {
+	c := rune(input[i])
+	if isDigit(c) || c == '.' || c == 'e' || c == 'E' {
+		return true
+	}
+	// Exponent sign, as in 1e-3
+	return (c == '-' || c == '+') && (input[i-1] == 'e' || input[i-1] == 'E')
+}
+
+// Metric names may contain '*' wildcards, '.', '_' and ':'.
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_' || c == '*'
+}
+
+func isIdentChar(c rune) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.' || c == ':'
+}
@@ -0,0 +1,292 @@
+package ruleexpr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Functions over a range of stored samples.
+const (
+	FuncRate        = "rate"
+	FuncDelta       = "delta"
+	FuncAvgOverTime = "avg_over_time"
+)
+
+var functions = []string{FuncRate, FuncDelta, FuncAvgOverTime}
+
+// Expr is a parsed rule expression.
+type Expr interface {
+	String() string
+}
+
+// NumberLiteral is a constant scalar.
+type NumberLiteral struct {
+	Value float64
+}
+
+func (n *NumberLiteral) String() string {
+	return strconv.FormatFloat(n.Value, 'g', -1, 64)
+}
+
+// Selector selects the current values of the metrics whose ID matches the pattern, '*' matches any characters.
+type Selector struct {
+	Pattern string
+	re      *regexp.Regexp
+}
+
+func newSelector(pattern string) *Selector {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return &Selector{
+		Pattern: pattern,
+		re:      regexp.MustCompile("^" + strings.Join(parts, ".*") + "$"),
+	}
+}
+
+func (s *Selector) String() string {
+	return s.Pattern
+}
+
+// Matches reports whether the metric ID is selected.
+func (s *Selector) Matches(id string) bool {
+	return s.re.MatchString(id)
+}
+
+// MatrixSelector selects the samples of the matching metrics over the range preceding the evaluation time.
+type MatrixSelector struct {
+	Selector *Selector
+	Range    time.Duration
+}
+
+func (m *MatrixSelector) String() string {
+	return m.Selector.String() + "[" + formatRange(m.Range) + "]"
+}
+
+// formatRange drops the zero units time.Duration prints, so 5m0s is shown as 5m.
+func formatRange(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Call applies a function to the samples of a range selector, yielding one value per metric.
+type Call struct {
+	Func string
+	Arg  *MatrixSelector
+}
+
+func (c *Call) String() string {
+	return c.Func + "(" + c.Arg.String() + ")"
+}
+
+// BinaryExpr applies a comparison or a set operator to two operands.
+type BinaryExpr struct {
+	Op  string
+	LHS Expr
+	RHS Expr
+}
+
+func (b *BinaryExpr) String() string {
+	return fmt.Sprintf("%s %s %s", operand(b.LHS), b.Op, operand(b.RHS))
+}
+
+func operand(e Expr) string {
+	if _, ok := e.(*BinaryExpr); ok {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+// Parse parses a rule expression such as "HeapInuse > 1e9", "CPUutilization* >= 90"
+// or "rate(PollCount[5m]) == 0 and HeapAlloc > 1e9".
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+
+	return expr, nil
+}
+
+// Selectors returns all metric selectors of the expression.
+func Selectors(expr Expr) []*Selector {
+	switch e := expr.(type) {
+	case *Selector:
+		return []*Selector{e}
+	case *Call:
+		return []*Selector{e.Arg.Selector}
+	case *BinaryExpr:
+		return append(Selectors(e.LHS), Selectors(e.RHS)...)
+	default:
+		return nil
+	}
+}
+
+// MatrixSelectors returns all range selectors of the expression.
+func MatrixSelectors(expr Expr) []*MatrixSelector {
+	switch e := expr.(type) {
+	case *Call:
+		return []*MatrixSelector{e.Arg}
+	case *BinaryExpr:
+		return append(MatrixSelectors(e.LHS), MatrixSelectors(e.RHS)...)
+	default:
+		return nil
+	}
+}
+
+// IsCondition reports whether the expression yields a condition rather than plain values.
+func IsCondition(expr Expr) bool {
+	_, ok := expr.(*BinaryExpr)
+	return ok
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseBinary(0)
+}
+
+// setPrecedence lists the set operators from the loosest to the tightest binding, comparisons bind tighter than all of them.
+var setPrecedence = [][]string{{"or"}, {"and", "unless"}}
+
+func (p *parser) parseBinary(level int) (Expr, error) {
+	if level == len(setPrecedence) {
+		return p.parseComparison()
+	}
+
+	lhs, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenIdent || !slices.Contains(setPrecedence[level], tok.text) {
+			return lhs, nil
+		}
+		p.next()
+
+		rhs, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryExpr{Op: tok.text, LHS: lhs, RHS: rhs}
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	lhs, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind == tokenOperator {
+		p.next()
+		rhs, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Op: tok.text, LHS: lhs, RHS: rhs}, nil
+	}
+
+	return lhs, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at position %d", tok, tok.pos)
+		}
+		return &NumberLiteral{Value: value}, nil
+
+	case tokenIdent:
+		switch next := p.peek(); next.kind {
+		case tokenLeftParen:
+			return p.parseCall(tok)
+		case tokenRange:
+			return nil, fmt.Errorf("range selector %s[%s] must be passed to a function such as rate", tok.text, next.text)
+		}
+		return newSelector(tok.text), nil
+
+	case tokenLeftParen:
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %s", closing.pos, closing)
+		}
+		return expr, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	if !slices.Contains(functions, name.text) {
+		return nil, fmt.Errorf("unknown function %s at position %d", name, name.pos)
+	}
+	p.next()
+
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return nil, fmt.Errorf("expected a metric at position %d, got %s", tok.pos, tok)
+	}
+
+	rng := p.next()
+	if rng.kind != tokenRange {
+		return nil, fmt.Errorf("%s expects a range selector such as %s[5m]", name.text, tok.text)
+	}
+	d, err := time.ParseDuration(rng.text)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid range %s at position %d", rng, rng.pos)
+	}
+
+	if closing := p.next(); closing.kind != tokenRightParen {
+		return nil, fmt.Errorf("expected \")\" at position %d, got %s", closing.pos, closing)
+	}
+
+	return &Call{Func: name.text, Arg: &MatrixSelector{Selector: newSelector(tok.text), Range: d}}, nil
+}
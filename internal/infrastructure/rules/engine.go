@@ -0,0 +1,433 @@
+package rules
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/ruleexpr"
+)
+
+type ruleState struct {
+	rule domain.Rule
+	expr ruleexpr.Expr
+	// pending holds the time each matching metric started to match
+	pending map[string]time.Time
+	// firing holds the metrics the rule currently fires for
+	firing map[string]bool
+}
+
+// Engine manages alert rules and periodically evaluates them against the stored metrics.
+// Functions such as rate are evaluated over the metric history.
+// All rules are kept in memory, the storage is only used to persist changes.
+type Engine struct {
+	storage   domain.RuleStorage
+	metrics   domain.MetricStorage
+	history   domain.MetricHistory
+	publisher domain.AlertPublisher
+	logger    *zerolog.Logger
+	now       func() time.Time
+	// writes serializes the rule changes, which are persisted without holding mu so evaluations and reads do
+	// not wait for the storage
+	writes sync.Mutex
+	// publishing is held by an evaluation until its alerts are published and while the alerts of a replaced
+	// rule are resolved, so a resolution is never overtaken by a late alert of the same rule
+	publishing sync.Mutex
+	mu         sync.Mutex
+	rules      map[string]*ruleState
+}
+
+var _ domain.RuleManager = (*Engine)(nil)
+
+func New(
+	ctx context.Context,
+	storage domain.RuleStorage,
+	metrics domain.MetricStorage,
+	history domain.MetricHistory,
+	publisher domain.AlertPublisher,
+	logger *zerolog.Logger,
+) (*Engine, error) {
+	rules, err := storage.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+
+	e := &Engine{
+		storage:   storage,
+		metrics:   metrics,
+		history:   history,
+		publisher: publisher,
+		logger:    logger,
+		now:       time.Now,
+		rules:     make(map[string]*ruleState, len(rules)),
+	}
+	for _, rule := range rules {
+		// Stored rules are not checked against the metrics, they may not have been reported yet after a restart
+		expr, err := parseCondition(rule.Expr)
+		if err != nil {
+			logger.Error().Err(err).Str("rule", rule.Name).Msg("skipping stored rule with invalid expression")
+			continue
+		}
+		e.rules[rule.Name] = newRuleState(rule, expr)
+	}
+
+	return e, nil
+}
+
+// ListRules returns all rules ordered by name.
+func (e *Engine) ListRules(_ context.Context) []domain.Rule {
+	e.mu.Lock()
+	rules := make([]domain.Rule, 0, len(e.rules))
+	for _, state := range e.rules {
+		rules = append(rules, state.rule)
+	}
+	e.mu.Unlock()
+
+	slices.SortFunc(rules, func(a, b domain.Rule) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return rules
+}
+
+func (e *Engine) GetRule(_ context.Context, name string) (domain.Rule, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, ok := e.rules[name]
+	if !ok {
+		return domain.Rule{}, false
+	}
+	return state.rule, true
+}
+
+func (e *Engine) CreateRule(ctx context.Context, rule domain.Rule) (domain.Rule, error) {
+	expr, err := e.validate(ctx, rule)
+	if err != nil {
+		return domain.Rule{}, err
+	}
+
+	now := e.now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	e.writes.Lock()
+	defer e.writes.Unlock()
+
+	if _, ok := e.state(rule.Name); ok {
+		return domain.Rule{}, fmt.Errorf("%w: %s", domain.ErrRuleExists, rule.Name)
+	}
+
+	if err := e.storage.SaveRule(ctx, rule); err != nil {
+		return domain.Rule{}, fmt.Errorf("failed to save rule: %w", err)
+	}
+
+	e.mu.Lock()
+	e.rules[rule.Name] = newRuleState(rule, expr)
+	e.mu.Unlock()
+
+	e.logger.Info().Str("rule", rule.Name).Str("expr", rule.Expr).Msg("rule created")
+
+	return rule, nil
+}
+
+// UpdateRule replaces the rule, the alerts it raised are resolved and its evaluation starts over.
+func (e *Engine) UpdateRule(ctx context.Context, rule domain.Rule) (domain.Rule, error) {
+	expr, err := e.validate(ctx, rule)
+	if err != nil {
+		return domain.Rule{}, err
+	}
+
+	e.writes.Lock()
+	defer e.writes.Unlock()
+
+	existing, ok := e.state(rule.Name)
+	if !ok {
+		return domain.Rule{}, fmt.Errorf("%w: %s", domain.ErrRuleNotFound, rule.Name)
+	}
+
+	rule.CreatedAt = existing.rule.CreatedAt
+	rule.UpdatedAt = e.now()
+
+	if err := e.storage.SaveRule(ctx, rule); err != nil {
+		return domain.Rule{}, fmt.Errorf("failed to save rule: %w", err)
+	}
+
+	e.mu.Lock()
+	e.rules[rule.Name] = newRuleState(rule, expr)
+	e.mu.Unlock()
+
+	e.resolveAll(ctx, existing)
+	e.logger.Info().Str("rule", rule.Name).Str("expr", rule.Expr).Msg("rule updated")
+
+	return rule, nil
+}
+
+// DeleteRule removes the rule and resolves the alerts it raised.
+func (e *Engine) DeleteRule(ctx context.Context, name string) (bool, error) {
+	e.writes.Lock()
+	defer e.writes.Unlock()
+
+	existing, ok := e.state(name)
+	if !ok {
+		return false, nil
+	}
+
+	if _, err := e.storage.DeleteRule(ctx, name); err != nil {
+		return false, fmt.Errorf("failed to delete rule: %w", err)
+	}
+
+	e.mu.Lock()
+	delete(e.rules, name)
+	e.mu.Unlock()
+
+	e.resolveAll(ctx, existing)
+	e.logger.Info().Str("rule", name).Msg("rule deleted")
+
+	return true, nil
+}
+
+// TestRule validates the rule and evaluates it once against the current metrics without raising any alerts.
+func (e *Engine) TestRule(ctx context.Context, rule domain.Rule) (domain.RuleTestResult, error) {
+	expr, err := e.validate(ctx, rule)
+	if err != nil {
+		return domain.RuleTestResult{}, err
+	}
+
+	env, err := e.env(ctx, e.now())
+	if err != nil {
+		return domain.RuleTestResult{}, err
+	}
+
+	result := domain.RuleTestResult{Series: []domain.RuleSample{}, Matches: []domain.RuleSample{}, Alerts: []domain.Alert{}}
+	for _, selector := range ruleexpr.Selectors(expr) {
+		samples, err := ruleexpr.EvalVector(ctx, selector, env)
+		if err != nil {
+			return domain.RuleTestResult{}, fmt.Errorf("%w: %w", domain.ErrInvalidRule, err)
+		}
+		result.Series = append(result.Series, samples...)
+	}
+
+	matches, err := ruleexpr.EvalVector(ctx, expr, env)
+	if err != nil {
+		return domain.RuleTestResult{}, fmt.Errorf("%w: %w", domain.ErrInvalidRule, err)
+	}
+
+	now := env.Now
+	for _, sample := range matches {
+		result.Matches = append(result.Matches, sample)
+
+		alert := ruleAlert(rule, sample)
+		alert.State = domain.AlertStateFiring
+		alert.StartsAt = now
+		result.Alerts = append(result.Alerts, alert)
+	}
+
+	return result, nil
+}
+
+// Evaluate evaluates all rules once, firing alerts for the metrics that matched for at least the rule
+// duration and resolving the alerts of the metrics that no longer match. The evaluation is skipped while the
+// metrics can not be read, rather than resolving every alert.
+func (e *Engine) Evaluate(ctx context.Context) {
+	e.publishing.Lock()
+	defer e.publishing.Unlock()
+
+	now := e.now()
+	env, err := e.env(ctx, now)
+	if err != nil {
+		e.logger.Error().Err(err).Msg("failed to evaluate rules")
+		return
+	}
+
+	var fired, resolved []domain.Alert
+	e.mu.Lock()
+	for _, state := range e.rules {
+		matches, err := ruleexpr.EvalVector(ctx, state.expr, env)
+		if err != nil {
+			e.logger.Error().Err(err).Str("rule", state.rule.Name).Msg("failed to evaluate rule")
+			continue
+		}
+
+		matched := make(map[string]bool, len(matches))
+		for _, sample := range matches {
+			matched[sample.MetricID] = true
+
+			since, ok := state.pending[sample.MetricID]
+			if !ok {
+				since = now
+				state.pending[sample.MetricID] = now
+			}
+			if now.Sub(since) < time.Duration(state.rule.For) {
+				continue
+			}
+
+			// Firing again refreshes the value annotation of an already firing alert
+			alert := ruleAlert(state.rule, sample)
+			alert.StartsAt = since
+			state.firing[sample.MetricID] = true
+			fired = append(fired, alert)
+		}
+
+		for id := range state.pending {
+			if matched[id] {
+				continue
+			}
+			delete(state.pending, id)
+			if state.firing[id] {
+				delete(state.firing, id)
+				alert := ruleAlert(state.rule, domain.RuleSample{MetricID: id})
+				alert.EndsAt = now
+				resolved = append(resolved, alert)
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	for _, alert := range fired {
+		e.publisher.Fire(ctx, alert)
+	}
+	for _, alert := range resolved {
+		e.publisher.Resolve(ctx, alert)
+	}
+}
+
+// Run periodically evaluates the rules until shutdownCh is closed.
+func (e *Engine) Run(interval time.Duration, shutdownCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCh:
+			return
+		case <-ticker.C:
+			e.Evaluate(context.Background())
+		}
+	}
+}
+
+// validate checks the rule fields and requires every metric selector of its expression to match an existing metric,
+// and every range to fit into the metric history.
+func (e *Engine) validate(ctx context.Context, rule domain.Rule) (ruleexpr.Expr, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	expr, err := parseCondition(rule.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := e.metrics.GetAllMetrics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	for _, selector := range ruleexpr.Selectors(expr) {
+		if !slices.ContainsFunc(metrics, func(metric domain.Metric) bool { return selector.Matches(metric.ID) }) {
+			return nil, fmt.Errorf("%w: no metric matches %s", domain.ErrInvalidRule, selector)
+		}
+	}
+
+	for _, matrix := range ruleexpr.MatrixSelectors(expr) {
+		if e.history == nil {
+			return nil, fmt.Errorf("%w: %s needs the metric history, which is not available", domain.ErrInvalidRule, matrix)
+		}
+		if retention := e.history.Retention(); matrix.Range > retention {
+			return nil, fmt.Errorf("%w: range of %s exceeds the metric history retention of %s", domain.ErrInvalidRule, matrix, retention)
+		}
+	}
+
+	return expr, nil
+}
+
+func (e *Engine) env(ctx context.Context, now time.Time) (ruleexpr.Env, error) {
+	metrics, err := e.metrics.GetAllMetrics(ctx)
+	if err != nil {
+		return ruleexpr.Env{}, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return ruleexpr.Env{Metrics: metrics, History: e.history, Now: now}, nil
+}
+
+// state returns the state of the rule.
+func (e *Engine) state(name string) (*ruleState, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, ok := e.rules[name]
+	return state, ok
+}
+
+// resolveAll resolves the alerts of a rule state that was replaced. An evaluation in progress still sees the
+// state, it is waited for, so its alerts are published first and resolved here.
+func (e *Engine) resolveAll(ctx context.Context, state *ruleState) {
+	e.publishing.Lock()
+	defer e.publishing.Unlock()
+
+	e.mu.Lock()
+	firing := slices.Collect(maps.Keys(state.firing))
+	e.mu.Unlock()
+
+	now := e.now()
+	for _, id := range firing {
+		alert := ruleAlert(state.rule, domain.RuleSample{MetricID: id})
+		alert.EndsAt = now
+		e.publisher.Resolve(ctx, alert)
+	}
+}
+
+func parseCondition(input string) (ruleexpr.Expr, error) {
+	expr, err := ruleexpr.Parse(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidRule, err)
+	}
+
+	if !ruleexpr.IsCondition(expr) {
+		return nil, fmt.Errorf("%w: expr must be a comparison such as HeapInuse > 1e9 or rate(PollCount[5m]) == 0", domain.ErrInvalidRule)
+	}
+
+	if len(ruleexpr.Selectors(expr)) == 0 {
+		return nil, fmt.Errorf("%w: expr must select at least one metric", domain.ErrInvalidRule)
+	}
+
+	return expr, nil
+}
+
+func newRuleState(rule domain.Rule, expr ruleexpr.Expr) *ruleState {
+	return &ruleState{
+		rule:    rule,
+		expr:    expr,
+		pending: make(map[string]time.Time),
+		firing:  make(map[string]bool),
+	}
+}
+
+// ruleAlert builds the alert of the rule for one metric. The metric ID is added to the rule labels,
+// and the current value to the rule annotations.
+func ruleAlert(rule domain.Rule, sample domain.RuleSample) domain.Alert {
+	labels := maps.Clone(rule.Labels)
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[domain.MetricLabel] = sample.MetricID
+
+	annotations := maps.Clone(rule.Annotations)
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations["value"] = strconv.FormatFloat(sample.Value, 'f', -1, 64)
+
+	return domain.Alert{
+		Name:        rule.Name,
+		Labels:      labels,
+		Annotations: annotations,
+	}
+}
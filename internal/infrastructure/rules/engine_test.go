@@ -0,0 +1,361 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+type recordingPublisher struct {
+	fired    []domain.Alert
+	resolved []domain.Alert
+}
+
+func (p *recordingPublisher) Fire(_ context.Context, alert domain.Alert) {
+	p.fired = append(p.fired, alert)
+}
+
+func (p *recordingPublisher) Resolve(_ context.Context, alert domain.Alert) {
+	p.resolved = append(p.resolved, alert)
+}
+
+// testHistory serves fixed samples by metric ID and keeps them for an hour.
+type testHistory map[string][]domain.Sample
+
+func (h testHistory) Samples(_ context.Context, _ domain.MetricType, metricName string, since time.Time) []domain.Sample {
+	var samples []domain.Sample
+	for _, sample := range h[metricName] {
+		if !sample.Time.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+func (h testHistory) Retention() time.Duration {
+	return time.Hour
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+// unavailableStorage fails to read the metrics, like a database that is down.
+type unavailableStorage struct {
+	domain.MetricStorage
+}
+
+func (unavailableStorage) GetAllMetrics(context.Context) ([]domain.Metric, error) {
+	return nil, fmt.Errorf("%w: connection refused", domain.ErrStorageUnavailable)
+}
+
+func newTestEngine(t *testing.T, storage domain.RuleStorage) (*Engine, domain.MetricStorage, *recordingPublisher, *time.Time) {
+	t.Helper()
+	logger := zerolog.Nop()
+	metrics := metricstorage.NewMemoryMetricStorage()
+	publisher := &recordingPublisher{}
+
+	engine, err := New(context.Background(), storage, metrics, testHistory{}, publisher, &logger)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	engine.now = func() time.Time { return now }
+
+	return engine, metrics, publisher, &now
+}
+
+func TestEngine_CRUD(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, _, _ := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("HeapInuse", 1)))
+
+	tests := []struct {
+		name    string
+		rule    domain.Rule
+		wantErr error
+	}{
+		{name: "valid", rule: domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 1e9"}},
+		{name: "duplicate", rule: domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 1e9"}, wantErr: domain.ErrRuleExists},
+		{name: "invalid name", rule: domain.Rule{Name: "high heap", Expr: "HeapInuse > 1e9"}, wantErr: domain.ErrInvalidRule},
+		{name: "syntax error", rule: domain.Rule{Name: "Broken", Expr: "HeapInuse >"}, wantErr: domain.ErrInvalidRule},
+		{name: "not a condition", rule: domain.Rule{Name: "Plain", Expr: "HeapInuse"}, wantErr: domain.ErrInvalidRule},
+		{name: "no selector", rule: domain.Rule{Name: "Const", Expr: "1 > 0"}, wantErr: domain.ErrInvalidRule},
+		{name: "unknown metric", rule: domain.Rule{Name: "Unknown", Expr: "Missing > 1"}, wantErr: domain.ErrInvalidRule},
+		{name: "negative for", rule: domain.Rule{Name: "Negative", Expr: "HeapInuse > 1", For: domain.Duration(-time.Second)}, wantErr: domain.ErrInvalidRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.CreateRule(ctx, tt.rule)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	rules := engine.ListRules(ctx)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "HighHeap", rules[0].Name)
+
+	updated, err := engine.UpdateRule(ctx, domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 2e9"})
+	require.NoError(t, err)
+	assert.Equal(t, rules[0].CreatedAt, updated.CreatedAt)
+
+	_, err = engine.UpdateRule(ctx, domain.Rule{Name: "Missing", Expr: "HeapInuse > 1"})
+	assert.ErrorIs(t, err, domain.ErrRuleNotFound)
+
+	rule, ok := engine.GetRule(ctx, "HighHeap")
+	require.True(t, ok)
+	assert.Equal(t, "HeapInuse > 2e9", rule.Expr)
+
+	deleted, err := engine.DeleteRule(ctx, "HighHeap")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = engine.DeleteRule(ctx, "HighHeap")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+// blockingStorage holds every rule write until release is closed.
+type blockingStorage struct {
+	domain.RuleStorage
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStorage) SaveRule(ctx context.Context, rule domain.Rule) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.RuleStorage.SaveRule(ctx, rule)
+}
+
+func TestEngine_PersistsOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	storage := blockingStorage{RuleStorage: NewMemoryStorage(), saving: make(chan struct{}), release: make(chan struct{})}
+	engine, metrics, _, _ := newTestEngine(t, storage)
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("HeapInuse", 1)))
+
+	created := make(chan error)
+	go func() {
+		_, err := engine.CreateRule(ctx, domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 1e9"})
+		created <- err
+	}()
+	<-storage.saving
+
+	// The rules are read and evaluated while the write is in progress
+	assert.Empty(t, engine.ListRules(ctx))
+	engine.Evaluate(ctx)
+
+	close(storage.release)
+	require.NoError(t, <-created)
+	assert.Len(t, engine.ListRules(ctx), 1)
+}
+
+// blockingPublisher holds every fired alert until release is closed, and records the order of the alerts.
+type blockingPublisher struct {
+	mu      sync.Mutex
+	order   []string
+	firing  chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPublisher) Fire(_ context.Context, alert domain.Alert) {
+	p.firing <- struct{}{}
+	<-p.release
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.order = append(p.order, "fire "+alert.Labels[domain.MetricLabel])
+}
+
+func (p *blockingPublisher) Resolve(_ context.Context, alert domain.Alert) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.order = append(p.order, "resolve "+alert.Labels[domain.MetricLabel])
+}
+
+func TestEngine_DeleteDuringPublish(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, _, _ := newTestEngine(t, NewMemoryStorage())
+	publisher := &blockingPublisher{firing: make(chan struct{}), release: make(chan struct{})}
+	engine.publisher = publisher
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("HeapInuse", 2e9)))
+	_, err := engine.CreateRule(ctx, domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 1e9"})
+	require.NoError(t, err)
+
+	evaluated := make(chan struct{})
+	go func() {
+		engine.Evaluate(ctx)
+		close(evaluated)
+	}()
+	<-publisher.firing
+
+	// The rule is deleted while its alert is being published, the resolution has to come after it
+	deleted := make(chan error)
+	go func() {
+		_, err := engine.DeleteRule(ctx, "HighHeap")
+		deleted <- err
+	}()
+	select {
+	case <-deleted:
+		t.Fatal("the rule was deleted before the evaluation published its alerts")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(publisher.release)
+	<-evaluated
+	require.NoError(t, <-deleted)
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	assert.Equal(t, []string{"fire HeapInuse", "resolve HeapInuse"}, publisher.order)
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, publisher, now := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetrics(ctx, []domain.Metric{gauge("CPUutilization1", 95), gauge("CPUutilization2", 10)}))
+
+	_, err := engine.CreateRule(ctx, domain.Rule{
+		Name:   "HighCPU",
+		Expr:   "CPUutilization* > 90",
+		For:    domain.Duration(time.Minute),
+		Labels: map[string]string{"severity": "critical"},
+	})
+	require.NoError(t, err)
+
+	engine.Evaluate(ctx)
+	assert.Empty(t, publisher.fired, "the rule has to match for a minute first")
+
+	*now = now.Add(time.Minute)
+	engine.Evaluate(ctx)
+	require.Len(t, publisher.fired, 1)
+	alert := publisher.fired[0]
+	assert.Equal(t, "HighCPU", alert.Name)
+	assert.Equal(t, map[string]string{"severity": "critical", domain.MetricLabel: "CPUutilization1"}, alert.Labels)
+	assert.Equal(t, "95", alert.Annotations["value"])
+	assert.Equal(t, now.Add(-time.Minute), alert.StartsAt)
+
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("CPUutilization1", 50)))
+	*now = now.Add(time.Minute)
+	engine.Evaluate(ctx)
+	require.Len(t, publisher.resolved, 1)
+	assert.Equal(t, alert.Fingerprint(), publisher.resolved[0].Fingerprint())
+	assert.Equal(t, *now, publisher.resolved[0].EndsAt)
+}
+
+func TestEngine_EvaluateStorageOutage(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, publisher, now := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("CPUutilization1", 95)))
+
+	_, err := engine.CreateRule(ctx, domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90"})
+	require.NoError(t, err)
+	engine.Evaluate(ctx)
+	require.Len(t, publisher.fired, 1)
+
+	engine.metrics = unavailableStorage{metrics}
+	*now = now.Add(time.Minute)
+	engine.Evaluate(ctx)
+	assert.Empty(t, publisher.resolved, "alerts stay firing while the metrics can not be read")
+
+	_, err = engine.CreateRule(ctx, domain.Rule{Name: "HighHeap", Expr: "HeapInuse > 1e9"})
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+	_, err = engine.TestRule(ctx, domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90"})
+	assert.ErrorIs(t, err, domain.ErrStorageUnavailable)
+}
+
+func TestEngine_EvaluateRate(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, publisher, now := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetric(ctx, counter("PollCount", 600)))
+
+	_, err := engine.CreateRule(ctx, domain.Rule{Name: "PollingTooFast", Expr: "rate(PollCount[2h]) > 1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidRule, "the range exceeds the history retention")
+
+	_, err = engine.CreateRule(ctx, domain.Rule{Name: "PollingTooFast", Expr: "rate(PollCount[5m]) > 1"})
+	require.NoError(t, err)
+
+	engine.history = testHistory{"PollCount": {
+		{Time: now.Add(-10 * time.Minute), Value: 0},
+		{Time: now.Add(-5 * time.Minute), Value: 150},
+		{Time: *now, Value: 600},
+	}}
+	engine.Evaluate(ctx)
+	require.Len(t, publisher.fired, 1)
+	assert.Equal(t, "1.5", publisher.fired[0].Annotations["value"])
+}
+
+func TestEngine_DeleteResolvesAlerts(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, publisher, _ := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("Alloc", 10)))
+
+	_, err := engine.CreateRule(ctx, domain.Rule{Name: "AllocSet", Expr: "Alloc >= 10"})
+	require.NoError(t, err)
+
+	engine.Evaluate(ctx)
+	require.Len(t, publisher.fired, 1)
+
+	_, err = engine.DeleteRule(ctx, "AllocSet")
+	require.NoError(t, err)
+	require.Len(t, publisher.resolved, 1)
+	assert.Equal(t, "AllocSet", publisher.resolved[0].Name)
+}
+
+func TestEngine_TestRule(t *testing.T) {
+	ctx := context.Background()
+	engine, metrics, publisher, _ := newTestEngine(t, NewMemoryStorage())
+	require.NoError(t, metrics.UpdateMetrics(ctx, []domain.Metric{gauge("CPUutilization1", 95), gauge("CPUutilization2", 10)}))
+
+	result, err := engine.TestRule(ctx, domain.Rule{Name: "HighCPU", Expr: "CPUutilization* > 90", For: domain.Duration(time.Hour)})
+	require.NoError(t, err)
+
+	assert.Len(t, result.Series, 2)
+	assert.Equal(t, []domain.RuleSample{{MetricID: "CPUutilization1", Value: 95}}, result.Matches)
+	require.Len(t, result.Alerts, 1)
+	assert.Equal(t, domain.AlertStateFiring, result.Alerts[0].State)
+
+	assert.Empty(t, engine.ListRules(ctx), "a dry-run must not save the rule")
+	assert.Empty(t, publisher.fired, "a dry-run must not raise alerts")
+
+	_, err = engine.TestRule(ctx, domain.Rule{Name: "Unknown", Expr: "Missing > 1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidRule)
+}
+
+func TestFileStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewFileStorage(filepath.Join(t.TempDir(), "metrics.json.rules"))
+
+	engine, metrics, _, _ := newTestEngine(t, storage)
+	require.NoError(t, metrics.UpdateMetric(ctx, gauge("Alloc", 10)))
+
+	_, err := engine.CreateRule(ctx, domain.Rule{Name: "AllocSet", Expr: "Alloc > 0", For: domain.Duration(5 * time.Minute)})
+	require.NoError(t, err)
+	_, err = engine.CreateRule(ctx, domain.Rule{Name: "AllocHigh", Expr: "Alloc > 100"})
+	require.NoError(t, err)
+	_, err = engine.DeleteRule(ctx, "AllocHigh")
+	require.NoError(t, err)
+
+	reloaded, _, _, _ := newTestEngine(t, storage)
+	rules := reloaded.ListRules(ctx)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "AllocSet", rules[0].Name)
+	assert.Equal(t, domain.Duration(5*time.Minute), rules[0].For)
+}
@@ -0,0 +1,33 @@
+package rules
+
+import (
+	"context"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/jsonfile"
+)
+
+// FileStorage keeps rules in a JSON file, rewriting it on every change.
+type FileStorage struct {
+	store *jsonfile.Store[domain.Rule]
+}
+
+var _ domain.RuleStorage = (*FileStorage)(nil)
+
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{
+		store: jsonfile.New(path, "rules", func(rule domain.Rule) string { return rule.Name }),
+	}
+}
+
+func (s *FileStorage) ListRules(_ context.Context) ([]domain.Rule, error) {
+	return s.store.List()
+}
+
+func (s *FileStorage) SaveRule(_ context.Context, rule domain.Rule) error {
+	return s.store.Save(rule)
+}
+
+func (s *FileStorage) DeleteRule(_ context.Context, name string) (bool, error) {
+	return s.store.Delete(name)
+}
@@ -0,0 +1,48 @@
+package rules
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// MemoryStorage keeps rules in memory only, they are lost on restart.
+type MemoryStorage struct {
+	mu    sync.Mutex
+	rules map[string]domain.Rule
+}
+
+var _ domain.RuleStorage = (*MemoryStorage)(nil)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		rules: make(map[string]domain.Rule),
+	}
+}
+
+func (s *MemoryStorage) ListRules(_ context.Context) ([]domain.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Values(s.rules)), nil
+}
+
+func (s *MemoryStorage) SaveRule(_ context.Context, rule domain.Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rules[rule.Name] = rule
+	return nil
+}
+
+func (s *MemoryStorage) DeleteRule(_ context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.rules[name]
+	delete(s.rules, name)
+	return ok, nil
+}
@@ -13,7 +13,7 @@ func NewGracefulShutdownNotifier() chan struct{} {
 
 	go func() {
 		<-sigint
-		ch <- struct{}{}
+		close(ch)
 	}()
 
 	return ch
@@ -0,0 +1,33 @@
+package silence
+
+import (
+	"context"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/jsonfile"
+)
+
+// FileStorage keeps silences in a JSON file, rewriting it on every change.
+type FileStorage struct {
+	store *jsonfile.Store[domain.Silence]
+}
+
+var _ domain.SilenceStorage = (*FileStorage)(nil)
+
+func NewFileStorage(path string) *FileStorage {
+	return &FileStorage{
+		store: jsonfile.New(path, "silences", func(silence domain.Silence) string { return silence.ID }),
+	}
+}
+
+func (s *FileStorage) ListSilences(_ context.Context) ([]domain.Silence, error) {
+	return s.store.List()
+}
+
+func (s *FileStorage) SaveSilence(_ context.Context, silence domain.Silence) error {
+	return s.store.Save(silence)
+}
+
+func (s *FileStorage) DeleteSilence(_ context.Context, id string) (bool, error) {
+	return s.store.Delete(id)
+}
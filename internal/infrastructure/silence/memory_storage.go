@@ -0,0 +1,48 @@
+package silence
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// MemoryStorage keeps silences in memory only, they are lost on restart.
+type MemoryStorage struct {
+	mu       sync.Mutex
+	silences map[string]domain.Silence
+}
+
+var _ domain.SilenceStorage = (*MemoryStorage)(nil)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		silences: make(map[string]domain.Silence),
+	}
+}
+
+func (s *MemoryStorage) ListSilences(_ context.Context) ([]domain.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Collect(maps.Values(s.silences)), nil
+}
+
+func (s *MemoryStorage) SaveSilence(_ context.Context, silence domain.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.silences[silence.ID] = silence
+	return nil
+}
+
+func (s *MemoryStorage) DeleteSilence(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.silences[id]
+	delete(s.silences, id)
+	return ok, nil
+}
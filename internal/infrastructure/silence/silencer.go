@@ -0,0 +1,232 @@
+package silence
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// Silencer manages silences and decides which alerts they mute.
+// All silences are kept in memory, the storage is only used to persist changes.
+type Silencer struct {
+	storage domain.SilenceStorage
+	history domain.HistoryRecorder
+	logger  *zerolog.Logger
+	now     func() time.Time
+	// writes serializes the silence changes, which are persisted without holding mu so SilencedBy does not wait
+	// for the storage
+	writes   sync.Mutex
+	mu       sync.RWMutex
+	silences map[string]domain.Silence
+}
+
+var _ domain.SilenceManager = (*Silencer)(nil)
+var _ domain.AlertSilencer = (*Silencer)(nil)
+
+func New(ctx context.Context, storage domain.SilenceStorage, history domain.HistoryRecorder, logger *zerolog.Logger) (*Silencer, error) {
+	silences, err := storage.ListSilences(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load silences: %w", err)
+	}
+
+	s := &Silencer{
+		storage:  storage,
+		history:  history,
+		logger:   logger,
+		now:      time.Now,
+		silences: make(map[string]domain.Silence, len(silences)),
+	}
+	for _, silence := range silences {
+		s.silences[silence.ID] = silence
+	}
+
+	return s, nil
+}
+
+// ListSilences returns all silences ordered by start time.
+func (s *Silencer) ListSilences(_ context.Context) []domain.Silence {
+	now := s.now()
+
+	s.mu.RLock()
+	silences := make([]domain.Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		silence.State = silence.StateAt(now)
+		silences = append(silences, silence)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(silences, func(a, b domain.Silence) int {
+		if c := a.StartsAt.Compare(b.StartsAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return silences
+}
+
+func (s *Silencer) GetSilence(_ context.Context, id string) (domain.Silence, bool) {
+	s.mu.RLock()
+	silence, ok := s.silences[id]
+	s.mu.RUnlock()
+
+	silence.State = silence.StateAt(s.now())
+	return silence, ok
+}
+
+// CreateSilence assigns an ID to the silence and stores it. A silence without a start time starts immediately.
+func (s *Silencer) CreateSilence(ctx context.Context, silence domain.Silence) (domain.Silence, error) {
+	now := s.now()
+
+	silence.ID = newID()
+	silence.CreatedAt = now
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+
+	s.writes.Lock()
+	err := s.save(ctx, silence)
+	s.writes.Unlock()
+	if err != nil {
+		return domain.Silence{}, err
+	}
+
+	s.logger.Info().Str("silence_id", silence.ID).Str("created_by", silence.CreatedBy).Msg("silence created")
+	s.record(ctx, domain.HistorySilenceCreated, silence, now)
+
+	silence.State = silence.StateAt(now)
+	return silence, nil
+}
+
+// UpdateSilence replaces the silence, keeping its creation time and, without a new one, its start time.
+func (s *Silencer) UpdateSilence(ctx context.Context, silence domain.Silence) (domain.Silence, error) {
+	s.writes.Lock()
+	// The silence can not be deleted until the update is stored
+	existing, ok := s.silence(silence.ID)
+	if !ok {
+		s.writes.Unlock()
+		return domain.Silence{}, fmt.Errorf("%w: %s", domain.ErrSilenceNotFound, silence.ID)
+	}
+
+	silence.CreatedAt = existing.CreatedAt
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = existing.StartsAt
+	}
+
+	err := s.save(ctx, silence)
+	s.writes.Unlock()
+	if err != nil {
+		return domain.Silence{}, err
+	}
+
+	s.logger.Info().Str("silence_id", silence.ID).Msg("silence updated")
+	s.record(ctx, domain.HistorySilenceUpdated, silence, s.now())
+
+	silence.State = silence.StateAt(s.now())
+	return silence, nil
+}
+
+func (s *Silencer) DeleteSilence(ctx context.Context, id string) (bool, error) {
+	s.writes.Lock()
+	silence, ok := s.silence(id)
+	if !ok {
+		s.writes.Unlock()
+		return false, nil
+	}
+
+	if _, err := s.storage.DeleteSilence(ctx, id); err != nil {
+		s.writes.Unlock()
+		return false, fmt.Errorf("failed to delete silence: %w", err)
+	}
+	s.mu.Lock()
+	delete(s.silences, id)
+	s.mu.Unlock()
+	s.writes.Unlock()
+
+	s.logger.Info().Str("silence_id", id).Msg("silence deleted")
+	s.record(ctx, domain.HistorySilenceDeleted, silence, s.now())
+
+	return true, nil
+}
+
+// SilencedBy returns the IDs of the active silences matching the alert.
+func (s *Silencer) SilencedBy(alert domain.Alert) []string {
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id, silence := range s.silences {
+		if silence.StateAt(now) == domain.SilenceStateActive && silence.Matches(alert) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+// silence returns the stored silence without its state.
+func (s *Silencer) silence(id string) (domain.Silence, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	silence, ok := s.silences[id]
+	return silence, ok
+}
+
+// save persists the silence and then makes it effective. It must be called with writes held.
+func (s *Silencer) save(ctx context.Context, silence domain.Silence) error {
+	silence.State = ""
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.storage.SaveSilence(ctx, silence); err != nil {
+		return fmt.Errorf("failed to save silence: %w", err)
+	}
+
+	s.mu.Lock()
+	s.silences[silence.ID] = silence
+	s.mu.Unlock()
+
+	return nil
+}
+
+// record adds a silence change to the history. The actor is the author of the silence,
+// as the API does not authenticate who changes it.
+func (s *Silencer) record(ctx context.Context, eventType domain.HistoryEventType, silence domain.Silence, at time.Time) {
+	matchers := make([]string, len(silence.Matchers))
+	for i, matcher := range silence.Matchers {
+		matchers[i] = matcher.Name + string(matcher.Operator) + strconv.Quote(matcher.Value)
+	}
+
+	s.history.Record(ctx, domain.HistoryEvent{
+		Time:      at,
+		Type:      eventType,
+		SilenceID: silence.ID,
+		Actor:     silence.CreatedBy,
+		Message:   fmt.Sprintf("{%s} until %s: %s", strings.Join(matchers, ","), silence.EndsAt.UTC().Format(time.RFC3339), silence.Comment),
+	})
+}
+
+// newID returns a random UUID v4.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
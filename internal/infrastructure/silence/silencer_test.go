@@ -0,0 +1,231 @@
+package silence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/history"
+)
+
+func agentSilence(agent string, duration time.Duration) domain.Silence {
+	return domain.Silence{
+		Matchers: []domain.Matcher{
+			{Name: domain.AlertNameLabel, Operator: domain.MatchEqual, Value: "AgentAbsent"},
+			{Name: "agent", Operator: domain.MatchRegexp, Value: agent},
+		},
+		EndsAt:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).Add(duration),
+		CreatedBy: "ops",
+		Comment:   "deploy",
+	}
+}
+
+func TestSilencer_CRUD(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	storage := NewMemoryStorage()
+	events := history.NewMemoryStorage()
+	silencer, err := New(ctx, storage, history.NewRecorder(events, &logger), &logger)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	silencer.now = func() time.Time { return now }
+
+	created, err := silencer.CreateSilence(ctx, agentSilence("web-.*", time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, created.ID, 36)
+	assert.Equal(t, now, created.StartsAt)
+	assert.Equal(t, now, created.CreatedAt)
+	assert.Equal(t, domain.SilenceStateActive, created.State)
+
+	_, err = silencer.CreateSilence(ctx, domain.Silence{CreatedBy: "ops"})
+	assert.ErrorIs(t, err, domain.ErrInvalidSilence)
+
+	stored, err := storage.ListSilences(ctx)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Empty(t, stored[0].State, "state is computed, not stored")
+
+	update := agentSilence("db-.*", 2*time.Hour)
+	update.ID = created.ID
+	updated, err := silencer.UpdateSilence(ctx, update)
+	require.NoError(t, err)
+	assert.Equal(t, created.StartsAt, updated.StartsAt)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+
+	missing := agentSilence("db-.*", time.Hour)
+	missing.ID = "missing"
+	_, err = silencer.UpdateSilence(ctx, missing)
+	assert.ErrorIs(t, err, domain.ErrSilenceNotFound)
+
+	got, ok := silencer.GetSilence(ctx, created.ID)
+	require.True(t, ok)
+	assert.Equal(t, "db-.*", got.Matchers[1].Value)
+
+	now = now.Add(3 * time.Hour)
+	silences := silencer.ListSilences(ctx)
+	require.Len(t, silences, 1)
+	assert.Equal(t, domain.SilenceStateExpired, silences[0].State)
+
+	deleted, err := silencer.DeleteSilence(ctx, created.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = silencer.DeleteSilence(ctx, created.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	assert.Empty(t, silencer.ListSilences(ctx))
+
+	audit, err := events.QueryEvents(ctx, domain.HistoryFilter{SilenceID: created.ID})
+	require.NoError(t, err)
+	require.Len(t, audit, 3)
+	assert.Equal(t, domain.HistorySilenceDeleted, audit[0].Type)
+	assert.Equal(t, domain.HistorySilenceUpdated, audit[1].Type)
+	assert.Equal(t, domain.HistorySilenceCreated, audit[2].Type)
+	assert.Equal(t, "ops", audit[2].Actor)
+	assert.Contains(t, audit[2].Message, `agent=~"web-.*"`)
+}
+
+func TestSilencer_SilencedBy(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	silencer, err := New(ctx, NewMemoryStorage(), history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	silencer.now = func() time.Time { return now }
+
+	web, err := silencer.CreateSilence(ctx, agentSilence("web-.*", time.Hour))
+	require.NoError(t, err)
+
+	pending := agentSilence("web-1", 3*time.Hour)
+	pending.StartsAt = now.Add(time.Hour)
+	later, err := silencer.CreateSilence(ctx, pending)
+	require.NoError(t, err)
+
+	webAlert := domain.Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "web-1"}}
+	dbAlert := domain.Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "db-1"}}
+	anomaly := domain.Alert{Name: "MetricAnomaly", Labels: map[string]string{"agent": "web-1"}}
+
+	assert.Equal(t, []string{web.ID}, silencer.SilencedBy(webAlert))
+	assert.Empty(t, silencer.SilencedBy(dbAlert))
+	assert.Empty(t, silencer.SilencedBy(anomaly))
+
+	now = now.Add(90 * time.Minute)
+	assert.Equal(t, []string{later.ID}, silencer.SilencedBy(webAlert), "the first silence expired, the second started")
+}
+
+// blockingStorage holds every silence write until release is closed.
+type blockingStorage struct {
+	domain.SilenceStorage
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStorage) SaveSilence(ctx context.Context, silence domain.Silence) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.SilenceStorage.SaveSilence(ctx, silence)
+}
+
+func TestSilencer_PersistsOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	storage := blockingStorage{SilenceStorage: NewMemoryStorage(), saving: make(chan struct{}), release: make(chan struct{})}
+	silencer, err := New(ctx, storage, history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	silencer.now = func() time.Time { return now }
+
+	created := make(chan error)
+	go func() {
+		_, err := silencer.CreateSilence(ctx, agentSilence("web-.*", time.Hour))
+		created <- err
+	}()
+	<-storage.saving
+
+	// Alerts are checked while the write is in progress
+	webAlert := domain.Alert{Name: "AgentAbsent", Labels: map[string]string{"agent": "web-1"}}
+	assert.Empty(t, silencer.SilencedBy(webAlert))
+
+	close(storage.release)
+	require.NoError(t, <-created)
+	assert.Len(t, silencer.SilencedBy(webAlert), 1)
+}
+
+func TestSilencer_UpdateDeleted(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	existing := agentSilence("web-.*", time.Hour)
+	existing.ID = "web"
+	existing.StartsAt = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	memory := NewMemoryStorage()
+	require.NoError(t, memory.SaveSilence(ctx, existing))
+
+	storage := blockingStorage{SilenceStorage: memory, saving: make(chan struct{}), release: make(chan struct{})}
+	silencer, err := New(ctx, storage, history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+
+	update := agentSilence("db-.*", time.Hour)
+	update.ID = existing.ID
+	updated := make(chan error)
+	go func() {
+		_, err := silencer.UpdateSilence(ctx, update)
+		updated <- err
+	}()
+	<-storage.saving
+
+	// The deletion waits for the update in progress, so the updated silence does not come back
+	deleted := make(chan bool)
+	go func() {
+		ok, err := silencer.DeleteSilence(ctx, existing.ID)
+		assert.NoError(t, err)
+		deleted <- ok
+	}()
+
+	close(storage.release)
+	require.NoError(t, <-updated)
+	assert.True(t, <-deleted)
+
+	_, ok := silencer.GetSilence(ctx, existing.ID)
+	assert.False(t, ok)
+	stored, err := memory.ListSilences(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+
+	_, err = silencer.UpdateSilence(ctx, update)
+	assert.ErrorIs(t, err, domain.ErrSilenceNotFound)
+}
+
+func TestFileStorage(t *testing.T) {
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	path := filepath.Join(t.TempDir(), "metrics.dump.silences")
+
+	silencer, err := New(ctx, NewFileStorage(path), history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+	silencer.now = func() time.Time { return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC) }
+	assert.Empty(t, silencer.ListSilences(ctx))
+
+	first, err := silencer.CreateSilence(ctx, agentSilence("web-.*", time.Hour))
+	require.NoError(t, err)
+	second, err := silencer.CreateSilence(ctx, agentSilence("db-.*", time.Hour))
+	require.NoError(t, err)
+	_, err = silencer.DeleteSilence(ctx, first.ID)
+	require.NoError(t, err)
+
+	restored, err := New(ctx, NewFileStorage(path), history.NewRecorder(history.NewMemoryStorage(), &logger), &logger)
+	require.NoError(t, err)
+
+	silences := restored.ListSilences(ctx)
+	require.Len(t, silences, 1)
+	assert.Equal(t, second.ID, silences[0].ID)
+	assert.Equal(t, second.Matchers, silences[0].Matchers)
+	assert.True(t, second.EndsAt.Equal(silences[0].EndsAt))
+}
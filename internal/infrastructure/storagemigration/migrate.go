@@ -0,0 +1,150 @@
+package storagemigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+)
+
+// DefaultBatchSize is the number of metrics written with one UpdateMetrics call.
+const DefaultBatchSize = 1000
+
+var (
+	ErrTargetNotEmpty     = errors.New("target storage is not empty")
+	ErrVerificationFailed = errors.New("migrated metrics do not match the source")
+)
+
+type Options struct {
+	// BatchSize is the number of metrics written at once, DefaultBatchSize if not positive.
+	BatchSize int
+	// Overwrite allows migrating into a storage that already has metrics. Metrics of the source replace the ones
+	// with the same type and ID, the others are kept.
+	Overwrite bool
+}
+
+// Report counts the migrated metrics.
+type Report struct {
+	Gauges   int `json:"gauges"`
+	Counters int `json:"counters"`
+	// Replaced is the number of target metrics overwritten by the source.
+	Replaced int `json:"replaced"`
+	// Verified is the number of metrics read back from the target with the source value.
+	Verified int `json:"verified"`
+}
+
+type metricKey struct {
+	mType domain.MetricType
+	id    string
+}
+
+// Migrate copies all metrics of the source storage to the target and reads them back to verify that every gauge
+// value and counter total arrived unchanged. Counters are added up by the storages, so an existing counter of
+// the target is brought to the source total by writing the difference, it is never deleted.
+// The migration is not atomic. A failure leaves the metrics of the batches written so far migrated and the others
+// as they were, running it again with Overwrite completes it.
+func Migrate(ctx context.Context, from, to domain.MetricStorage, options Options) (Report, error) {
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	current, err := to.GetAllMetrics(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read target: %w", err)
+	}
+	existing := make(map[metricKey]domain.Metric)
+	for _, metric := range current {
+		existing[metricKey{mType: metric.MType, id: metric.ID}] = metric
+	}
+	if len(existing) > 0 && !options.Overwrite {
+		return Report{}, fmt.Errorf("%w: it has %d metrics", ErrTargetNotEmpty, len(existing))
+	}
+
+	metrics, err := from.GetAllMetrics(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to read source: %w", err)
+	}
+
+	var report Report
+	for start := 0; start < len(metrics); start += batchSize {
+		batch := make([]domain.Metric, 0, min(batchSize, len(metrics)-start))
+
+		for _, metric := range metrics[start:min(start+batchSize, len(metrics))] {
+			switch metric.MType {
+			case domain.MetricTypeGauge:
+				report.Gauges++
+			case domain.MetricTypeCounter:
+				report.Counters++
+			default:
+				return report, fmt.Errorf("metric %s has unsupported type %q", metric.ID, metric.MType)
+			}
+
+			target, found := existing[metricKey{mType: metric.MType, id: metric.ID}]
+			if found {
+				report.Replaced++
+				if metric.MType == domain.MetricTypeCounter {
+					delta := *metric.Delta - *target.Delta
+					metric.Delta = &delta
+				}
+			}
+			batch = append(batch, metric)
+		}
+
+		if err := to.UpdateMetrics(ctx, batch); err != nil {
+			return report, fmt.Errorf("failed to write metrics %d to %d: %w", start+1, start+len(batch), err)
+		}
+	}
+
+	verified, err := verify(ctx, metrics, to, len(existing)-report.Replaced)
+	report.Verified = verified
+	return report, err
+}
+
+// verify reads the target back and compares it with the source metrics. The target may hold kept metrics
+// in addition to the migrated ones.
+func verify(ctx context.Context, metrics []domain.Metric, to domain.MetricStorage, kept int) (int, error) {
+	current, err := to.GetAllMetrics(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read target back: %w", err)
+	}
+	stored := make(map[metricKey]domain.Metric)
+	for _, metric := range current {
+		stored[metricKey{mType: metric.MType, id: metric.ID}] = metric
+	}
+
+	var errs []error
+	verified := 0
+	for _, metric := range metrics {
+		target, found := stored[metricKey{mType: metric.MType, id: metric.ID}]
+		switch {
+		case !found:
+			errs = append(errs, fmt.Errorf("%s %s is missing", metric.MType, metric.ID))
+		case !sameValue(metric, target):
+			errs = append(errs, fmt.Errorf("%s %s is %s, expected %s", metric.MType, metric.ID, target.StringValue(), metric.StringValue()))
+		default:
+			verified++
+		}
+	}
+
+	if expected := len(metrics) + kept; len(stored) != expected {
+		errs = append(errs, fmt.Errorf("target has %d metrics, expected %d", len(stored), expected))
+	}
+
+	if len(errs) > 0 {
+		return verified, fmt.Errorf("%w: %w", ErrVerificationFailed, errors.Join(errs...))
+	}
+	return verified, nil
+}
+
+func sameValue(a, b domain.Metric) bool {
+	switch a.MType {
+	case domain.MetricTypeGauge:
+		return a.Value != nil && b.Value != nil && *a.Value == *b.Value
+	case domain.MetricTypeCounter:
+		return a.Delta != nil && b.Delta != nil && *a.Delta == *b.Delta
+	default:
+		return false
+	}
+}
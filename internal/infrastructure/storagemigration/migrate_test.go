@@ -0,0 +1,118 @@
+package storagemigration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/angryscorp/alert-metrics/internal/domain"
+	"github.com/angryscorp/alert-metrics/internal/domain/domaintest"
+	"github.com/angryscorp/alert-metrics/internal/infrastructure/metricstorage"
+)
+
+func gauge(id string, value float64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeGauge, Value: &value}
+}
+
+func counter(id string, delta int64) domain.Metric {
+	return domain.Metric{ID: id, MType: domain.MetricTypeCounter, Delta: &delta}
+}
+
+// lossyStorage drops the updates of Sys, like a broken backend.
+type lossyStorage struct {
+	*metricstorage.MemoryMetricStorage
+}
+
+func (s lossyStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	for _, metric := range metrics {
+		if metric.ID == "Sys" {
+			continue
+		}
+		if err := s.UpdateMetric(ctx, metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sourceStorage(t *testing.T) *metricstorage.MemoryMetricStorage {
+	t.Helper()
+	source := metricstorage.NewMemoryMetricStorage()
+	updatedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	alloc := gauge("Alloc", 0.1+0.2)
+	alloc.UpdatedAt = updatedAt
+	require.NoError(t, source.UpdateMetrics(context.Background(), []domain.Metric{
+		alloc, gauge("Sys", -1e300), counter("PollCount", 1<<62), counter("PollCount", 7), counter("Requests", 3),
+	}))
+	return source
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	source := sourceStorage(t)
+	target := metricstorage.NewMemoryMetricStorage()
+
+	report, err := Migrate(ctx, source, target, Options{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, Report{Gauges: 2, Counters: 2, Verified: 4}, report)
+
+	pollCount, _ := domaintest.GetMetric(t, target, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(1<<62+7), *pollCount.Delta, "counter totals are copied exactly")
+	alloc, _ := domaintest.GetMetric(t, target, domain.MetricTypeGauge, "Alloc")
+	assert.Equal(t, 0.1+0.2, *alloc.Value)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), alloc.UpdatedAt, "update times are kept")
+
+	_, err = Migrate(ctx, source, target, Options{})
+	assert.ErrorIs(t, err, ErrTargetNotEmpty)
+}
+
+func TestMigrate_Overwrite(t *testing.T) {
+	ctx := context.Background()
+	source := sourceStorage(t)
+	target := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, target.UpdateMetrics(ctx, []domain.Metric{counter("PollCount", 100), gauge("Alloc", 5), gauge("Kept", 1)}))
+
+	report, err := Migrate(ctx, source, target, Options{Overwrite: true})
+	require.NoError(t, err)
+	assert.Equal(t, Report{Gauges: 2, Counters: 2, Replaced: 2, Verified: 4}, report)
+
+	pollCount, _ := domaintest.GetMetric(t, target, domain.MetricTypeCounter, "PollCount")
+	assert.Equal(t, int64(1<<62+7), *pollCount.Delta, "the source total replaces the target counter instead of adding up")
+	_, found := domaintest.GetMetric(t, target, domain.MetricTypeGauge, "Kept")
+	assert.True(t, found)
+}
+
+func TestMigrate_VerificationFails(t *testing.T) {
+	ctx := context.Background()
+
+	report, err := Migrate(ctx, sourceStorage(t), lossyStorage{metricstorage.NewMemoryMetricStorage()}, Options{BatchSize: 2})
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	assert.Equal(t, 3, report.Verified)
+	assert.Contains(t, err.Error(), "gauge Sys is missing")
+}
+
+// failingStorage rejects all updates, like a backend that went down.
+type failingStorage struct {
+	*metricstorage.MemoryMetricStorage
+}
+
+func (s failingStorage) UpdateMetrics(ctx context.Context, metrics []domain.Metric) error {
+	return errors.New("storage is down")
+}
+
+func TestMigrate_FailedOverwriteKeepsTarget(t *testing.T) {
+	ctx := context.Background()
+	target := metricstorage.NewMemoryMetricStorage()
+	require.NoError(t, target.UpdateMetric(ctx, counter("PollCount", 100)))
+
+	_, err := Migrate(ctx, sourceStorage(t), failingStorage{target}, Options{Overwrite: true})
+	require.Error(t, err)
+
+	pollCount, found := domaintest.GetMetric(t, target, domain.MetricTypeCounter, "PollCount")
+	require.True(t, found, "a failed write does not lose the counters of the target")
+	assert.Equal(t, int64(100), *pollCount.Delta)
+}
@@ -0,0 +1,54 @@
+package resilience
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+const defaultMultiplier = 2
+
+// Backoff configures the delays between the attempts of an operation: exponential growth from Initial up to Max,
+// with a random part so that clients failing together do not retry together.
+type Backoff struct {
+	// Retries is the number of attempts after the first one.
+	Retries int
+	// Initial is the delay before the first retry.
+	Initial time.Duration
+	// Max caps the delay, including a delay requested by the failed operation.
+	Max time.Duration
+	// Multiplier grows the delay with every retry, 2 if not set.
+	Multiplier float64
+	// Jitter is the fraction of the delay that is random, from 0 for fixed delays to 1.
+	Jitter float64
+}
+
+// Delay returns the delay before the retry with the given number, starting at 1. Random is a number in [0, 1)
+// taking off up to the Jitter fraction of the delay.
+func (b Backoff) Delay(retry int, random float64) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultMultiplier
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(retry-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	jitter := min(max(b.Jitter, 0), 1)
+	return time.Duration(delay * (1 - jitter*random))
+}
+
+// sleep waits for the delay, returning early with the context error when ctx is done.
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
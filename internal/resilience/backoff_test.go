@@ -0,0 +1,31 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  Backoff
+		retry    int
+		random   float64
+		expected time.Duration
+	}{
+		{name: "first retry", backoff: Backoff{Initial: time.Second}, retry: 1, expected: time.Second},
+		{name: "doubles by default", backoff: Backoff{Initial: time.Second}, retry: 3, expected: 4 * time.Second},
+		{name: "custom multiplier", backoff: Backoff{Initial: time.Second, Multiplier: 3}, retry: 3, expected: 9 * time.Second},
+		{name: "capped", backoff: Backoff{Initial: time.Second, Max: 5 * time.Second}, retry: 10, expected: 5 * time.Second},
+		{name: "jitter takes off a part", backoff: Backoff{Initial: time.Second, Jitter: 0.5}, retry: 1, random: 0.5, expected: 750 * time.Millisecond},
+		{name: "jitter is at most the delay", backoff: Backoff{Initial: time.Second, Jitter: 2}, retry: 1, random: 0.5, expected: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.backoff.Delay(tt.retry, tt.random))
+		})
+	}
+}
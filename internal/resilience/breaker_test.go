@@ -0,0 +1,47 @@
+package resilience
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	breaker := NewBreaker(2, time.Minute)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+
+	assert.NoError(t, breaker.Allow())
+	breaker.Failure()
+	breaker.Success()
+	breaker.Failure()
+	assert.NoError(t, breaker.Allow(), "only consecutive failures count")
+
+	breaker.Failure()
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen)
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow(), "a probe is let through after the cooldown")
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen, "only one probe at a time")
+	breaker.Failure()
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen, "a failed probe opens the breaker again")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Allow())
+	breaker.Success()
+	assert.NoError(t, breaker.Allow())
+	assert.NoError(t, breaker.Allow())
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	var nilBreaker *Breaker
+	assert.NoError(t, nilBreaker.Allow())
+	nilBreaker.Failure()
+
+	breaker := NewBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	assert.NoError(t, breaker.Allow())
+}
@@ -0,0 +1,159 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Class tells how a failed call is handled.
+type Class int
+
+const (
+	// Permanent errors come from a dependency that handled the call, retrying does not help.
+	Permanent Class = iota
+	// Retryable errors come from a failing dependency and the call was not applied, so it may be repeated.
+	Retryable
+	// Unavailable errors come from a failing dependency, but the call may have been applied and is not repeated.
+	Unavailable
+)
+
+// Classifier classifies an error returned by a call.
+type Classifier func(error) Class
+
+// Idempotent classifies the Unavailable errors as Retryable, for calls that can be applied twice.
+func Idempotent(classify Classifier) Classifier {
+	return func(err error) Class {
+		if class := classify(err); class != Unavailable {
+			return class
+		}
+		return Retryable
+	}
+}
+
+// retryablePgCodes are the SQLSTATE codes besides the connection exceptions of class 08 that mean the statement
+// was not applied, but may succeed later.
+var retryablePgCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"53000": true, // insufficient_resources
+	"53300": true, // too_many_connections
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+}
+
+// ClassifyPgError classifies an error of a Postgres statement. Server errors are Retryable for lost connections,
+// shutdowns, overload and aborted transactions, failures to connect are Retryable too. A connection lost after
+// the statement was sent is Unavailable, it may have been committed.
+func ClassifyPgError(err error) Class {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Permanent
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if strings.HasPrefix(pgErr.Code, "08") || retryablePgCodes[pgErr.Code] {
+			return Retryable
+		}
+		return Permanent
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return Retryable
+	}
+
+	if isNetworkError(err) {
+		return Unavailable
+	}
+
+	return Permanent
+}
+
+// StatusError reports an HTTP response with a status worth retrying the request for.
+type StatusError struct {
+	StatusCode int
+	// After is the delay requested by the Retry-After header, zero if there is none.
+	After time.Duration
+}
+
+// NewStatusError returns the error for the response, with the delay of a Retry-After header in seconds.
+func NewStatusError(resp *http.Response) *StatusError {
+	err := &StatusError{StatusCode: resp.StatusCode}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+		err.After = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server responded with %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// RetryDelay is the minimum delay before the next attempt.
+func (e *StatusError) RetryDelay() time.Duration {
+	return e.After
+}
+
+// RetryableStatus tells whether a request may succeed later after a response with the status: server errors
+// other than 501 Not Implemented, and 429 Too Many Requests.
+func RetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		statusCode >= http.StatusInternalServerError && statusCode != http.StatusNotImplemented
+}
+
+// ClassifyHTTPError classifies an error of an HTTP round trip. Failures to connect and StatusError with a retryable
+// status are Retryable. Other network errors are Unavailable, like in ClassifyPgError, as the request may have
+// reached the server before the connection was lost. Cancellation and errors of the request itself are Permanent.
+func ClassifyHTTPError(err error) Class {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Permanent
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if RetryableStatus(statusErr.StatusCode) {
+			return Retryable
+		}
+		return Permanent
+	}
+
+	if isDialError(err) {
+		return Retryable
+	}
+
+	if isNetworkError(err) {
+		return Unavailable
+	}
+
+	return Permanent
+}
+
+// isDialError tells whether the connection could not be established, so nothing was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) && opErr.Op == "dial" ||
+		errors.As(err, &dnsErr) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
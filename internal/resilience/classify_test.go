@@ -0,0 +1,82 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyPgError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Class
+	}{
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, expected: Retryable},
+		{name: "serialization failure", err: fmt.Errorf("failed to update metrics: %w", &pgconn.PgError{Code: "40001"}), expected: Retryable},
+		{name: "server shutting down", err: &pgconn.PgError{Code: "57P01"}, expected: Retryable},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, expected: Retryable},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, expected: Permanent},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, expected: Permanent},
+		{name: "failed to connect", err: &pgconn.ConnectError{}, expected: Retryable},
+		{name: "connection lost", err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, expected: Unavailable},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, expected: Unavailable},
+		{name: "canceled", err: context.Canceled, expected: Permanent},
+		{name: "other", err: errors.New("unsupported metric type"), expected: Permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyPgError(tt.err))
+		})
+	}
+
+	assert.Equal(t, Retryable, Idempotent(ClassifyPgError)(io.ErrUnexpectedEOF))
+	assert.Equal(t, Permanent, Idempotent(ClassifyPgError)(context.Canceled))
+}
+
+func TestClassifyHTTPError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected Class
+	}{
+		{name: "service unavailable", err: &StatusError{StatusCode: http.StatusServiceUnavailable}, expected: Retryable},
+		{name: "too many requests", err: &StatusError{StatusCode: http.StatusTooManyRequests}, expected: Retryable},
+		{name: "not implemented", err: &StatusError{StatusCode: http.StatusNotImplemented}, expected: Permanent},
+		{name: "bad request", err: &StatusError{StatusCode: http.StatusBadRequest}, expected: Permanent},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, expected: Retryable},
+		{name: "unknown host", err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "metrics.local"}}, expected: Retryable},
+		{name: "connection reset", err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}, expected: Unavailable},
+		{name: "response lost", err: fmt.Errorf("read response: %w", io.ErrUnexpectedEOF), expected: Unavailable},
+		{name: "deadline exceeded", err: fmt.Errorf("request: %w", context.DeadlineExceeded), expected: Permanent},
+		{name: "request error", err: errors.New("failed to encrypt body"), expected: Permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ClassifyHTTPError(tt.err))
+		})
+	}
+
+	assert.Equal(t, Retryable, Idempotent(ClassifyHTTPError)(io.ErrUnexpectedEOF))
+}
+
+func TestNewStatusError(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+	err := NewStatusError(resp)
+	assert.Equal(t, 3*time.Second, err.RetryDelay())
+	assert.Equal(t, "server responded with 429 Too Many Requests", err.Error())
+
+	resp.Header.Set("Retry-After", "Wed, 21 Oct 2015 07:28:00 GMT")
+	assert.Zero(t, NewStatusError(resp).RetryDelay(), "only delays in seconds are supported")
+}
@@ -0,0 +1,85 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Retrier runs calls to a dependency with retries and a circuit breaker.
+type Retrier struct {
+	backoff  Backoff
+	breaker  *Breaker
+	classify Classifier
+	logger   *zerolog.Logger
+	random   func() float64
+}
+
+// NewRetrier retries the Retryable errors with the backoff. The breaker, which may be nil and may be shared by
+// several retriers of the same dependency, counts the Retryable and Unavailable errors as failures.
+func NewRetrier(backoff Backoff, breaker *Breaker, classify Classifier, logger *zerolog.Logger) *Retrier {
+	return &Retrier{
+		backoff:  backoff,
+		breaker:  breaker,
+		classify: classify,
+		logger:   logger,
+		random:   rand.Float64,
+	}
+}
+
+// Do calls op until it succeeds, fails with an error that is not Retryable or the retries are used up, and returns
+// the error of the last attempt. While ctx is done or the breaker is open no further attempt is made, the error
+// then wraps ErrCircuitOpen or the error of the last attempt.
+func (r *Retrier) Do(ctx context.Context, op func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			delay := r.delay(attempt, err)
+			r.logger.Warn().Err(err).Int("retry", attempt).Dur("delay", delay).Msg("retrying failed call")
+			if sleep(ctx, delay) != nil {
+				return err
+			}
+		}
+
+		if openErr := r.breaker.Allow(); openErr != nil {
+			if err != nil {
+				return fmt.Errorf("%w: %w", openErr, err)
+			}
+			return openErr
+		}
+
+		err = op()
+		class := Permanent
+		if err != nil {
+			class = r.classify(err)
+		}
+		if class == Permanent {
+			r.breaker.Success()
+		} else {
+			r.breaker.Failure()
+		}
+
+		if class != Retryable || attempt >= r.backoff.Retries {
+			return err
+		}
+	}
+}
+
+// delay returns the backoff delay of the retry, or the longer delay requested by the error up to the maximum.
+func (r *Retrier) delay(retry int, err error) time.Duration {
+	delay := r.backoff.Delay(retry, r.random())
+
+	var requested interface{ RetryDelay() time.Duration }
+	if errors.As(err, &requested) && requested.RetryDelay() > delay {
+		delay = requested.RetryDelay()
+		if r.backoff.Max > 0 {
+			delay = min(delay, r.backoff.Max)
+		}
+	}
+
+	return delay
+}
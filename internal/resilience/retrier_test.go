@@ -0,0 +1,130 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errRetryable   = errors.New("retryable")
+	errUnavailable = errors.New("unavailable")
+	errPermanent   = errors.New("permanent")
+)
+
+func testClassifier(err error) Class {
+	switch {
+	case errors.Is(err, errRetryable):
+		return Retryable
+	case errors.Is(err, errUnavailable):
+		return Unavailable
+	default:
+		return Permanent
+	}
+}
+
+func newTestRetrier(retries int, breaker *Breaker) *Retrier {
+	logger := zerolog.Nop()
+	return NewRetrier(Backoff{Retries: retries, Initial: time.Millisecond}, breaker, testClassifier, &logger)
+}
+
+// failing returns an operation failing with the errors in turn and then succeeding, counting the calls.
+func failing(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetrier_Do(t *testing.T) {
+	tests := []struct {
+		name          string
+		errs          []error
+		expectedErr   error
+		expectedCalls int
+	}{
+		{name: "success", expectedCalls: 1},
+		{name: "success after retries", errs: []error{errRetryable, errRetryable}, expectedCalls: 3},
+		{name: "retries used up", errs: []error{errRetryable, errRetryable, errRetryable, errRetryable}, expectedErr: errRetryable, expectedCalls: 4},
+		{name: "permanent error", errs: []error{errPermanent}, expectedErr: errPermanent, expectedCalls: 1},
+		{name: "unavailable error", errs: []error{errUnavailable}, expectedErr: errUnavailable, expectedCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := newTestRetrier(3, nil).Do(context.Background(), failing(&calls, tt.errs...))
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestRetrier_DoStopsOnCancel(t *testing.T) {
+	logger := zerolog.Nop()
+	retrier := NewRetrier(Backoff{Retries: 3, Initial: time.Hour}, nil, testClassifier, &logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	calls := 0
+	err := retrier.Do(ctx, failing(&calls, errRetryable, errRetryable))
+	assert.ErrorIs(t, err, errRetryable)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetrier_DoWithBreaker(t *testing.T) {
+	breaker := NewBreaker(3, time.Hour)
+	retrier := newTestRetrier(5, breaker)
+
+	calls := 0
+	err := retrier.Do(context.Background(), failing(&calls, errRetryable, errRetryable, errRetryable, errRetryable))
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, errRetryable, "the last error is kept")
+	assert.Equal(t, 3, calls, "retries stop when the breaker opens")
+
+	err = retrier.Do(context.Background(), failing(&calls))
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetrier_DoCountsFailures(t *testing.T) {
+	breaker := NewBreaker(2, time.Hour)
+	retrier := newTestRetrier(0, breaker)
+	permanent := func() error { return errPermanent }
+	unavailable := func() error { return errUnavailable }
+
+	require.ErrorIs(t, retrier.Do(context.Background(), permanent), errPermanent)
+	require.ErrorIs(t, retrier.Do(context.Background(), permanent), errPermanent)
+	assert.NoError(t, breaker.Allow(), "permanent errors do not open the breaker")
+	breaker.Success()
+
+	require.ErrorIs(t, retrier.Do(context.Background(), unavailable), errUnavailable)
+	require.ErrorIs(t, retrier.Do(context.Background(), unavailable), errUnavailable)
+	assert.ErrorIs(t, breaker.Allow(), ErrCircuitOpen, "unavailable errors are not retried, but open the breaker")
+}
+
+func TestRetrier_DoHonorsRequestedDelay(t *testing.T) {
+	logger := zerolog.Nop()
+	retrier := NewRetrier(Backoff{Retries: 1, Initial: time.Millisecond, Max: 50 * time.Millisecond}, nil, ClassifyHTTPError, &logger)
+
+	calls := 0
+	start := time.Now()
+	err := retrier.Do(context.Background(), failing(&calls, &StatusError{StatusCode: 503, After: time.Hour}))
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond, "the requested delay is waited for")
+	assert.Less(t, elapsed, time.Second, "up to the maximum delay")
+}